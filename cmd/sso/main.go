@@ -11,6 +11,7 @@ import (
 	"github.com/eragon-mdi/sso/internal/repository"
 	"github.com/eragon-mdi/sso/internal/service"
 	"github.com/eragon-mdi/sso/internal/transport"
+	"github.com/eragon-mdi/sso/internal/transport/clientip"
 
 	rootctx "github.com/eragon-mdi/go-playground/server/root-ctx"
 )
@@ -23,6 +24,12 @@ func main() {
 		log.Fatalf("failed to set logger: %v", err)
 	}
 
+	proxies, err := clientip.ParseProxies(cfg.Servers.TrustedProxies)
+	if err != nil {
+		l.Error(err)
+		return
+	}
+
 	ctx, cancelAppCtx := rootctx.NotifyBackgroundCtxToShutdownSignal()
 	defer cancelAppCtx()
 
@@ -41,7 +48,8 @@ func main() {
 	}
 	t := transport.New(s, l)
 
-	srv := server.New(&cfg.Servers)
+	srv := server.New(&cfg.Servers, transport.GRPCServerOptions(s, l, proxies)...)
+	srv.HTTP().Use(proxies.Middleware)
 	api.RegisterRoutes(srv, t)
 	go func() {
 		if err := srv.StartAll(); err != nil {
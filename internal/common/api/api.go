@@ -1,6 +1,8 @@
 package api
 
 import (
+	"net/http"
+
 	"github.com/eragon-mdi/protos/gen/go/sso/v1"
 	"github.com/eragon-mdi/sso/internal/common/server"
 	"google.golang.org/grpc/reflection"
@@ -9,6 +11,9 @@ import (
 type Transport interface {
 	AuthTransport
 	PermissionTransport
+	SessionTransport
+	WellKnownTransport
+	OAuthTransport
 }
 
 type AuthTransport interface {
@@ -19,10 +24,36 @@ type PermissionTransport interface {
 	sso.PermissionServer
 }
 
+type SessionTransport interface {
+	sso.SessionsServer
+}
+
+type WellKnownTransport interface {
+	JWKS(http.ResponseWriter, *http.Request)
+	OpenIDConfiguration(http.ResponseWriter, *http.Request)
+}
+
+type OAuthTransport interface {
+	AuthorizeForm(http.ResponseWriter, *http.Request)
+	Authorize(http.ResponseWriter, *http.Request)
+	Token(http.ResponseWriter, *http.Request)
+	UserInfo(http.ResponseWriter, *http.Request)
+}
+
 func RegisterRoutes(s server.Server, t Transport) {
 	// grpc
 	sso.RegisterAuthServer(s.GRPC(), t)
 	sso.RegisterPermissionServer(s.GRPC(), t)
+	sso.RegisterSessionsServer(s.GRPC(), t)
 
 	reflection.Register(s.GRPC())
+
+	// http
+	s.HTTP().HandleFunc("GET /.well-known/jwks.json", t.JWKS)
+	s.HTTP().HandleFunc("GET /.well-known/openid-configuration", t.OpenIDConfiguration)
+	s.HTTP().HandleFunc("GET /oauth2/authorize", t.AuthorizeForm)
+	s.HTTP().HandleFunc("POST /oauth2/authorize", t.Authorize)
+	s.HTTP().HandleFunc("POST /oauth2/token", t.Token)
+	s.HTTP().HandleFunc("GET /oauth2/userinfo", t.UserInfo)
+	s.HTTP().HandleFunc("POST /oauth2/userinfo", t.UserInfo)
 }
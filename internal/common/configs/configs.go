@@ -2,7 +2,11 @@ package configs
 
 import (
 	"log"
+	"slices"
 	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
 )
 
 type Config struct {
@@ -49,6 +53,9 @@ type RedisStore struct {
 
 type Servers struct {
 	GRPC Server `envconfig:"GRPC"`
+	HTTP Server `envconfig:"HTTP"`
+	// адреса или подсети прокси, чьим x-forwarded-for / x-real-ip можно верить; пусто — адрес соединения
+	TrustedProxies []string `envconfig:"TRUSTED_PROXIES"`
 }
 
 type Server struct {
@@ -68,9 +75,85 @@ type Logger struct {
 }
 
 type BussinesLogic struct {
-	PassHasherCost       int           `envconfig:"PASS_HASHER_COST" required:"true"`
-	TokenTTL             time.Duration `envconfig:"TOKEN_TTL" required:"true"`
-	PathSecretPrivate    string        `envconfig:"PATH_SECRET_PRIVATE" required:"true"`
-	PathSecretPublic     string        `envconfig:"PATH_SECRET_PUBLIC" required:"true"`
-	SecretForTokerHasher string        `envconfig:"SECRET_FOR_TOKER_HASHER" required:"true"`
+	PassHasherAlgo           string        `envconfig:"PASS_HASHER_ALGO" default:"bcrypt"`  // bcrypt | argon2id
+	PassHasherCost           int           `envconfig:"PASS_HASHER_COST" required:"true"`   // bcrypt cost
+	PassArgon2Memory         uint32        `envconfig:"PASS_ARGON2_MEMORY" default:"65536"` // KiB
+	PassArgon2Iterations     uint32        `envconfig:"PASS_ARGON2_ITERATIONS" default:"3"`
+	PassArgon2Parallelism    uint8         `envconfig:"PASS_ARGON2_PARALLELISM" default:"2"`
+	PasswordPolicyPath       string        `envconfig:"PASSWORD_POLICY_PATH"`    // json: {"default": {...}, "apps": {"<app_id>": {...}}}; пусто — встроенная политика
+	PasswordBlocklistPath    string        `envconfig:"PASSWORD_BLOCKLIST_PATH"` // запрещённые пароли по одному в строке, дополняют встроенный список
+	PwnedPasswordsPath       string        `envconfig:"PWNED_PASSWORDS_PATH"`    // корпус HIBP (SHA-1): каталог range-файлов или отсортированный файл; пусто — не проверять
+	AccessTokenTTL           time.Duration `envconfig:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL          time.Duration `envconfig:"REFRESH_TOKEN_TTL"`
+	TokenTTL                 time.Duration `envconfig:"TOKEN_TTL"` // устаревший общий TTL: подставляется, если ACCESS_/REFRESH_TOKEN_TTL не заданы
+	TokenIssuer              string        `envconfig:"TOKEN_ISSUER" required:"true"`
+	TokenAudience            string        `envconfig:"TOKEN_AUDIENCE" required:"true"`
+	TokenKeysDir             string        `envconfig:"TOKEN_KEYS_DIR"`      // каталог ключей с keyset.json (active/retiring); пусто — пара PATH_SECRET_*
+	TokenSigningAlg          string        `envconfig:"TOKEN_SIGNING_ALG"`   // RS256 | ES256 | EdDSA; пусто — по типу active-ключа
+	PathSecretPrivate        string        `envconfig:"PATH_SECRET_PRIVATE"` // один RSA-ключ без ротации
+	PathSecretPublic         string        `envconfig:"PATH_SECRET_PUBLIC"`
+	SecretForTokerHasher     string        `envconfig:"SECRET_FOR_TOKER_HASHER" required:"true"`
+	PasswordResetTTL         time.Duration `envconfig:"PASSWORD_RESET_TTL" default:"15m"`
+	NotifierFilePath         string        `envconfig:"NOTIFIER_FILE_PATH"` // пусто — уведомления пишутся в stdout
+	EmailVerificationTTL     time.Duration `envconfig:"EMAIL_VERIFICATION_TTL" default:"24h"`
+	MfaChallengeTTL          time.Duration `envconfig:"MFA_CHALLENGE_TTL" default:"5m"`
+	MfaSecretKey             string        `envconfig:"MFA_SECRET_KEY" required:"true"` // base64, 32 байта: AES-256 для TOTP-секретов в БД
+	RequireVerifiedEmailApps []int32       `envconfig:"REQUIRE_VERIFIED_EMAIL_APPS"`    // app_id, куда не пускают без подтверждённого email
+	LoginMaxFailuresPerEmail int           `envconfig:"LOGIN_MAX_FAILURES_PER_EMAIL" default:"5"`
+	LoginMaxFailuresPerIP    int           `envconfig:"LOGIN_MAX_FAILURES_PER_IP" default:"20"`
+	LoginFailureWindow       time.Duration `envconfig:"LOGIN_FAILURE_WINDOW" default:"15m"`
+	LoginLockoutBase         time.Duration `envconfig:"LOGIN_LOCKOUT_BASE" default:"1m"` // первая блокировка, дальше x2 за каждую ошибку
+	LoginLockoutMax          time.Duration `envconfig:"LOGIN_LOCKOUT_MAX" default:"1h"`
+	OAuthCodeTTL             time.Duration `envconfig:"OAUTH_CODE_TTL" default:"1m"`
+	IdentityProvidersPath    string        `envconfig:"IDENTITY_PROVIDERS_PATH"` // JSON с внешними OIDC IdP; пусто — вход через IdP выключен
+	FederationStateTTL       time.Duration `envconfig:"FEDERATION_STATE_TTL" default:"10m"`
+	LdapConfigPath           string        `envconfig:"LDAP_CONFIG_PATH"` // JSON с каталогом LDAP/AD; пусто — вход через каталог выключен
+	PasswordlessTTL          time.Duration `envconfig:"PASSWORDLESS_TTL" default:"10m"`
+	PasswordlessMaxAttempts  int           `envconfig:"PASSWORDLESS_MAX_ATTEMPTS" default:"5"` // неверных кодов на один challenge
+	AppsEnforced             bool          `envconfig:"APPS_ENFORCED" default:"false"`         // false — незарегистрированный app_id входит как до реестра apps (password, refresh_token)
+	DevicesGraceUntil        time.Time     `envconfig:"DEVICES_GRACE_UNTIL"`                   // RFC 3339; до этого момента legacy device_id и Refresh с device_id = 0 работают без реестра
+}
+
+// tokenTTLs — ACCESS_TOKEN_TTL и REFRESH_TOKEN_TTL, по умолчанию из TOKEN_TTL
+func (b *BussinesLogic) tokenTTLs() error {
+	if b.AccessTokenTTL == 0 {
+		b.AccessTokenTTL = b.TokenTTL
+	}
+	if b.RefreshTokenTTL == 0 {
+		b.RefreshTokenTTL = b.TokenTTL
+	}
+	if b.AccessTokenTTL <= 0 || b.RefreshTokenTTL <= 0 {
+		return errors.New("ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL (or legacy TOKEN_TTL) required")
+	}
+	// access, переживший refresh, выпадает из индекса отзыва вместе с сессией
+	if b.AccessTokenTTL > b.RefreshTokenTTL {
+		return errors.New("ACCESS_TOKEN_TTL must not exceed REFRESH_TOKEN_TTL")
+	}
+	return nil
+}
+
+func (b *BussinesLogic) RequiresVerifiedEmail(appID int32) bool {
+	return slices.Contains(b.RequireVerifiedEmailApps, appID)
+}
+
+// DeviceGrace — идёт переход на выданные сервером device_id (см. DevicesGraceUntil)
+func (b *BussinesLogic) DeviceGrace(now time.Time) bool {
+	return now.Before(b.DevicesGraceUntil)
+}
+
+func (b *BussinesLogic) EmailLockoutPolicy() domain.LockoutPolicy {
+	return b.lockoutPolicy(b.LoginMaxFailuresPerEmail)
+}
+
+func (b *BussinesLogic) IPLockoutPolicy() domain.LockoutPolicy {
+	return b.lockoutPolicy(b.LoginMaxFailuresPerIP)
+}
+
+func (b *BussinesLogic) lockoutPolicy(maxFailures int) domain.LockoutPolicy {
+	return domain.LockoutPolicy{
+		MaxFailures: maxFailures,
+		Window:      b.LoginFailureWindow,
+		BaseLockout: b.LoginLockoutBase,
+		MaxLockout:  b.LoginLockoutMax,
+	}
 }
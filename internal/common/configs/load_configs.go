@@ -21,6 +21,9 @@ func loadCfg(c *Config) error {
 	if err := envconfig.Process("", c); err != nil {
 		return errors.Join(errors.Wrap(err, ErrUnmarshalCfgsFromFile), errLoad)
 	}
+	if err := c.BussinesLogic.tokenTTLs(); err != nil {
+		return errors.Wrap(err, ErrUnmarshalCfgsFromFile)
+	}
 
 	return nil
 }
@@ -15,9 +15,9 @@ type GrpcSrv struct {
 	port string
 }
 
-func New(cfg configs.Server) *GrpcSrv {
+func New(cfg configs.Server, opts ...grpc.ServerOption) *GrpcSrv {
 	return &GrpcSrv{
-		Server: grpc.NewServer(),
+		Server: grpc.NewServer(opts...),
 		port:   cfg.PortF,
 	}
 }
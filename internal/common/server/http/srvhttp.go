@@ -0,0 +1,51 @@
+package srvhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/eragon-mdi/sso/internal/common/configs"
+	"github.com/go-faster/errors"
+)
+
+type HttpSrv struct {
+	*http.ServeMux
+
+	srv *http.Server
+}
+
+func New(cfg configs.Server) *HttpSrv {
+	mux := http.NewServeMux()
+	return &HttpSrv{
+		ServeMux: mux,
+		srv: &http.Server{
+			Addr:              net.JoinHostPort(cfg.Address(), cfg.Port()),
+			Handler:           mux,
+			ReadTimeout:       cfg.ReadTimeout(),
+			WriteTimeout:      cfg.WriteTimeout(),
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout(),
+			IdleTimeout:       cfg.IdleTimeout(),
+		},
+	}
+}
+
+// Use оборачивает все маршруты в middleware; вызывать до Serve
+func (s *HttpSrv) Use(mw func(http.Handler) http.Handler) {
+	s.srv.Handler = mw(s.srv.Handler)
+}
+
+// Try open listener port from cfg && start http-srv
+// Use in gorutine!
+func (s *HttpSrv) Serve() error {
+	if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Wrap(err, "failed start httpSrv:")
+	}
+
+	return nil
+}
+
+// Shutdown — дожидается активных запросов, пока не истечёт ctx
+func (s *HttpSrv) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
@@ -1,57 +1,79 @@
 package server
 
 import (
+	"context"
+	"time"
+
 	"github.com/eragon-mdi/sso/internal/common/configs"
 	srvgrpc "github.com/eragon-mdi/sso/internal/common/server/grpc"
-	"golang.org/x/sync/errgroup"
+	srvhttp "github.com/eragon-mdi/sso/internal/common/server/http"
+	"google.golang.org/grpc"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 type Server interface {
 	StartAll() error
 	GracefulShutdown() error
 
 	GRPC() *srvgrpc.GrpcSrv
-	//todo other servers, example REST
+	HTTP() *srvhttp.HttpSrv
 }
 
 type server struct {
 	grpc *srvgrpc.GrpcSrv
+	http *srvhttp.HttpSrv
 }
 
-func New(cfg *configs.Servers) Server {
+// grpcOpts — интерсепторы и прочие настройки gRPC-сервера
+func New(cfg *configs.Servers, grpcOpts ...grpc.ServerOption) Server {
 	return &server{
-		grpc: srvgrpc.New(cfg.GRPC),
+		grpc: srvgrpc.New(cfg.GRPC, grpcOpts...),
+		http: srvhttp.New(cfg.HTTP),
 	}
 }
 
+// StartAll — ошибка любого сервера возвращается сразу, не дожидаясь остальных:
+// иначе при занятом порту HTTP приложение продолжило бы работать с одним gRPC
 func (s *server) StartAll() error {
-	eg := errgroup.Group{}
-	defer func() {
-		_ = eg.Wait()
-	}()
+	servers := []func() error{
+		s.GRPC().Serve,
+		s.HTTP().Serve,
+	}
 
-	eg.Go(func() error {
-		return s.GRPC().Serve()
-	})
+	errs := make(chan error, len(servers))
+	for _, serve := range servers {
+		go func() {
+			errs <- serve()
+		}()
+	}
 
-	//eg.Go(func() error {
-	//	return http.ListenAndServe("0.0.0.0:7070", nil)
-	//})
+	for range servers {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
 
-	return eg.Wait()
+	return nil
 }
 
 func (s *server) GRPC() *srvgrpc.GrpcSrv {
 	return s.grpc
 }
 
+func (s *server) HTTP() *srvhttp.HttpSrv {
+	return s.http
+}
+
 // waiting all
 func (s *server) GracefulShutdown() error {
 	s.grpc.GracefulStop()
 
-	// if err := s.rest.Shutdown(context.Background()); err != nil {
-	// return err
-	// }
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.http.Shutdown(ctx); err != nil {
+		return err
+	}
 
 	return nil
 }
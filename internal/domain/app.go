@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+type AppStatus string
+
+const (
+	AppActive   AppStatus = "active"
+	AppDisabled AppStatus = "disabled"
+)
+
+// GrantPassword — вход по email и паролю (Login, CompleteMfaLogin)
+const GrantPassword = "password"
+
+// AppGrantTypes — grant-ы, которые можно разрешить приложению; client_credentials — у машинных клиентов
+var AppGrantTypes = []string{GrantPassword, GrantRefreshToken, GrantAuthorizationCode, GrantFederated, GrantPasswordless}
+
+// App — приложение из реестра; app_id в DeviceCtx и client_id в /oauth2 — его ID.
+// Нулевой TTL — значение по умолчанию из конфига, пустой EmailDomains — без ограничений
+type App struct {
+	ID              int32
+	Name            string
+	Status          AppStatus
+	GrantTypes      []string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	EmailDomains    []string
+	RedirectURIs    []string
+	Scopes          []string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (a App) Active() bool {
+	return a.Status == AppActive
+}
+
+func (a App) AllowsGrant(grant string) bool {
+	return slices.Contains(a.GrantTypes, grant)
+}
+
+// AllowsEmail — домен email (после последней @) в списке приложения, без учёта регистра
+func (a App) AllowsEmail(email string) bool {
+	if len(a.EmailDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	return slices.Contains(a.EmailDomains, strings.ToLower(email[at+1:]))
+}
+
+// RedirectAllowed — только точное совпадение с зарегистрированным адресом
+func (a App) RedirectAllowed(uri string) bool {
+	return slices.Contains(a.RedirectURIs, uri)
+}
+
+func (a App) ScopesAllowed(scopes []string) bool {
+	return scopesSubset(scopes, a.Scopes)
+}
+
+func (a App) AccessTTL(def time.Duration) time.Duration {
+	if a.AccessTokenTTL > 0 {
+		return a.AccessTokenTTL
+	}
+	return def
+}
+
+func (a App) RefreshTTL(def time.Duration) time.Duration {
+	if a.RefreshTokenTTL > 0 {
+		return a.RefreshTokenTTL
+	}
+	return def
+}
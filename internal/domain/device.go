@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// UnregisteredDevice — device_id до RegisterDevice (и у сессий OAuth): устройство не выдано сервером
+const UnregisteredDevice int32 = 0
+
+// IssuedDevice — device_id выдан сервером. Выданные id отрицательные: положительные
+// клиенты выбирали сами до реестра устройств (legacy), диапазоны не пересекаются
+func IssuedDevice(id int32) bool {
+	return id < 0
+}
+
+// Device — устройство, выданное сервером (RegisterDevice) и привязанное к пользователю
+type Device struct {
+	ID          int32
+	UserID      string
+	Platform    string
+	Name        string
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+}
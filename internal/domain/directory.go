@@ -0,0 +1,11 @@
+package domain
+
+// DirectoryProvider — provider в identities для пользователей внешнего каталога (LDAP / AD)
+const DirectoryProvider = "ldap"
+
+// DirectoryUser — пользователь каталога, прошедший bind
+type DirectoryUser struct {
+	DN      string
+	Subject string   // стабильный id в каталоге (id_attribute или DN), subject в identities
+	Roles   []string // роли SSO по группам каталога
+}
@@ -6,4 +6,14 @@ var (
 	ErrNotFound   = errors.New("no content found")
 	ErrValidation = errors.New("bad expertion")
 	ErrDuplicate  = errors.New("duplicate")
+	ErrTokenReuse = errors.New("refresh token reuse")
+
+	ErrEmailNotVerified  = errors.New("email not verified")
+	ErrTooManyAttempts   = errors.New("too many failed attempts")
+	ErrWeakPassword      = errors.New("password does not satisfy policy")
+	ErrMfaRequired       = errors.New("second factor required")
+	ErrInsufficientScope = errors.New("insufficient scope")
+	ErrAppAccessDenied   = errors.New("app access denied")
+	// ErrNotDelivered — операция выполнена, не ушло только уведомление пользователю
+	ErrNotDelivered = errors.New("notification not delivered")
 )
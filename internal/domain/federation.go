@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// GrantFederated — вход через внешний IdP (BeginFederatedLogin, CompleteFederatedLogin)
+const GrantFederated = "federated"
+
+// ExternalIdentity — личность из проверенного id_token внешнего IdP
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string // sub у IdP, стабилен в пределах провайдера
+	Email         string
+	EmailVerified bool
+}
+
+// Identity — привязка учётной записи внешнего IdP к пользователю
+type Identity struct {
+	Provider  string
+	Subject   string
+	UserID    string
+	Email     string // email у IdP на момент привязки
+	CreatedAt time.Time
+}
+
+// FederationState — незавершённый вход через IdP: по хэшу state хранятся PKCE verifier, nonce и устройство
+type FederationState struct {
+	Hash         string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	Ctx          DeviceCtx
+	Exp          time.Time
+}
+
+// FederatedLogin — адрес входа у IdP; state вернётся в redirect вместе с code
+type FederatedLogin struct {
+	AuthURL string
+	State   string
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// Introspection — состояние токена по RFC 7662. У неактивного токена заполнено только Active
+type Introspection struct {
+	Active    bool
+	ID        string
+	Type      TokenType
+	Issuer    string
+	Audience  string
+	UserID    string
+	ClientID  string // машинный клиент, UserID тогда пуст
+	SessionID string
+	Ctx       DeviceCtx
+	IssuedAt  time.Time
+	Exp       time.Time
+	Scopes    []string
+	Roles     []string
+}
+
+func NewActiveIntrospection(m Meta, roles []string) Introspection {
+	return Introspection{
+		Active:    true,
+		ID:        m.ID,
+		Type:      m.Type,
+		Issuer:    m.Issuer,
+		Audience:  m.Audience,
+		UserID:    m.UserID,
+		ClientID:  m.ClientID,
+		SessionID: m.FamilyID,
+		Ctx:       m.Ctx,
+		IssuedAt:  m.IssuedAt,
+		Exp:       m.Exp,
+		Scopes:    m.Scopes,
+		Roles:     roles,
+	}
+}
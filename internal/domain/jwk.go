@@ -0,0 +1,19 @@
+package domain
+
+// JWK — открытый ключ подписи токенов (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`   // RSA modulus, base64url
+	E   string `json:"e,omitempty"`   // RSA exponent, base64url
+	Crv string `json:"crv,omitempty"` // EC: P-256, OKP: Ed25519
+	X   string `json:"x,omitempty"`   // EC/OKP, base64url
+	Y   string `json:"y,omitempty"`   // EC, base64url
+}
+
+// JWKS — набор открытых ключей, которыми проверяются выданные токены
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LockoutPolicy — порог неудачных входов и экспоненциальная блокировка:
+// после MaxFailures ошибок в окне Window блокировка BaseLockout, затем x2 за каждую следующую, не больше MaxLockout
+type LockoutPolicy struct {
+	MaxFailures int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// LockoutError — вход временно заблокирован; errors.Is(err, ErrTooManyAttempts)
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s: retry after %s", ErrTooManyAttempts, e.RetryAfter)
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+type clientIPKey struct{}
+
+// WithClientIP кладёт в контекст адрес клиента (определяется транспортом)
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"crypto/subtle"
+	"time"
+)
+
+// MachineClient — сервис, который получает токены сам за себя (client_credentials), без пользователя.
+// Секрет хранится только хэшем; открытый секрет отдаётся один раз — при создании и ротации
+type MachineClient struct {
+	ID              string
+	Name            string
+	SecretHash      []byte
+	Scopes          []string
+	CreatedAt       time.Time
+	SecretRotatedAt time.Time
+	DisabledAt      *time.Time
+}
+
+func NewMachineClient(id, name string, secretHash []byte, scopes []string) MachineClient {
+	return MachineClient{
+		ID:         id,
+		Name:       name,
+		SecretHash: secretHash,
+		Scopes:     scopes,
+	}
+}
+
+func (c MachineClient) Disabled() bool {
+	return c.DisabledAt != nil
+}
+
+func (c MachineClient) SecretMatches(hash []byte) bool {
+	return subtle.ConstantTimeCompare(c.SecretHash, hash) == 1
+}
+
+// GrantScopes — запрошенные scopes, если все разрешены клиенту; без запроса — все разрешённые
+func (c MachineClient) GrantScopes(requested []string) ([]string, bool) {
+	if len(requested) == 0 {
+		return c.Scopes, true
+	}
+	return requested, scopesSubset(requested, c.Scopes)
+}
+
+// MachineClientSecret — ответ на создание и ротацию: единственный момент, когда секрет виден
+type MachineClientSecret struct {
+	ClientID     string
+	ClientSecret string
+}
+
+// ValidScope — scope-token по RFC 6749, 3.3: печатные ASCII без пробела, '"' и '\'
+func ValidScope(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x21 || r > 0x7e || r == '"' || r == '\\' {
+			return false
+		}
+	}
+	return true
+}
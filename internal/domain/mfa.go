@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// LoginResult — итог первого шага входа: либо пара токенов, либо MFA-челлендж
+type LoginResult struct {
+	Token
+	MfaChallenge string // непусто — нужен второй шаг CompleteMfaLogin
+}
+
+func (r LoginResult) MfaRequired() bool {
+	return r.MfaChallenge != ""
+}
+
+// UserMfa — TOTP пользователя; секрет хранится только в зашифрованном виде
+type UserMfa struct {
+	UserID          string
+	EncryptedSecret []byte
+	ConfirmedAt     *time.Time // nil — регистрация TOTP начата, но не подтверждена
+	LastUsedStep    int64      // последний принятый временной шаг TOTP (защита от повтора кода)
+}
+
+func (m UserMfa) Enabled() bool {
+	return m.ConfirmedAt != nil
+}
+
+// TotpEnrollment — данные для добавления аккаунта в приложение-аутентификатор
+type TotpEnrollment struct {
+	Secret string // base32
+	URI    string // otpauth://totp/...
+}
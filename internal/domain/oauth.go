@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"slices"
+	"time"
+)
+
+const (
+	ResponseTypeCode       = "code"
+	PKCEMethodS256         = "S256"
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+// Коды ошибок OAuth 2.0 (RFC 6749, 4.1.2.1 и 5.2)
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+)
+
+// OAuthError — ошибка протокола, которую видит клиент.
+// Redirect — можно вернуть на redirect_uri: клиент и redirect_uri уже проверены
+type OAuthError struct {
+	Code        string
+	Description string
+	Redirect    bool
+}
+
+func NewOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func scopesSubset(scopes, allowed []string) bool {
+	for _, s := range scopes {
+		if !slices.Contains(allowed, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// AuthorizeRequest — параметры /authorize
+type AuthorizeRequest struct {
+	ClientID            int32
+	RedirectURI         string
+	ResponseType        string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string // OIDC: возвращается в id_token как есть
+}
+
+// AuthorizationCode — выданный код; хранится только хэш, живёт секунды и погашается один раз
+type AuthorizationCode struct {
+	Hash          string
+	UserID        string
+	ClientID      int32
+	RedirectURI   string
+	Scopes        []string
+	CodeChallenge string
+	Exp           time.Time
+	// для id_token: как и когда пользователь вошёл
+	Nonce    string
+	AuthTime time.Time
+	AMR      []string
+}
+
+func NewAuthorizationCode(hash, userID string, req AuthorizeRequest, amr []string, ttl time.Duration) AuthorizationCode {
+	now := time.Now()
+	return AuthorizationCode{
+		Hash:          hash,
+		UserID:        userID,
+		ClientID:      req.ClientID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        req.Scopes,
+		CodeChallenge: req.CodeChallenge,
+		Exp:           now.Add(ttl),
+		Nonce:         req.Nonce,
+		AuthTime:      now,
+		AMR:           amr,
+	}
+}
+
+// VerifyPKCE — S256: BASE64URL(SHA256(code_verifier)) == code_challenge (RFC 7636, 4.6)
+func (c AuthorizationCode) VerifyPKCE(verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(c.CodeChallenge)) == 1
+}
+
+// CodeExchange — grant_type=authorization_code
+type CodeExchange struct {
+	Code         string
+	ClientID     int32
+	RedirectURI  string
+	CodeVerifier string
+}
+
+// OAuthToken — ответ /token
+type OAuthToken struct {
+	Token
+	IDToken   string // только authorization_code со scope openid
+	ExpiresIn time.Duration
+	Scopes    []string // пусто — те же, что запрошены
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	ScopeOpenID = "openid"
+	ScopeEmail  = "email"
+)
+
+// Методы аутентификации для claim amr (RFC 8176)
+const (
+	AMRPassword = "pwd"
+	AMROtp      = "otp"
+	AMRMfa      = "mfa"
+)
+
+// IDToken — id_token OpenID Connect: кто вошёл, когда и как; iss проставляет tokener при подписи
+type IDToken struct {
+	Issuer   string
+	Subject  string
+	Audience string // client_id
+	IssuedAt time.Time
+	Exp      time.Time
+	AuthTime time.Time
+	Nonce    string
+	AMR      []string
+	// только при scope email
+	Email         string
+	EmailVerified bool
+}
+
+func (t IDToken) Claims() map[string]any {
+	claims := map[string]any{
+		"iss":       t.Issuer,
+		"sub":       t.Subject,
+		"aud":       t.Audience,
+		"iat":       t.IssuedAt.Unix(),
+		"exp":       t.Exp.Unix(),
+		"auth_time": t.AuthTime.Unix(),
+		"amr":       t.AMR,
+	}
+	if t.Nonce != "" {
+		claims["nonce"] = t.Nonce
+	}
+	if t.Email != "" {
+		claims["email"] = t.Email
+		claims["email_verified"] = t.EmailVerified
+	}
+	return claims
+}
+
+// UserInfo — ответ userinfo; email только при scope email
+type UserInfo struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// OpenIDConfiguration — /.well-known/openid-configuration (OpenID Connect Discovery 1.0)
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+func HasScope(scopes []string, scope string) bool {
+	return slices.Contains(scopes, scope)
+}
@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// OneTimePurpose — назначение одноразового токена (сброс пароля и т.п.)
+type OneTimePurpose string
+
+const (
+	PurposePasswordReset     OneTimePurpose = "password_reset"
+	PurposeEmailVerification OneTimePurpose = "email_verification"
+	PurposeMfaChallenge      OneTimePurpose = "mfa_challenge"
+	PurposePasswordlessLink  OneTimePurpose = "passwordless_link"
+	PurposePasswordlessCode  OneTimePurpose = "passwordless_code"
+)
+
+// OneTimeToken — одноразовый токен; хранится только хэш, сам токен уходит пользователю через Notifier
+type OneTimeToken struct {
+	Hash    string
+	UserID  string
+	Purpose OneTimePurpose
+	Ctx     DeviceCtx // устройство, к которому привязан токен (если важно)
+	Exp     time.Time
+}
+
+func NewOneTimeToken(hash, userID string, purpose OneTimePurpose, ttl time.Duration) OneTimeToken {
+	return OneTimeToken{
+		Hash:    hash,
+		UserID:  userID,
+		Purpose: purpose,
+		Exp:     time.Now().Add(ttl),
+	}
+}
+
+func (t *OneTimeToken) SetCtx(dctx DeviceCtx) {
+	t.Ctx = dctx
+}
+
+// Notification — сообщение пользователю с одноразовым токеном
+type Notification struct {
+	Purpose   OneTimePurpose
+	To        string
+	Token     string
+	ExpiresAt time.Time
+}
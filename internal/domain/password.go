@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// правила политики паролей
+const (
+	RuleMinLength     = "min_length"
+	RuleMaxLength     = "max_length"
+	RuleUpper         = "upper"
+	RuleLower         = "lower"
+	RuleDigit         = "digit"
+	RuleSymbol        = "symbol"
+	RuleCommon        = "common"
+	RuleContainsEmail = "contains_email"
+	RuleReused        = "reused"
+	RuleBreached      = "breached"
+)
+
+// PasswordViolation — нарушенное правило политики паролей
+type PasswordViolation struct {
+	Rule    string
+	Message string
+}
+
+// PasswordPolicyError — все нарушения сразу; errors.Is(err, ErrWeakPassword)
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return fmt.Sprintf("%s: %s", ErrWeakPassword, strings.Join(rules, ", "))
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
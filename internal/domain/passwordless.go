@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// GrantPasswordless — вход без пароля по ссылке или коду из письма (StartPasswordlessLogin, CompletePasswordlessLogin)
+const GrantPasswordless = "passwordless"
+
+// PasswordlessMethod — чем пользователь подтверждает владение email
+type PasswordlessMethod string
+
+const (
+	PasswordlessLink PasswordlessMethod = "link" // токен ссылки из письма
+	PasswordlessCode PasswordlessMethod = "code" // 6 цифр из письма вместе с challenge из ответа StartPasswordlessLogin
+)
+
+// PasswordlessChallenge — незавершённый вход без пароля. Hash — хэш токена ссылки или challenge;
+// для кода хранится и хэш кода, каждая ошибка ввода тратит попытку
+type PasswordlessChallenge struct {
+	Hash     string
+	CodeHash string // пусто — вход по ссылке
+	UserID   string
+	Email    string
+	Ctx      DeviceCtx
+	Attempts int
+	Exp      time.Time
+}
+
+// PasswordlessLogin — ответ StartPasswordlessLogin. Для ссылки Challenge пуст: токен есть только в письме
+type PasswordlessLogin struct {
+	Challenge string
+	ExpiresAt time.Time
+}
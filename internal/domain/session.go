@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// Session — семейство refresh-токенов одного входа (login на конкретном устройстве).
+// ID сессии совпадает с FamilyID и не меняется при ротации.
+type Session struct {
+	ID              string
+	UserID          string
+	Ctx             DeviceCtx
+	CreatedAt       time.Time
+	LastRefreshedAt time.Time
+	ExpiresAt       time.Time
+}
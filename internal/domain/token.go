@@ -2,7 +2,10 @@ package domain
 
 import (
 	"errors"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Token struct {
@@ -25,10 +28,26 @@ func (t *Token) SetRefresh(token string) {
 	t.Refresh = token
 }
 
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+)
+
 type Meta struct {
-	Exp    time.Time
-	UserID string
-	Ctx    DeviceCtx
+	ID       string
+	Type     TokenType
+	Issuer   string
+	Audience string
+	IssuedAt time.Time
+	Exp      time.Time
+	UserID   string
+	FamilyID string // семейство refresh-токенов одной сессии (login -> refresh -> ...); в access — claim sid
+	Ctx      DeviceCtx
+	Scopes   []string
+	Roles    []string // только в access: роли пользователя на момент выдачи
+	ClientID string   // только в access: машинный клиент (client_credentials), UserID тогда пуст
 }
 
 type DeviceCtx struct {
@@ -36,12 +55,49 @@ type DeviceCtx struct {
 	DeviceID int32
 }
 
-func NewMeta(ttl time.Duration, userId string, appID, deviceId int32) Meta {
+func NewAccessMeta(ttl time.Duration, userId string, appID, deviceId int32) Meta {
+	return newMeta(TokenTypeAccess, ttl, userId, appID, deviceId)
+}
+
+func NewRefreshMeta(ttl time.Duration, userId string, appID, deviceId int32) Meta {
+	return newMeta(TokenTypeRefresh, ttl, userId, appID, deviceId)
+}
+
+// iss/aud проставляет tokener при подписи
+func newMeta(typ TokenType, ttl time.Duration, userId string, appID, deviceId int32) Meta {
+	now := time.Now()
 	return Meta{
-		Exp:    time.Now().Add(ttl),
-		UserID: userId,
-		Ctx:    NewDeviceCtx(appID, deviceId),
+		ID:       uuid.NewString(),
+		Type:     typ,
+		IssuedAt: now,
+		Exp:      now.Add(ttl),
+		UserID:   userId,
+		Ctx:      NewDeviceCtx(appID, deviceId),
+	}
+}
+
+// NewClientAccessMeta — access-токен машинного клиента: sub = client_id, без сессии и устройства
+func NewClientAccessMeta(ttl time.Duration, clientID string, scopes []string) Meta {
+	m := newMeta(TokenTypeAccess, ttl, "", 0, 0)
+	m.ClientID = clientID
+	m.Scopes = scopes
+	return m
+}
+
+// Subject — claim sub: пользователь или, для client_credentials, машинный клиент
+func (m Meta) Subject() string {
+	if m.UserID == "" {
+		return m.ClientID
 	}
+	return m.UserID
+}
+
+func (m *Meta) SetFamily(id string) {
+	m.FamilyID = id
+}
+
+func (m *Meta) SetRoles(roles []string) {
+	m.Roles = roles
 }
 
 func NewDeviceCtx(appID, deviceId int32) DeviceCtx {
@@ -54,6 +110,9 @@ func NewDeviceCtx(appID, deviceId int32) DeviceCtx {
 type RefreshToken struct {
 	Token string
 	Meta  Meta
+	// access-токен, выданный в паре: при отзыве сессии его jti уходит в denylist
+	AccessID  string
+	AccessExp time.Time
 }
 
 func NewRefreshTorken(t string, m Meta) RefreshToken {
@@ -63,22 +122,144 @@ func NewRefreshTorken(t string, m Meta) RefreshToken {
 	}
 }
 
+func (rt *RefreshToken) BindAccess(m Meta) {
+	rt.AccessID = m.ID
+	rt.AccessExp = m.Exp
+}
+
 func (dctx DeviceCtx) Compare(otherDctx DeviceCtx) bool {
 	return dctx.AppId == otherDctx.AppId && dctx.DeviceID == otherDctx.DeviceID
 }
 
 // / implement for tokener.Claims interface
+// access: iss, aud, sub, sid, roles, client_id, iat, nbf, exp, jti, typ + device ctx
+// refresh: iss, user_id, fid, iat, exp, jti, typ + device ctx
+// scope (через пробел) — у обоих, если задан
 func (m Meta) Claims() map[string]any {
-	return map[string]any{
-		"user_id":   m.UserID,
+	claims := map[string]any{
+		"jti":       m.ID,
+		"typ":       string(m.Type),
 		"app_id":    m.Ctx.AppId,
 		"device_id": m.Ctx.DeviceID,
+		"iat":       m.IssuedAt.Unix(),
 		"exp":       m.Exp.Unix(),
 	}
+	if m.Issuer != "" {
+		claims["iss"] = m.Issuer
+	}
+	if len(m.Scopes) > 0 {
+		claims["scope"] = strings.Join(m.Scopes, " ")
+	}
+
+	switch m.Type {
+	case TokenTypeAccess:
+		claims["sub"] = m.Subject()
+		claims["nbf"] = m.IssuedAt.Unix()
+		if m.FamilyID != "" {
+			claims["sid"] = m.FamilyID
+		}
+		if m.Audience != "" {
+			claims["aud"] = m.Audience
+		}
+		if len(m.Roles) > 0 {
+			claims["roles"] = m.Roles
+		}
+		if m.ClientID != "" {
+			claims["client_id"] = m.ClientID
+		}
+	default:
+		claims["user_id"] = m.UserID
+		claims["fid"] = m.FamilyID
+	}
+
+	return claims
+}
+
+// Legacy — refresh-токен, выданный до разделения access/refresh: без typ, jti и семейства.
+// Принимается только для обмена на новую сессию, пока не истечёт
+func (m Meta) Legacy() bool {
+	return m.Type == TokenTypeRefresh && m.ID == ""
 }
 
 // / implement for tokenerAdapter.UnClaims interface
 func (m *Meta) UnClaims(claims map[string]any) error {
+	if _, ok := claims["typ"]; !ok {
+		return m.unClaimsLegacy(claims)
+	}
+
+	typ, ok := claims["typ"].(string)
+	if !ok || (TokenType(typ) != TokenTypeAccess && TokenType(typ) != TokenTypeRefresh) {
+		return errors.New("claims: missing or invalid typ")
+	}
+
+	userClaim := "user_id"
+	if TokenType(typ) == TokenTypeAccess {
+		userClaim = "sub"
+	}
+	sub, ok := claims[userClaim].(string)
+	if !ok || sub == "" {
+		return errors.New("claims: missing or invalid " + userClaim)
+	}
+
+	fid, _ := claims["fid"].(string)
+	if TokenType(typ) == TokenTypeAccess {
+		// sid нет у access-токенов, выданных до интроспекции
+		fid, _ = claims["sid"].(string)
+	}
+	if TokenType(typ) == TokenTypeRefresh && fid == "" {
+		return errors.New("claims: missing or invalid fid")
+	}
+
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return errors.New("claims: missing or invalid jti")
+	}
+
+	appF, ok := claims["app_id"].(float64)
+	if !ok {
+		return errors.New("claims: missing or invalid app_id")
+	}
+
+	devF, ok := claims["device_id"].(float64)
+	if !ok {
+		return errors.New("claims: missing or invalid device_id")
+	}
+
+	iatF, ok := claims["iat"].(float64)
+	if !ok {
+		return errors.New("claims: missing or invalid iat")
+	}
+
+	expF, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("claims: missing or invalid exp")
+	}
+
+	m.ID = jti
+	m.Type = TokenType(typ)
+	m.Issuer, _ = claims["iss"].(string)
+	m.Audience = audience(claims["aud"])
+	m.UserID = sub
+	m.FamilyID = fid
+	m.Ctx = NewDeviceCtx(int32(appF), int32(devF))
+	m.IssuedAt = time.Unix(int64(iatF), 0)
+	m.Exp = time.Unix(int64(expF), 0)
+	if scope, _ := claims["scope"].(string); scope != "" {
+		m.Scopes = strings.Fields(scope)
+	}
+	if m.Type == TokenTypeAccess {
+		m.Roles = stringList(claims["roles"])
+		// токен машинного клиента: sub = client_id, пользователя нет
+		if clientID, _ := claims["client_id"].(string); clientID != "" && clientID == sub {
+			m.ClientID = clientID
+			m.UserID = ""
+		}
+	}
+	return nil
+}
+
+// unClaimsLegacy — прежний набор claims: user_id, app_id, device_id, exp
+func (m *Meta) unClaimsLegacy(claims map[string]any) error {
 	sub, ok := claims["user_id"].(string)
 	if !ok || sub == "" {
 		return errors.New("claims: missing or invalid user_id")
@@ -99,8 +280,40 @@ func (m *Meta) UnClaims(claims map[string]any) error {
 		return errors.New("claims: missing or invalid exp")
 	}
 
+	m.Type = TokenTypeRefresh
 	m.UserID = sub
 	m.Ctx = NewDeviceCtx(int32(appF), int32(devF))
 	m.Exp = time.Unix(int64(expF), 0)
 	return nil
 }
+
+// aud по RFC 7519 может быть строкой или массивом строк
+func audience(v any) string {
+	switch aud := v.(type) {
+	case string:
+		return aud
+	case []any:
+		if len(aud) > 0 {
+			s, _ := aud[0].(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// после JSON массив приходит как []any, до сериализации — как []string
+func stringList(v any) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []any:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
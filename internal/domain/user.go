@@ -1,9 +1,15 @@
 package domain
 
+import "time"
+
+// RoleAdmin — администратор SSO: реестр приложений, машинные клиенты, блокировки, сессии любых пользователей
+const RoleAdmin = "admin"
+
 type User struct {
-	ID       string
-	Email    string
-	Password string
+	ID              string
+	Email           string
+	Password        string     // хэш; пусто — пароль проверяет внешний каталог (LDAP)
+	EmailVerifiedAt *time.Time // nil — email не подтверждён
 }
 
 func (u *User) SetID(id string) {
@@ -13,3 +19,12 @@ func (u *User) SetID(id string) {
 func (u *User) SetPass(pass string) {
 	u.Password = pass
 }
+
+func (u User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// HasLocalPassword — пароль хранится в SSO; у пользователей из каталога его нет
+func (u User) HasLocalPassword() bool {
+	return u.Password != ""
+}
@@ -0,0 +1,38 @@
+package redisrepo
+
+import (
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+)
+
+type federationStateMeta struct {
+	Provider     string    `json:"provider"`
+	CodeVerifier string    `json:"code_verifier"`
+	Nonce        string    `json:"nonce"`
+	AppID        int32     `json:"app_id"`
+	DeviceID     int32     `json:"device_id"`
+	Exp          time.Time `json:"exp"`
+}
+
+func newFederationStateMeta(st domain.FederationState) *federationStateMeta {
+	return &federationStateMeta{
+		Provider:     st.Provider,
+		CodeVerifier: st.CodeVerifier,
+		Nonce:        st.Nonce,
+		AppID:        st.Ctx.AppId,
+		DeviceID:     st.Ctx.DeviceID,
+		Exp:          st.Exp,
+	}
+}
+
+func (m federationStateMeta) toDomain(hash string) domain.FederationState {
+	return domain.FederationState{
+		Hash:         hash,
+		Provider:     m.Provider,
+		CodeVerifier: m.CodeVerifier,
+		Nonce:        m.Nonce,
+		Ctx:          domain.NewDeviceCtx(m.AppID, m.DeviceID),
+		Exp:          m.Exp,
+	}
+}
@@ -0,0 +1,48 @@
+package redisrepo
+
+import (
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+)
+
+type authCodeMeta struct {
+	UserID        string    `json:"user_id"`
+	ClientID      int32     `json:"client_id"`
+	RedirectURI   string    `json:"redirect_uri"`
+	Scopes        []string  `json:"scopes,omitempty"`
+	CodeChallenge string    `json:"code_challenge"`
+	Exp           time.Time `json:"exp"`
+	Nonce         string    `json:"nonce,omitempty"`
+	AuthTime      time.Time `json:"auth_time"`
+	AMR           []string  `json:"amr,omitempty"`
+}
+
+func newAuthCodeMeta(c domain.AuthorizationCode) *authCodeMeta {
+	return &authCodeMeta{
+		UserID:        c.UserID,
+		ClientID:      c.ClientID,
+		RedirectURI:   c.RedirectURI,
+		Scopes:        c.Scopes,
+		CodeChallenge: c.CodeChallenge,
+		Exp:           c.Exp,
+		Nonce:         c.Nonce,
+		AuthTime:      c.AuthTime,
+		AMR:           c.AMR,
+	}
+}
+
+func (m authCodeMeta) toDomain(hash string) domain.AuthorizationCode {
+	return domain.AuthorizationCode{
+		Hash:          hash,
+		UserID:        m.UserID,
+		ClientID:      m.ClientID,
+		RedirectURI:   m.RedirectURI,
+		Scopes:        m.Scopes,
+		CodeChallenge: m.CodeChallenge,
+		Exp:           m.Exp,
+		Nonce:         m.Nonce,
+		AuthTime:      m.AuthTime,
+		AMR:           m.AMR,
+	}
+}
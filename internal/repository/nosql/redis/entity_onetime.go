@@ -0,0 +1,33 @@
+package redisrepo
+
+import (
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+)
+
+type oneTimeMeta struct {
+	UserID   string    `json:"user_id"`
+	AppID    int32     `json:"app_id,omitempty"`
+	DeviceID int32     `json:"device_id,omitempty"`
+	Exp      time.Time `json:"exp"`
+}
+
+func newOneTimeMeta(t domain.OneTimeToken) *oneTimeMeta {
+	return &oneTimeMeta{
+		UserID:   t.UserID,
+		AppID:    t.Ctx.AppId,
+		DeviceID: t.Ctx.DeviceID,
+		Exp:      t.Exp,
+	}
+}
+
+func (m oneTimeMeta) toDomain(purpose domain.OneTimePurpose, hash string) domain.OneTimeToken {
+	return domain.OneTimeToken{
+		Hash:    hash,
+		UserID:  m.UserID,
+		Purpose: purpose,
+		Ctx:     domain.NewDeviceCtx(m.AppID, m.DeviceID),
+		Exp:     m.Exp,
+	}
+}
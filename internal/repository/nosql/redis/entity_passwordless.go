@@ -0,0 +1,35 @@
+package redisrepo
+
+import (
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+)
+
+type passwordlessMeta struct {
+	UserID   string    `json:"user_id"`
+	Email    string    `json:"email"`
+	AppID    int32     `json:"app_id"`
+	DeviceID int32     `json:"device_id"`
+	Exp      time.Time `json:"exp"`
+}
+
+func newPasswordlessMeta(c domain.PasswordlessChallenge) *passwordlessMeta {
+	return &passwordlessMeta{
+		UserID:   c.UserID,
+		Email:    c.Email,
+		AppID:    c.Ctx.AppId,
+		DeviceID: c.Ctx.DeviceID,
+		Exp:      c.Exp,
+	}
+}
+
+func (m passwordlessMeta) toDomain(hash string) domain.PasswordlessChallenge {
+	return domain.PasswordlessChallenge{
+		Hash:   hash,
+		UserID: m.UserID,
+		Email:  m.Email,
+		Ctx:    domain.NewDeviceCtx(m.AppID, m.DeviceID),
+		Exp:    m.Exp,
+	}
+}
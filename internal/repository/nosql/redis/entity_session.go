@@ -0,0 +1,59 @@
+package redisrepo
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+// поля hash-а семейства rtf:<family>
+const (
+	famUserID      = "user_id"
+	famAppID       = "app_id"
+	famDeviceID    = "device_id"
+	famCreatedAt   = "created_at"
+	famRefreshedAt = "refreshed_at"
+	famExp         = "exp"
+)
+
+func sessionFromFamily(id string, h map[string]string) (domain.Session, error) {
+	appID, err := strconv.ParseInt(h[famAppID], 10, 32)
+	if err != nil {
+		return domain.Session{}, errors.Wrap(err, "parse app_id")
+	}
+	deviceID, err := strconv.ParseInt(h[famDeviceID], 10, 32)
+	if err != nil {
+		return domain.Session{}, errors.Wrap(err, "parse device_id")
+	}
+	createdAt, err := parseUnixMilli(h[famCreatedAt])
+	if err != nil {
+		return domain.Session{}, errors.Wrap(err, "parse created_at")
+	}
+	refreshedAt, err := parseUnixMilli(h[famRefreshedAt])
+	if err != nil {
+		return domain.Session{}, errors.Wrap(err, "parse refreshed_at")
+	}
+	exp, err := parseUnixMilli(h[famExp])
+	if err != nil {
+		return domain.Session{}, errors.Wrap(err, "parse exp")
+	}
+
+	return domain.Session{
+		ID:              id,
+		UserID:          h[famUserID],
+		Ctx:             domain.NewDeviceCtx(int32(appID), int32(deviceID)),
+		CreatedAt:       createdAt,
+		LastRefreshedAt: refreshedAt,
+		ExpiresAt:       exp,
+	}, nil
+}
+
+func parseUnixMilli(v string) (time.Time, error) {
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
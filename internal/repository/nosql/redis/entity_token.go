@@ -8,6 +8,7 @@ import (
 
 type tokenMeta struct {
 	UserID   string    `json:"user_id"`
+	FamilyID string    `json:"family_id"`
 	AppID    int32     `json:"app_id"`
 	DeviceID int32     `json:"device_id"`
 	Exp      time.Time `json:"exp"`
@@ -16,8 +17,28 @@ type tokenMeta struct {
 func newTokenMeta(m domain.Meta) *tokenMeta {
 	return &tokenMeta{
 		UserID:   m.UserID,
+		FamilyID: m.FamilyID,
 		AppID:    m.Ctx.AppId,
 		DeviceID: m.Ctx.DeviceID,
 		Exp:      m.Exp,
 	}
 }
+
+// запись о повторном предъявлении ротированного refresh-токена
+type tokenReuseEvent struct {
+	UserID     string    `json:"user_id"`
+	FamilyID   string    `json:"family_id"`
+	AppID      int32     `json:"app_id"`
+	DeviceID   int32     `json:"device_id"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+func newTokenReuseEvent(m domain.Meta) *tokenReuseEvent {
+	return &tokenReuseEvent{
+		UserID:     m.UserID,
+		FamilyID:   m.FamilyID,
+		AppID:      m.Ctx.AppId,
+		DeviceID:   m.Ctx.DeviceID,
+		DetectedAt: time.Now(),
+	}
+}
@@ -0,0 +1,58 @@
+package redisrepo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// fst:<hash> — state входа через внешний IdP, значение — json federationStateMeta
+const federationStatePrefix = "fst:"
+
+func federationStateKey(hash string) string {
+	return federationStatePrefix + hash
+}
+
+func (r *redisRepo) SaveFederationState(ctx context.Context, st domain.FederationState) error {
+	ttl := time.Until(st.Exp)
+	if ttl <= 0 {
+		return errors.New("redis: federation state already expired")
+	}
+
+	val, err := json.Marshal(newFederationStateMeta(st))
+	if err != nil {
+		return errors.Wrap(err, "redis: marshal federation state")
+	}
+
+	ok, err := r.s.SetNX(ctx, federationStateKey(st.Hash), val, ttl).Result()
+	if err != nil {
+		return errors.Wrap(err, "redis: setnx federation state")
+	}
+	if !ok {
+		return domain.ErrDuplicate
+	}
+
+	return nil
+}
+
+// ConsumeFederationState — GETDEL: state одноразовый, повтор вернёт ErrNotFound
+func (r *redisRepo) ConsumeFederationState(ctx context.Context, hash string) (domain.FederationState, error) {
+	raw, err := r.s.GetDel(ctx, federationStateKey(hash)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return domain.FederationState{}, domain.ErrNotFound
+		}
+		return domain.FederationState{}, errors.Wrap(err, "redis: getdel federation state")
+	}
+
+	var m federationStateMeta
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return domain.FederationState{}, errors.Wrap(err, "redis: unmarshal federation state")
+	}
+
+	return m.toDomain(hash), nil
+}
@@ -0,0 +1,64 @@
+package redisrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+const (
+	loginFailuresPrefix = "lf:"
+	loginLockPrefix     = "ll:"
+)
+
+func loginFailuresKey(k string) string { return loginFailuresPrefix + k }
+func loginLockKey(k string) string     { return loginLockPrefix + k }
+
+func (r *redisRepo) LoginLockedFor(ctx context.Context, keys []string) (time.Duration, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	locks := make([]string, 0, len(keys))
+	for _, k := range keys {
+		locks = append(locks, loginLockKey(k))
+	}
+
+	ms, err := r.s.Eval(ctx, loginLockedForLua, locks).Int64()
+	if err != nil {
+		return 0, errors.Wrap(err, "redis: eval loginLockedForLua")
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func (r *redisRepo) RegisterLoginFailure(ctx context.Context, key string, p domain.LockoutPolicy) (time.Duration, error) {
+	ms, err := r.s.Eval(ctx, registerLoginFailureLua,
+		[]string{loginFailuresKey(key), loginLockKey(key)},
+		p.MaxFailures, p.Window.Milliseconds(), p.BaseLockout.Milliseconds(), p.MaxLockout.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return 0, errors.Wrap(err, "redis: eval registerLoginFailureLua")
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func (r *redisRepo) ResetLoginFailures(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	del := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		del = append(del, loginFailuresKey(k), loginLockKey(k))
+	}
+
+	if err := r.s.Del(ctx, del...).Err(); err != nil {
+		return errors.Wrap(err, "redis: del login failures")
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package redisrepo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// oac:<hash> — код authorization code flow, значение — json authCodeMeta
+const authCodePrefix = "oac:"
+
+func authCodeKey(hash string) string {
+	return authCodePrefix + hash
+}
+
+func (r *redisRepo) SaveAuthorizationCode(ctx context.Context, c domain.AuthorizationCode) error {
+	ttl := time.Until(c.Exp)
+	if ttl <= 0 {
+		return errors.New("redis: authorization code already expired")
+	}
+
+	val, err := json.Marshal(newAuthCodeMeta(c))
+	if err != nil {
+		return errors.Wrap(err, "redis: marshal authorization code")
+	}
+
+	ok, err := r.s.SetNX(ctx, authCodeKey(c.Hash), val, ttl).Result()
+	if err != nil {
+		return errors.Wrap(err, "redis: setnx authorization code")
+	}
+	if !ok {
+		return domain.ErrDuplicate
+	}
+
+	return nil
+}
+
+// ConsumeAuthorizationCode — GETDEL: повторное предъявление кода вернёт ErrNotFound
+func (r *redisRepo) ConsumeAuthorizationCode(ctx context.Context, hash string) (domain.AuthorizationCode, error) {
+	raw, err := r.s.GetDel(ctx, authCodeKey(hash)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return domain.AuthorizationCode{}, domain.ErrNotFound
+		}
+		return domain.AuthorizationCode{}, errors.Wrap(err, "redis: getdel authorization code")
+	}
+
+	var m authCodeMeta
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return domain.AuthorizationCode{}, errors.Wrap(err, "redis: unmarshal authorization code")
+	}
+
+	return m.toDomain(hash), nil
+}
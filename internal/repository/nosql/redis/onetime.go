@@ -0,0 +1,58 @@
+package redisrepo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// ott:<purpose>:<hash> — одноразовый токен, значение — json oneTimeMeta
+const oneTimePrefix = "ott:"
+
+func oneTimeKey(purpose domain.OneTimePurpose, hash string) string {
+	return oneTimePrefix + string(purpose) + ":" + hash
+}
+
+func (r *redisRepo) SaveOneTimeToken(ctx context.Context, t domain.OneTimeToken) error {
+	ttl := time.Until(t.Exp)
+	if ttl <= 0 {
+		return errors.New("redis: one-time token already expired")
+	}
+
+	val, err := json.Marshal(newOneTimeMeta(t))
+	if err != nil {
+		return errors.Wrap(err, "redis: marshal one-time token")
+	}
+
+	ok, err := r.s.SetNX(ctx, oneTimeKey(t.Purpose, t.Hash), val, ttl).Result()
+	if err != nil {
+		return errors.Wrap(err, "redis: setnx one-time token")
+	}
+	if !ok {
+		return domain.ErrDuplicate
+	}
+
+	return nil
+}
+
+// ConsumeOneTimeToken — GETDEL: повторное предъявление того же токена вернёт ErrNotFound
+func (r *redisRepo) ConsumeOneTimeToken(ctx context.Context, purpose domain.OneTimePurpose, hash string) (domain.OneTimeToken, error) {
+	raw, err := r.s.GetDel(ctx, oneTimeKey(purpose, hash)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return domain.OneTimeToken{}, domain.ErrNotFound
+		}
+		return domain.OneTimeToken{}, errors.Wrap(err, "redis: getdel one-time token")
+	}
+
+	var m oneTimeMeta
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return domain.OneTimeToken{}, errors.Wrap(err, "redis: unmarshal one-time token")
+	}
+
+	return m.toDomain(purpose, hash), nil
+}
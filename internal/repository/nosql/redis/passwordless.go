@@ -0,0 +1,71 @@
+package redisrepo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+// pwl:<hash> — challenge входа без пароля, см. querys.go
+const passwordlessPrefix = "pwl:"
+
+func passwordlessKey(hash string) string {
+	return passwordlessPrefix + hash
+}
+
+func (r *redisRepo) SavePasswordlessChallenge(ctx context.Context, c domain.PasswordlessChallenge) error {
+	ttl := time.Until(c.Exp)
+	if ttl <= 0 {
+		return errors.New("redis: passwordless challenge already expired")
+	}
+	if c.Attempts <= 0 {
+		return errors.New("redis: passwordless challenge without attempts")
+	}
+
+	val, err := json.Marshal(newPasswordlessMeta(c))
+	if err != nil {
+		return errors.Wrap(err, "redis: marshal passwordless challenge")
+	}
+
+	res, err := r.s.Eval(ctx, savePasswordlessLua, []string{passwordlessKey(c.Hash)},
+		string(val), c.CodeHash, c.Attempts, ttl.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return errors.Wrap(err, "redis: eval savePasswordlessLua")
+	}
+	if res == -1 {
+		return domain.ErrDuplicate
+	}
+
+	return nil
+}
+
+func (r *redisRepo) ConsumePasswordlessChallenge(ctx context.Context, hash, codeHash string) (domain.PasswordlessChallenge, error) {
+	res, err := r.s.Eval(ctx, consumePasswordlessLua, []string{passwordlessKey(hash)}, codeHash).Slice()
+	if err != nil {
+		return domain.PasswordlessChallenge{}, errors.Wrap(err, "redis: eval consumePasswordlessLua")
+	}
+	if len(res) == 0 || res[0] == int64(0) {
+		return domain.PasswordlessChallenge{}, domain.ErrNotFound
+	}
+
+	// {1, meta} или {-1, left, meta}
+	raw, _ := res[len(res)-1].(string)
+	var m passwordlessMeta
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return domain.PasswordlessChallenge{}, errors.Wrap(err, "redis: unmarshal passwordless challenge")
+	}
+	c := m.toDomain(hash)
+
+	if res[0] == int64(-1) && len(res) == 3 {
+		left, _ := res[1].(int64)
+		c.Attempts = int(left)
+		return c, errors.Wrapf(domain.ErrValidation, "wrong passwordless code, attempts left: %d", left)
+	}
+
+	c.CodeHash = codeHash
+	return c, nil
+}
@@ -1,23 +1,309 @@
 package redisrepo
 
+// Ключи:
+// rt:<hash>      — актуальный refresh-токен (json tokenMeta)
+// rtu:<hash>     — метка «токен уже ротирован», значение — id семейства
+// rtf:<family>   — семейство (сессия): hash {user_id, current, app_id, device_id, created_at, refreshed_at, exp}
+// us:<user_id>   — индекс сессий пользователя: set id семейств
+// rta:<family>   — живые access-токены семейства: zset jti со score = exp в мс
+// atd:<jti>      — denylist отозванных access-токенов, живёт до exp токена
+// rt:reuse:events — журнал обнаруженных повторных предъявлений
+// rtl:<user_id>  — refresh-токены пользователя без семейства (выданные до семейств) отозваны
+// lf:<key>       — счётчик неудачных входов (key = email:<email> | ip:<ip>)
+// ll:<key>       — блокировка входа, живёт ровно срок блокировки
+// pwl:<hash>     — вход без пароля: hash {meta (json), code (хэш кода), left (осталось попыток)}
+
+// denyFamilyAccessLua — общая часть скриптов отзыва: access-токены семейства,
+// которые ещё не истекли, попадают в denylist на остаток жизни
+const denyFamilyAccessLua = `
+local function deny_family_access(family_id, access_prefix, deny_prefix, now_ms)
+  local live = access_prefix .. family_id
+  local tokens = redis.call('ZRANGEBYSCORE', live, '(' .. now_ms, '+inf', 'WITHSCORES')
+  for i = 1, #tokens, 2 do
+    redis.call('SET', deny_prefix .. tokens[i], '1', 'PX', tonumber(tokens[i + 1]) - now_ms)
+  end
+  redis.call('DEL', live)
+end
+`
+
+// return 1  — успех
+// return -1 — токен уже существует => duplicate
+const saveTokenLua = `
+local newk = KEYS[1]
+local fam = KEYS[2]
+local idx = KEYS[3]
+local val = ARGV[1]
+local ttl_ms = tonumber(ARGV[2])
+local new_hash = ARGV[3]
+local user_id = ARGV[4]
+local family_id = ARGV[5]
+local app_id = ARGV[6]
+local device_id = ARGV[7]
+local now_ms = ARGV[8]
+local exp_ms = ARGV[9]
+local access_key = KEYS[4]
+local access_jti = ARGV[10]
+local access_exp_ms = ARGV[11]
+
+local function extend(k, ttl)
+  local cur = redis.call('PTTL', k)
+  if cur < ttl then
+    redis.call('PEXPIRE', k, ttl)
+  end
+end
+
+local ok = redis.call('SET', newk, val, 'PX', ttl_ms, 'NX')
+if not ok then
+  return -1
+end
+
+if access_jti ~= '' then
+  redis.call('ZADD', access_key, access_exp_ms, access_jti)
+  redis.call('PEXPIRE', access_key, ttl_ms)
+end
+
+redis.call('HSET', fam,
+  'user_id', user_id, 'current', new_hash,
+  'app_id', app_id, 'device_id', device_id,
+  'created_at', now_ms, 'refreshed_at', now_ms, 'exp', exp_ms)
+redis.call('PEXPIRE', fam, ttl_ms)
+redis.call('SADD', idx, family_id)
+extend(idx, ttl_ms)
+return 1
+`
+
 // return 1  — успех
 // return 0  — старого нет (not found)
 // return -1 — новый уже существует (редкий случай коллизии) => считаем duplicate
-const rotateTokenLua = `
+// return -2 — старый уже был ротирован (reuse) => семейство отозвано
+const rotateTokenLua = denyFamilyAccessLua + `
 local old = KEYS[1]
 local newk = KEYS[2]
+local used = KEYS[3]
+local fam = KEYS[4]
+local events = KEYS[5]
+local idx = KEYS[6]
 local val = ARGV[1]
 local ttl_ms = tonumber(ARGV[2])
+local new_hash = ARGV[3]
+local family_id = ARGV[4]
+local event = ARGV[5]
+local token_prefix = ARGV[6]
+local max_events = tonumber(ARGV[7])
+local now_ms = tonumber(ARGV[8])
+local exp_ms = ARGV[9]
+local access_key = KEYS[7]
+local access_jti = ARGV[10]
+local access_exp_ms = ARGV[11]
+local access_prefix = ARGV[12]
+local deny_prefix = ARGV[13]
+
+local function extend(k, ttl)
+  local cur = redis.call('PTTL', k)
+  if cur < ttl then
+    redis.call('PEXPIRE', k, ttl)
+  end
+end
 
 if redis.call('EXISTS', old) == 0 then
+  if redis.call('EXISTS', used) == 1 then
+    local cur = redis.call('HGET', fam, 'current')
+    if cur then
+      redis.call('DEL', token_prefix .. cur)
+    end
+    deny_family_access(family_id, access_prefix, deny_prefix, now_ms)
+    redis.call('DEL', fam)
+    redis.call('SREM', idx, family_id)
+    redis.call('LPUSH', events, event)
+    redis.call('LTRIM', events, 0, max_events - 1)
+    return -2
+  end
   return 0
 end
 
 local ok = redis.call('SET', newk, val, 'PX', ttl_ms, 'NX')
 if ok then
   redis.call('DEL', old)
+  redis.call('SET', used, family_id, 'PX', ttl_ms)
+  redis.call('HSET', fam, 'current', new_hash, 'refreshed_at', now_ms, 'exp', exp_ms)
+  redis.call('PEXPIRE', fam, ttl_ms)
+  if access_jti ~= '' then
+    redis.call('ZREMRANGEBYSCORE', access_key, '-inf', now_ms)
+    redis.call('ZADD', access_key, access_exp_ms, access_jti)
+    redis.call('PEXPIRE', access_key, ttl_ms)
+  end
+  extend(idx, ttl_ms)
   return 1
 else
   return -1
 end
 `
+
+// return 1 — отозван
+// return 0 — токена нет (not found)
+const revokeTokenLua = denyFamilyAccessLua + `
+local tok = KEYS[1]
+local family_prefix = ARGV[1]
+local index_prefix = ARGV[2]
+local access_prefix = ARGV[3]
+local deny_prefix = ARGV[4]
+local now_ms = tonumber(ARGV[5])
+
+local val = redis.call('GET', tok)
+if not val then
+  return 0
+end
+redis.call('DEL', tok)
+
+local meta = cjson.decode(val)
+if meta.family_id and meta.family_id ~= '' then
+  deny_family_access(meta.family_id, access_prefix, deny_prefix, now_ms)
+  redis.call('DEL', family_prefix .. meta.family_id)
+  redis.call('SREM', index_prefix .. meta.user_id, meta.family_id)
+end
+return 1
+`
+
+// return — число отозванных сессий
+// ARGV[3] — id семейства, которое нужно сохранить (пустая строка — отозвать все)
+const revokeUserTokensLua = denyFamilyAccessLua + `
+local idx = KEYS[1]
+local token_prefix = ARGV[1]
+local family_prefix = ARGV[2]
+local keep = ARGV[3]
+local access_prefix = ARGV[4]
+local deny_prefix = ARGV[5]
+local now_ms = tonumber(ARGV[6])
+
+local revoked = 0
+for _, family_id in ipairs(redis.call('SMEMBERS', idx)) do
+  if family_id ~= keep then
+    local fam = family_prefix .. family_id
+    local cur = redis.call('HGET', fam, 'current')
+    if cur then
+      redis.call('DEL', token_prefix .. cur)
+      revoked = revoked + 1
+    end
+    deny_family_access(family_id, access_prefix, deny_prefix, now_ms)
+    redis.call('DEL', fam)
+    redis.call('SREM', idx, family_id)
+  end
+end
+if redis.call('SCARD', idx) == 0 then
+  redis.call('DEL', idx)
+end
+return revoked
+`
+
+// return 1 — сессия отозвана
+// return 0 — сессии нет или она принадлежит другому пользователю (not found)
+const revokeSessionLua = denyFamilyAccessLua + `
+local fam = KEYS[1]
+local idx = KEYS[2]
+local user_id = ARGV[1]
+local family_id = ARGV[2]
+local token_prefix = ARGV[3]
+local access_prefix = ARGV[4]
+local deny_prefix = ARGV[5]
+local now_ms = tonumber(ARGV[6])
+
+if redis.call('HGET', fam, 'user_id') ~= user_id then
+  return 0
+end
+
+local cur = redis.call('HGET', fam, 'current')
+if cur then
+  redis.call('DEL', token_prefix .. cur)
+end
+deny_family_access(family_id, access_prefix, deny_prefix, now_ms)
+redis.call('DEL', fam)
+redis.call('SREM', idx, family_id)
+return 1
+`
+
+// return — срок блокировки в мс, 0 — порог ещё не достигнут
+// после max_failures каждая следующая ошибка удваивает блокировку: base * 2^(n-max), но не больше max_lock
+const registerLoginFailureLua = `
+local cnt = KEYS[1]
+local lock = KEYS[2]
+local max_failures = tonumber(ARGV[1])
+local window_ms = tonumber(ARGV[2])
+local base_ms = tonumber(ARGV[3])
+local max_lock_ms = tonumber(ARGV[4])
+
+local n = redis.call('INCR', cnt)
+if n == 1 then
+  redis.call('PEXPIRE', cnt, window_ms)
+end
+if n < max_failures then
+  return 0
+end
+
+local lock_ms = base_ms
+for _ = 1, n - max_failures do
+  lock_ms = lock_ms * 2
+  if lock_ms >= max_lock_ms then
+    break
+  end
+end
+if lock_ms > max_lock_ms then
+  lock_ms = max_lock_ms
+end
+
+redis.call('SET', lock, n, 'PX', lock_ms)
+-- счётчик переживает блокировку, чтобы следующая ошибка после неё удвоила срок
+redis.call('PEXPIRE', cnt, lock_ms + window_ms)
+return lock_ms
+`
+
+// return — максимальный оставшийся срок блокировки по ключам в мс, 0 — блокировок нет
+const loginLockedForLua = `
+local left = 0
+for _, k in ipairs(KEYS) do
+  local ttl = redis.call('PTTL', k)
+  if ttl > left then
+    left = ttl
+  end
+end
+return left
+`
+
+// return 1  — сохранён
+// return -1 — challenge с таким хэшем уже есть => duplicate
+const savePasswordlessLua = `
+local key = KEYS[1]
+local meta = ARGV[1]
+local code = ARGV[2]
+local left = ARGV[3]
+local ttl_ms = tonumber(ARGV[4])
+
+if redis.call('EXISTS', key) == 1 then
+  return -1
+end
+redis.call('HSET', key, 'meta', meta, 'code', code, 'left', left)
+redis.call('PEXPIRE', key, ttl_ms)
+return 1
+`
+
+// return {1, meta}  — код верный, challenge погашен
+// return {0}        — challenge нет (not found)
+// return {-1, left, meta} — код неверный; left = 0 — попытки кончились, challenge удалён
+const consumePasswordlessLua = `
+local key = KEYS[1]
+local code = ARGV[1]
+
+local v = redis.call('HMGET', key, 'meta', 'code')
+if not v[1] then
+  return {0}
+end
+if v[2] == code then
+  redis.call('DEL', key)
+  return {1, v[1]}
+end
+
+local left = redis.call('HINCRBY', key, 'left', -1)
+if left <= 0 then
+  redis.call('DEL', key)
+end
+return {-1, left, v[1]}
+`
@@ -3,10 +3,17 @@ package redisrepo
 import (
 	redisstore "github.com/eragon-mdi/go-playground/storage/nosql/redis"
 	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
+	sessionservice "github.com/eragon-mdi/sso/internal/service/sso/session"
 )
 
 type RedisRepo interface {
 	authservice.TokenRepository
+	authservice.OneTimeTokenRepository
+	authservice.LoginAttemptRepository
+	authservice.AuthorizationCodeRepository
+	authservice.FederationStateRepository
+	authservice.PasswordlessRepository
+	sessionservice.SessionRepository
 }
 
 type redisRepo struct {
@@ -0,0 +1,53 @@
+package redisrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+func (r *redisRepo) ListUserSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	families, err := r.s.SMembers(ctx, userIndexKey(userID)).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "redis: smembers user index")
+	}
+
+	sessions := make([]domain.Session, 0, len(families))
+	for _, family := range families {
+		h, err := r.s.HGetAll(ctx, familyKey(family)).Result()
+		if err != nil {
+			return nil, errors.Wrap(err, "redis: hgetall family")
+		}
+		// семейство истекло по TTL — подчищаем индекс
+		if len(h) == 0 {
+			if err := r.s.SRem(ctx, userIndexKey(userID), family).Err(); err != nil {
+				return nil, errors.Wrap(err, "redis: srem stale family")
+			}
+			continue
+		}
+
+		session, err := sessionFromFamily(family, h)
+		if err != nil {
+			return nil, errors.Wrap(err, "redis: broken family record")
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+func (r *redisRepo) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	res, err := r.s.Eval(ctx, revokeSessionLua,
+		[]string{familyKey(sessionID), userIndexKey(userID)},
+		userID, sessionID, tokenPrefix, familyAccessPrefix, accessDenyPrefix, time.Now().UnixMilli(),
+	).Int()
+	if err != nil {
+		return errors.Wrap(err, "redis: eval revokeSessionLua")
+	}
+	if res == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -9,23 +9,24 @@ import (
 	"github.com/go-faster/errors"
 )
 
+const maxReuseEvents = 1000
+
 func (r *redisRepo) SaveRefreshToken(ctx context.Context, rt domain.RefreshToken) error {
 	val, err := json.Marshal(newTokenMeta(rt.Meta))
 	if err != nil {
 		return errors.Wrap(err, "failed prepare data: marshal")
 	}
 
-	ttl := time.Until(rt.Meta.Exp)
-	// минимальный TTL, чтобы ключ не жил вечно, если exp «в прошлом»
-	if ttl <= 0 {
-		ttl = time.Second
-	}
-
-	ok, err := r.s.SetNX(ctx, key(rt.Token), val, ttl).Result()
+	res, err := r.s.Eval(ctx, saveTokenLua,
+		[]string{key(rt.Token), familyKey(rt.Meta.FamilyID), userIndexKey(rt.Meta.UserID), familyAccessKey(rt.Meta.FamilyID)},
+		val, ttlMs(rt.Meta.Exp), rt.Token, rt.Meta.UserID, rt.Meta.FamilyID,
+		rt.Meta.Ctx.AppId, rt.Meta.Ctx.DeviceID, time.Now().UnixMilli(), rt.Meta.Exp.UnixMilli(),
+		rt.AccessID, rt.AccessExp.UnixMilli(),
+	).Int()
 	if err != nil {
-		return err
+		return errors.Wrap(err, "redis: eval saveTokenLua")
 	}
-	if !ok {
+	if res == -1 {
 		return domain.ErrDuplicate
 	}
 
@@ -37,14 +38,21 @@ func (r *redisRepo) RotateToken(ctx context.Context, oldHash string, rt domain.R
 	if err != nil {
 		return errors.Wrap(err, "redis: marshal new token meta")
 	}
-
-	ttl := time.Until(rt.Meta.Exp)
-	if ttl <= 0 {
-		ttl = time.Second
+	event, err := json.Marshal(newTokenReuseEvent(rt.Meta))
+	if err != nil {
+		return errors.Wrap(err, "redis: marshal reuse event")
 	}
-	ttlMs := int64(ttl / time.Millisecond)
 
-	res, err := r.s.Eval(ctx, rotateTokenLua, []string{key(oldHash), key(rt.Token)}, val, ttlMs).Int()
+	res, err := r.s.Eval(ctx, rotateTokenLua,
+		[]string{
+			key(oldHash), key(rt.Token), usedKey(oldHash),
+			familyKey(rt.Meta.FamilyID), reuseEventsKey, userIndexKey(rt.Meta.UserID),
+			familyAccessKey(rt.Meta.FamilyID),
+		},
+		val, ttlMs(rt.Meta.Exp), rt.Token, rt.Meta.FamilyID, event, tokenPrefix, maxReuseEvents,
+		time.Now().UnixMilli(), rt.Meta.Exp.UnixMilli(),
+		rt.AccessID, rt.AccessExp.UnixMilli(), familyAccessPrefix, accessDenyPrefix,
+	).Int()
 	if err != nil {
 		return errors.Wrap(err, "redis: eval rotateTokenLua")
 	}
@@ -55,20 +63,97 @@ func (r *redisRepo) RotateToken(ctx context.Context, oldHash string, rt domain.R
 		return domain.ErrNotFound
 	case -1:
 		return domain.ErrDuplicate
+	case -2:
+		return domain.ErrTokenReuse
 	default:
 		return errors.New("redis: unexpected result from rotate script")
 	}
 }
 
 func (r *redisRepo) RevokeTokenByHash(ctx context.Context, hash string) error {
-	n, err := r.s.Del(ctx, key(hash)).Result()
+	res, err := r.s.Eval(ctx, revokeTokenLua, []string{key(hash)},
+		familyPrefix, userIndexPrefix, familyAccessPrefix, accessDenyPrefix, time.Now().UnixMilli(),
+	).Int()
 	if err != nil {
-		return err
+		return errors.Wrap(err, "redis: eval revokeTokenLua")
 	}
-	if n == 0 {
+	if res == 0 {
 		return domain.ErrNotFound
 	}
 	return nil
 }
 
-func key(hash string) string { return "rt:" + hash }
+func (r *redisRepo) RevokeUserTokens(ctx context.Context, userID, exceptFamilyID string) (int, error) {
+	n, err := r.s.Eval(ctx, revokeUserTokensLua, []string{userIndexKey(userID)},
+		tokenPrefix, familyPrefix, exceptFamilyID, familyAccessPrefix, accessDenyPrefix, time.Now().UnixMilli(),
+	).Int()
+	if err != nil {
+		return 0, errors.Wrap(err, "redis: eval revokeUserTokensLua")
+	}
+	return n, nil
+}
+
+func (r *redisRepo) RevokeLegacyTokens(ctx context.Context, userID string, ttl time.Duration) error {
+	if err := r.s.Set(ctx, legacyKey(userID), 1, ttl).Err(); err != nil {
+		return errors.Wrap(err, "redis: set legacy revoked")
+	}
+	return nil
+}
+
+func (r *redisRepo) LegacyTokensRevoked(ctx context.Context, userID string) (bool, error) {
+	n, err := r.s.Exists(ctx, legacyKey(userID)).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "redis: exists legacy revoked")
+	}
+	return n > 0, nil
+}
+
+func (r *redisRepo) RefreshTokenExists(ctx context.Context, hash string) (bool, error) {
+	n, err := r.s.Exists(ctx, key(hash)).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "redis: exists token")
+	}
+	return n > 0, nil
+}
+
+func (r *redisRepo) SessionExists(ctx context.Context, familyID string) (bool, error) {
+	n, err := r.s.Exists(ctx, familyKey(familyID)).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "redis: exists family")
+	}
+	return n > 0, nil
+}
+
+func (r *redisRepo) AccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := r.s.Exists(ctx, accessDenyKey(jti)).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "redis: exists access denylist")
+	}
+	return n > 0, nil
+}
+
+// минимальный TTL, чтобы ключ не жил вечно, если exp «в прошлом»
+func ttlMs(exp time.Time) int64 {
+	ttl := time.Until(exp)
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+	return int64(ttl / time.Millisecond)
+}
+
+const (
+	tokenPrefix        = "rt:"
+	familyPrefix       = "rtf:"
+	familyAccessPrefix = "rta:"
+	accessDenyPrefix   = "atd:"
+	userIndexPrefix    = "us:"
+	reuseEventsKey     = "rt:reuse:events"
+)
+
+func key(hash string) string               { return tokenPrefix + hash }
+func usedKey(hash string) string           { return "rtu:" + hash }
+func familyKey(family string) string       { return familyPrefix + family }
+func userIndexKey(userID string) string    { return userIndexPrefix + userID }
+func familyAccessKey(family string) string { return familyAccessPrefix + family }
+func accessDenyKey(jti string) string      { return accessDenyPrefix + jti }
+func legacyKey(userID string) string       { return "rtl:" + userID }
@@ -0,0 +1,121 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"github.com/lib/pq"
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func (r sqlRepo) NewApp(ctx context.Context, app domain.App) (domain.App, error) {
+	row := r.s.QueryRowContext(ctx, queryInsertApp,
+		app.ID, app.Name, string(app.Status), textArray(app.GrantTypes),
+		int64(app.AccessTokenTTL.Seconds()), int64(app.RefreshTokenTTL.Seconds()),
+		textArray(app.EmailDomains), textArray(app.RedirectURIs), textArray(app.Scopes))
+
+	created, err := scanApp(row)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.App{}, errors.Wrap(domain.ErrDuplicate, ErrFailedExec)
+		}
+		return domain.App{}, errors.Wrap(err, ErrFailedScan)
+	}
+
+	return created, nil
+}
+
+func (r sqlRepo) GetApp(ctx context.Context, appID int32) (domain.App, error) {
+	app, err := scanApp(r.s.QueryRowContext(ctx, queryGetApp, appID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.App{}, errors.Wrap(domain.ErrNotFound, ErrFailedQuery)
+		}
+		return domain.App{}, errors.Wrap(err, ErrFailedScan)
+	}
+
+	return app, nil
+}
+
+func (r sqlRepo) ListApps(ctx context.Context) ([]domain.App, error) {
+	rows, err := r.s.QueryContext(ctx, queryListApps)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrFailedQuery)
+	}
+	defer rows.Close()
+
+	var apps []domain.App
+	for rows.Next() {
+		app, err := scanApp(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, ErrFailedScan)
+		}
+		apps = append(apps, app)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, ErrRowsIterations)
+	}
+
+	return apps, nil
+}
+
+func (r sqlRepo) UpdateApp(ctx context.Context, app domain.App) (domain.App, error) {
+	row := r.s.QueryRowContext(ctx, queryUpdateApp,
+		app.ID, app.Name, textArray(app.GrantTypes),
+		int64(app.AccessTokenTTL.Seconds()), int64(app.RefreshTokenTTL.Seconds()),
+		textArray(app.EmailDomains), textArray(app.RedirectURIs), textArray(app.Scopes))
+
+	updated, err := scanApp(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.App{}, errors.Wrap(domain.ErrNotFound, ErrFailedQuery)
+		}
+		return domain.App{}, errors.Wrap(err, ErrFailedScan)
+	}
+
+	return updated, nil
+}
+
+func (r sqlRepo) SetAppStatus(ctx context.Context, appID int32, status domain.AppStatus) error {
+	n, err := r.execAffected(ctx, querySetAppStatus, appID, string(status))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.Wrap(domain.ErrNotFound, ErrFailedExec)
+	}
+
+	return nil
+}
+
+func scanApp(row rowScanner) (domain.App, error) {
+	var (
+		app                   domain.App
+		status                string
+		accessTTL, refreshTTL int64
+	)
+	if err := row.Scan(&app.ID, &app.Name, &status, pq.Array(&app.GrantTypes), &accessTTL, &refreshTTL,
+		pq.Array(&app.EmailDomains), pq.Array(&app.RedirectURIs), pq.Array(&app.Scopes),
+		&app.CreatedAt, &app.UpdatedAt); err != nil {
+		return domain.App{}, err
+	}
+	app.Status = domain.AppStatus(status)
+	app.AccessTokenTTL = time.Duration(accessTTL) * time.Second
+	app.RefreshTokenTTL = time.Duration(refreshTTL) * time.Second
+
+	return app, nil
+}
+
+// textArray — NOT NULL-колонки TEXT[]: nil пишется пустым массивом
+func textArray(ss []string) any {
+	if ss == nil {
+		ss = []string{}
+	}
+	return pq.Array(ss)
+}
@@ -12,8 +12,8 @@ import (
 func (r sqlRepo) NewUser(ctx context.Context, u domain.User) (domain.User, error) {
 	row := r.s.QueryRowContext(ctx, queryInsertUser, u.ID, u.Email, u.Password)
 
-	var user domain.User
-	if err := row.Scan(&user.ID, &user.Email, &user.Password); err != nil {
+	user, err := scanUser(row)
+	if err != nil {
 		if isUniqueViolation(err) {
 			return domain.User{}, errors.Wrap(domain.ErrDuplicate, ErrFailedExec)
 		}
@@ -26,8 +26,8 @@ func (r sqlRepo) NewUser(ctx context.Context, u domain.User) (domain.User, error
 func (r sqlRepo) GetUserInfoByEmail(ctx context.Context, email string) (domain.User, error) {
 	row := r.s.QueryRowContext(ctx, queryGetUserByEmail, email)
 
-	var user domain.User
-	if err := row.Scan(&user.ID, &user.Email, &user.Password); err != nil {
+	user, err := scanUser(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.User{}, errors.Wrap(domain.ErrNotFound, ErrFailedQuery)
 		}
@@ -37,6 +37,69 @@ func (r sqlRepo) GetUserInfoByEmail(ctx context.Context, email string) (domain.U
 	return user, nil
 }
 
+func (r sqlRepo) GetUserInfoByID(ctx context.Context, id string) (domain.User, error) {
+	row := r.s.QueryRowContext(ctx, queryGetUserByID, id)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, errors.Wrap(domain.ErrNotFound, ErrFailedQuery)
+		}
+		return domain.User{}, errors.Wrap(err, ErrFailedScan)
+	}
+
+	return user, nil
+}
+
+func (r sqlRepo) UpdateUserPassword(ctx context.Context, userID, passwordHash string) error {
+	res, err := r.s.ExecContext(ctx, queryUpdateUserPassword, userID, passwordHash)
+	if err != nil {
+		return errors.Wrap(err, ErrFailedExec)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, ErrFailedAffectedRows)
+	}
+	if n == 0 {
+		return errors.Wrap(domain.ErrNotFound, ErrFailedExec)
+	}
+
+	return nil
+}
+
+func (r sqlRepo) MarkEmailVerified(ctx context.Context, userID string) error {
+	res, err := r.s.ExecContext(ctx, queryMarkEmailVerified, userID)
+	if err != nil {
+		return errors.Wrap(err, ErrFailedExec)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, ErrFailedAffectedRows)
+	}
+	if n == 0 {
+		return errors.Wrap(domain.ErrNotFound, ErrFailedExec)
+	}
+
+	return nil
+}
+
+func scanUser(row *sql.Row) (domain.User, error) {
+	var (
+		user       domain.User
+		verifiedAt sql.NullTime
+	)
+	if err := row.Scan(&user.ID, &user.Email, &user.Password, &verifiedAt); err != nil {
+		return domain.User{}, err
+	}
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
+	}
+
+	return user, nil
+}
+
 func isUniqueViolation(err error) bool {
 	var pqErr *pq.Error
 	if errors.As(err, &pqErr) {
@@ -0,0 +1,34 @@
+package sqlrepo
+
+import (
+	"context"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+func (r sqlRepo) NewDevice(ctx context.Context, d domain.Device) (domain.Device, error) {
+	var created domain.Device
+	err := r.s.QueryRowContext(ctx, queryInsertDevice, d.ID, d.UserID, d.Platform, d.Name).Scan(
+		&created.ID, &created.UserID, &created.Platform, &created.Name, &created.FirstSeenAt, &created.LastSeenAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.Device{}, errors.Wrap(domain.ErrDuplicate, ErrFailedExec)
+		}
+		return domain.Device{}, errors.Wrap(err, ErrFailedScan)
+	}
+
+	return created, nil
+}
+
+func (r sqlRepo) TouchDevice(ctx context.Context, deviceID int32, userID string) error {
+	n, err := r.execAffected(ctx, queryTouchDevice, deviceID, userID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.Wrap(domain.ErrNotFound, ErrFailedExec)
+	}
+
+	return nil
+}
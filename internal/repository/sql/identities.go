@@ -0,0 +1,34 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+func (r sqlRepo) NewIdentity(ctx context.Context, id domain.Identity) error {
+	if _, err := r.s.ExecContext(ctx, queryInsertIdentity, id.Provider, id.Subject, id.UserID, id.Email); err != nil {
+		if isUniqueViolation(err) {
+			return errors.Wrap(domain.ErrDuplicate, ErrFailedExec)
+		}
+		return errors.Wrap(err, ErrFailedExec)
+	}
+
+	return nil
+}
+
+func (r sqlRepo) GetIdentity(ctx context.Context, provider, subject string) (domain.Identity, error) {
+	var id domain.Identity
+	err := r.s.QueryRowContext(ctx, queryGetIdentity, provider, subject).Scan(
+		&id.Provider, &id.Subject, &id.UserID, &id.Email, &id.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Identity{}, errors.Wrap(domain.ErrNotFound, ErrFailedQuery)
+		}
+		return domain.Identity{}, errors.Wrap(err, ErrFailedScan)
+	}
+
+	return id, nil
+}
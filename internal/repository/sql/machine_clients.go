@@ -0,0 +1,69 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"github.com/lib/pq"
+)
+
+func (r sqlRepo) NewMachineClient(ctx context.Context, c domain.MachineClient) error {
+	scopes := c.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	if _, err := r.s.ExecContext(ctx, queryInsertMachineClient, c.ID, c.Name, c.SecretHash, pq.Array(scopes)); err != nil {
+		if isUniqueViolation(err) {
+			return errors.Wrap(domain.ErrDuplicate, ErrFailedExec)
+		}
+		return errors.Wrap(err, ErrFailedExec)
+	}
+
+	return nil
+}
+
+func (r sqlRepo) GetMachineClient(ctx context.Context, clientID string) (domain.MachineClient, error) {
+	row := r.s.QueryRowContext(ctx, queryGetMachineClient, clientID)
+
+	var (
+		c          domain.MachineClient
+		disabledAt sql.NullTime
+	)
+	if err := row.Scan(&c.ID, &c.Name, &c.SecretHash, pq.Array(&c.Scopes), &c.CreatedAt, &c.SecretRotatedAt, &disabledAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.MachineClient{}, errors.Wrap(domain.ErrNotFound, ErrFailedQuery)
+		}
+		return domain.MachineClient{}, errors.Wrap(err, ErrFailedScan)
+	}
+	if disabledAt.Valid {
+		c.DisabledAt = &disabledAt.Time
+	}
+
+	return c, nil
+}
+
+func (r sqlRepo) UpdateMachineClientSecret(ctx context.Context, clientID string, secretHash []byte) error {
+	n, err := r.execAffected(ctx, queryUpdateMachineClientSecret, clientID, secretHash)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.Wrap(domain.ErrNotFound, ErrFailedExec)
+	}
+
+	return nil
+}
+
+func (r sqlRepo) DisableMachineClient(ctx context.Context, clientID string) error {
+	n, err := r.execAffected(ctx, queryDisableMachineClient, clientID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.Wrap(domain.ErrNotFound, ErrFailedExec)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+func (r sqlRepo) SaveTotpSecret(ctx context.Context, userID string, encryptedSecret []byte) error {
+	n, err := r.execAffected(ctx, queryUpsertTotpSecret, userID, encryptedSecret)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.Wrap(domain.ErrDuplicate, ErrFailedExec)
+	}
+
+	return nil
+}
+
+func (r sqlRepo) GetUserMfa(ctx context.Context, userID string) (domain.UserMfa, error) {
+	row := r.s.QueryRowContext(ctx, queryGetUserMfa, userID)
+
+	var (
+		mfa         domain.UserMfa
+		confirmedAt sql.NullTime
+	)
+	if err := row.Scan(&mfa.UserID, &mfa.EncryptedSecret, &confirmedAt, &mfa.LastUsedStep); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.UserMfa{}, errors.Wrap(domain.ErrNotFound, ErrFailedQuery)
+		}
+		return domain.UserMfa{}, errors.Wrap(err, ErrFailedScan)
+	}
+	if confirmedAt.Valid {
+		mfa.ConfirmedAt = &confirmedAt.Time
+	}
+
+	return mfa, nil
+}
+
+func (r sqlRepo) ConfirmTotp(ctx context.Context, userID string, step int64) error {
+	n, err := r.execAffected(ctx, queryConfirmTotp, userID, step)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.Wrap(domain.ErrNotFound, ErrFailedExec)
+	}
+
+	return nil
+}
+
+func (r sqlRepo) UseTotpStep(ctx context.Context, userID string, step int64) error {
+	n, err := r.execAffected(ctx, queryUseTotpStep, userID, step)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.Wrap(domain.ErrTokenReuse, ErrFailedExec)
+	}
+
+	return nil
+}
+
+func (r sqlRepo) execAffected(ctx context.Context, query string, args ...any) (int64, error) {
+	res, err := r.s.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, errors.Wrap(err, ErrFailedExec)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, ErrFailedAffectedRows)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,59 @@
+package sqlrepo
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+)
+
+// maxPasswordHistory — сколько последних хэшей хранится на пользователя (больше любой разумной history_depth)
+const maxPasswordHistory = 24
+
+func (r sqlRepo) SavePasswordHistory(ctx context.Context, userID, passwordHash string) (err error) {
+	tx, err := r.s.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, ErrFailedStartTX)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = errors.Join(err, errors.Wrap(rbErr, ErrFailedRollbackTX))
+			}
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, queryInsertPasswordHistory, userID, passwordHash); err != nil {
+		return errors.Wrap(err, ErrFailedExec)
+	}
+	if _, err = tx.ExecContext(ctx, queryTrimPasswordHistory, userID, maxPasswordHistory); err != nil {
+		return errors.Wrap(err, ErrFailedExec)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, ErrFailedCommitTX)
+	}
+
+	return nil
+}
+
+func (r sqlRepo) GetPasswordHistory(ctx context.Context, userID string, limit int) ([]string, error) {
+	rows, err := r.s.QueryContext(ctx, queryGetPasswordHistory, userID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrFailedQuery)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, errors.Wrap(err, ErrFailedScan)
+		}
+		hashes = append(hashes, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, ErrRowsIterations)
+	}
+
+	return hashes, nil
+}
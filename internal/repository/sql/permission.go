@@ -14,3 +14,53 @@ func (r sqlRepo) CheckUserIsAdminByID(ctx context.Context, userID string) (bool,
 
 	return ok, nil
 }
+
+func (r sqlRepo) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	rows, err := r.s.QueryContext(ctx, queryGetUserRoles, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrFailedQuery)
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, errors.Wrap(err, ErrFailedScan)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, ErrRowsIterations)
+	}
+
+	return roles, nil
+}
+
+// SyncUserRoles приводит роли из managed к roles; роли вне managed не трогаются
+func (r sqlRepo) SyncUserRoles(ctx context.Context, userID string, managed, roles []string) (err error) {
+	tx, err := r.s.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, ErrFailedStartTX)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = errors.Join(err, errors.Wrap(rbErr, ErrFailedRollbackTX))
+			}
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, queryRevokeManagedRoles, userID, textArray(managed), textArray(roles)); err != nil {
+		return errors.Wrap(err, ErrFailedExec)
+	}
+	if _, err = tx.ExecContext(ctx, queryGrantRoles, userID, textArray(roles)); err != nil {
+		return errors.Wrap(err, ErrFailedExec)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, ErrFailedCommitTX)
+	}
+
+	return nil
+}
@@ -4,18 +4,194 @@ package sqlrepo
 const queryInsertUser = `
 INSERT INTO users (id, email, password_hash)
 VALUES ($1, $2, $3)
-RETURNING id, email, password_hash
+RETURNING id, email, password_hash, email_verified_at
 `
 
 const queryGetUserByEmail = `
-SELECT id, email, password_hash
+SELECT id, email, password_hash, email_verified_at
 FROM users
 WHERE email = $1
 `
 
+const queryGetUserByID = `
+SELECT id, email, password_hash, email_verified_at
+FROM users
+WHERE id = $1
+`
+
+const queryUpdateUserPassword = `
+UPDATE users
+SET password_hash = $2
+WHERE id = $1
+`
+
+// повторное подтверждение не сдвигает исходную дату
+const queryMarkEmailVerified = `
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, now())
+WHERE id = $1
+`
+
+// --- PASSWORD HISTORY ---
+const queryInsertPasswordHistory = `
+INSERT INTO password_history (user_id, password_hash)
+VALUES ($1, $2)
+`
+
+// хранится не больше $2 последних записей
+const queryTrimPasswordHistory = `
+DELETE FROM password_history
+WHERE user_id = $1 AND id NOT IN (
+    SELECT id FROM password_history
+    WHERE user_id = $1
+    ORDER BY id DESC
+    LIMIT $2
+)
+`
+
+const queryGetPasswordHistory = `
+SELECT password_hash
+FROM password_history
+WHERE user_id = $1
+ORDER BY id DESC
+LIMIT $2
+`
+
+// --- MFA ---
+// подтверждённую MFA перезаписать нельзя: 0 строк => уже включена
+const queryUpsertTotpSecret = `
+INSERT INTO user_mfa (user_id, totp_secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET totp_secret = EXCLUDED.totp_secret, last_used_step = 0, created_at = now()
+WHERE user_mfa.confirmed_at IS NULL
+`
+
+const queryGetUserMfa = `
+SELECT user_id, totp_secret, confirmed_at, last_used_step
+FROM user_mfa
+WHERE user_id = $1
+`
+
+const queryConfirmTotp = `
+UPDATE user_mfa
+SET confirmed_at = now(), last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NULL
+`
+
+// шаг принимается только если он новее последнего принятого
+const queryUseTotpStep = `
+UPDATE user_mfa
+SET last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2
+`
+
 // --- PERMISSION ---
 const queryCheckUserIsAdmin = `
 SELECT EXISTS (
 	SELECT 1 FROM user_roles WHERE user_id = $1 AND role = 'admin'
 )
 `
+
+const queryGetUserRoles = `
+SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role
+`
+
+// снимаются только роли из $2 (ими управляет каталог), которых нет в $3
+const queryRevokeManagedRoles = `
+DELETE FROM user_roles
+WHERE user_id = $1 AND role = ANY($2) AND NOT (role = ANY($3))
+`
+
+// роли, которых нет в roles, пропускаются
+const queryGrantRoles = `
+INSERT INTO user_roles (user_id, role)
+SELECT $1, role FROM roles WHERE role = ANY($2)
+ON CONFLICT DO NOTHING
+`
+
+// --- MACHINE CLIENTS ---
+const queryInsertMachineClient = `
+INSERT INTO machine_clients (id, name, secret_hash, scopes)
+VALUES ($1, $2, $3, $4)
+`
+
+const queryGetMachineClient = `
+SELECT id, name, secret_hash, scopes, created_at, secret_rotated_at, disabled_at
+FROM machine_clients
+WHERE id = $1
+`
+
+// у отключённого клиента секрет не меняется
+const queryUpdateMachineClientSecret = `
+UPDATE machine_clients
+SET secret_hash = $2, secret_rotated_at = now()
+WHERE id = $1 AND disabled_at IS NULL
+`
+
+// повторное отключение не сдвигает исходную дату
+const queryDisableMachineClient = `
+UPDATE machine_clients
+SET disabled_at = COALESCE(disabled_at, now())
+WHERE id = $1
+`
+
+// --- APPS ---
+const appColumns = `id, name, status, grant_types, access_token_ttl_seconds, refresh_token_ttl_seconds,
+	email_domains, redirect_uris, scopes, created_at, updated_at`
+
+const queryInsertApp = `
+INSERT INTO apps (id, name, status, grant_types, access_token_ttl_seconds, refresh_token_ttl_seconds,
+	email_domains, redirect_uris, scopes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING ` + appColumns
+
+const queryGetApp = `
+SELECT ` + appColumns + `
+FROM apps
+WHERE id = $1
+`
+
+const queryListApps = `
+SELECT ` + appColumns + `
+FROM apps
+ORDER BY id
+`
+
+// статус меняется отдельно (SetAppStatus)
+const queryUpdateApp = `
+UPDATE apps
+SET name = $2, grant_types = $3, access_token_ttl_seconds = $4, refresh_token_ttl_seconds = $5,
+	email_domains = $6, redirect_uris = $7, scopes = $8, updated_at = now()
+WHERE id = $1
+RETURNING ` + appColumns
+
+const querySetAppStatus = `
+UPDATE apps
+SET status = $2, updated_at = now()
+WHERE id = $1
+`
+
+const queryInsertDevice = `
+INSERT INTO devices (id, user_id, platform, name)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, platform, name, first_seen_at, last_seen_at
+`
+
+// владелец проверяется в том же запросе: чужое устройство не обновляется
+const queryTouchDevice = `
+UPDATE devices
+SET last_seen_at = now()
+WHERE id = $1 AND user_id = $2
+`
+
+const queryInsertIdentity = `
+INSERT INTO identities (provider, subject, user_id, email)
+VALUES ($1, $2, $3, $4)
+`
+
+const queryGetIdentity = `
+SELECT provider, subject, user_id, email, created_at
+FROM identities
+WHERE provider = $1 AND subject = $2
+`
@@ -8,6 +8,12 @@ import (
 
 type SqlRepo interface {
 	authservice.UserRepository
+	authservice.MfaRepository
+	authservice.PasswordHistoryRepository
+	authservice.MachineClientRepository
+	authservice.AppRepository
+	authservice.DeviceRepository
+	authservice.IdentityRepository
 	permissionservice.UserRepository
 }
 
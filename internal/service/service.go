@@ -1,12 +1,22 @@
 package service
 
 import (
+	"encoding/base64"
+
 	"github.com/eragon-mdi/sso/internal/common/configs"
 	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
+	"github.com/eragon-mdi/sso/internal/service/sso/auth/federation"
 	"github.com/eragon-mdi/sso/internal/service/sso/auth/hasher"
 	hashertokener "github.com/eragon-mdi/sso/internal/service/sso/auth/hasher-tokener"
+	"github.com/eragon-mdi/sso/internal/service/sso/auth/ldap"
+	"github.com/eragon-mdi/sso/internal/service/sso/auth/notifier"
+	passwordpolicy "github.com/eragon-mdi/sso/internal/service/sso/auth/password-policy"
+	"github.com/eragon-mdi/sso/internal/service/sso/auth/pwned"
+	secretcipher "github.com/eragon-mdi/sso/internal/service/sso/auth/secret-cipher"
 	tokener "github.com/eragon-mdi/sso/internal/service/sso/auth/tokener"
+	"github.com/eragon-mdi/sso/internal/service/sso/auth/totp"
 	permissionservice "github.com/eragon-mdi/sso/internal/service/sso/permission"
+	sessionservice "github.com/eragon-mdi/sso/internal/service/sso/session"
 	"github.com/eragon-mdi/sso/internal/transport"
 	"github.com/go-faster/errors"
 )
@@ -17,9 +27,42 @@ type service struct {
 }
 
 func New(r Repository, cfg *configs.BussinesLogic) (transport.Service, error) {
-	t, err := tokener.New(cfg.PathSecretPrivate, cfg.PathSecretPublic)
+	keys, err := loadSigningKeys(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed load token signing keys")
+	}
+	t := tokener.New(keys, cfg.TokenIssuer, cfg.TokenAudience)
+	n, err := notifier.NewFromPath(cfg.NotifierFilePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed init notifier")
+	}
+	mfaKey, err := base64.StdEncoding.DecodeString(cfg.MfaSecretKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed decode mfa secret key")
+	}
+	sc, err := secretcipher.New(mfaKey)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed init tokener")
+		return nil, errors.Wrap(err, "failed init mfa secret cipher")
+	}
+	ph, err := newPassHasher(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed init password hasher")
+	}
+	pp, err := passwordpolicy.NewFromFiles(cfg.PasswordPolicyPath, cfg.PasswordBlocklistPath, passMaxBytes(cfg))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed init password policy")
+	}
+	bc, err := pwned.NewFromPath(cfg.PwnedPasswordsPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed init pwned passwords corpus")
+	}
+	idps, err := federation.NewFromPath(cfg.IdentityProvidersPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed init identity providers")
+	}
+	dir, err := ldap.NewFromPath(cfg.LdapConfigPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed init ldap directory")
 	}
 
 	return &service{
@@ -27,22 +70,67 @@ func New(r Repository, cfg *configs.BussinesLogic) (transport.Service, error) {
 		sso: sso{
 			Auth: authservice.New(
 				r,
-				hasher.New(cfg.PassHasherCost),
+				ph,
+				pp,
+				bc,
 				t,
 				hashertokener.New([]byte(cfg.SecretForTokerHasher)),
+				n,
+				totp.New(cfg.TokenIssuer),
+				sc,
+				idps,
+				dir,
 				cfg),
 
 			Permission: permissionservice.New(r),
+			Sessions:   sessionservice.New(r),
 		},
 	}, nil
 }
 
+// loadSigningKeys — каталог ключей с ротацией или, для старых конфигов, одна пара файлов
+func loadSigningKeys(cfg *configs.BussinesLogic) (*tokener.KeySet, error) {
+	if cfg.TokenKeysDir != "" {
+		return tokener.LoadKeySet(cfg.TokenKeysDir, cfg.TokenSigningAlg)
+	}
+	if cfg.PathSecretPrivate == "" || cfg.PathSecretPublic == "" {
+		return nil, errors.New("neither token keys dir nor key pair paths are set")
+	}
+	return tokener.LoadKeyPair(cfg.PathSecretPrivate, cfg.PathSecretPublic, cfg.TokenSigningAlg)
+}
+
+// newPassHasher — хэшер новых паролей по конфигу; проверяются оба формата (bcrypt и argon2id)
+func newPassHasher(cfg *configs.BussinesLogic) (authservice.PasswordHasher, error) {
+	switch cfg.PassHasherAlgo {
+	case hasher.AlgoBcrypt, "":
+		return hasher.New(cfg.PassHasherCost), nil
+	case hasher.AlgoArgon2id:
+		return hasher.NewArgon2id(hasher.Argon2Params{
+			Memory:      cfg.PassArgon2Memory,
+			Iterations:  cfg.PassArgon2Iterations,
+			Parallelism: cfg.PassArgon2Parallelism,
+		}), nil
+	default:
+		return nil, errors.Errorf("unknown password hasher %q", cfg.PassHasherAlgo)
+	}
+}
+
+// passMaxBytes — предел длины пароля в байтах у активного хэшера; 0 — без предела
+func passMaxBytes(cfg *configs.BussinesLogic) int {
+	if cfg.PassHasherAlgo == hasher.AlgoArgon2id {
+		return 0
+	}
+	return hasher.BcryptMaxBytes
+}
+
 type Repository interface {
 	authservice.Repository
 	permissionservice.Repository
+	sessionservice.Repository
 }
 
 type sso struct {
 	*authservice.Auth
 	*permissionservice.Permission
+	*sessionservice.Sessions
 }
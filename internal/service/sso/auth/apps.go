@@ -0,0 +1,204 @@
+package authservice
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+const (
+	ErrFailedGetApp     = "failed get app"
+	ErrFailedSaveApp    = "failed save app"
+	ErrFailedListApps   = "failed list apps"
+	ErrFailedUpdateApp  = "failed update app"
+	ErrFailedSetStatus  = "failed set app status"
+	ErrDuplicateApp     = "cause: duplicate app id"
+	ErrUnknownApp       = "unknown app"
+	ErrAppDisabled      = "app disabled"
+	ErrGrantNotAllowed  = "grant not allowed for app"
+	ErrEmailNotAllowed  = "email domain not allowed for app"
+	ErrInvalidGrantType = "invalid grant type"
+	ErrInvalidTokenTTL  = "token ttl must not be negative"
+	ErrAccessTTLTooLong = "access token ttl must not exceed refresh token ttl"
+	ErrInvalidDomain    = "invalid email domain"
+	ErrInvalidRedirect  = "invalid redirect uri"
+	ErrNoRedirectURIs   = "authorization_code requires redirect uris"
+)
+
+// CreateApp — админская операция; app_id задаёт администратор, новое приложение активно
+func (s *Auth) CreateApp(ctx context.Context, app domain.App) (domain.App, error) {
+	app, err := s.normalizeApp(app)
+	if err != nil {
+		return domain.App{}, err
+	}
+	app.Status = domain.AppActive
+
+	created, err := s.r.NewApp(ctx, app)
+	if err != nil {
+		if errors.Is(err, domain.ErrDuplicate) {
+			return domain.App{}, errors.Wrap(domain.ErrDuplicate, ErrDuplicateApp)
+		}
+		return domain.App{}, errors.Wrap(err, ErrFailedSaveApp)
+	}
+
+	return created, nil
+}
+
+// UpdateApp заменяет настройки приложения целиком; статус меняют DisableApp и EnableApp
+func (s *Auth) UpdateApp(ctx context.Context, app domain.App) (domain.App, error) {
+	app, err := s.normalizeApp(app)
+	if err != nil {
+		return domain.App{}, err
+	}
+
+	updated, err := s.r.UpdateApp(ctx, app)
+	if err != nil {
+		return domain.App{}, errors.Wrap(err, ErrFailedUpdateApp)
+	}
+
+	return updated, nil
+}
+
+func (s *Auth) GetApp(ctx context.Context, appID int32) (domain.App, error) {
+	app, err := s.r.GetApp(ctx, appID)
+	if err != nil {
+		return domain.App{}, errors.Wrap(err, ErrFailedGetApp)
+	}
+	return app, nil
+}
+
+func (s *Auth) ListApps(ctx context.Context) ([]domain.App, error) {
+	apps, err := s.r.ListApps(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrFailedListApps)
+	}
+	return apps, nil
+}
+
+// DisableApp — вход, Refresh и Logout для приложения отклоняются; выданные access живут до exp
+func (s *Auth) DisableApp(ctx context.Context, appID int32) error {
+	return s.setAppStatus(ctx, appID, domain.AppDisabled)
+}
+
+func (s *Auth) EnableApp(ctx context.Context, appID int32) error {
+	return s.setAppStatus(ctx, appID, domain.AppActive)
+}
+
+func (s *Auth) setAppStatus(ctx context.Context, appID int32, status domain.AppStatus) error {
+	if err := s.r.SetAppStatus(ctx, appID, status); err != nil {
+		return errors.Wrap(err, ErrFailedSetStatus)
+	}
+	return nil
+}
+
+// allowedApp — приложение из реестра, если оно активно и ему разрешён grant (пустой — любой).
+// Неизвестное (при включённом AppsEnforced), отключённое приложение и запрещённый grant — ErrAppAccessDenied
+func (s *Auth) allowedApp(ctx context.Context, appID int32, grant string) (domain.App, error) {
+	app, err := s.r.GetApp(ctx, appID)
+	if errors.Is(err, domain.ErrNotFound) && !s.cfg.AppsEnforced {
+		app, err = legacyApp(appID), nil
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.App{}, errors.Wrapf(domain.ErrAppAccessDenied, "%s: %d", ErrUnknownApp, appID)
+		}
+		return domain.App{}, errors.Wrap(err, ErrFailedGetApp)
+	}
+	if !app.Active() {
+		return domain.App{}, errors.Wrapf(domain.ErrAppAccessDenied, "%s: %d", ErrAppDisabled, appID)
+	}
+	if grant != "" && !app.AllowsGrant(grant) {
+		return domain.App{}, errors.Wrapf(domain.ErrAppAccessDenied, "%s: %s", ErrGrantNotAllowed, grant)
+	}
+
+	return app, nil
+}
+
+// legacyApp — app_id, ещё не перенесённый в реестр: только то, что работало до реестра, TTL по умолчанию
+func legacyApp(appID int32) domain.App {
+	return domain.App{
+		ID:         appID,
+		Status:     domain.AppActive,
+		GrantTypes: []string{domain.GrantPassword, domain.GrantRefreshToken},
+	}
+}
+
+// normalizeApp проверяет настройки и приводит списки к каноничному виду: без повторов, домены в нижнем регистре
+func (s *Auth) normalizeApp(app domain.App) (domain.App, error) {
+	for _, g := range app.GrantTypes {
+		if !slices.Contains(domain.AppGrantTypes, g) {
+			return domain.App{}, errors.Wrapf(domain.ErrValidation, "%s: %q", ErrInvalidGrantType, g)
+		}
+	}
+	if app.AccessTokenTTL < 0 || app.RefreshTokenTTL < 0 {
+		return domain.App{}, errors.Wrap(domain.ErrValidation, ErrInvalidTokenTTL)
+	}
+	// индекс живых access-токенов (rta:) живёт со сроком refresh: более долгий access не попал бы в denylist
+	if app.AccessTTL(s.cfg.AccessTokenTTL) > app.RefreshTTL(s.cfg.RefreshTokenTTL) {
+		return domain.App{}, errors.Wrap(domain.ErrValidation, ErrAccessTTLTooLong)
+	}
+
+	domains := make([]string, 0, len(app.EmailDomains))
+	for _, d := range app.EmailDomains {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d == "" || strings.ContainsAny(d, "@ ") {
+			return domain.App{}, errors.Wrapf(domain.ErrValidation, "%s: %q", ErrInvalidDomain, d)
+		}
+		domains = append(domains, d)
+	}
+	for _, uri := range app.RedirectURIs {
+		if err := validRedirectURI(uri); err != nil {
+			return domain.App{}, errors.Wrapf(domain.ErrValidation, "%s: %v", ErrInvalidRedirect, err)
+		}
+	}
+	if slices.Contains(app.GrantTypes, domain.GrantAuthorizationCode) && len(app.RedirectURIs) == 0 {
+		return domain.App{}, errors.Wrap(domain.ErrValidation, ErrNoRedirectURIs)
+	}
+	for _, scope := range app.Scopes {
+		if !domain.ValidScope(scope) {
+			return domain.App{}, errors.Wrapf(domain.ErrValidation, "%s: %q", ErrInvalidScope, scope)
+		}
+	}
+
+	app.GrantTypes = uniqueSorted(app.GrantTypes)
+	app.EmailDomains = uniqueSorted(domains)
+	app.RedirectURIs = uniqueSorted(app.RedirectURIs)
+	app.Scopes = uniqueSorted(app.Scopes)
+
+	return app, nil
+}
+
+func uniqueSorted(ss []string) []string {
+	ss = slices.Clone(ss)
+	slices.Sort(ss)
+	return slices.Compact(ss)
+}
+
+// validRedirectURI — абсолютный адрес без фрагмента (RFC 6749, 3.1.2); http — только на loopback
+// (нативные приложения, RFC 8252), кастомные схемы приложений разрешены
+func validRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return errors.Wrapf(err, "redirect uri %q", uri)
+	}
+	if !u.IsAbs() || u.Fragment != "" {
+		return errors.Errorf("redirect uri %q: must be absolute and without fragment", uri)
+	}
+	if u.Scheme == "http" && !isLoopback(u.Hostname()) {
+		return errors.Errorf("redirect uri %q: http is allowed only for loopback", uri)
+	}
+	return nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
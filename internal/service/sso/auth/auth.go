@@ -2,6 +2,7 @@ package authservice
 
 import (
 	"context"
+	"time"
 
 	"github.com/eragon-mdi/sso/internal/domain"
 	"github.com/go-faster/errors"
@@ -12,29 +13,188 @@ import (
 type Repository interface {
 	UserRepository
 	TokenRepository
+	OneTimeTokenRepository
+	MfaRepository
+	LoginAttemptRepository
+	PasswordHistoryRepository
+	AuthorizationCodeRepository
+	MachineClientRepository
+	AppRepository
+	DeviceRepository
+	IdentityRepository
+	FederationStateRepository
+	PasswordlessRepository
 }
 
 type UserRepository interface {
 	NewUser(context.Context, domain.User) (domain.User, error)
 	GetUserInfoByEmail(context.Context, string) (domain.User, error)
+	GetUserInfoByID(context.Context, string) (domain.User, error)
+	UpdateUserPassword(_ context.Context, userID, passwordHash string) error
+	MarkEmailVerified(_ context.Context, userID string) error
+	GetUserRoles(_ context.Context, userID string) ([]string, error)
+	// SyncUserRoles выдаёт roles и снимает остальные роли из managed; роли вне managed не меняются
+	SyncUserRoles(_ context.Context, userID string, managed, roles []string) error
+}
+
+// PasswordHistoryRepository — хэши установленных паролей (включая текущий), от новых к старым
+type PasswordHistoryRepository interface {
+	SavePasswordHistory(_ context.Context, userID, passwordHash string) error
+	GetPasswordHistory(_ context.Context, userID string, limit int) ([]string, error)
 }
 
 type TokenRepository interface {
 	SaveRefreshToken(context.Context, domain.RefreshToken) error
 	RotateToken(_ context.Context, oldHash string, newRT domain.RefreshToken) error
 	RevokeTokenByHash(context.Context, string) error
+	RevokeUserTokens(_ context.Context, userID, exceptFamilyID string) (int, error)
+	// RevokeLegacyTokens — метка на ttl: refresh-токены пользователя без семейства больше не принимаются
+	RevokeLegacyTokens(_ context.Context, userID string, ttl time.Duration) error
+	LegacyTokensRevoked(_ context.Context, userID string) (bool, error)
+	// RefreshTokenExists — refresh по хэшу ещё не ротирован и не отозван
+	RefreshTokenExists(_ context.Context, hash string) (bool, error)
+	// SessionExists — семейство (сессия) не отозвано и не истекло
+	SessionExists(_ context.Context, familyID string) (bool, error)
+	// AccessTokenRevoked — jti в denylist; туда попадают access-токены любой отозванной сессии
+	AccessTokenRevoked(_ context.Context, jti string) (bool, error)
+}
+
+// OneTimeTokenRepository — одноразовые токены (сброс пароля и т.п.), хранятся по хэшу
+type OneTimeTokenRepository interface {
+	SaveOneTimeToken(context.Context, domain.OneTimeToken) error
+	// ConsumeOneTimeToken атомарно получает и удаляет токен
+	ConsumeOneTimeToken(_ context.Context, purpose domain.OneTimePurpose, hash string) (domain.OneTimeToken, error)
+}
+
+// AuthorizationCodeRepository — коды authorization code flow, хранятся по хэшу
+type AuthorizationCodeRepository interface {
+	SaveAuthorizationCode(context.Context, domain.AuthorizationCode) error
+	// ConsumeAuthorizationCode атомарно получает и удаляет код; ErrNotFound — нет, истёк или уже погашен
+	ConsumeAuthorizationCode(_ context.Context, hash string) (domain.AuthorizationCode, error)
+}
+
+// MachineClientRepository — машинные клиенты (client_credentials); секрет хранится только хэшем
+type MachineClientRepository interface {
+	NewMachineClient(context.Context, domain.MachineClient) error
+	// GetMachineClient — и отключённый клиент тоже; ErrNotFound — нет такого
+	GetMachineClient(_ context.Context, clientID string) (domain.MachineClient, error)
+	// UpdateMachineClientSecret — ErrNotFound: нет такого или клиент отключён
+	UpdateMachineClientSecret(_ context.Context, clientID string, secretHash []byte) error
+	// DisableMachineClient — повторное отключение не ошибка; ErrNotFound — нет такого
+	DisableMachineClient(_ context.Context, clientID string) error
+}
+
+// AppRepository — реестр приложений (app_id)
+type AppRepository interface {
+	// NewApp — ErrDuplicate: app_id занят
+	NewApp(context.Context, domain.App) (domain.App, error)
+	// GetApp — и отключённое тоже; ErrNotFound — нет такого
+	GetApp(_ context.Context, appID int32) (domain.App, error)
+	ListApps(context.Context) ([]domain.App, error)
+	// UpdateApp меняет настройки, статус не трогает; ErrNotFound — нет такого
+	UpdateApp(context.Context, domain.App) (domain.App, error)
+	// SetAppStatus — ErrNotFound: нет такого
+	SetAppStatus(_ context.Context, appID int32, status domain.AppStatus) error
+}
+
+// DeviceRepository — устройства, выданные сервером (RegisterDevice)
+type DeviceRepository interface {
+	// NewDevice — ErrDuplicate: device_id занят
+	NewDevice(context.Context, domain.Device) (domain.Device, error)
+	// TouchDevice обновляет last_seen; ErrNotFound — нет такого устройства или оно чужое
+	TouchDevice(_ context.Context, deviceID int32, userID string) error
+}
+
+// IdentityRepository — привязки учётных записей внешних IdP к пользователям
+type IdentityRepository interface {
+	// NewIdentity — ErrDuplicate: учётная запись IdP уже привязана
+	NewIdentity(context.Context, domain.Identity) error
+	GetIdentity(_ context.Context, provider, subject string) (domain.Identity, error)
+}
+
+// FederationStateRepository — state незавершённых входов через IdP, хранятся по хэшу
+type FederationStateRepository interface {
+	SaveFederationState(context.Context, domain.FederationState) error
+	// ConsumeFederationState атомарно получает и удаляет state; ErrNotFound — нет, истёк или уже использован
+	ConsumeFederationState(_ context.Context, hash string) (domain.FederationState, error)
+}
+
+// PasswordlessRepository — challenge входа без пароля с ограничением попыток ввода кода
+type PasswordlessRepository interface {
+	SavePasswordlessChallenge(context.Context, domain.PasswordlessChallenge) error
+	// ConsumePasswordlessChallenge гасит challenge, если хэш кода совпал (у ссылки — пустой). Иначе тратит попытку
+	// и возвращает challenge (Attempts — сколько осталось) с ErrValidation, последняя попытка удаляет challenge;
+	// ErrNotFound — нет, истёк или уже погашен
+	ConsumePasswordlessChallenge(_ context.Context, hash, codeHash string) (domain.PasswordlessChallenge, error)
+}
+
+type MfaRepository interface {
+	// SaveTotpSecret сохраняет (или заменяет неподтверждённый) секрет; ErrDuplicate — MFA уже включена
+	SaveTotpSecret(_ context.Context, userID string, encryptedSecret []byte) error
+	GetUserMfa(_ context.Context, userID string) (domain.UserMfa, error)
+	ConfirmTotp(_ context.Context, userID string, step int64) error
+	// UseTotpStep фиксирует принятый шаг; ErrTokenReuse — код этого (или более позднего) шага уже использован
+	UseTotpStep(_ context.Context, userID string, step int64) error
+}
+
+// LoginAttemptRepository — счётчики неудачных входов и блокировки по ключам (email, ip)
+type LoginAttemptRepository interface {
+	// LoginLockedFor — оставшийся срок самой долгой из блокировок ключей, 0 — не заблокирован
+	LoginLockedFor(_ context.Context, keys []string) (time.Duration, error)
+	// RegisterLoginFailure учитывает ошибку; возвращает срок блокировки, если порог политики достигнут
+	RegisterLoginFailure(_ context.Context, key string, p domain.LockoutPolicy) (time.Duration, error)
+	ResetLoginFailures(_ context.Context, keys []string) error
 }
 
 //go:generate mockery --name=PasswordHasher --with-expecter --output=./mocks/password-hasher --exported
 type PasswordHasher interface {
 	Gen([]byte) ([]byte, error)
 	Compare(hash []byte, pass []byte) (bool, error)
+	// NeedsRehash — хэш сделан другим алгоритмом или с другими параметрами, чем текущие
+	NeedsRehash(hash []byte) bool
+}
+
+//go:generate mockery --name=PasswordPolicy --with-expecter --output=./mocks/password-policy --exported
+type PasswordPolicy interface {
+	// Check — нарушения статических правил приложения (длина, классы символов, словарь, email)
+	Check(appID int32, email, pass string) []domain.PasswordViolation
+	// HistoryDepth — сколько последних паролей нельзя использовать повторно
+	HistoryDepth(appID int32) int
+}
+
+//go:generate mockery --name=BreachChecker --with-expecter --output=./mocks/breach-checker --exported
+type BreachChecker interface {
+	// Breached — сколько раз пароль встречался в утечках, 0 — не найден
+	Breached(pass string) (int, error)
 }
 
 //go:generate mockery --name=Tokener --with-expecter --output=./mocks/tokener --exported
 type Tokener interface {
-	GenPair(domain.Meta) (access, refresh []byte, err error)
+	GenPair(access, refresh domain.Meta) ([]byte, []byte, error)
+	GenAccess(domain.Meta) ([]byte, error) // access без refresh (client_credentials)
+	GenIDToken(domain.IDToken) ([]byte, error)
 	VerifyRefresh([]byte) (domain.Meta, error)
+	Verify([]byte) (domain.Meta, error) // access или refresh
+	JWKS() domain.JWKS                  // открытые ключи active и retiring
+}
+
+// ExternalIdentityProvider — внешний IdP (OIDC). Ошибки из-за ответа IdP или самого code — ErrValidation
+//
+//go:generate mockery --name=ExternalIdentityProvider --with-expecter --output=./mocks/identity-provider --exported
+type ExternalIdentityProvider interface {
+	// AuthCodeURL — адрес входа у IdP; state, nonce и code_challenge (S256) выпускает сервис
+	AuthCodeURL(_ context.Context, state, nonce, codeChallenge string) (string, error)
+	// Exchange меняет code на личность из id_token: подпись, iss, aud, exp и nonce проверены
+	Exchange(_ context.Context, code, codeVerifier, nonce string) (domain.ExternalIdentity, error)
+}
+
+// DirectoryAuthenticator — внешний каталог (LDAP / AD) для Login. Неверный логин или пароль — ErrValidation
+//
+//go:generate mockery --name=DirectoryAuthenticator --with-expecter --output=./mocks/directory --exported
+type DirectoryAuthenticator interface {
+	Authenticate(_ context.Context, login, password string) (domain.DirectoryUser, error)
+	// ManagedRoles — все роли из сопоставления групп: их выдаёт и снимает только каталог
+	ManagedRoles() []string
 }
 
 //go:generate mockery --name=TokenHasher --with-expecter --output=./mocks/token-hasher --exported
@@ -42,6 +202,24 @@ type TokenHasher interface {
 	Sum([]byte) ([]byte, error) // например, HMAC-SHA256(secret, token)
 }
 
+//go:generate mockery --name=Totp --with-expecter --output=./mocks/totp --exported
+type Totp interface {
+	NewSecret() (string, error)
+	URI(account, secret string) string
+	Validate(secret, code string, at time.Time) (step int64, ok bool)
+}
+
+//go:generate mockery --name=SecretCipher --with-expecter --output=./mocks/secret-cipher --exported
+type SecretCipher interface {
+	Seal([]byte) ([]byte, error)
+	Open([]byte) ([]byte, error)
+}
+
+//go:generate mockery --name=Notifier --with-expecter --output=./mocks/notifier --exported
+type Notifier interface {
+	Notify(context.Context, domain.Notification) error
+}
+
 const (
 	ErrFailedHashPass      = "failed to hash pass"
 	ErrFailedSaveUser      = "failed save new user in repo"
@@ -54,9 +232,47 @@ const (
 	ErrFailedHashToken     = "failed hashing refresh token"
 	ErrFailedRotateToken   = "rotate failed"
 	ErrFailedRevokeToken   = "failed get refresh token: internal"
+	ErrTokenReuseDetected  = "refresh token reuse detected: token family revoked"
+	ErrFailedRevokeAll     = "failed revoke all user sessions"
+	ErrWrongPassword       = "wrong password"
+	ErrFailedUpdatePass    = "failed update user password"
+	ErrFailedSendReset     = "failed send password reset token"
+	ErrInvalidResetToken   = "password reset token invalid or expired"
+	ErrFailedConsumeReset  = "failed consume password reset token"
+	ErrFailedSendVerify    = "failed send email verification"
+	ErrInvalidVerifyToken  = "email verification token invalid or expired"
+	ErrFailedConsumeVerify = "failed consume email verification token"
+	ErrFailedMarkVerified  = "failed mark email verified"
+	ErrEmailNotVerified    = "email not verified for this app"
+	ErrFailedGetMfa        = "failed get user mfa"
+	ErrFailedGenSecret     = "failed generate totp secret"
+	ErrFailedSealSecret    = "failed encrypt totp secret"
+	ErrFailedOpenSecret    = "failed decrypt totp secret"
+	ErrFailedSaveSecret    = "failed save totp secret"
+	ErrMfaAlreadyEnabled   = "mfa already enabled"
+	ErrMfaNotPending       = "no pending totp enrollment"
+	ErrWrongTotpCode       = "wrong totp code"
+	ErrFailedConfirmTotp   = "failed confirm totp"
+	ErrFailedIssueMfa      = "failed issue mfa challenge"
+	ErrInvalidMfaChallenge = "mfa challenge invalid or expired"
+	ErrFailedConsumeMfa    = "failed consume mfa challenge"
+	ErrWrongCredentials    = "wrong email or password"
+	ErrFailedClearLockout  = "failed clear login lockout"
+	ErrFailedCheckPolicy   = "failed check password policy"
+	ErrFailedSaveHistory   = "failed save password history"
+	ErrFailedRestoreReset  = "failed restore password reset token"
+	ErrFailedCheckRevoked  = "failed check token revocation"
+	ErrFailedGetRoles      = "failed get user roles"
+	ErrFailedCheckDenylist = "failed check access token denylist"
 )
 
-func (s *Auth) Register(ctx context.Context, u domain.User) (domain.User, error) {
+// Register создаёт пользователя; пароль проверяется политикой приложения appID (0 — политика по умолчанию).
+// Если не ушло письмо подтверждения, пользователь всё равно возвращается — вместе с ошибкой domain.ErrNotDelivered
+func (s *Auth) Register(ctx context.Context, u domain.User, appID int32) (domain.User, error) {
+	if err := s.checkPassword(ctx, appID, u, u.Password); err != nil {
+		return domain.User{}, err
+	}
+
 	hashedPass, err := s.passHasher.Gen([]byte(u.Password))
 	if err != nil {
 		return domain.User{}, errors.Wrap(err, ErrFailedHashPass)
@@ -71,62 +287,167 @@ func (s *Auth) Register(ctx context.Context, u domain.User) (domain.User, error)
 		}
 		return domain.User{}, errors.Wrap(err, ErrFailedSaveUser)
 	}
+	if err := s.r.SavePasswordHistory(ctx, user.ID, string(hashedPass)); err != nil {
+		return domain.User{}, errors.Wrap(err, ErrFailedSaveHistory)
+	}
+
+	// пользователь уже создан: при сбое отправки письмо можно запросить повторно (ResendEmailVerification)
+	if err := s.sendOneTimeToken(ctx, user, domain.PurposeEmailVerification, s.cfg.EmailVerificationTTL); err != nil {
+		return user, errors.Wrapf(domain.ErrNotDelivered, "%s: %v", ErrFailedSendVerify, err)
+	}
 
 	return user, nil
 }
 
-func (s *Auth) Login(ctx context.Context, u domain.User, dctx domain.DeviceCtx) (domain.Token, error) {
-	originPass := []byte(u.Password)
+// Login — первый шаг входа. Если у пользователя включена MFA, вместо пары токенов
+// возвращается челлендж, который обменивается на токены в CompleteMfaLogin
+func (s *Auth) Login(ctx context.Context, u domain.User, dctx domain.DeviceCtx) (domain.LoginResult, error) {
+	app, err := s.allowedApp(ctx, dctx.AppId, domain.GrantPassword)
+	if err != nil {
+		return domain.LoginResult{}, err
+	}
 
-	// авторизация
-	u, err := s.r.GetUserInfoByEmail(ctx, u.Email)
+	u, err = s.authenticatePassword(ctx, u, app)
 	if err != nil {
-		return domain.Token{}, errors.Wrap(err, ErrFailedGetUserInfo)
+		return domain.LoginResult{}, err
 	}
-	hashedPass := u.Password
-	isCorrect, err := s.passHasher.Compare([]byte(hashedPass), originPass)
-	if err != nil || !isCorrect {
-		return domain.Token{}, errors.Wrap(err, ErrFailedCheckPass)
+	// чужое или не выданное сервером устройство отклоняется до MFA: челлендж привязан к dctx
+	if err := s.deviceOwned(ctx, u.ID, dctx); err != nil {
+		return domain.LoginResult{}, err
+	}
+
+	return s.sessionOrMfa(ctx, app, u.ID, dctx)
+}
+
+// ClearLoginLockout снимает блокировку и обнуляет счётчики неудачных входов (админская операция).
+// Пустой email или ip пропускается
+func (s *Auth) ClearLoginLockout(ctx context.Context, email, ip string) error {
+	var keys []string
+	if email != "" {
+		keys = append(keys, emailAttemptKey(email))
+	}
+	if ip != "" {
+		keys = append(keys, ipAttemptKey(ip))
+	}
+
+	if err := s.r.ResetLoginFailures(ctx, keys); err != nil {
+		return errors.Wrap(err, ErrFailedClearLockout)
+	}
+
+	return nil
+}
+
+// CompleteMfaLogin — второй шаг входа: челлендж одноразовый и привязан к устройству первого шага
+func (s *Auth) CompleteMfaLogin(ctx context.Context, challenge, code string, dctx domain.DeviceCtx) (domain.Token, error) {
+	app, err := s.allowedApp(ctx, dctx.AppId, domain.GrantPassword)
+	if err != nil {
+		return domain.Token{}, err
+	}
+
+	hash, err := s.tokenHasher.Sum([]byte(challenge))
+	if err != nil {
+		return domain.Token{}, errors.Wrap(err, ErrFailedHashToken)
 	}
 
-	token, newRt, err := s.genTokensFlow(u.ID, dctx)
+	ott, err := s.r.ConsumeOneTimeToken(ctx, domain.PurposeMfaChallenge, string(hash))
 	if err != nil {
-		return domain.Token{}, errors.Wrap(err, ErrFailedGenerateToken)
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.Token{}, errors.Wrap(domain.ErrValidation, ErrInvalidMfaChallenge)
+		}
+		return domain.Token{}, errors.Wrap(err, ErrFailedConsumeMfa)
+	}
+	if !dctx.Compare(ott.Ctx) {
+		return domain.Token{}, errors.Wrap(domain.ErrValidation, ErrUnauthenticatedCtx)
 	}
 
-	if err := s.r.SaveRefreshToken(ctx, *newRt); err != nil {
-		return domain.Token{}, errors.Wrap(err, ErrFailedSaveToken)
+	mfa, err := s.r.GetUserMfa(ctx, ott.UserID)
+	if err != nil {
+		return domain.Token{}, errors.Wrap(err, ErrFailedGetMfa)
+	}
+	if err := s.checkTotp(ctx, mfa, code); err != nil {
+		return domain.Token{}, err
 	}
 
-	return *token, nil
+	return s.openSession(ctx, app, ott.UserID, dctx, nil)
 }
 
 func (s *Auth) Refresh(ctx context.Context, oldRefresh string, dctx domain.DeviceCtx) (domain.Token, error) {
-	m, err := s.verificationToken(oldRefresh, dctx)
+	if err := s.sessionDevice(dctx); err != nil {
+		return domain.Token{}, err
+	}
+	token, _, err := s.rotateRefresh(ctx, oldRefresh, dctx)
+	return token, err
+}
+
+// rotateRefresh — ротация refresh; возвращает и приложение, чьи настройки TTL применены к новой паре
+func (s *Auth) rotateRefresh(ctx context.Context, oldRefresh string, dctx domain.DeviceCtx) (domain.Token, domain.App, error) {
+	m, err := s.verificationToken(ctx, oldRefresh, dctx)
+	if err != nil {
+		return domain.Token{}, domain.App{}, errors.Wrap(err, ErrFailedVerifyToken)
+	}
+	app, err := s.allowedApp(ctx, dctx.AppId, domain.GrantRefreshToken)
 	if err != nil {
-		return domain.Token{}, errors.Wrap(err, ErrFailedVerifyToken)
+		return domain.Token{}, domain.App{}, err
+	}
+	if err := s.deviceOwned(ctx, m.UserID, dctx); err != nil {
+		return domain.Token{}, domain.App{}, err
+	}
+	if m.Legacy() {
+		token, err := s.exchangeLegacy(ctx, app, oldRefresh, m)
+		return token, app, err
 	}
 
-	token, newRt, err := s.genTokensFlow(m.UserID, m.Ctx)
+	token, newRt, err := s.genTokensFlow(ctx, app, m.UserID, m.FamilyID, m.Ctx, m.Scopes)
 	if err != nil {
-		return domain.Token{}, errors.Wrap(err, ErrFailedGenerateToken)
+		return domain.Token{}, domain.App{}, errors.Wrap(err, ErrFailedGenerateToken)
 	}
 
 	oldRefreshHash, err := s.tokenHasher.Sum([]byte(oldRefresh))
 	if err != nil {
-		return domain.Token{}, errors.Wrap(err, ErrFailedHashToken)
+		return domain.Token{}, domain.App{}, errors.Wrap(err, ErrFailedHashToken)
 	}
 	if err := s.r.RotateToken(ctx, string(oldRefreshHash), *newRt); err != nil {
-		return domain.Token{}, errors.Wrap(err, ErrFailedRotateToken)
+		// предъявлен уже ротированный токен: репозиторий отозвал всё семейство
+		if errors.Is(err, domain.ErrTokenReuse) {
+			return domain.Token{}, domain.App{}, errors.Wrap(err, ErrTokenReuseDetected)
+		}
+		return domain.Token{}, domain.App{}, errors.Wrap(err, ErrFailedRotateToken)
+	}
+
+	return *token, app, nil
+}
+
+// exchangeLegacy — refresh без семейства не ротируется, а один раз меняется на новую сессию:
+// отзыв первым, повтор того же токена получает ошибку
+func (s *Auth) exchangeLegacy(ctx context.Context, app domain.App, refresh string, m domain.Meta) (domain.Token, error) {
+	hash, err := s.tokenHasher.Sum([]byte(refresh))
+	if err != nil {
+		return domain.Token{}, errors.Wrap(err, ErrFailedHashToken)
+	}
+	if err := s.r.RevokeTokenByHash(ctx, string(hash)); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.Token{}, errors.Wrap(domain.ErrValidation, ErrRefreshNotActive)
+		}
+		return domain.Token{}, errors.Wrap(err, ErrFailedRevokeToken)
 	}
 
-	return *token, nil
+	return s.openSession(ctx, app, m.UserID, m.Ctx, nil)
 }
 
 func (s *Auth) Logout(ctx context.Context, refresh string, dctx domain.DeviceCtx) error {
-	if _, err := s.verificationToken(refresh, dctx); err != nil {
+	m, err := s.verificationToken(ctx, refresh, dctx)
+	if err != nil {
 		return errors.Wrap(err, ErrFailedVerifyToken)
 	}
+	if _, err := s.allowedApp(ctx, dctx.AppId, ""); err != nil {
+		return err
+	}
+	// legacy device_id после переходного периода не мешает выходу: отзывается только сам токен
+	if domain.IssuedDevice(dctx.DeviceID) {
+		if err := s.deviceOwned(ctx, m.UserID, dctx); err != nil {
+			return err
+		}
+	}
 
 	hashBytes, err := s.tokenHasher.Sum([]byte(refresh))
 	if err != nil {
@@ -139,3 +460,220 @@ func (s *Auth) Logout(ctx context.Context, refresh string, dctx domain.DeviceCtx
 
 	return nil
 }
+
+// LogoutAll отзывает все сессии (семейства refresh-токенов) пользователя
+func (s *Auth) LogoutAll(ctx context.Context, userID string) error {
+	return s.revokeUserTokens(ctx, userID, "")
+}
+
+// ChangePassword меняет пароль владельца refresh-токена и отзывает все его сессии,
+// кроме текущей (семейства, к которому относится refresh).
+// Refresh должен быть действующим; неверный старый пароль учитывается блокировкой перебора, как при Login
+func (s *Auth) ChangePassword(ctx context.Context, refresh string, dctx domain.DeviceCtx, oldPass, newPass string) error {
+	m, err := s.activeRefresh(ctx, refresh, dctx)
+	if err != nil {
+		return errors.Wrap(err, ErrFailedVerifyToken)
+	}
+
+	u, err := s.r.GetUserInfoByID(ctx, m.UserID)
+	if err != nil {
+		return errors.Wrap(err, ErrFailedGetUserInfo)
+	}
+	if !u.HasLocalPassword() {
+		return errors.Wrap(domain.ErrValidation, ErrDirectoryPassword)
+	}
+
+	keys := loginAttemptKeys(ctx, u.Email)
+	if err := s.checkLoginLock(ctx, keys); err != nil {
+		return err
+	}
+	isCorrect, err := s.passHasher.Compare([]byte(u.Password), []byte(oldPass))
+	if err != nil || !isCorrect {
+		return errors.Wrap(s.loginFailed(ctx, keys), ErrWrongPassword)
+	}
+	if err := s.r.ResetLoginFailures(ctx, []string{emailAttemptKey(u.Email)}); err != nil {
+		return errors.Wrap(err, ErrFailedResetAttempts)
+	}
+	if err := s.checkPassword(ctx, dctx.AppId, u, newPass); err != nil {
+		return err
+	}
+
+	if err := s.setPassword(ctx, u.ID, newPass); err != nil {
+		return err
+	}
+
+	return s.revokeUserTokens(ctx, u.ID, m.FamilyID)
+}
+
+// RequestPasswordReset выпускает одноразовый токен сброса пароля и отправляет его через Notifier.
+// Для неизвестного email молча возвращает nil, чтобы не раскрывать наличие аккаунта
+func (s *Auth) RequestPasswordReset(ctx context.Context, email string) error {
+	u, err := s.r.GetUserInfoByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil
+		}
+		return errors.Wrap(err, ErrFailedGetUserInfo)
+	}
+	// пароль пользователя каталога меняется в каталоге: локальный пароль обошёл бы его блокировку
+	if !u.HasLocalPassword() {
+		return nil
+	}
+
+	if err := s.sendOneTimeToken(ctx, u, domain.PurposePasswordReset, s.cfg.PasswordResetTTL); err != nil {
+		return errors.Wrap(err, ErrFailedSendReset)
+	}
+
+	return nil
+}
+
+// ConfirmPasswordReset гасит токен сброса, устанавливает новый пароль и отзывает все сессии пользователя.
+// Если пароль не проходит политику приложения appID, токен возвращается — можно повторить с другим паролем
+func (s *Auth) ConfirmPasswordReset(ctx context.Context, token, newPass string, appID int32) error {
+	hash, err := s.tokenHasher.Sum([]byte(token))
+	if err != nil {
+		return errors.Wrap(err, ErrFailedHashToken)
+	}
+
+	ott, err := s.r.ConsumeOneTimeToken(ctx, domain.PurposePasswordReset, string(hash))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return errors.Wrap(domain.ErrValidation, ErrInvalidResetToken)
+		}
+		return errors.Wrap(err, ErrFailedConsumeReset)
+	}
+
+	u, err := s.r.GetUserInfoByID(ctx, ott.UserID)
+	if err != nil {
+		return errors.Wrap(err, ErrFailedGetUserInfo)
+	}
+	if !u.HasLocalPassword() {
+		return errors.Wrap(domain.ErrValidation, ErrDirectoryPassword)
+	}
+	if err := s.checkPassword(ctx, appID, u, newPass); err != nil {
+		if errors.Is(err, domain.ErrWeakPassword) {
+			if err := s.r.SaveOneTimeToken(ctx, ott); err != nil {
+				return errors.Wrap(err, ErrFailedRestoreReset)
+			}
+		}
+		return err
+	}
+
+	if err := s.setPassword(ctx, u.ID, newPass); err != nil {
+		return err
+	}
+
+	return s.revokeUserTokens(ctx, u.ID, "")
+}
+
+// VerifyEmail гасит токен подтверждения и отмечает email пользователя подтверждённым
+func (s *Auth) VerifyEmail(ctx context.Context, token string) error {
+	hash, err := s.tokenHasher.Sum([]byte(token))
+	if err != nil {
+		return errors.Wrap(err, ErrFailedHashToken)
+	}
+
+	ott, err := s.r.ConsumeOneTimeToken(ctx, domain.PurposeEmailVerification, string(hash))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return errors.Wrap(domain.ErrValidation, ErrInvalidVerifyToken)
+		}
+		return errors.Wrap(err, ErrFailedConsumeVerify)
+	}
+
+	if err := s.r.MarkEmailVerified(ctx, ott.UserID); err != nil {
+		return errors.Wrap(err, ErrFailedMarkVerified)
+	}
+
+	return nil
+}
+
+// ResendEmailVerification повторно отправляет токен подтверждения.
+// Для неизвестного или уже подтверждённого email молча возвращает nil
+func (s *Auth) ResendEmailVerification(ctx context.Context, email string) error {
+	u, err := s.r.GetUserInfoByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil
+		}
+		return errors.Wrap(err, ErrFailedGetUserInfo)
+	}
+	if u.IsEmailVerified() {
+		return nil
+	}
+
+	if err := s.sendOneTimeToken(ctx, u, domain.PurposeEmailVerification, s.cfg.EmailVerificationTTL); err != nil {
+		return errors.Wrap(err, ErrFailedSendVerify)
+	}
+
+	return nil
+}
+
+// BeginTotpEnrollment выдаёт новый TOTP-секрет владельцу refresh-токена.
+// До ConfirmTotpEnrollment MFA не включена, повторный вызов заменяет секрет
+func (s *Auth) BeginTotpEnrollment(ctx context.Context, refresh string, dctx domain.DeviceCtx) (domain.TotpEnrollment, error) {
+	m, err := s.activeRefresh(ctx, refresh, dctx)
+	if err != nil {
+		return domain.TotpEnrollment{}, errors.Wrap(err, ErrFailedVerifyToken)
+	}
+
+	u, err := s.r.GetUserInfoByID(ctx, m.UserID)
+	if err != nil {
+		return domain.TotpEnrollment{}, errors.Wrap(err, ErrFailedGetUserInfo)
+	}
+
+	secret, err := s.totp.NewSecret()
+	if err != nil {
+		return domain.TotpEnrollment{}, errors.Wrap(err, ErrFailedGenSecret)
+	}
+	sealed, err := s.secretCipher.Seal([]byte(secret))
+	if err != nil {
+		return domain.TotpEnrollment{}, errors.Wrap(err, ErrFailedSealSecret)
+	}
+
+	if err := s.r.SaveTotpSecret(ctx, u.ID, sealed); err != nil {
+		if errors.Is(err, domain.ErrDuplicate) {
+			return domain.TotpEnrollment{}, errors.Wrap(domain.ErrDuplicate, ErrMfaAlreadyEnabled)
+		}
+		return domain.TotpEnrollment{}, errors.Wrap(err, ErrFailedSaveSecret)
+	}
+
+	return domain.TotpEnrollment{
+		Secret: secret,
+		URI:    s.totp.URI(u.Email, secret),
+	}, nil
+}
+
+// ConfirmTotpEnrollment включает MFA, если код из аутентификатора верный
+func (s *Auth) ConfirmTotpEnrollment(ctx context.Context, refresh string, dctx domain.DeviceCtx, code string) error {
+	m, err := s.activeRefresh(ctx, refresh, dctx)
+	if err != nil {
+		return errors.Wrap(err, ErrFailedVerifyToken)
+	}
+
+	mfa, err := s.r.GetUserMfa(ctx, m.UserID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return errors.Wrap(domain.ErrNotFound, ErrMfaNotPending)
+		}
+		return errors.Wrap(err, ErrFailedGetMfa)
+	}
+	if mfa.Enabled() {
+		return errors.Wrap(domain.ErrDuplicate, ErrMfaAlreadyEnabled)
+	}
+
+	step, err := s.matchTotp(mfa, code)
+	if err != nil {
+		return err
+	}
+	if err := s.r.ConfirmTotp(ctx, m.UserID, step); err != nil {
+		return errors.Wrap(err, ErrFailedConfirmTotp)
+	}
+
+	return nil
+}
+
+// JWKS — открытые ключи для локальной проверки access-токенов на resource-серверах
+func (s *Auth) JWKS() domain.JWKS {
+	return s.tokener.JWKS()
+}
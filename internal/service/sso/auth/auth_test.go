@@ -2,8 +2,11 @@ package authservice
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,18 +15,84 @@ import (
 	"github.com/eragon-mdi/sso/internal/common/configs"
 	"github.com/eragon-mdi/sso/internal/domain"
 
+	mocks_breach "github.com/eragon-mdi/sso/internal/service/sso/auth/mocks/breach-checker"
+	mocks_directory "github.com/eragon-mdi/sso/internal/service/sso/auth/mocks/directory"
+	mocks_idp "github.com/eragon-mdi/sso/internal/service/sso/auth/mocks/identity-provider"
+	mocks_notifier "github.com/eragon-mdi/sso/internal/service/sso/auth/mocks/notifier"
 	mocks_hasher "github.com/eragon-mdi/sso/internal/service/sso/auth/mocks/password-hasher"
+	mocks_policy "github.com/eragon-mdi/sso/internal/service/sso/auth/mocks/password-policy"
 	mocks_repo "github.com/eragon-mdi/sso/internal/service/sso/auth/mocks/repository"
+	mocks_cipher "github.com/eragon-mdi/sso/internal/service/sso/auth/mocks/secret-cipher"
 	mocks_tokenhasher "github.com/eragon-mdi/sso/internal/service/sso/auth/mocks/token-hasher"
 	mocks_tokener "github.com/eragon-mdi/sso/internal/service/sso/auth/mocks/tokener"
+	mocks_totp "github.com/eragon-mdi/sso/internal/service/sso/auth/mocks/totp"
 )
 
 func baseCfg() *configs.BussinesLogic {
 	return &configs.BussinesLogic{
-		TokenTTL: time.Hour,
+		AccessTokenTTL:       15 * time.Minute,
+		RefreshTokenTTL:      time.Hour,
+		PasswordResetTTL:     15 * time.Minute,
+		EmailVerificationTTL: 24 * time.Hour,
+		OAuthCodeTTL:         time.Minute,
+		AppsEnforced:         true,
 	}
 }
 
+// permissivePolicy — политика паролей, которая пропускает всё
+func permissivePolicy() *mocks_policy.PasswordPolicy {
+	p := &mocks_policy.PasswordPolicy{}
+	p.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	p.On("HistoryDepth", mock.Anything).Return(0).Maybe()
+	return p
+}
+
+// notBreached — пароль не найден в корпусе утечек
+func notBreached() *mocks_breach.BreachChecker {
+	b := &mocks_breach.BreachChecker{}
+	b.On("Breached", mock.Anything).Return(0, nil).Maybe()
+	return b
+}
+
+// noLockout — вход не заблокирован, счётчики сбрасываются без ошибок
+// genTokensFlow кладёт роли в access-токен
+func noRoles(repo *mocks_repo.Repository) {
+	repo.On("GetUserRoles", mock.Anything, mock.Anything).Return([]string(nil), nil).Maybe()
+}
+
+func noLockout(repo *mocks_repo.Repository) {
+	repo.On("LoginLockedFor", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+	repo.On("ResetLoginFailures", mock.Anything, mock.Anything).Return(nil).Maybe()
+}
+
+// anyApp — любой app_id зарегистрирован, активен, ему разрешены все grant-ы, TTL по умолчанию
+func anyApp(repo *mocks_repo.Repository) {
+	repo.On("GetApp", mock.Anything, mock.Anything).Return(func(_ context.Context, id int32) (domain.App, error) {
+		return domain.App{ID: id, Status: domain.AppActive, GrantTypes: domain.AppGrantTypes}, nil
+	}).Maybe()
+}
+
+// liveRefresh — предъявленный refresh не отозван и не ротирован
+func liveRefresh(repo *mocks_repo.Repository) *mocks_tokenhasher.TokenHasher {
+	tokenHasher := &mocks_tokenhasher.TokenHasher{}
+	tokenHasher.On("Sum", mock.Anything).Return([]byte("refresh-hash"), nil).Maybe()
+	repo.On("RefreshTokenExists", mock.Anything, "refresh-hash").Return(true, nil).Maybe()
+	return tokenHasher
+}
+
+// anyDevice — любой выданный сервером (отрицательный) device_id принадлежит владельцу токена
+func anyDevice(repo *mocks_repo.Repository) {
+	repo.On("TouchDevice", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+}
+
+// withApps — в реестре только apps
+func withApps(repo *mocks_repo.Repository, apps ...domain.App) {
+	for _, app := range apps {
+		repo.On("GetApp", mock.Anything, app.ID).Return(app, nil).Maybe()
+	}
+	repo.On("GetApp", mock.Anything, mock.Anything).Return(domain.App{}, domain.ErrNotFound).Maybe()
+}
+
 func TestRegister_AllCases(t *testing.T) {
 	ctx := context.Background()
 	inUser := domain.User{
@@ -37,21 +106,63 @@ func TestRegister_AllCases(t *testing.T) {
 		repo.On("NewUser", mock.Anything, mock.MatchedBy(func(u domain.User) bool {
 			return u.Email == inUser.Email && u.Password != inUser.Password // пароль должен быть уже захеширован
 		})).Return(func(_ context.Context, u domain.User) domain.User { return u }, nil)
+		repo.On("SavePasswordHistory", mock.Anything, mock.Anything, "hashed-pass").Return(nil)
+
+		repo.On("SaveOneTimeToken", mock.Anything, mock.MatchedBy(func(ott domain.OneTimeToken) bool {
+			return ott.Purpose == domain.PurposeEmailVerification && ott.Hash == "verify-hash"
+		})).Return(nil)
 
 		hasher := &mocks_hasher.PasswordHasher{}
 		hasher.On("Gen", mock.Anything).Return([]byte("hashed-pass"), nil)
 
-		s := New(repo, hasher, nil, nil, baseCfg())
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("verify-hash"), nil)
+
+		notifier := &mocks_notifier.Notifier{}
+		notifier.On("Notify", mock.Anything, mock.MatchedBy(func(n domain.Notification) bool {
+			return n.Purpose == domain.PurposeEmailVerification && n.To == inUser.Email && n.Token != ""
+		})).Return(nil)
+
+		s := New(repo, hasher, permissivePolicy(), notBreached(), nil, tokenHasher, notifier, nil, nil, nil, nil, baseCfg())
 
-		got, err := s.Register(ctx, inUser)
+		got, err := s.Register(ctx, inUser, 0)
 		if err != nil {
 			t.Fatalf("unexpected err: %v", err)
 		}
 		if got.Email != inUser.Email {
 			t.Fatalf("email mismatch: got %q want %q", got.Email, inUser.Email)
 		}
+		if got.IsEmailVerified() {
+			t.Fatal("new user must not be verified")
+		}
 		hasher.AssertCalled(t, "Gen", mock.Anything)
 		repo.AssertExpectations(t)
+		notifier.AssertExpectations(t)
+	})
+
+	t.Run("verification delivery fail -> user created, ErrNotDelivered", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("NewUser", mock.Anything, mock.Anything).Return(func(_ context.Context, u domain.User) domain.User { return u }, nil)
+		repo.On("SavePasswordHistory", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		repo.On("SaveOneTimeToken", mock.Anything, mock.Anything).Return(nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Gen", mock.Anything).Return([]byte("hashed-pass"), nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("verify-hash"), nil)
+
+		notifier := &mocks_notifier.Notifier{}
+		notifier.On("Notify", mock.Anything, mock.Anything).Return(errors.New("smtp down"))
+
+		s := New(repo, hasher, permissivePolicy(), notBreached(), nil, tokenHasher, notifier, nil, nil, nil, nil, baseCfg())
+		got, err := s.Register(ctx, inUser, 0)
+		if !errors.Is(err, domain.ErrNotDelivered) {
+			t.Fatalf("expected wrapped domain.ErrNotDelivered; got: %v", err)
+		}
+		if got.ID == "" {
+			t.Fatal("created user must be returned along with delivery error")
+		}
 	})
 
 	t.Run("hash fail", func(t *testing.T) {
@@ -59,8 +170,8 @@ func TestRegister_AllCases(t *testing.T) {
 		hasher := &mocks_hasher.PasswordHasher{}
 		hasher.On("Gen", mock.Anything).Return([]byte(nil), errors.New("hash fail"))
 
-		s := New(repo, hasher, nil, nil, baseCfg())
-		_, err := s.Register(ctx, inUser)
+		s := New(repo, hasher, permissivePolicy(), notBreached(), nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.Register(ctx, inUser, 0)
 		if err == nil {
 			t.Fatal("expected error but got nil")
 		}
@@ -74,8 +185,8 @@ func TestRegister_AllCases(t *testing.T) {
 		hasher := &mocks_hasher.PasswordHasher{}
 		hasher.On("Gen", mock.Anything).Return([]byte("ok"), nil)
 
-		s := New(repo, hasher, nil, nil, baseCfg())
-		_, err := s.Register(ctx, inUser)
+		s := New(repo, hasher, permissivePolicy(), notBreached(), nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.Register(ctx, inUser, 0)
 		if err == nil {
 			t.Fatal("expected error but got nil")
 		}
@@ -91,12 +202,65 @@ func TestRegister_AllCases(t *testing.T) {
 		hasher := &mocks_hasher.PasswordHasher{}
 		hasher.On("Gen", mock.Anything).Return([]byte("ok"), nil)
 
-		s := New(repo, hasher, nil, nil, baseCfg())
-		_, err := s.Register(ctx, inUser)
+		s := New(repo, hasher, permissivePolicy(), notBreached(), nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.Register(ctx, inUser, 0)
 		if err == nil {
 			t.Fatal("expected repo error")
 		}
 	})
+
+	t.Run("breached password rejected before hashing", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		hasher := &mocks_hasher.PasswordHasher{}
+
+		breach := &mocks_breach.BreachChecker{}
+		breach.On("Breached", inUser.Password).Return(42, nil)
+
+		s := New(repo, hasher, permissivePolicy(), breach, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.Register(ctx, inUser, 0)
+
+		var policyErr *domain.PasswordPolicyError
+		if !errors.As(err, &policyErr) || policyErr.Violations[0].Rule != domain.RuleBreached {
+			t.Fatalf("expected breached violation; got: %v", err)
+		}
+		hasher.AssertNotCalled(t, "Gen", mock.Anything)
+		repo.AssertNotCalled(t, "NewUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("breach corpus error", func(t *testing.T) {
+		breach := &mocks_breach.BreachChecker{}
+		breach.On("Breached", mock.Anything).Return(0, errors.New("io"))
+
+		s := New(&mocks_repo.Repository{}, nil, permissivePolicy(), breach, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.Register(ctx, inUser, 0)
+		if err == nil || errors.Is(err, domain.ErrWeakPassword) {
+			t.Fatalf("expected internal error; got: %v", err)
+		}
+	})
+
+	t.Run("weak password -> policy violations, user not created", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+
+		policy := &mocks_policy.PasswordPolicy{}
+		policy.On("Check", int32(3), inUser.Email, inUser.Password).Return([]domain.PasswordViolation{
+			{Rule: domain.RuleMinLength, Message: "must be at least 8 characters"},
+		})
+		policy.On("HistoryDepth", int32(3)).Return(5)
+
+		s := New(repo, nil, policy, notBreached(), nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.Register(ctx, inUser, 3)
+
+		var policyErr *domain.PasswordPolicyError
+		if !errors.As(err, &policyErr) || len(policyErr.Violations) != 1 || policyErr.Violations[0].Rule != domain.RuleMinLength {
+			t.Fatalf("expected PasswordPolicyError with min_length; got: %v", err)
+		}
+		if !errors.Is(err, domain.ErrWeakPassword) {
+			t.Fatalf("expected wrapped domain.ErrWeakPassword; got: %v", err)
+		}
+		// у нового пользователя истории нет
+		repo.AssertNotCalled(t, "GetPasswordHistory", mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "NewUser", mock.Anything, mock.Anything)
+	})
 }
 
 func TestLogin_AllCases(t *testing.T) {
@@ -109,23 +273,32 @@ func TestLogin_AllCases(t *testing.T) {
 	}
 
 	// dctx: int32 fields
-	dctx := domain.DeviceCtx{AppId: int32(10), DeviceID: int32(20)}
+	dctx := domain.DeviceCtx{AppId: int32(10), DeviceID: int32(-20)}
 
 	t.Run("success", func(t *testing.T) {
 		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
 		repo.On("GetUserInfoByEmail", mock.Anything, stored.Email).Return(stored, nil)
-		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil)
+		repo.On("GetUserMfa", mock.Anything, stored.ID).Return(domain.UserMfa{}, domain.ErrNotFound)
+		// login открывает новое семейство refresh-токенов
+		repo.On("SaveRefreshToken", mock.Anything, mock.MatchedBy(func(rt domain.RefreshToken) bool {
+			return rt.Meta.FamilyID != "" && rt.Meta.UserID == stored.ID
+		})).Return(nil)
 
 		hasher := &mocks_hasher.PasswordHasher{}
 		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
 
 		tokener := &mocks_tokener.Tokener{}
-		tokener.On("GenPair", mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte("href"), nil)
 
-		s := New(repo, hasher, tokener, tokenHasher, baseCfg())
+		noRoles(repo)
+		s := New(repo, hasher, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
 
 		got, err := s.Login(ctx, domain.User{Email: stored.Email, Password: "plain"}, dctx)
 		if err != nil {
@@ -141,12 +314,73 @@ func TestLogin_AllCases(t *testing.T) {
 		tokenHasher.AssertExpectations(t)
 	})
 
+	t.Run("outdated hash is rehashed", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, stored.Email).Return(stored, nil)
+		repo.On("UpdateUserPassword", mock.Anything, stored.ID, "new-hash").Return(nil)
+		repo.On("GetUserMfa", mock.Anything, stored.ID).Return(domain.UserMfa{}, domain.ErrNotFound)
+		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", []byte(stored.Password), []byte("plain")).Return(true, nil)
+		hasher.On("NeedsRehash", []byte(stored.Password)).Return(true)
+		hasher.On("Gen", []byte("plain")).Return([]byte("new-hash"), nil)
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("href"), nil)
+
+		noRoles(repo)
+		s := New(repo, hasher, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.Login(ctx, domain.User{Email: stored.Email, Password: "plain"}, dctx); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+		hasher.AssertExpectations(t)
+	})
+
+	t.Run("failed rehash does not block login", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, stored.Email).Return(stored, nil)
+		repo.On("UpdateUserPassword", mock.Anything, stored.ID, "new-hash").Return(errors.New("db down"))
+		repo.On("GetUserMfa", mock.Anything, stored.ID).Return(domain.UserMfa{}, domain.ErrNotFound)
+		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(true)
+		hasher.On("Gen", mock.Anything).Return([]byte("new-hash"), nil)
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("href"), nil)
+
+		noRoles(repo)
+		s := New(repo, hasher, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.Login(ctx, domain.User{Email: stored.Email, Password: "plain"}, dctx); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	})
+
 	t.Run("get user error", func(t *testing.T) {
 		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
 		repo.On("GetUserInfoByEmail", mock.Anything, mock.Anything).Return(domain.User{}, errors.New("no user"))
 
 		hasher := &mocks_hasher.PasswordHasher{}
-		s := New(repo, hasher, nil, nil, baseCfg())
+		s := New(repo, hasher, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
 
 		_, err := s.Login(ctx, domain.User{Email: "x"}, dctx)
 		if err == nil {
@@ -156,34 +390,45 @@ func TestLogin_AllCases(t *testing.T) {
 
 	t.Run("compare error or wrong pass", func(t *testing.T) {
 		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
 		repo.On("GetUserInfoByEmail", mock.Anything, mock.Anything).Return(stored, nil)
+		repo.On("RegisterLoginFailure", mock.Anything, "email:e@x.y", mock.Anything).Return(time.Duration(0), nil)
 
 		hasher := &mocks_hasher.PasswordHasher{}
 		// simulate wrong password
 		hasher.On("Compare", mock.Anything, mock.Anything).Return(false, nil)
 
-		s := New(repo, hasher, nil, nil, baseCfg())
+		s := New(repo, hasher, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
 		_, err := s.Login(ctx, domain.User{Email: stored.Email, Password: "bad"}, dctx)
-		if err == nil {
-			t.Fatal("expected error on wrong password")
+		if !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation on wrong password; got: %v", err)
 		}
+		repo.AssertNotCalled(t, "ResetLoginFailures", mock.Anything, mock.Anything)
 	})
 
 	t.Run("tokener generation error", func(t *testing.T) {
 		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
 		repo.On("GetUserInfoByEmail", mock.Anything, mock.Anything).Return(stored, nil)
+		repo.On("GetUserMfa", mock.Anything, stored.ID).Return(domain.UserMfa{}, domain.ErrNotFound)
 		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil) // won't be called but safe
 
 		hasher := &mocks_hasher.PasswordHasher{}
 		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
 
 		tokener := &mocks_tokener.Tokener{}
-		tokener.On("GenPair", mock.Anything).Return([]byte(nil), []byte(nil), errors.New("jwt fail"))
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte(nil), []byte(nil), errors.New("jwt fail"))
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
 
-		s := New(repo, hasher, tokener, tokenHasher, baseCfg())
+		noRoles(repo)
+		s := New(repo, hasher, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
 		_, err := s.Login(ctx, domain.User{Email: stored.Email, Password: "ok"}, dctx)
 		if err == nil {
 			t.Fatal("expected error when tokener.GenPair fails")
@@ -192,19 +437,25 @@ func TestLogin_AllCases(t *testing.T) {
 
 	t.Run("save refresh token error", func(t *testing.T) {
 		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
 		repo.On("GetUserInfoByEmail", mock.Anything, mock.Anything).Return(stored, nil)
+		repo.On("GetUserMfa", mock.Anything, stored.ID).Return(domain.UserMfa{}, domain.ErrNotFound)
 		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(errors.New("save fail"))
 
 		hasher := &mocks_hasher.PasswordHasher{}
 		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
 
 		tokener := &mocks_tokener.Tokener{}
-		tokener.On("GenPair", mock.Anything).Return([]byte("a"), []byte("r"), nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("a"), []byte("r"), nil)
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
 
-		s := New(repo, hasher, tokener, tokenHasher, baseCfg())
+		noRoles(repo)
+		s := New(repo, hasher, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
 		_, err := s.Login(ctx, domain.User{Email: stored.Email, Password: "ok"}, dctx)
 		if err == nil {
 			t.Fatal("expected error when SaveRefreshToken fails")
@@ -214,16 +465,16 @@ func TestLogin_AllCases(t *testing.T) {
 
 func TestVerificationTokenAndGenFlow(t *testing.T) {
 	// проверим verificationToken и genTokensFlow частично (различные ошибки и успех)
-	//ctx := context.Background()
-	userDctx := domain.NewDeviceCtx(int32(1), int32(2))
-	// validMeta := domain.NewMeta(time.Hour, "user-1", userDctx.AppId, userDctx.DeviceID)
+	ctx := context.Background()
+	userDctx := domain.NewDeviceCtx(int32(1), int32(-2))
+	// validMeta := domain.NewRefreshMeta(time.Hour, "user-1", userDctx.AppId, userDctx.DeviceID)
 
 	t.Run("verificationToken invalid token", func(t *testing.T) {
 		tokener := &mocks_tokener.Tokener{}
 		tokener.On("VerifyRefresh", mock.Anything).Return(domain.Meta{}, errors.New("bad"))
 
-		s := New(nil, nil, tokener, nil, baseCfg())
-		_, err := s.verificationToken("bad", userDctx)
+		s := New(nil, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.verificationToken(ctx, "bad", userDctx)
 		if err == nil {
 			t.Fatal("expected error for invalid token")
 		}
@@ -231,12 +482,12 @@ func TestVerificationTokenAndGenFlow(t *testing.T) {
 
 	t.Run("verificationToken ctx mismatch", func(t *testing.T) {
 		// tokener returns meta with different ctx
-		meta := domain.NewMeta(time.Hour, "u", int32(9), int32(9))
+		meta := domain.NewRefreshMeta(time.Hour, "u", int32(9), int32(9))
 		tokener := &mocks_tokener.Tokener{}
 		tokener.On("VerifyRefresh", mock.Anything).Return(meta, nil)
 
-		s := New(nil, nil, tokener, nil, baseCfg())
-		_, err := s.verificationToken("tok", userDctx)
+		s := New(nil, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.verificationToken(ctx, "tok", userDctx)
 		if err == nil {
 			t.Fatal("expected ctx mismatch error")
 		}
@@ -244,13 +495,16 @@ func TestVerificationTokenAndGenFlow(t *testing.T) {
 
 	t.Run("genTokensFlow: tokener.GenPair fail", func(t *testing.T) {
 		tokener := &mocks_tokener.Tokener{}
-		tokener.On("GenPair", mock.Anything).Return([]byte(nil), []byte(nil), errors.New("gen fail"))
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte(nil), []byte(nil), errors.New("gen fail"))
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
 
-		s := New(nil, nil, tokener, tokenHasher, baseCfg())
-		_, _, err := s.genTokensFlow("uid", userDctx)
+		repo := &mocks_repo.Repository{}
+		noRoles(repo)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		_, _, err := s.genTokensFlow(ctx, domain.App{}, "uid", "fam", userDctx, nil)
 		if err == nil {
 			t.Fatal("expected tokener gen error")
 		}
@@ -258,13 +512,16 @@ func TestVerificationTokenAndGenFlow(t *testing.T) {
 
 	t.Run("genTokensFlow: tokenHasher fail", func(t *testing.T) {
 		tokener := &mocks_tokener.Tokener{}
-		tokener.On("GenPair", mock.Anything).Return([]byte("a"), []byte("r"), nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("a"), []byte("r"), nil)
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte(nil), errors.New("sum fail"))
 
-		s := New(nil, nil, tokener, tokenHasher, baseCfg())
-		_, _, err := s.genTokensFlow("uid", userDctx)
+		repo := &mocks_repo.Repository{}
+		noRoles(repo)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		_, _, err := s.genTokensFlow(ctx, domain.App{}, "uid", "fam", userDctx, nil)
 		if err == nil {
 			t.Fatal("expected tokenHasher sum error")
 		}
@@ -272,13 +529,16 @@ func TestVerificationTokenAndGenFlow(t *testing.T) {
 
 	t.Run("genTokensFlow success", func(t *testing.T) {
 		tokener := &mocks_tokener.Tokener{}
-		tokener.On("GenPair", mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte("hashref"), nil)
 
-		s := New(nil, nil, tokener, tokenHasher, baseCfg())
-		tok, rt, err := s.genTokensFlow("uid", userDctx)
+		repo := &mocks_repo.Repository{}
+		noRoles(repo)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		tok, rt, err := s.genTokensFlow(ctx, domain.App{}, "uid", "fam", userDctx, nil)
 		if err != nil {
 			t.Fatalf("unexpected err: %v", err)
 		}
@@ -292,17 +552,84 @@ func TestVerificationTokenAndGenFlow(t *testing.T) {
 			t.Fatal("refresh token hash empty")
 		}
 	})
+
+	t.Run("genTokensFlow: distinct access and refresh meta", func(t *testing.T) {
+		cfg := baseCfg()
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair",
+			mock.MatchedBy(func(m domain.Meta) bool {
+				return m.Type == domain.TokenTypeAccess && m.UserID == "uid" &&
+					m.Exp.Sub(m.IssuedAt) == cfg.AccessTokenTTL
+			}),
+			mock.MatchedBy(func(m domain.Meta) bool {
+				return m.Type == domain.TokenTypeRefresh && m.UserID == "uid" &&
+					m.Exp.Sub(m.IssuedAt) == cfg.RefreshTokenTTL
+			}),
+		).Return([]byte("acc"), []byte("ref"), nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("hashref"), nil)
+
+		repo := &mocks_repo.Repository{}
+		noRoles(repo)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, cfg)
+		_, rt, err := s.genTokensFlow(ctx, domain.App{}, "uid", "fam", userDctx, nil)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if rt.Meta.Type != domain.TokenTypeRefresh {
+			t.Fatalf("stored meta must be refresh meta, got %q", rt.Meta.Type)
+		}
+		tokener.AssertExpectations(t)
+	})
+
+	t.Run("genTokensFlow: roles go to access only", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair",
+			mock.MatchedBy(func(m domain.Meta) bool {
+				return reflect.DeepEqual(m.Roles, []string{"admin", "user"})
+			}),
+			mock.MatchedBy(func(m domain.Meta) bool { return m.Roles == nil }),
+		).Return([]byte("acc"), []byte("ref"), nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("hashref"), nil)
+
+		repo := &mocks_repo.Repository{}
+		repo.On("GetUserRoles", mock.Anything, "uid").Return([]string{"admin", "user"}, nil)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if _, _, err := s.genTokensFlow(ctx, domain.App{}, "uid", "fam", userDctx, nil); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		tokener.AssertExpectations(t)
+	})
+
+	t.Run("genTokensFlow: get roles fail", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("GetUserRoles", mock.Anything, "uid").Return(nil, errors.New("db down"))
+		tokener := &mocks_tokener.Tokener{}
+
+		s := New(repo, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		if _, _, err := s.genTokensFlow(ctx, domain.App{}, "uid", "fam", userDctx, nil); err == nil {
+			t.Fatal("expected roles error")
+		}
+		tokener.AssertNotCalled(t, "GenPair", mock.Anything, mock.Anything)
+	})
 }
 
 func TestRefreshAndLogout_AllCases(t *testing.T) {
 	ctx := context.Background()
-	userDctx := domain.NewDeviceCtx(int32(5), int32(7))
-	validMeta := domain.NewMeta(time.Hour, "u1", userDctx.AppId, userDctx.DeviceID)
+	userDctx := domain.NewDeviceCtx(int32(5), int32(-7))
+	validMeta := domain.NewRefreshMeta(time.Hour, "u1", userDctx.AppId, userDctx.DeviceID)
+	validMeta.SetFamily("fam-1")
 
 	t.Run("Refresh verify token fail", func(t *testing.T) {
 		tokener := &mocks_tokener.Tokener{}
 		tokener.On("VerifyRefresh", mock.Anything).Return(domain.Meta{}, errors.New("invalid"))
-		s := New(nil, nil, tokener, nil, baseCfg())
+		s := New(nil, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
 		_, err := s.Refresh(ctx, "old", userDctx)
 		if err == nil {
 			t.Fatal("expected verify error")
@@ -312,12 +639,17 @@ func TestRefreshAndLogout_AllCases(t *testing.T) {
 	t.Run("Refresh gen tokens fail", func(t *testing.T) {
 		tokener := &mocks_tokener.Tokener{}
 		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
-		tokener.On("GenPair", mock.Anything).Return([]byte(nil), []byte(nil), errors.New("gen fail"))
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte(nil), []byte(nil), errors.New("gen fail"))
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
 
-		s := New(nil, nil, tokener, tokenHasher, baseCfg())
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noRoles(repo)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
 		_, err := s.Refresh(ctx, "old", userDctx)
 		if err == nil {
 			t.Fatal("expected gen tokens error")
@@ -327,12 +659,17 @@ func TestRefreshAndLogout_AllCases(t *testing.T) {
 	t.Run("Refresh tokenHasher sum fail", func(t *testing.T) {
 		tokener := &mocks_tokener.Tokener{}
 		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
-		tokener.On("GenPair", mock.Anything).Return([]byte("a"), []byte("r"), nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("a"), []byte("r"), nil)
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte(nil), errors.New("sum fail"))
 
-		s := New(nil, nil, tokener, tokenHasher, baseCfg())
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noRoles(repo)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
 		_, err := s.Refresh(ctx, "old", userDctx)
 		if err == nil {
 			t.Fatal("expected sum error")
@@ -342,15 +679,18 @@ func TestRefreshAndLogout_AllCases(t *testing.T) {
 	t.Run("Refresh rotate token fail", func(t *testing.T) {
 		tokener := &mocks_tokener.Tokener{}
 		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
-		tokener.On("GenPair", mock.Anything).Return([]byte("a"), []byte("r"), nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("a"), []byte("r"), nil)
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
 
 		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
 		repo.On("RotateToken", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("rotate fail"))
 
-		s := New(repo, nil, tokener, tokenHasher, baseCfg())
+		noRoles(repo)
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
 		_, err := s.Refresh(ctx, "old", userDctx)
 		if err == nil {
 			t.Fatal("expected rotate error")
@@ -359,17 +699,22 @@ func TestRefreshAndLogout_AllCases(t *testing.T) {
 
 	t.Run("Refresh tokenHasher.Sum fail", func(t *testing.T) {
 		ctx := context.Background()
-		userDctx := domain.NewDeviceCtx(int32(5), int32(7))
-		validMeta := domain.NewMeta(time.Hour, "u1", userDctx.AppId, userDctx.DeviceID)
+		userDctx := domain.NewDeviceCtx(int32(5), int32(-7))
+		validMeta := domain.NewRefreshMeta(time.Hour, "u1", userDctx.AppId, userDctx.DeviceID)
 
 		tokener := &mocks_tokener.Tokener{}
 		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
-		tokener.On("GenPair", mock.Anything).Return([]byte("access"), []byte("refresh"), nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("access"), []byte("refresh"), nil)
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte(nil), errors.New("sum fail"))
 
-		s := New(nil, nil, tokener, tokenHasher, baseCfg())
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noRoles(repo)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
 		_, err := s.Refresh(ctx, "old-refresh", userDctx)
 		if err == nil {
 			t.Fatal("expected error when tokenHasher.Sum fails")
@@ -379,15 +724,49 @@ func TestRefreshAndLogout_AllCases(t *testing.T) {
 	t.Run("Refresh success", func(t *testing.T) {
 		tokener := &mocks_tokener.Tokener{}
 		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
-		tokener.On("GenPair", mock.Anything).Return([]byte("a"), []byte("r"), nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("a"), []byte("r"), nil)
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
 
 		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
 		repo.On("RotateToken", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-		s := New(repo, nil, tokener, tokenHasher, baseCfg())
+		noRoles(repo)
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		got, err := s.Refresh(ctx, "old", userDctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if got.Access == "" || got.Refresh == "" {
+			t.Fatal("tokens empty")
+		}
+	})
+
+	// refresh в формате до семейств: без jti и fid
+	legacyMeta := domain.Meta{Type: domain.TokenTypeRefresh, UserID: "u1", Ctx: userDctx, Exp: time.Now().Add(30 * time.Minute)}
+
+	t.Run("Refresh legacy token exchanged for a new session", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(legacyMeta, nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("a"), []byte("r"), nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noRoles(repo)
+		repo.On("LegacyTokensRevoked", mock.Anything, "u1").Return(false, nil)
+		repo.On("RevokeTokenByHash", mock.Anything, "h").Return(nil).Once()
+		repo.On("SaveRefreshToken", mock.Anything, mock.MatchedBy(func(rt domain.RefreshToken) bool {
+			return rt.Meta.FamilyID != "" && rt.Meta.ID != "" && rt.Meta.UserID == "u1"
+		})).Return(nil).Once()
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
 		got, err := s.Refresh(ctx, "old", userDctx)
 		if err != nil {
 			t.Fatalf("unexpected err: %v", err)
@@ -395,12 +774,110 @@ func TestRefreshAndLogout_AllCases(t *testing.T) {
 		if got.Access == "" || got.Refresh == "" {
 			t.Fatal("tokens empty")
 		}
+		repo.AssertNotCalled(t, "RotateToken", mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Refresh legacy token exchanged only once", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(legacyMeta, nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("LegacyTokensRevoked", mock.Anything, "u1").Return(false, nil)
+		repo.On("RevokeTokenByHash", mock.Anything, "h").Return(domain.ErrNotFound)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.Refresh(ctx, "old", userDctx)
+		if !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected ErrValidation, got %v", err)
+		}
+		repo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Refresh legacy token after LogoutAll rejected", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(legacyMeta, nil)
+
+		repo := &mocks_repo.Repository{}
+		repo.On("LegacyTokensRevoked", mock.Anything, "u1").Return(true, nil)
+
+		s := New(repo, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.Refresh(ctx, "old", userDctx)
+		if !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected ErrValidation, got %v", err)
+		}
+		repo.AssertNotCalled(t, "RevokeTokenByHash", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Refresh legacy token living longer than refresh TTL rejected", func(t *testing.T) {
+		long := legacyMeta
+		long.Exp = time.Now().Add(2 * time.Hour)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(long, nil)
+
+		repo := &mocks_repo.Repository{}
+
+		s := New(repo, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.Refresh(ctx, "old", userDctx)
+		if !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected ErrValidation, got %v", err)
+		}
+		repo.AssertNotCalled(t, "LegacyTokensRevoked", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Refresh keeps token family", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("a"), []byte("r"), nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("RotateToken", mock.Anything, "h", mock.MatchedBy(func(rt domain.RefreshToken) bool {
+			return rt.Meta.FamilyID == validMeta.FamilyID
+		})).Return(nil)
+
+		noRoles(repo)
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.Refresh(ctx, "old", userDctx); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Refresh reuse detected -> family revoked", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("a"), []byte("r"), nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("RotateToken", mock.Anything, mock.Anything, mock.Anything).Return(domain.ErrTokenReuse)
+
+		noRoles(repo)
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.Refresh(ctx, "old", userDctx)
+		if !errors.Is(err, domain.ErrTokenReuse) {
+			t.Fatalf("expected wrapped domain.ErrTokenReuse; got: %v", err)
+		}
 	})
 
 	t.Run("Logout verify fail", func(t *testing.T) {
 		tokener := &mocks_tokener.Tokener{}
 		tokener.On("VerifyRefresh", mock.Anything).Return(domain.Meta{}, errors.New("invalid"))
-		s := New(nil, nil, tokener, nil, baseCfg())
+		s := New(nil, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
 		err := s.Logout(ctx, "r", userDctx)
 		if err == nil {
 			t.Fatal("expected verify error on logout")
@@ -413,8 +890,11 @@ func TestRefreshAndLogout_AllCases(t *testing.T) {
 
 		tokenHasher := &mocks_tokenhasher.TokenHasher{}
 		tokenHasher.On("Sum", mock.Anything).Return([]byte(nil), errors.New("sum fail"))
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
 
-		s := New(nil, nil, tokener, tokenHasher, baseCfg())
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
 		err := s.Logout(ctx, "r", userDctx)
 		if err == nil {
 			t.Fatal("expected hashing error")
@@ -429,9 +909,11 @@ func TestRefreshAndLogout_AllCases(t *testing.T) {
 		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
 
 		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
 		repo.On("RevokeTokenByHash", mock.Anything, mock.Anything).Return(domain.ErrNotFound)
 
-		s := New(repo, nil, tokener, tokenHasher, baseCfg())
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
 		if err := s.Logout(ctx, "r", userDctx); err != nil {
 			t.Fatalf("unexpected err: %v", err)
 		}
@@ -445,38 +927,2805 @@ func TestRefreshAndLogout_AllCases(t *testing.T) {
 		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
 
 		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
 		repo.On("RevokeTokenByHash", mock.Anything, mock.Anything).Return(errors.New("boom"))
 
-		s := New(repo, nil, tokener, tokenHasher, baseCfg())
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
 		if err := s.Logout(ctx, "r", userDctx); err == nil {
 			t.Fatal("expected revoke error propagated")
 		}
 	})
 }
 
-// sanity check internal functions behaviour (types/values)
-func Test_internalSanity(t *testing.T) {
-	// simple sanity: genTokensFlow + verificationToken round-ish checks
-	userDctx := domain.NewDeviceCtx(int32(2), int32(3))
-	tokener := &mocks_tokener.Tokener{}
-	tokener.On("GenPair", mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
-	tokener.On("VerifyRefresh", mock.Anything).Return(domain.NewMeta(time.Minute, "u1", userDctx.AppId, userDctx.DeviceID), nil)
+func TestLogoutAll(t *testing.T) {
+	ctx := context.Background()
 
-	tokenHasher := &mocks_tokenhasher.TokenHasher{}
-	tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+	t.Run("success", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("RevokeUserTokens", mock.Anything, "u1", "").Return(3, nil)
+		repo.On("RevokeLegacyTokens", mock.Anything, "u1", time.Hour).Return(nil)
 
-	s := New(nil, nil, tokener, tokenHasher, baseCfg())
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.LogoutAll(ctx, "u1"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+	})
 
-	tok, rt, err := s.genTokensFlow("u1", userDctx)
-	if err != nil {
-		t.Fatalf("genTokensFlow err: %v", err)
-	}
-	if reflect.DeepEqual(tok, (*domain.Token)(nil)) || reflect.DeepEqual(rt, (*domain.RefreshToken)(nil)) {
-		t.Fatalf("unexpected nil results")
+	t.Run("no sessions is not an error", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("RevokeUserTokens", mock.Anything, "u1", "").Return(0, nil)
+		repo.On("RevokeLegacyTokens", mock.Anything, "u1", time.Hour).Return(nil)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.LogoutAll(ctx, "u1"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	})
+
+	t.Run("repo error", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("RevokeUserTokens", mock.Anything, "u1", "").Return(0, errors.New("boom"))
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.LogoutAll(ctx, "u1"); err == nil {
+			t.Fatal("expected repo error propagated")
+		}
+	})
+
+	t.Run("legacy revoke error", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("RevokeUserTokens", mock.Anything, "u1", "").Return(1, nil)
+		repo.On("RevokeLegacyTokens", mock.Anything, "u1", time.Hour).Return(errors.New("boom"))
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.LogoutAll(ctx, "u1"); err == nil {
+			t.Fatal("expected repo error propagated")
+		}
+	})
+}
+
+func TestChangePassword_AllCases(t *testing.T) {
+	ctx := context.Background()
+	userDctx := domain.NewDeviceCtx(int32(5), int32(-7))
+	validMeta := domain.NewRefreshMeta(time.Hour, "u1", userDctx.AppId, userDctx.DeviceID)
+	validMeta.SetFamily("fam-current")
+	stored := domain.User{ID: "u1", Email: "e@x.y", Password: "stored-hash"}
+
+	t.Run("verify token fail", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(domain.Meta{}, errors.New("invalid"))
+
+		s := New(nil, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.ChangePassword(ctx, "r", userDctx, "old", "new-pass"); err == nil {
+			t.Fatal("expected verify error")
+		}
+	})
+
+	t.Run("revoked refresh -> domain.ErrValidation", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", []byte("r")).Return([]byte("refresh-hash"), nil)
+
+		repo := &mocks_repo.Repository{}
+		repo.On("RefreshTokenExists", mock.Anything, "refresh-hash").Return(false, nil)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		err := s.ChangePassword(ctx, "r", userDctx, "old", "new-pass")
+		if !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "GetUserInfoByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("wrong old password -> counted as login failure", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
+
+		repo := &mocks_repo.Repository{}
+		tokenHasher := liveRefresh(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("RegisterLoginFailure", mock.Anything, "email:e@x.y", mock.Anything).Return(time.Duration(0), nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", []byte("stored-hash"), []byte("bad")).Return(false, errors.New("mismatch"))
+
+		s := New(repo, hasher, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		err := s.ChangePassword(ctx, "r", userDctx, "bad", "new-pass")
+		if !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertCalled(t, "RegisterLoginFailure", mock.Anything, "email:e@x.y", mock.Anything)
+		repo.AssertNotCalled(t, "UpdateUserPassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("locked -> LockoutError before password check", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
+
+		repo := &mocks_repo.Repository{}
+		tokenHasher := liveRefresh(repo)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("LoginLockedFor", mock.Anything, []string{"email:e@x.y"}).Return(time.Minute, nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+
+		s := New(repo, hasher, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		err := s.ChangePassword(ctx, "r", userDctx, "old", "new-pass")
+		var lockErr *domain.LockoutError
+		if !errors.As(err, &lockErr) {
+			t.Fatalf("expected LockoutError; got: %v", err)
+		}
+		hasher.AssertNotCalled(t, "Compare", mock.Anything, mock.Anything)
+	})
+
+	t.Run("update password fail", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
+
+		repo := &mocks_repo.Repository{}
+		tokenHasher := liveRefresh(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("UpdateUserPassword", mock.Anything, "u1", "new-hash").Return(errors.New("db boom"))
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("Gen", []byte("new-pass")).Return([]byte("new-hash"), nil)
+
+		s := New(repo, hasher, permissivePolicy(), notBreached(), tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.ChangePassword(ctx, "r", userDctx, "old", "new-pass"); err == nil {
+			t.Fatal("expected update error")
+		}
+		repo.AssertNotCalled(t, "RevokeUserTokens", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("success revokes other sessions only", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
+
+		repo := &mocks_repo.Repository{}
+		tokenHasher := liveRefresh(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("UpdateUserPassword", mock.Anything, "u1", "new-hash").Return(nil)
+		repo.On("SavePasswordHistory", mock.Anything, "u1", "new-hash").Return(nil)
+		repo.On("RevokeUserTokens", mock.Anything, "u1", "fam-current").Return(2, nil)
+		repo.On("RevokeLegacyTokens", mock.Anything, "u1", time.Hour).Return(nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("Gen", []byte("new-pass")).Return([]byte("new-hash"), nil)
+
+		s := New(repo, hasher, permissivePolicy(), notBreached(), tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.ChangePassword(ctx, "r", userDctx, "old", "new-pass"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("recently used password -> reused violation", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
+
+		repo := &mocks_repo.Repository{}
+		tokenHasher := liveRefresh(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("GetPasswordHistory", mock.Anything, "u1", 3).Return([]string{"h-current", "h-previous"}, nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", []byte("stored-hash"), []byte("old")).Return(true, nil)
+		hasher.On("Compare", []byte("h-current"), []byte("new-pass")).Return(false, errors.New("mismatch"))
+		hasher.On("Compare", []byte("h-previous"), []byte("new-pass")).Return(true, nil)
+
+		policy := &mocks_policy.PasswordPolicy{}
+		policy.On("Check", userDctx.AppId, stored.Email, "new-pass").Return(nil)
+		policy.On("HistoryDepth", userDctx.AppId).Return(3)
+
+		s := New(repo, hasher, policy, notBreached(), tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		err := s.ChangePassword(ctx, "r", userDctx, "old", "new-pass")
+
+		var policyErr *domain.PasswordPolicyError
+		if !errors.As(err, &policyErr) || policyErr.Violations[0].Rule != domain.RuleReused {
+			t.Fatalf("expected reused violation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "UpdateUserPassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPasswordReset_AllCases(t *testing.T) {
+	ctx := context.Background()
+	stored := domain.User{ID: "u1", Email: "e@x.y", Password: "stored-hash"}
+
+	t.Run("request: unknown email is silent", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("GetUserInfoByEmail", mock.Anything, "nobody@x.y").Return(domain.User{}, domain.ErrNotFound)
+
+		notifier := &mocks_notifier.Notifier{}
+
+		s := New(repo, nil, nil, nil, nil, nil, notifier, nil, nil, nil, nil, baseCfg())
+		if err := s.RequestPasswordReset(ctx, "nobody@x.y"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertNotCalled(t, "SaveOneTimeToken", mock.Anything, mock.Anything)
+		notifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+	})
+
+	t.Run("request: repo error", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("GetUserInfoByEmail", mock.Anything, mock.Anything).Return(domain.User{}, errors.New("db boom"))
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.RequestPasswordReset(ctx, "e@x.y"); err == nil {
+			t.Fatal("expected repo error")
+		}
+	})
+
+	t.Run("request: token stored hashed and delivered", func(t *testing.T) {
+		var sent domain.Notification
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return(func(b []byte) []byte { return append([]byte("h:"), b...) }, nil)
+
+		notifier := &mocks_notifier.Notifier{}
+		notifier.On("Notify", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			sent = args.Get(1).(domain.Notification)
+		}).Return(nil)
+
+		repo := &mocks_repo.Repository{}
+		repo.On("GetUserInfoByEmail", mock.Anything, stored.Email).Return(stored, nil)
+		repo.On("SaveOneTimeToken", mock.Anything, mock.MatchedBy(func(ott domain.OneTimeToken) bool {
+			return ott.UserID == "u1" && ott.Purpose == domain.PurposePasswordReset &&
+				time.Until(ott.Exp) > 14*time.Minute && time.Until(ott.Exp) <= 15*time.Minute
+		})).Return(nil)
+
+		s := New(repo, nil, nil, nil, nil, tokenHasher, notifier, nil, nil, nil, nil, baseCfg())
+		if err := s.RequestPasswordReset(ctx, stored.Email); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+
+		if sent.To != stored.Email || sent.Purpose != domain.PurposePasswordReset || sent.Token == "" {
+			t.Fatalf("unexpected notification: %+v", sent)
+		}
+		saved := repo.Calls[1].Arguments.Get(1).(domain.OneTimeToken)
+		if saved.Hash != "h:"+sent.Token {
+			t.Fatalf("expected stored hash of sent token, got %q", saved.Hash)
+		}
+	})
+
+	t.Run("confirm: unknown or used token -> domain.ErrValidation", func(t *testing.T) {
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", []byte("tok")).Return([]byte("hash"), nil)
+
+		repo := &mocks_repo.Repository{}
+		repo.On("ConsumeOneTimeToken", mock.Anything, domain.PurposePasswordReset, "hash").Return(domain.OneTimeToken{}, domain.ErrNotFound)
+
+		s := New(repo, nil, nil, nil, nil, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		err := s.ConfirmPasswordReset(ctx, "tok", "new-pass", 0)
+		if !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "UpdateUserPassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("confirm: success revokes all sessions", func(t *testing.T) {
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", []byte("tok")).Return([]byte("hash"), nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Gen", []byte("new-pass")).Return([]byte("new-hash"), nil)
+
+		repo := &mocks_repo.Repository{}
+		repo.On("ConsumeOneTimeToken", mock.Anything, domain.PurposePasswordReset, "hash").Return(domain.OneTimeToken{UserID: "u1"}, nil)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("UpdateUserPassword", mock.Anything, "u1", "new-hash").Return(nil)
+		repo.On("SavePasswordHistory", mock.Anything, "u1", "new-hash").Return(nil)
+		repo.On("RevokeUserTokens", mock.Anything, "u1", "").Return(3, nil)
+		repo.On("RevokeLegacyTokens", mock.Anything, "u1", time.Hour).Return(nil)
+
+		s := New(repo, hasher, permissivePolicy(), notBreached(), nil, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.ConfirmPasswordReset(ctx, "tok", "new-pass", 0); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("confirm: weak password keeps token usable", func(t *testing.T) {
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", []byte("tok")).Return([]byte("hash"), nil)
+
+		ott := domain.NewOneTimeToken("hash", "u1", domain.PurposePasswordReset, 10*time.Minute)
+		repo := &mocks_repo.Repository{}
+		repo.On("ConsumeOneTimeToken", mock.Anything, domain.PurposePasswordReset, "hash").Return(ott, nil)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("SaveOneTimeToken", mock.Anything, ott).Return(nil)
+
+		policy := &mocks_policy.PasswordPolicy{}
+		policy.On("Check", int32(2), stored.Email, "e").Return([]domain.PasswordViolation{
+			{Rule: domain.RuleMinLength, Message: "must be at least 8 characters"},
+			{Rule: domain.RuleContainsEmail, Message: "must not contain the email name"},
+		})
+		policy.On("HistoryDepth", int32(2)).Return(0)
+
+		s := New(repo, nil, policy, notBreached(), nil, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		err := s.ConfirmPasswordReset(ctx, "tok", "e", 2)
+
+		var policyErr *domain.PasswordPolicyError
+		if !errors.As(err, &policyErr) || len(policyErr.Violations) != 2 {
+			t.Fatalf("expected two violations; got: %v", err)
+		}
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "UpdateUserPassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestEmailVerification_AllCases(t *testing.T) {
+	ctx := context.Background()
+	strictApp := domain.NewDeviceCtx(int32(7), int32(-1))
+	cfg := baseCfg()
+	cfg.RequireVerifiedEmailApps = []int32{strictApp.AppId}
+
+	unverified := domain.User{ID: "u1", Email: "e@x.y", Password: "stored-hash"}
+	verifiedAt := time.Now()
+	verified := unverified
+	verified.EmailVerifiedAt = &verifiedAt
+
+	t.Run("login: unverified refused in strict app", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, unverified.Email).Return(unverified, nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
+
+		s := New(repo, hasher, nil, nil, nil, nil, nil, nil, nil, nil, nil, cfg)
+		_, err := s.Login(ctx, domain.User{Email: unverified.Email, Password: "plain"}, strictApp)
+		if !errors.Is(err, domain.ErrEmailNotVerified) {
+			t.Fatalf("expected wrapped domain.ErrEmailNotVerified; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("login: verified passes strict app", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, verified.Email).Return(verified, nil)
+		repo.On("GetUserMfa", mock.Anything, verified.ID).Return(domain.UserMfa{}, domain.ErrNotFound)
+		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("href"), nil)
+
+		noRoles(repo)
+		s := New(repo, hasher, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, cfg)
+		if _, err := s.Login(ctx, domain.User{Email: verified.Email, Password: "plain"}, strictApp); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	})
+
+	t.Run("verify: unknown token -> domain.ErrValidation", func(t *testing.T) {
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", []byte("tok")).Return([]byte("hash"), nil)
+
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("ConsumeOneTimeToken", mock.Anything, domain.PurposeEmailVerification, "hash").Return(domain.OneTimeToken{}, domain.ErrNotFound)
+
+		s := New(repo, nil, nil, nil, nil, tokenHasher, nil, nil, nil, nil, nil, cfg)
+		if err := s.VerifyEmail(ctx, "tok"); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "MarkEmailVerified", mock.Anything, mock.Anything)
+	})
+
+	t.Run("verify: success", func(t *testing.T) {
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", []byte("tok")).Return([]byte("hash"), nil)
+
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("ConsumeOneTimeToken", mock.Anything, domain.PurposeEmailVerification, "hash").Return(domain.OneTimeToken{UserID: "u1"}, nil)
+		repo.On("MarkEmailVerified", mock.Anything, "u1").Return(nil)
+
+		s := New(repo, nil, nil, nil, nil, tokenHasher, nil, nil, nil, nil, nil, cfg)
+		if err := s.VerifyEmail(ctx, "tok"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("resend: verified or unknown email is silent", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, verified.Email).Return(verified, nil)
+		repo.On("GetUserInfoByEmail", mock.Anything, "nobody@x.y").Return(domain.User{}, domain.ErrNotFound)
+
+		notifier := &mocks_notifier.Notifier{}
+
+		s := New(repo, nil, nil, nil, nil, nil, notifier, nil, nil, nil, nil, cfg)
+		if err := s.ResendEmailVerification(ctx, verified.Email); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if err := s.ResendEmailVerification(ctx, "nobody@x.y"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		notifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+	})
+}
+
+func TestMfa_AllCases(t *testing.T) {
+	ctx := context.Background()
+	dctx := domain.NewDeviceCtx(int32(3), int32(-4))
+	stored := domain.User{ID: "u1", Email: "e@x.y", Password: "stored-hash"}
+	confirmedAt := time.Now()
+	enabled := domain.UserMfa{UserID: "u1", EncryptedSecret: []byte("sealed"), ConfirmedAt: &confirmedAt}
+	pending := domain.UserMfa{UserID: "u1", EncryptedSecret: []byte("sealed")}
+	refreshMeta := domain.NewRefreshMeta(time.Hour, "u1", dctx.AppId, dctx.DeviceID)
+	refreshMeta.SetFamily("fam")
+
+	newCipher := func() *mocks_cipher.SecretCipher {
+		c := &mocks_cipher.SecretCipher{}
+		c.On("Open", []byte("sealed")).Return([]byte("SECRET"), nil)
+		return c
+	}
+
+	t.Run("login with mfa returns device-bound challenge", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, stored.Email).Return(stored, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(enabled, nil)
+		repo.On("SaveOneTimeToken", mock.Anything, mock.MatchedBy(func(ott domain.OneTimeToken) bool {
+			return ott.Purpose == domain.PurposeMfaChallenge && ott.UserID == "u1" && ott.Ctx.Compare(dctx)
+		})).Return(nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("challenge-hash"), nil)
+
+		cfg := baseCfg()
+		cfg.MfaChallengeTTL = 5 * time.Minute
+		s := New(repo, hasher, nil, nil, nil, tokenHasher, nil, nil, nil, nil, nil, cfg)
+
+		res, err := s.Login(ctx, domain.User{Email: stored.Email, Password: "plain"}, dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if !res.MfaRequired() || res.Access != "" || res.Refresh != "" {
+			t.Fatalf("expected challenge only, got %+v", res)
+		}
+		repo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: other device rejected", func(t *testing.T) {
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", []byte("ch")).Return([]byte("h"), nil)
+
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("ConsumeOneTimeToken", mock.Anything, domain.PurposeMfaChallenge, "h").
+			Return(domain.OneTimeToken{UserID: "u1", Ctx: domain.NewDeviceCtx(9, 9)}, nil)
+
+		s := New(repo, nil, nil, nil, nil, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.CompleteMfaLogin(ctx, "ch", "123456", dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "GetUserMfa", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: wrong code rejected", func(t *testing.T) {
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", []byte("ch")).Return([]byte("h"), nil)
+
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("ConsumeOneTimeToken", mock.Anything, domain.PurposeMfaChallenge, "h").
+			Return(domain.OneTimeToken{UserID: "u1", Ctx: dctx}, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(enabled, nil)
+
+		totp := &mocks_totp.Totp{}
+		totp.On("Validate", "SECRET", "000000", mock.Anything).Return(int64(0), false)
+
+		s := New(repo, nil, nil, nil, nil, tokenHasher, nil, totp, newCipher(), nil, nil, baseCfg())
+		if _, err := s.CompleteMfaLogin(ctx, "ch", "000000", dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "UseTotpStep", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: replayed code rejected", func(t *testing.T) {
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", []byte("ch")).Return([]byte("h"), nil)
+
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("ConsumeOneTimeToken", mock.Anything, domain.PurposeMfaChallenge, "h").
+			Return(domain.OneTimeToken{UserID: "u1", Ctx: dctx}, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(enabled, nil)
+		repo.On("UseTotpStep", mock.Anything, "u1", int64(42)).Return(domain.ErrTokenReuse)
+
+		totp := &mocks_totp.Totp{}
+		totp.On("Validate", "SECRET", "123456", mock.Anything).Return(int64(42), true)
+
+		s := New(repo, nil, nil, nil, nil, tokenHasher, nil, totp, newCipher(), nil, nil, baseCfg())
+		if _, err := s.CompleteMfaLogin(ctx, "ch", "123456", dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: success issues tokens", func(t *testing.T) {
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("ConsumeOneTimeToken", mock.Anything, domain.PurposeMfaChallenge, "h").
+			Return(domain.OneTimeToken{UserID: "u1", Ctx: dctx}, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(enabled, nil)
+		repo.On("UseTotpStep", mock.Anything, "u1", int64(42)).Return(nil)
+		repo.On("SaveRefreshToken", mock.Anything, mock.MatchedBy(func(rt domain.RefreshToken) bool {
+			return rt.Meta.UserID == "u1" && rt.Meta.FamilyID != ""
+		})).Return(nil)
+
+		totp := &mocks_totp.Totp{}
+		totp.On("Validate", "SECRET", "123456", mock.Anything).Return(int64(42), true)
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+
+		noRoles(repo)
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, totp, newCipher(), nil, nil, baseCfg())
+		tk, err := s.CompleteMfaLogin(ctx, "ch", "123456", dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if tk.Access != "acc" || tk.Refresh != "ref" {
+			t.Fatalf("unexpected tokens: %+v", tk)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	for name, call := range map[string]func(*Auth) error{
+		"begin": func(s *Auth) error {
+			_, err := s.BeginTotpEnrollment(ctx, "r", dctx)
+			return err
+		},
+		"confirm": func(s *Auth) error { return s.ConfirmTotpEnrollment(ctx, "r", dctx, "123456") },
+	} {
+		t.Run(name+": revoked refresh -> domain.ErrValidation", func(t *testing.T) {
+			tokener := &mocks_tokener.Tokener{}
+			tokener.On("VerifyRefresh", mock.Anything).Return(refreshMeta, nil)
+			tokenHasher := &mocks_tokenhasher.TokenHasher{}
+			tokenHasher.On("Sum", []byte("r")).Return([]byte("refresh-hash"), nil)
+
+			repo := &mocks_repo.Repository{}
+			repo.On("RefreshTokenExists", mock.Anything, "refresh-hash").Return(false, nil)
+
+			s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+			if err := call(s); !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+			}
+			repo.AssertNotCalled(t, "SaveTotpSecret", mock.Anything, mock.Anything, mock.Anything)
+			repo.AssertNotCalled(t, "ConfirmTotp", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("begin: already enabled -> domain.ErrDuplicate", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(refreshMeta, nil)
+
+		repo := &mocks_repo.Repository{}
+		tokenHasher := liveRefresh(repo)
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("SaveTotpSecret", mock.Anything, "u1", []byte("sealed")).Return(domain.ErrDuplicate)
+
+		totp := &mocks_totp.Totp{}
+		totp.On("NewSecret").Return("SECRET", nil)
+
+		cipher := &mocks_cipher.SecretCipher{}
+		cipher.On("Seal", []byte("SECRET")).Return([]byte("sealed"), nil)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, totp, cipher, nil, nil, baseCfg())
+		if _, err := s.BeginTotpEnrollment(ctx, "r", dctx); !errors.Is(err, domain.ErrDuplicate) {
+			t.Fatalf("expected wrapped domain.ErrDuplicate; got: %v", err)
+		}
+	})
+
+	t.Run("begin: success stores only sealed secret", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(refreshMeta, nil)
+
+		repo := &mocks_repo.Repository{}
+		tokenHasher := liveRefresh(repo)
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("SaveTotpSecret", mock.Anything, "u1", []byte("sealed")).Return(nil)
+
+		totp := &mocks_totp.Totp{}
+		totp.On("NewSecret").Return("SECRET", nil)
+		totp.On("URI", stored.Email, "SECRET").Return("otpauth://totp/x")
+
+		cipher := &mocks_cipher.SecretCipher{}
+		cipher.On("Seal", []byte("SECRET")).Return([]byte("sealed"), nil)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, totp, cipher, nil, nil, baseCfg())
+		enr, err := s.BeginTotpEnrollment(ctx, "r", dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if enr.Secret != "SECRET" || enr.URI != "otpauth://totp/x" {
+			t.Fatalf("unexpected enrollment: %+v", enr)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("confirm: nothing pending -> domain.ErrNotFound", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(refreshMeta, nil)
+
+		repo := &mocks_repo.Repository{}
+		tokenHasher := liveRefresh(repo)
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(domain.UserMfa{}, domain.ErrNotFound)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.ConfirmTotpEnrollment(ctx, "r", dctx, "123456"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected wrapped domain.ErrNotFound; got: %v", err)
+		}
+	})
+
+	t.Run("confirm: success enables mfa", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(refreshMeta, nil)
+
+		repo := &mocks_repo.Repository{}
+		tokenHasher := liveRefresh(repo)
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(pending, nil)
+		repo.On("ConfirmTotp", mock.Anything, "u1", int64(7)).Return(nil)
+
+		totp := &mocks_totp.Totp{}
+		totp.On("Validate", "SECRET", "123456", mock.Anything).Return(int64(7), true)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, totp, newCipher(), nil, nil, baseCfg())
+		if err := s.ConfirmTotpEnrollment(ctx, "r", dctx, "123456"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+	})
+}
+
+// sanity check internal functions behaviour (types/values)
+func TestLoginLockout_AllCases(t *testing.T) {
+	ctx := domain.WithClientIP(context.Background(), "10.0.0.1")
+	dctx := domain.NewDeviceCtx(1, -1)
+	stored := domain.User{ID: "u1", Email: "e@x.y", Password: "stored-hash"}
+	keys := []string{"email:e@x.y", "ip:10.0.0.1"}
+
+	cfg := baseCfg()
+	cfg.LoginMaxFailuresPerEmail = 5
+	cfg.LoginMaxFailuresPerIP = 20
+	cfg.LoginFailureWindow = 15 * time.Minute
+	cfg.LoginLockoutBase = time.Minute
+	cfg.LoginLockoutMax = time.Hour
+
+	t.Run("locked: credentials are not checked", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("LoginLockedFor", mock.Anything, keys).Return(30*time.Second, nil)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, cfg)
+		_, err := s.Login(ctx, domain.User{Email: " E@x.y ", Password: "plain"}, dctx)
+
+		var lockErr *domain.LockoutError
+		if !errors.As(err, &lockErr) || lockErr.RetryAfter != 30*time.Second {
+			t.Fatalf("expected LockoutError with 30s; got: %v", err)
+		}
+		if !errors.Is(err, domain.ErrTooManyAttempts) {
+			t.Fatalf("expected wrapped domain.ErrTooManyAttempts; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "GetUserInfoByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown email counts per key and may trigger lockout", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("LoginLockedFor", mock.Anything, keys).Return(time.Duration(0), nil)
+		repo.On("GetUserInfoByEmail", mock.Anything, stored.Email).Return(domain.User{}, domain.ErrNotFound)
+		repo.On("RegisterLoginFailure", mock.Anything, "email:e@x.y", cfg.EmailLockoutPolicy()).Return(time.Duration(0), nil)
+		repo.On("RegisterLoginFailure", mock.Anything, "ip:10.0.0.1", cfg.IPLockoutPolicy()).Return(2*time.Minute, nil)
+
+		// неизвестный email проверяется против хэша-заглушки: время ответа не выдаёт наличие аккаунта
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Gen", mock.Anything).Return([]byte("dummy-hash"), nil).Once()
+		hasher.On("Compare", []byte("dummy-hash"), []byte("plain")).Return(false, nil)
+
+		s := New(repo, hasher, nil, nil, nil, nil, nil, nil, nil, nil, nil, cfg)
+		_, err := s.Login(ctx, domain.User{Email: stored.Email, Password: "plain"}, dctx)
+
+		var lockErr *domain.LockoutError
+		if !errors.As(err, &lockErr) || lockErr.RetryAfter != 2*time.Minute {
+			t.Fatalf("expected LockoutError with 2m; got: %v", err)
+		}
+		repo.AssertExpectations(t)
+		hasher.AssertExpectations(t)
+	})
+
+	t.Run("success resets only email counter", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("LoginLockedFor", mock.Anything, keys).Return(time.Duration(0), nil)
+		repo.On("GetUserInfoByEmail", mock.Anything, stored.Email).Return(stored, nil)
+		repo.On("ResetLoginFailures", mock.Anything, []string{"email:e@x.y"}).Return(nil)
+		repo.On("GetUserMfa", mock.Anything, stored.ID).Return(domain.UserMfa{}, domain.ErrNotFound)
+		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("href"), nil)
+
+		noRoles(repo)
+		s := New(repo, hasher, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, cfg)
+		if _, err := s.Login(ctx, domain.User{Email: stored.Email, Password: "plain"}, dctx); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "RegisterLoginFailure", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("clear lockout", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("ResetLoginFailures", mock.Anything, keys).Return(nil)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, cfg)
+		if err := s.ClearLoginLockout(context.Background(), "E@X.Y", "10.0.0.1"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestIntrospect_AllCases(t *testing.T) {
+	ctx := context.Background()
+
+	access := domain.NewAccessMeta(time.Minute, "u1", 2, 3)
+	access.SetFamily("fam")
+	refresh := domain.NewRefreshMeta(time.Hour, "u1", 2, 3)
+	refresh.SetFamily("fam")
+
+	newAuth := func(repo *mocks_repo.Repository, tk *mocks_tokener.Tokener) *Auth {
+		th := &mocks_tokenhasher.TokenHasher{}
+		th.On("Sum", []byte("refresh-token")).Return([]byte("h"), nil).Maybe()
+		return New(repo, nil, nil, nil, tk, th, nil, nil, nil, nil, nil, baseCfg())
+	}
+
+	t.Run("bad signature or expired is inactive, not error", func(t *testing.T) {
+		tk := &mocks_tokener.Tokener{}
+		tk.On("Verify", []byte("garbage")).Return(domain.Meta{}, errors.New("bad sig"))
+		repo := &mocks_repo.Repository{}
+
+		got, err := newAuth(repo, tk).Introspect(ctx, "garbage")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if got.Active {
+			t.Fatal("expected inactive")
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("active access with roles", func(t *testing.T) {
+		tk := &mocks_tokener.Tokener{}
+		tk.On("Verify", []byte("access-token")).Return(access, nil)
+		repo := &mocks_repo.Repository{}
+		repo.On("AccessTokenRevoked", mock.Anything, access.ID).Return(false, nil)
+		repo.On("SessionExists", mock.Anything, "fam").Return(true, nil)
+		repo.On("GetUserRoles", mock.Anything, "u1").Return([]string{"admin", "user"}, nil)
+
+		got, err := newAuth(repo, tk).Introspect(ctx, "access-token")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if !got.Active || got.UserID != "u1" || got.SessionID != "fam" || got.Type != domain.TokenTypeAccess {
+			t.Fatalf("unexpected introspection: %+v", got)
+		}
+		if !got.Ctx.Compare(domain.NewDeviceCtx(2, 3)) || !reflect.DeepEqual(got.Roles, []string{"admin", "user"}) {
+			t.Fatalf("unexpected ctx/roles: %+v", got)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("legacy refresh inactive after LogoutAll", func(t *testing.T) {
+		legacy := domain.Meta{Type: domain.TokenTypeRefresh, UserID: "u1", Ctx: domain.NewDeviceCtx(2, 3), Exp: time.Now().Add(time.Minute)}
+		tk := &mocks_tokener.Tokener{}
+		tk.On("Verify", []byte("refresh-token")).Return(legacy, nil)
+		repo := &mocks_repo.Repository{}
+		repo.On("LegacyTokensRevoked", mock.Anything, "u1").Return(true, nil)
+
+		got, err := newAuth(repo, tk).Introspect(ctx, "refresh-token")
+		if err != nil || got.Active {
+			t.Fatalf("expected inactive, got %+v, err %v", got, err)
+		}
+		repo.AssertNotCalled(t, "RefreshTokenExists", mock.Anything, mock.Anything)
+	})
+
+	t.Run("access of revoked session is inactive", func(t *testing.T) {
+		tk := &mocks_tokener.Tokener{}
+		tk.On("Verify", mock.Anything).Return(access, nil)
+		repo := &mocks_repo.Repository{}
+		repo.On("AccessTokenRevoked", mock.Anything, access.ID).Return(false, nil)
+		repo.On("SessionExists", mock.Anything, "fam").Return(false, nil)
+
+		got, err := newAuth(repo, tk).Introspect(ctx, "access-token")
+		if err != nil || got.Active {
+			t.Fatalf("expected inactive, got %+v, err %v", got, err)
+		}
+		repo.AssertNotCalled(t, "GetUserRoles", mock.Anything, mock.Anything)
+	})
+
+	t.Run("denylisted access is inactive", func(t *testing.T) {
+		tk := &mocks_tokener.Tokener{}
+		tk.On("Verify", mock.Anything).Return(access, nil)
+		repo := &mocks_repo.Repository{}
+		repo.On("AccessTokenRevoked", mock.Anything, access.ID).Return(true, nil)
+
+		got, err := newAuth(repo, tk).Introspect(ctx, "access-token")
+		if err != nil || got.Active {
+			t.Fatalf("expected inactive, got %+v, err %v", got, err)
+		}
+		repo.AssertNotCalled(t, "SessionExists", mock.Anything, mock.Anything)
+	})
+
+	t.Run("legacy access without sid checked by denylist only", func(t *testing.T) {
+		legacy := domain.NewAccessMeta(time.Minute, "u1", 2, 3)
+		tk := &mocks_tokener.Tokener{}
+		tk.On("Verify", mock.Anything).Return(legacy, nil)
+		repo := &mocks_repo.Repository{}
+		repo.On("AccessTokenRevoked", mock.Anything, legacy.ID).Return(false, nil)
+		repo.On("GetUserRoles", mock.Anything, "u1").Return(nil, nil)
+
+		got, err := newAuth(repo, tk).Introspect(ctx, "access-token")
+		if err != nil || !got.Active {
+			t.Fatalf("expected active, got %+v, err %v", got, err)
+		}
+		repo.AssertNotCalled(t, "SessionExists", mock.Anything, mock.Anything)
+	})
+
+	t.Run("refresh checked by stored hash", func(t *testing.T) {
+		for _, exists := range []bool{true, false} {
+			tk := &mocks_tokener.Tokener{}
+			tk.On("Verify", []byte("refresh-token")).Return(refresh, nil)
+			repo := &mocks_repo.Repository{}
+			repo.On("RefreshTokenExists", mock.Anything, "h").Return(exists, nil)
+			repo.On("GetUserRoles", mock.Anything, "u1").Return([]string{"user"}, nil).Maybe()
+
+			got, err := newAuth(repo, tk).Introspect(ctx, "refresh-token")
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got.Active != exists {
+				t.Fatalf("active = %v, want %v", got.Active, exists)
+			}
+			repo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		tk := &mocks_tokener.Tokener{}
+		tk.On("Verify", mock.Anything).Return(access, nil)
+		repo := &mocks_repo.Repository{}
+		repo.On("AccessTokenRevoked", mock.Anything, access.ID).Return(false, nil)
+		repo.On("SessionExists", mock.Anything, "fam").Return(false, errors.New("redis down"))
+
+		if _, err := newAuth(repo, tk).Introspect(ctx, "access-token"); err == nil {
+			t.Fatal("expected storage error propagated")
+		}
+	})
+
+	t.Run("roles error", func(t *testing.T) {
+		tk := &mocks_tokener.Tokener{}
+		tk.On("Verify", mock.Anything).Return(access, nil)
+		repo := &mocks_repo.Repository{}
+		repo.On("AccessTokenRevoked", mock.Anything, access.ID).Return(false, nil)
+		repo.On("SessionExists", mock.Anything, "fam").Return(true, nil)
+		repo.On("GetUserRoles", mock.Anything, "u1").Return(nil, errors.New("db down"))
+
+		if _, err := newAuth(repo, tk).Introspect(ctx, "access-token"); err == nil {
+			t.Fatal("expected roles error propagated")
+		}
+	})
+}
+
+func TestIsAccessTokenRevoked(t *testing.T) {
+	ctx := context.Background()
+
+	for _, revoked := range []bool{true, false} {
+		repo := &mocks_repo.Repository{}
+		repo.On("AccessTokenRevoked", mock.Anything, "jti-1").Return(revoked, nil)
+
+		got, err := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg()).IsAccessTokenRevoked(ctx, "jti-1")
+		if err != nil || got != revoked {
+			t.Fatalf("got %v, err %v; want %v", got, err, revoked)
+		}
+	}
+
+	t.Run("repo error", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("AccessTokenRevoked", mock.Anything, "jti-1").Return(false, errors.New("redis down"))
+
+		if _, err := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg()).IsAccessTokenRevoked(ctx, "jti-1"); err == nil {
+			t.Fatal("expected repo error propagated")
+		}
+	})
+}
+
+func TestOAuthAuthorizationCode_AllCases(t *testing.T) {
+	ctx := context.Background()
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	client := domain.App{ID: 7, Status: domain.AppActive, GrantTypes: domain.AppGrantTypes,
+		RedirectURIs: []string{"https://app.example/cb"}, Scopes: []string{"profile"}}
+	newRepo := func() *mocks_repo.Repository {
+		repo := &mocks_repo.Repository{}
+		withApps(repo, client)
+		anyDevice(repo)
+		return repo
+	}
+	validReq := func() domain.AuthorizeRequest {
+		return domain.AuthorizeRequest{
+			ClientID:            7,
+			RedirectURI:         "https://app.example/cb",
+			ResponseType:        domain.ResponseTypeCode,
+			Scopes:              []string{"profile"},
+			State:               "xyz",
+			CodeChallenge:       challenge,
+			CodeChallengeMethod: domain.PKCEMethodS256,
+		}
+	}
+	oauthErr := func(t *testing.T, err error, code string, redirect bool) {
+		t.Helper()
+		var oe *domain.OAuthError
+		if !errors.As(err, &oe) || oe.Code != code || oe.Redirect != redirect {
+			t.Fatalf("expected oauth error %s (redirect %v), got: %v", code, redirect, err)
+		}
+	}
+
+	t.Run("check: client and redirect errors are not redirectable", func(t *testing.T) {
+		s := New(newRepo(), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+
+		req := validReq()
+		req.ClientID = 8
+		oauthErr(t, s.CheckAuthorizeRequest(ctx, req), domain.OAuthInvalidClient, false)
+
+		req = validReq()
+		req.RedirectURI = "https://evil.example/cb"
+		oauthErr(t, s.CheckAuthorizeRequest(ctx, req), domain.OAuthInvalidRequest, false)
+	})
+
+	t.Run("check: pkce, response type and scope", func(t *testing.T) {
+		s := New(newRepo(), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.CheckAuthorizeRequest(ctx, validReq()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+
+		req := validReq()
+		req.CodeChallengeMethod = "plain"
+		oauthErr(t, s.CheckAuthorizeRequest(ctx, req), domain.OAuthInvalidRequest, true)
+
+		req = validReq()
+		req.CodeChallenge = ""
+		oauthErr(t, s.CheckAuthorizeRequest(ctx, req), domain.OAuthInvalidRequest, true)
+
+		req = validReq()
+		req.ResponseType = "token"
+		oauthErr(t, s.CheckAuthorizeRequest(ctx, req), domain.OAuthUnsupportedResponseType, true)
+
+		req = validReq()
+		req.Scopes = []string{"admin"}
+		oauthErr(t, s.CheckAuthorizeRequest(ctx, req), domain.OAuthInvalidScope, true)
+	})
+
+	t.Run("authorize: code saved by hash with request binding", func(t *testing.T) {
+		repo := newRepo()
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, "u@e.x").Return(domain.User{ID: "u1", Email: "u@e.x", Password: "hash"}, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(domain.UserMfa{}, domain.ErrNotFound)
+		repo.On("SaveAuthorizationCode", mock.Anything, mock.MatchedBy(func(c domain.AuthorizationCode) bool {
+			return c.Hash == "code-hash" && c.UserID == "u1" && c.ClientID == 7 &&
+				c.RedirectURI == "https://app.example/cb" && c.CodeChallenge == challenge &&
+				reflect.DeepEqual(c.Scopes, []string{"profile"}) && time.Until(c.Exp) > 0
+		})).Return(nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", []byte("hash"), []byte("pass")).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("code-hash"), nil)
+
+		s := New(repo, hasher, nil, nil, nil, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		code, err := s.Authorize(ctx, validReq(), domain.User{Email: "u@e.x", Password: "pass"}, "")
+		if err != nil || code == "" {
+			t.Fatalf("expected code, got %q, err %v", code, err)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("authorize: wrong password", func(t *testing.T) {
+		repo := newRepo()
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, "u@e.x").Return(domain.User{ID: "u1", Password: "hash"}, nil)
+		repo.On("RegisterLoginFailure", mock.Anything, mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(false, nil)
+
+		s := New(repo, hasher, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.Authorize(ctx, validReq(), domain.User{Email: "u@e.x", Password: "bad"}, ""); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "SaveAuthorizationCode", mock.Anything, mock.Anything)
+	})
+
+	t.Run("authorize: mfa requires totp code", func(t *testing.T) {
+		confirmedAt := time.Now()
+		repo := newRepo()
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, "u@e.x").Return(domain.User{ID: "u1", Password: "hash"}, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(domain.UserMfa{UserID: "u1", ConfirmedAt: &confirmedAt}, nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
+
+		s := New(repo, hasher, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.Authorize(ctx, validReq(), domain.User{Email: "u@e.x", Password: "pass"}, ""); !errors.Is(err, domain.ErrMfaRequired) {
+			t.Fatalf("expected wrapped domain.ErrMfaRequired; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "SaveAuthorizationCode", mock.Anything, mock.Anything)
+	})
+
+	t.Run("authorize: invalid request stops before credentials", func(t *testing.T) {
+		repo := newRepo()
+		req := validReq()
+		req.CodeChallengeMethod = "plain"
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		oauthErr(t, func() error { _, err := s.Authorize(ctx, req, domain.User{Email: "u@e.x"}, ""); return err }(),
+			domain.OAuthInvalidRequest, true)
+		repo.AssertNotCalled(t, "GetUserInfoByEmail", mock.Anything, mock.Anything)
+	})
+
+	storedCode := domain.AuthorizationCode{
+		Hash: "code-hash", UserID: "u1", ClientID: 7, RedirectURI: "https://app.example/cb",
+		Scopes: []string{"profile"}, CodeChallenge: challenge, Exp: time.Now().Add(time.Minute),
+	}
+	exchange := domain.CodeExchange{Code: "code", ClientID: 7, RedirectURI: "https://app.example/cb", CodeVerifier: verifier}
+
+	t.Run("exchange: success opens scoped session without device", func(t *testing.T) {
+		repo := newRepo()
+		noRoles(repo)
+		repo.On("ConsumeAuthorizationCode", mock.Anything, "code-hash").Return(storedCode, nil)
+		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", []byte("code")).Return([]byte("code-hash"), nil)
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("refresh-hash"), nil)
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair",
+			mock.MatchedBy(func(m domain.Meta) bool {
+				return m.UserID == "u1" && m.Ctx == domain.NewDeviceCtx(7, 0) && reflect.DeepEqual(m.Scopes, []string{"profile"})
+			}),
+			mock.MatchedBy(func(m domain.Meta) bool { return reflect.DeepEqual(m.Scopes, []string{"profile"}) }),
+		).Return([]byte("acc"), []byte("ref"), nil)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		got, err := s.ExchangeAuthorizationCode(ctx, exchange)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if got.Access != "acc" || got.Refresh != "ref" || got.ExpiresIn != baseCfg().AccessTokenTTL {
+			t.Fatalf("unexpected token: %+v", got)
+		}
+		tokener.AssertExpectations(t)
+	})
+
+	t.Run("exchange: rejected codes", func(t *testing.T) {
+		cases := map[string]struct {
+			stored   domain.AuthorizationCode
+			err      error
+			exchange domain.CodeExchange
+		}{
+			"unknown or used": {err: domain.ErrNotFound, exchange: exchange},
+			"other client":    {stored: storedCode, exchange: domain.CodeExchange{Code: "code", ClientID: 8, RedirectURI: exchange.RedirectURI, CodeVerifier: verifier}},
+			"other redirect":  {stored: storedCode, exchange: domain.CodeExchange{Code: "code", ClientID: 7, RedirectURI: "https://app.example/other", CodeVerifier: verifier}},
+			"wrong verifier":  {stored: storedCode, exchange: domain.CodeExchange{Code: "code", ClientID: 7, RedirectURI: exchange.RedirectURI, CodeVerifier: strings.Repeat("w", 43)}},
+		}
+		for name, tc := range cases {
+			repo := newRepo()
+			repo.On("ConsumeAuthorizationCode", mock.Anything, "code-hash").Return(tc.stored, tc.err)
+			tokenHasher := &mocks_tokenhasher.TokenHasher{}
+			tokenHasher.On("Sum", mock.Anything).Return([]byte("code-hash"), nil)
+
+			s := New(repo, nil, nil, nil, nil, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+			_, err := s.ExchangeAuthorizationCode(ctx, tc.exchange)
+			t.Run(name, func(t *testing.T) { oauthErr(t, err, domain.OAuthInvalidGrant, false) })
+			repo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("exchange: malformed verifier rejected before consuming code", func(t *testing.T) {
+		repo := newRepo()
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+
+		bad := exchange
+		bad.CodeVerifier = "short"
+		_, err := s.ExchangeAuthorizationCode(ctx, bad)
+		oauthErr(t, err, domain.OAuthInvalidRequest, false)
+		repo.AssertNotCalled(t, "ConsumeAuthorizationCode", mock.Anything, mock.Anything)
+	})
+
+	t.Run("refresh: client without device, invalid token is invalid_grant", func(t *testing.T) {
+		rm := domain.NewRefreshMeta(time.Hour, "u1", 7, 0)
+		rm.SetFamily("fam")
+		rm.Scopes = []string{"profile"}
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", []byte("ref")).Return(rm, nil)
+		tokener.On("VerifyRefresh", mock.Anything).Return(domain.Meta{}, errors.New("bad signature"))
+		tokener.On("GenPair", mock.MatchedBy(func(m domain.Meta) bool {
+			return reflect.DeepEqual(m.Scopes, []string{"profile"})
+		}), mock.Anything).Return([]byte("acc2"), []byte("ref2"), nil)
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+		repo := newRepo()
+		noRoles(repo)
+		repo.On("RotateToken", mock.Anything, "h", mock.Anything).Return(nil)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		got, err := s.RefreshOAuthToken(ctx, "ref", 7)
+		if err != nil || got.Access != "acc2" {
+			t.Fatalf("unexpected refresh result %+v, err %v", got, err)
+		}
+
+		// токен выдан клиенту 7, клиент 8 его не обновит
+		_, err = s.RefreshOAuthToken(ctx, "ref", 8)
+		oauthErr(t, err, domain.OAuthInvalidGrant, false)
+		_, err = s.RefreshOAuthToken(ctx, "garbage", 7)
+		oauthErr(t, err, domain.OAuthInvalidGrant, false)
+	})
+}
+
+func TestMachineClients_AllCases(t *testing.T) {
+	ctx := context.Background()
+	const clientID = "6f1c2a6e-2d55-4c36-9a47-6f0f3f0b9d11"
+
+	hasher := func() *mocks_tokenhasher.TokenHasher {
+		th := &mocks_tokenhasher.TokenHasher{}
+		th.On("Sum", []byte("right-secret")).Return([]byte("secret-hash"), nil).Maybe()
+		th.On("Sum", mock.Anything).Return([]byte("other-hash"), nil).Maybe()
+		return th
+	}
+	stored := func() domain.MachineClient {
+		return domain.MachineClient{ID: clientID, Name: "billing", SecretHash: []byte("secret-hash"), Scopes: []string{"orders:read", "orders:write"}}
+	}
+	oauthErr := func(t *testing.T, err error, code string) {
+		t.Helper()
+		var oe *domain.OAuthError
+		if !errors.As(err, &oe) || oe.Code != code {
+			t.Fatalf("expected oauth error %s, got: %v", code, err)
+		}
+	}
+
+	t.Run("create: secret returned once, stored hashed, scopes normalized", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("NewMachineClient", mock.Anything, mock.MatchedBy(func(c domain.MachineClient) bool {
+			return c.ID != "" && c.Name == "billing" && string(c.SecretHash) == "other-hash" &&
+				reflect.DeepEqual(c.Scopes, []string{"orders:read", "orders:write"})
+		})).Return(nil)
+
+		s := New(repo, nil, nil, nil, nil, hasher(), nil, nil, nil, nil, nil, baseCfg())
+		got, err := s.CreateMachineClient(ctx, "billing", []string{"orders:write", "orders:read", "orders:write"})
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if got.ClientID == "" || got.ClientSecret == "" || got.ClientSecret == "other-hash" {
+			t.Fatalf("unexpected secret: %+v", got)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("create: invalid scope", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		s := New(repo, nil, nil, nil, nil, hasher(), nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.CreateMachineClient(ctx, "billing", []string{"orders read"}); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "NewMachineClient", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rotate and disable pass through not found", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("UpdateMachineClientSecret", mock.Anything, clientID, []byte("other-hash")).Return(nil).Once()
+		repo.On("UpdateMachineClientSecret", mock.Anything, clientID, mock.Anything).Return(domain.ErrNotFound).Once()
+		repo.On("DisableMachineClient", mock.Anything, clientID).Return(domain.ErrNotFound)
+
+		s := New(repo, nil, nil, nil, nil, hasher(), nil, nil, nil, nil, nil, baseCfg())
+		got, err := s.RotateMachineClientSecret(ctx, clientID)
+		if err != nil || got.ClientID != clientID || got.ClientSecret == "" {
+			t.Fatalf("unexpected rotate result %+v, err %v", got, err)
+		}
+		if _, err := s.RotateMachineClientSecret(ctx, clientID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected wrapped domain.ErrNotFound; got: %v", err)
+		}
+		if err := s.DisableMachineClient(ctx, clientID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected wrapped domain.ErrNotFound; got: %v", err)
+		}
+	})
+
+	t.Run("client_credentials: access only, subject is client", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("GetMachineClient", mock.Anything, clientID).Return(stored(), nil)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenAccess", mock.MatchedBy(func(m domain.Meta) bool {
+			return m.Type == domain.TokenTypeAccess && m.UserID == "" && m.ClientID == clientID &&
+				m.FamilyID == "" && reflect.DeepEqual(m.Scopes, []string{"orders:read"})
+		})).Return([]byte("acc"), nil)
+
+		s := New(repo, nil, nil, nil, tokener, hasher(), nil, nil, nil, nil, nil, baseCfg())
+		got, err := s.ClientCredentialsToken(ctx, clientID, "right-secret", []string{"orders:read"})
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if got.Access != "acc" || got.Refresh != "" || got.ExpiresIn != baseCfg().AccessTokenTTL {
+			t.Fatalf("unexpected token: %+v", got)
+		}
+		tokener.AssertExpectations(t)
+		repo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("client_credentials: no scope requested gets all client scopes", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("GetMachineClient", mock.Anything, clientID).Return(stored(), nil)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenAccess", mock.Anything).Return([]byte("acc"), nil)
+
+		s := New(repo, nil, nil, nil, tokener, hasher(), nil, nil, nil, nil, nil, baseCfg())
+		got, err := s.ClientCredentialsToken(ctx, clientID, "right-secret", nil)
+		if err != nil || !reflect.DeepEqual(got.Scopes, []string{"orders:read", "orders:write"}) {
+			t.Fatalf("unexpected scopes %v, err %v", got.Scopes, err)
+		}
+	})
+
+	t.Run("client_credentials: rejected clients", func(t *testing.T) {
+		disabledAt := time.Now()
+		disabled := stored()
+		disabled.DisabledAt = &disabledAt
+
+		cases := map[string]struct {
+			id, secret string
+			scopes     []string
+			client     domain.MachineClient
+			err        error
+			code       string
+		}{
+			"not uuid":      {id: "billing", secret: "right-secret", code: domain.OAuthInvalidClient},
+			"empty secret":  {id: clientID, code: domain.OAuthInvalidClient},
+			"unknown":       {id: clientID, secret: "right-secret", err: domain.ErrNotFound, code: domain.OAuthInvalidClient},
+			"wrong secret":  {id: clientID, secret: "wrong", client: stored(), code: domain.OAuthInvalidClient},
+			"disabled":      {id: clientID, secret: "right-secret", client: disabled, code: domain.OAuthInvalidClient},
+			"foreign scope": {id: clientID, secret: "right-secret", scopes: []string{"orders:read", "admin"}, client: stored(), code: domain.OAuthInvalidScope},
+		}
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				repo := &mocks_repo.Repository{}
+				repo.On("GetMachineClient", mock.Anything, clientID).Return(tc.client, tc.err).Maybe()
+				tokener := &mocks_tokener.Tokener{}
+
+				s := New(repo, nil, nil, nil, tokener, hasher(), nil, nil, nil, nil, nil, baseCfg())
+				_, err := s.ClientCredentialsToken(ctx, tc.id, tc.secret, tc.scopes)
+				oauthErr(t, err, tc.code)
+				tokener.AssertNotCalled(t, "GenAccess", mock.Anything)
+			})
+		}
+	})
+
+	t.Run("introspect: machine token inactive once client disabled", func(t *testing.T) {
+		am := domain.NewClientAccessMeta(time.Minute, clientID, []string{"orders:read"})
+		disabledAt := time.Now()
+		disabled := stored()
+		disabled.DisabledAt = &disabledAt
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("Verify", mock.Anything).Return(am, nil)
+		repo := &mocks_repo.Repository{}
+		repo.On("AccessTokenRevoked", mock.Anything, am.ID).Return(false, nil)
+		repo.On("GetMachineClient", mock.Anything, clientID).Return(stored(), nil).Once()
+		repo.On("GetMachineClient", mock.Anything, clientID).Return(disabled, nil).Once()
+
+		s := New(repo, nil, nil, nil, tokener, hasher(), nil, nil, nil, nil, nil, baseCfg())
+		got, err := s.Introspect(ctx, "acc")
+		if err != nil || !got.Active || got.ClientID != clientID || got.UserID != "" {
+			t.Fatalf("expected active machine token, got %+v, err %v", got, err)
+		}
+		got, err = s.Introspect(ctx, "acc")
+		if err != nil || got.Active {
+			t.Fatalf("expected inactive after disable, got %+v, err %v", got, err)
+		}
+		repo.AssertNotCalled(t, "GetUserRoles", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "SessionExists", mock.Anything, mock.Anything)
+	})
+}
+
+func TestOIDC_AllCases(t *testing.T) {
+	ctx := context.Background()
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	authTime := time.Now().Add(-time.Minute)
+	verifiedAt := time.Now()
+
+	code := domain.AuthorizationCode{
+		Hash: "code-hash", UserID: "u1", ClientID: 7, RedirectURI: "https://app.example/cb",
+		Scopes: []string{"openid", "email"}, CodeChallenge: challenge, Exp: time.Now().Add(time.Minute),
+		Nonce: "n-1", AuthTime: authTime, AMR: []string{domain.AMRPassword, domain.AMROtp, domain.AMRMfa},
+	}
+	exchange := domain.CodeExchange{Code: "code", ClientID: 7, RedirectURI: "https://app.example/cb", CodeVerifier: verifier}
+
+	t.Run("exchange with openid issues id_token", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noRoles(repo)
+		repo.On("ConsumeAuthorizationCode", mock.Anything, "code-hash").Return(code, nil)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(domain.User{ID: "u1", Email: "u@e.x", EmailVerifiedAt: &verifiedAt}, nil)
+		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("code-hash"), nil)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenIDToken", mock.MatchedBy(func(id domain.IDToken) bool {
+			return id.Subject == "u1" && id.Audience == "7" && id.Nonce == "n-1" && id.AuthTime.Equal(authTime) &&
+				reflect.DeepEqual(id.AMR, []string{"pwd", "otp", "mfa"}) && id.Email == "u@e.x" && id.EmailVerified
+		})).Return([]byte("idt"), nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		got, err := s.ExchangeAuthorizationCode(ctx, exchange)
+		if err != nil || got.IDToken != "idt" || got.Access != "acc" {
+			t.Fatalf("unexpected token %+v, err %v", got, err)
+		}
+		tokener.AssertExpectations(t)
+	})
+
+	t.Run("exchange without openid has no id_token", func(t *testing.T) {
+		plain := code
+		plain.Scopes = []string{"profile"}
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noRoles(repo)
+		repo.On("ConsumeAuthorizationCode", mock.Anything, "code-hash").Return(plain, nil)
+		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("code-hash"), nil)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		got, err := s.ExchangeAuthorizationCode(ctx, exchange)
+		if err != nil || got.IDToken != "" {
+			t.Fatalf("unexpected token %+v, err %v", got, err)
+		}
+		tokener.AssertNotCalled(t, "GenIDToken", mock.Anything)
+		repo.AssertNotCalled(t, "GetUserInfoByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("id_token failure opens no session", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("ConsumeAuthorizationCode", mock.Anything, "code-hash").Return(code, nil)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(domain.User{}, errors.New("db down"))
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("code-hash"), nil)
+
+		s := New(repo, nil, nil, nil, &mocks_tokener.Tokener{}, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.ExchangeAuthorizationCode(ctx, exchange); err == nil {
+			t.Fatal("expected error")
+		}
+		repo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("authorize records amr and nonce", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, "u@e.x").Return(domain.User{ID: "u1", Email: "u@e.x", Password: "hash"}, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(domain.UserMfa{}, domain.ErrNotFound)
+		repo.On("SaveAuthorizationCode", mock.Anything, mock.MatchedBy(func(c domain.AuthorizationCode) bool {
+			return c.Nonce == "n-1" && reflect.DeepEqual(c.AMR, []string{"pwd"}) && time.Since(c.AuthTime) < time.Minute
+		})).Return(nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("code-hash"), nil)
+		withApps(repo, domain.App{ID: 7, Status: domain.AppActive, GrantTypes: domain.AppGrantTypes,
+			RedirectURIs: []string{"https://app.example/cb"}, Scopes: []string{"openid"}})
+
+		s := New(repo, hasher, nil, nil, nil, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.Authorize(ctx, domain.AuthorizeRequest{
+			ClientID: 7, RedirectURI: "https://app.example/cb", ResponseType: domain.ResponseTypeCode,
+			Scopes: []string{"openid"}, CodeChallenge: challenge, CodeChallengeMethod: domain.PKCEMethodS256, Nonce: "n-1",
+		}, domain.User{Email: "u@e.x", Password: "pass"}, "")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	userAccess := domain.NewAccessMeta(time.Minute, "u1", 7, 0)
+	userAccess.SetFamily("fam")
+	userAccess.Scopes = []string{"openid", "email"}
+
+	t.Run("userinfo", func(t *testing.T) {
+		noEmail := userAccess
+		noEmail.Scopes = []string{"openid"}
+		noOpenID := userAccess
+		noOpenID.Scopes = []string{"email"}
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("Verify", []byte("acc")).Return(userAccess, nil)
+		tokener.On("Verify", []byte("no-email")).Return(noEmail, nil)
+		tokener.On("Verify", []byte("no-openid")).Return(noOpenID, nil)
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("AccessTokenRevoked", mock.Anything, userAccess.ID).Return(false, nil)
+		repo.On("SessionExists", mock.Anything, "fam").Return(true, nil)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(domain.User{ID: "u1", Email: "u@e.x"}, nil)
+
+		s := New(repo, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		got, err := s.UserInfo(ctx, "acc")
+		if err != nil || got != (domain.UserInfo{Subject: "u1", Email: "u@e.x", EmailVerified: false}) {
+			t.Fatalf("unexpected userinfo %+v, err %v", got, err)
+		}
+		got, err = s.UserInfo(ctx, "no-email")
+		if err != nil || got != (domain.UserInfo{Subject: "u1"}) {
+			t.Fatalf("unexpected userinfo %+v, err %v", got, err)
+		}
+		if _, err := s.UserInfo(ctx, "no-openid"); !errors.Is(err, domain.ErrInsufficientScope) {
+			t.Fatalf("expected wrapped domain.ErrInsufficientScope; got: %v", err)
+		}
+	})
+
+	t.Run("userinfo rejects invalid, revoked, refresh and machine tokens", func(t *testing.T) {
+		refresh := domain.NewRefreshMeta(time.Hour, "u1", 7, 0)
+		refresh.SetFamily("fam")
+		machine := domain.NewClientAccessMeta(time.Minute, "client-1", []string{"openid"})
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("Verify", []byte("garbage")).Return(domain.Meta{}, errors.New("bad sig"))
+		tokener.On("Verify", []byte("revoked")).Return(userAccess, nil)
+		tokener.On("Verify", []byte("refresh")).Return(refresh, nil)
+		tokener.On("Verify", []byte("machine")).Return(machine, nil)
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		repo.On("AccessTokenRevoked", mock.Anything, userAccess.ID).Return(true, nil)
+
+		s := New(repo, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		for _, tok := range []string{"garbage", "revoked", "refresh", "machine"} {
+			if _, err := s.UserInfo(ctx, tok); !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("%s: expected wrapped domain.ErrValidation; got: %v", tok, err)
+			}
+		}
+		repo.AssertNotCalled(t, "GetUserInfoByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("discovery built from issuer url", func(t *testing.T) {
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("JWKS").Return(domain.JWKS{Keys: []domain.JWK{{Alg: "EdDSA"}, {Alg: "RS256"}, {Alg: "EdDSA"}}})
+
+		cfg := baseCfg()
+		cfg.TokenIssuer = "https://sso.example.com/"
+		got, err := New(nil, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, cfg).OpenIDConfiguration()
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if got.Issuer != cfg.TokenIssuer || got.TokenEndpoint != "https://sso.example.com/oauth2/token" ||
+			got.JwksURI != "https://sso.example.com/.well-known/jwks.json" ||
+			!reflect.DeepEqual(got.IDTokenSigningAlgValuesSupported, []string{"EdDSA", "RS256"}) {
+			t.Fatalf("unexpected discovery: %+v", got)
+		}
+
+		cfg.TokenIssuer = "sso"
+		if _, err := New(nil, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, cfg).OpenIDConfiguration(); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected wrapped domain.ErrNotFound; got: %v", err)
+		}
+	})
+}
+
+func TestApps_AllCases(t *testing.T) {
+	ctx := context.Background()
+	dctx := domain.NewDeviceCtx(3, -1)
+	stored := domain.User{ID: "u1", Email: "u@corp.example", Password: "hash"}
+	validMeta := domain.NewRefreshMeta(time.Hour, "u1", dctx.AppId, dctx.DeviceID)
+	validMeta.SetFamily("fam-1")
+
+	app := func(mod func(*domain.App)) domain.App {
+		a := domain.App{ID: 3, Name: "web", Status: domain.AppActive, GrantTypes: domain.AppGrantTypes}
+		if mod != nil {
+			mod(&a)
+		}
+		return a
+	}
+
+	t.Run("login: unknown, disabled app or disallowed grant — before credentials", func(t *testing.T) {
+		for name, apps := range map[string][]domain.App{
+			"unknown":  nil,
+			"disabled": {app(func(a *domain.App) { a.Status = domain.AppDisabled })},
+			"no grant": {app(func(a *domain.App) { a.GrantTypes = []string{domain.GrantAuthorizationCode} })},
+		} {
+			repo := &mocks_repo.Repository{}
+			withApps(repo, apps...)
+			anyDevice(repo)
+
+			s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+			if _, err := s.Login(ctx, domain.User{Email: stored.Email, Password: "p"}, dctx); !errors.Is(err, domain.ErrAppAccessDenied) {
+				t.Fatalf("%s: expected wrapped domain.ErrAppAccessDenied; got: %v", name, err)
+			}
+			repo.AssertNotCalled(t, "GetUserInfoByEmail", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("registry not enforced: unknown app gets only pre-registry grants", func(t *testing.T) {
+		cfg := baseCfg()
+		cfg.AppsEnforced = false
+		repo := &mocks_repo.Repository{}
+		withApps(repo, app(func(a *domain.App) { a.Status = domain.AppDisabled }))
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, cfg)
+		for _, grant := range []string{domain.GrantPassword, domain.GrantRefreshToken} {
+			got, err := s.allowedApp(ctx, 42, grant)
+			if err != nil || got.ID != 42 {
+				t.Fatalf("%s: expected legacy app 42; got: %+v, %v", grant, got, err)
+			}
+		}
+		for _, grant := range []string{domain.GrantAuthorizationCode, domain.GrantFederated, domain.GrantPasswordless} {
+			if _, err := s.allowedApp(ctx, 42, grant); !errors.Is(err, domain.ErrAppAccessDenied) {
+				t.Fatalf("%s: expected wrapped domain.ErrAppAccessDenied; got: %v", grant, err)
+			}
+		}
+		// зарегистрированное приложение подчиняется своим настройкам и без AppsEnforced
+		if _, err := s.allowedApp(ctx, 3, domain.GrantPassword); !errors.Is(err, domain.ErrAppAccessDenied) {
+			t.Fatalf("disabled: expected wrapped domain.ErrAppAccessDenied; got: %v", err)
+		}
+	})
+
+	t.Run("login: email domain not allowed, not counted as failure", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		withApps(repo, app(func(a *domain.App) { a.EmailDomains = []string{"partner.example"} }))
+		anyDevice(repo)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.Login(ctx, domain.User{Email: stored.Email, Password: "p"}, dctx); !errors.Is(err, domain.ErrAppAccessDenied) {
+			t.Fatalf("expected wrapped domain.ErrAppAccessDenied; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "LoginLockedFor", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "GetUserInfoByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("login: allowed domain case-insensitive, per-app ttl", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		withApps(repo, app(func(a *domain.App) {
+			a.EmailDomains = []string{"corp.example"}
+			a.AccessTokenTTL = 5 * time.Minute
+			a.RefreshTokenTTL = 30 * 24 * time.Hour
+		}))
+		anyDevice(repo)
+		noLockout(repo)
+		noRoles(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, "u@CORP.example").Return(stored, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(domain.UserMfa{}, domain.ErrNotFound)
+		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil)
+
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair", mock.MatchedBy(func(m domain.Meta) bool {
+			return m.Exp.Sub(m.IssuedAt) == 5*time.Minute
+		}), mock.MatchedBy(func(m domain.Meta) bool {
+			return m.Exp.Sub(m.IssuedAt) == 30*24*time.Hour
+		})).Return([]byte("acc"), []byte("ref"), nil)
+
+		s := New(repo, hasher, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.Login(ctx, domain.User{Email: "u@CORP.example", Password: "p"}, dctx); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		tokener.AssertExpectations(t)
+	})
+
+	t.Run("refresh: disabled app or no refresh grant — no rotation", func(t *testing.T) {
+		for name, a := range map[string]domain.App{
+			"disabled": app(func(a *domain.App) { a.Status = domain.AppDisabled }),
+			"no grant": app(func(a *domain.App) { a.GrantTypes = []string{domain.GrantPassword} }),
+		} {
+			repo := &mocks_repo.Repository{}
+			withApps(repo, a)
+			anyDevice(repo)
+			tokener := &mocks_tokener.Tokener{}
+			tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
+
+			s := New(repo, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+			if _, err := s.Refresh(ctx, "r", dctx); !errors.Is(err, domain.ErrAppAccessDenied) {
+				t.Fatalf("%s: expected wrapped domain.ErrAppAccessDenied; got: %v", name, err)
+			}
+			repo.AssertNotCalled(t, "RotateToken", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("logout: unknown app — nothing revoked", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		withApps(repo)
+		anyDevice(repo)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(validMeta, nil)
+
+		s := New(repo, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.Logout(ctx, "r", dctx); !errors.Is(err, domain.ErrAppAccessDenied) {
+			t.Fatalf("expected wrapped domain.ErrAppAccessDenied; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "RevokeTokenByHash", mock.Anything, mock.Anything)
+	})
+
+	t.Run("oauth refresh: disabled client — unauthorized_client", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		withApps(repo, app(func(a *domain.App) { a.Status = domain.AppDisabled }))
+		anyDevice(repo)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(domain.NewRefreshMeta(time.Hour, "u1", 3, 0), nil)
+
+		s := New(repo, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.RefreshOAuthToken(ctx, "r", 3)
+		var oe *domain.OAuthError
+		if !errors.As(err, &oe) || oe.Code != domain.OAuthUnauthorizedClient {
+			t.Fatalf("expected unauthorized_client, got: %v", err)
+		}
+	})
+
+	t.Run("create: normalized and active", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("NewApp", mock.Anything, domain.App{
+			ID: 3, Name: "web", Status: domain.AppActive,
+			GrantTypes:   []string{domain.GrantAuthorizationCode, domain.GrantPassword},
+			EmailDomains: []string{"corp.example"},
+			RedirectURIs: []string{"com.example.app:/cb", "http://127.0.0.1:8400/cb", "https://app.example/cb"},
+			Scopes:       []string{"openid"},
+		}).Return(domain.App{ID: 3}, nil)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		_, err := s.CreateApp(ctx, domain.App{
+			ID: 3, Name: "web", Status: domain.AppDisabled,
+			GrantTypes:   []string{domain.GrantPassword, domain.GrantAuthorizationCode, domain.GrantPassword},
+			EmailDomains: []string{" Corp.Example", "corp.example"},
+			RedirectURIs: []string{"https://app.example/cb", "http://127.0.0.1:8400/cb", "com.example.app:/cb"},
+			Scopes:       []string{"openid", "openid"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("create: invalid settings", func(t *testing.T) {
+		for name, a := range map[string]domain.App{
+			"machine grant":         {ID: 3, GrantTypes: []string{domain.GrantClientCredentials}},
+			"negative ttl":          {ID: 3, AccessTokenTTL: -time.Second},
+			"access over default":   {ID: 3, AccessTokenTTL: 2 * time.Hour},
+			"access over refresh":   {ID: 3, AccessTokenTTL: 10 * time.Minute, RefreshTokenTTL: 5 * time.Minute},
+			"email in domains":      {ID: 3, EmailDomains: []string{"u@corp.example"}},
+			"code without redirect": {ID: 3, GrantTypes: []string{domain.GrantAuthorizationCode}},
+			"relative redirect":     {ID: 3, RedirectURIs: []string{"/cb"}},
+			"redirect fragment":     {ID: 3, RedirectURIs: []string{"https://a.example/cb#x"}},
+			"http external":         {ID: 3, RedirectURIs: []string{"http://a.example/cb"}},
+			"bad scope":             {ID: 3, Scopes: []string{"a b"}},
+		} {
+			repo := &mocks_repo.Repository{}
+			s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+			if _, err := s.CreateApp(ctx, a); !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("%s: expected wrapped domain.ErrValidation; got: %v", name, err)
+			}
+			repo.AssertNotCalled(t, "NewApp", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("create: duplicate id", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("NewApp", mock.Anything, mock.Anything).Return(domain.App{}, domain.ErrDuplicate)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.CreateApp(ctx, domain.App{ID: 3, Name: "web"}); !errors.Is(err, domain.ErrDuplicate) {
+			t.Fatalf("expected wrapped domain.ErrDuplicate; got: %v", err)
+		}
+	})
+
+	t.Run("disable and enable: not found passes through", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		repo.On("SetAppStatus", mock.Anything, int32(3), domain.AppDisabled).Return(nil)
+		repo.On("SetAppStatus", mock.Anything, int32(4), domain.AppActive).Return(domain.ErrNotFound)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if err := s.DisableApp(ctx, 3); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if err := s.EnableApp(ctx, 4); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected wrapped domain.ErrNotFound; got: %v", err)
+		}
+	})
+}
+
+func TestDevices_AllCases(t *testing.T) {
+	ctx := context.Background()
+	fresh := domain.NewDeviceCtx(3, domain.UnregisteredDevice)
+	freshMeta := domain.NewRefreshMeta(time.Hour, "u1", fresh.AppId, fresh.DeviceID)
+	freshMeta.SetFamily("fam-1")
+	freshMeta.Scopes = []string{"profile"}
+	foreign := domain.NewDeviceCtx(3, -42)
+	foreignMeta := domain.NewRefreshMeta(time.Hour, "u1", foreign.AppId, foreign.DeviceID)
+	foreignMeta.SetFamily("fam-2")
+
+	notOwned := func(repo *mocks_repo.Repository) {
+		repo.On("TouchDevice", mock.Anything, int32(-42), "u1").Return(domain.ErrNotFound)
+	}
+
+	t.Run("register: new device, session moved to it", func(t *testing.T) {
+		var issued int32
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		noRoles(repo)
+		repo.On("RevokeTokenByHash", mock.Anything, "h-old").Return(nil)
+		repo.On("NewDevice", mock.Anything, mock.MatchedBy(func(d domain.Device) bool {
+			return domain.IssuedDevice(d.ID) && d.UserID == "u1" && d.Platform == "ios" && d.Name == "iPhone"
+		})).Return(func(_ context.Context, d domain.Device) (domain.Device, error) {
+			issued = d.ID
+			return d, nil
+		})
+		repo.On("SaveRefreshToken", mock.Anything, mock.MatchedBy(func(rt domain.RefreshToken) bool {
+			return rt.Meta.Ctx.DeviceID == issued && rt.Meta.FamilyID != "fam-1" && reflect.DeepEqual(rt.Meta.Scopes, []string{"profile"})
+		})).Return(nil)
+
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", []byte("old")).Return(freshMeta, nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", []byte("old")).Return([]byte("h-old"), nil)
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("h-new"), nil)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		device, tok, err := s.RegisterDevice(ctx, "old", fresh, "ios", "iPhone")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if device.ID != issued || tok.Access != "acc" || tok.Refresh != "ref" {
+			t.Fatalf("unexpected result: %+v %+v", device, tok)
+		}
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "TouchDevice", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("register: refresh already used — no device", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		repo.On("RevokeTokenByHash", mock.Anything, mock.Anything).Return(domain.ErrNotFound)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(freshMeta, nil)
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		if _, _, err := s.RegisterDevice(ctx, "old", fresh, "ios", ""); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "NewDevice", mock.Anything, mock.Anything)
+	})
+
+	t.Run("register: id collision retried", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		noRoles(repo)
+		repo.On("RevokeTokenByHash", mock.Anything, mock.Anything).Return(nil)
+		repo.On("NewDevice", mock.Anything, mock.Anything).Return(domain.Device{}, domain.ErrDuplicate).Once()
+		repo.On("NewDevice", mock.Anything, mock.Anything).Return(domain.Device{ID: -9, UserID: "u1"}, nil).Once()
+		repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(freshMeta, nil)
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+		device, _, err := s.RegisterDevice(ctx, "old", fresh, "android", "")
+		if err != nil || device.ID != -9 {
+			t.Fatalf("unexpected result: %+v, %v", device, err)
+		}
+		repo.AssertNumberOfCalls(t, "NewDevice", 2)
+	})
+
+	t.Run("register: from foreign device", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		notOwned(repo)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(foreignMeta, nil)
+
+		s := New(repo, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		if _, _, err := s.RegisterDevice(ctx, "old", foreign, "ios", ""); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "RevokeTokenByHash", mock.Anything, mock.Anything)
+	})
+
+	t.Run("login: foreign device rejected before mfa", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		noLockout(repo)
+		notOwned(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, "u@e.x").Return(domain.User{ID: "u1", Email: "u@e.x", Password: "hash"}, nil)
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(true, nil)
+		hasher.On("NeedsRehash", mock.Anything).Return(false)
+
+		s := New(repo, hasher, nil, nil, nil, nil, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.Login(ctx, domain.User{Email: "u@e.x", Password: "p"}, foreign); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "GetUserMfa", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("refresh and logout: foreign device rejected", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		notOwned(repo)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(foreignMeta, nil)
+
+		s := New(repo, nil, nil, nil, tokener, nil, nil, nil, nil, nil, nil, baseCfg())
+		if _, err := s.Refresh(ctx, "r", foreign); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("refresh: expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		if err := s.Logout(ctx, "r", foreign); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("logout: expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "RotateToken", mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "RevokeTokenByHash", mock.Anything, mock.Anything)
+	})
+
+	t.Run("refresh: unregistered device only during grace period", func(t *testing.T) {
+		for name, graceUntil := range map[string]time.Time{
+			"grace":       time.Now().Add(time.Hour),
+			"after grace": time.Now().Add(-time.Hour),
+			"no grace":    {},
+		} {
+			repo := &mocks_repo.Repository{}
+			anyApp(repo)
+			noRoles(repo)
+			repo.On("RotateToken", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			tokener := &mocks_tokener.Tokener{}
+			tokener.On("VerifyRefresh", mock.Anything).Return(freshMeta, nil)
+			tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+			tokenHasher := &mocks_tokenhasher.TokenHasher{}
+			tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+
+			cfg := baseCfg()
+			cfg.DevicesGraceUntil = graceUntil
+			s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, cfg)
+			_, err := s.Refresh(ctx, "r", fresh)
+			if name == "grace" && err != nil {
+				t.Fatalf("%s: unexpected err: %v", name, err)
+			}
+			if name != "grace" && !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("%s: expected wrapped domain.ErrValidation; got: %v", name, err)
+			}
+			repo.AssertNotCalled(t, "TouchDevice", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("legacy client-chosen device: accepted only during grace period, can register", func(t *testing.T) {
+		legacy := domain.NewDeviceCtx(3, 7)
+		legacyMeta := domain.NewRefreshMeta(time.Hour, "u1", legacy.AppId, legacy.DeviceID)
+		legacyMeta.SetFamily("fam-3")
+
+		for name, graceUntil := range map[string]time.Time{
+			"grace":       time.Now().Add(time.Hour),
+			"after grace": time.Now().Add(-time.Hour),
+		} {
+			repo := &mocks_repo.Repository{}
+			anyApp(repo)
+			noRoles(repo)
+			repo.On("RevokeTokenByHash", mock.Anything, mock.Anything).Return(nil)
+			repo.On("NewDevice", mock.Anything, mock.Anything).Return(domain.Device{ID: -9, UserID: "u1"}, nil)
+			repo.On("SaveRefreshToken", mock.Anything, mock.Anything).Return(nil)
+			tokener := &mocks_tokener.Tokener{}
+			tokener.On("VerifyRefresh", mock.Anything).Return(legacyMeta, nil)
+			tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+			tokenHasher := &mocks_tokenhasher.TokenHasher{}
+			tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+
+			cfg := baseCfg()
+			cfg.DevicesGraceUntil = graceUntil
+			s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, cfg)
+			device, _, err := s.RegisterDevice(ctx, "old", legacy, "ios", "")
+			if name == "grace" && (err != nil || device.ID != -9) {
+				t.Fatalf("%s: unexpected result: %+v, %v", name, device, err)
+			}
+			if name != "grace" && !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("%s: expected wrapped domain.ErrValidation; got: %v", name, err)
+			}
+			// legacy id в реестре не ищется: там только выданные сервером
+			repo.AssertNotCalled(t, "TouchDevice", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("legacy client-chosen device: logout works after grace period", func(t *testing.T) {
+		legacy := domain.NewDeviceCtx(3, 7)
+		legacyMeta := domain.NewRefreshMeta(time.Hour, "u1", legacy.AppId, legacy.DeviceID)
+		legacyMeta.SetFamily("fam-3")
+
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		repo.On("RevokeTokenByHash", mock.Anything, "h").Return(nil)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("VerifyRefresh", mock.Anything).Return(legacyMeta, nil)
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+
+		cfg := baseCfg()
+		cfg.DevicesGraceUntil = time.Now().Add(-time.Hour)
+		s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, cfg)
+		if err := s.Logout(ctx, "old", legacy); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "TouchDevice", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("issued ids never overlap legacy ones", func(t *testing.T) {
+		for range 1000 {
+			id, err := newDeviceID()
+			if err != nil || !domain.IssuedDevice(id) {
+				t.Fatalf("unexpected id: %d, %v", id, err)
+			}
+		}
+	})
+}
+
+func TestFederation_AllCases(t *testing.T) {
+	ctx := context.Background()
+	dctx := domain.NewDeviceCtx(1, domain.UnregisteredDevice)
+	verifiedAt := time.Now()
+	stored := domain.User{ID: "u1", Email: "alice@example.com", EmailVerifiedAt: &verifiedAt}
+	ident := domain.ExternalIdentity{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true}
+
+	fedCfg := func() *configs.BussinesLogic {
+		cfg := baseCfg()
+		cfg.FederationStateTTL = 10 * time.Minute
+		cfg.MfaChallengeTTL = 5 * time.Minute
+		return cfg
+	}
+	// pending — в redis лежит state, выданный для corp и dctx
+	pending := func(repo *mocks_repo.Repository) {
+		repo.On("ConsumeFederationState", mock.Anything, "h-state").Return(domain.FederationState{
+			Hash: "h-state", Provider: "corp", CodeVerifier: "verifier", Nonce: "nonce", Ctx: dctx,
+		}, nil)
+	}
+	stateHasher := func() *mocks_tokenhasher.TokenHasher {
+		th := &mocks_tokenhasher.TokenHasher{}
+		th.On("Sum", []byte("state")).Return([]byte("h-state"), nil).Maybe()
+		th.On("Sum", mock.Anything).Return([]byte("h"), nil).Maybe()
+		return th
+	}
+	exchanging := func(id domain.ExternalIdentity) (*mocks_idp.ExternalIdentityProvider, map[string]ExternalIdentityProvider) {
+		idp := &mocks_idp.ExternalIdentityProvider{}
+		idp.On("Exchange", mock.Anything, "code", "verifier", "nonce").Return(id, nil)
+		return idp, map[string]ExternalIdentityProvider{"corp": idp}
+	}
+	sessionOpened := func(repo *mocks_repo.Repository) *mocks_tokener.Tokener {
+		noRoles(repo)
+		repo.On("SaveRefreshToken", mock.Anything, mock.MatchedBy(func(rt domain.RefreshToken) bool {
+			return rt.Meta.UserID == "u1" && rt.Meta.Ctx.Compare(dctx)
+		})).Return(nil)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+		return tokener
+	}
+
+	t.Run("begin: state saved, url built with pkce challenge", func(t *testing.T) {
+		var saved domain.FederationState
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		repo.On("SaveFederationState", mock.Anything, mock.MatchedBy(func(st domain.FederationState) bool {
+			saved = st
+			return st.Provider == "corp" && st.Ctx.Compare(dctx) && st.CodeVerifier != "" && st.Nonce != "" &&
+				time.Until(st.Exp) > 9*time.Minute
+		})).Return(nil)
+
+		tokenHasher := &mocks_tokenhasher.TokenHasher{}
+		tokenHasher.On("Sum", mock.Anything).Return(func(b []byte) ([]byte, error) {
+			return append([]byte("h-"), b...), nil
+		})
+		idp := &mocks_idp.ExternalIdentityProvider{}
+		idp.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(func(_ context.Context, state, nonce, challenge string) (string, error) {
+				sum := sha256.Sum256([]byte(saved.CodeVerifier))
+				if nonce != saved.Nonce || challenge != base64.RawURLEncoding.EncodeToString(sum[:]) {
+					return "", errors.New("nonce or challenge mismatch")
+				}
+				return "https://idp.example.com/authorize?state=" + state, nil
+			})
+
+		s := New(repo, nil, nil, nil, nil, tokenHasher, nil, nil, nil, map[string]ExternalIdentityProvider{"corp": idp}, nil, fedCfg())
+		res, err := s.BeginFederatedLogin(ctx, "corp", dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if res.State == "" || res.AuthURL != "https://idp.example.com/authorize?state="+res.State {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+		if saved.Hash != "h-"+res.State {
+			t.Fatalf("state must be stored hashed: %q", saved.Hash)
+		}
+	})
+
+	t.Run("begin: unknown provider", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, fedCfg())
+		if _, err := s.BeginFederatedLogin(ctx, "corp", dctx); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected wrapped domain.ErrNotFound; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "SaveFederationState", mock.Anything, mock.Anything)
+	})
+
+	t.Run("begin: app without federated grant", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		withApps(repo, domain.App{ID: 1, Status: domain.AppActive, GrantTypes: []string{domain.GrantPassword}})
+		_, idps := exchanging(ident)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, idps, nil, fedCfg())
+		if _, err := s.BeginFederatedLogin(ctx, "corp", dctx); !errors.Is(err, domain.ErrAppAccessDenied) {
+			t.Fatalf("expected wrapped domain.ErrAppAccessDenied; got: %v", err)
+		}
+	})
+
+	t.Run("complete: linked identity gets tokens", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		pending(repo)
+		repo.On("GetIdentity", mock.Anything, "corp", "sub-1").Return(domain.Identity{Provider: "corp", Subject: "sub-1", UserID: "u1"}, nil)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(domain.UserMfa{}, domain.ErrNotFound)
+		tokener := sessionOpened(repo)
+		// email у IdP сменился — привязка всё равно по (provider, sub)
+		_, idps := exchanging(domain.ExternalIdentity{Subject: "sub-1", Email: "new@else.where"})
+
+		s := New(repo, nil, nil, nil, tokener, stateHasher(), nil, nil, nil, idps, nil, fedCfg())
+		res, err := s.CompleteFederatedLogin(ctx, "corp", "state", "code", dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if res.MfaRequired() || res.Access != "acc" || res.Refresh != "ref" {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+		repo.AssertNotCalled(t, "GetUserInfoByEmail", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "NewIdentity", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: first login links by verified email", func(t *testing.T) {
+		unverified := stored
+		unverified.EmailVerifiedAt = nil
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		pending(repo)
+		repo.On("GetIdentity", mock.Anything, "corp", "sub-1").Return(domain.Identity{}, domain.ErrNotFound)
+		repo.On("GetUserInfoByEmail", mock.Anything, "alice@example.com").Return(unverified, nil)
+		repo.On("NewIdentity", mock.Anything, domain.Identity{
+			Provider: "corp", Subject: "sub-1", UserID: "u1", Email: "alice@example.com",
+		}).Return(nil)
+		repo.On("MarkEmailVerified", mock.Anything, "u1").Return(nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(domain.UserMfa{}, domain.ErrNotFound)
+		tokener := sessionOpened(repo)
+		_, idps := exchanging(ident)
+
+		s := New(repo, nil, nil, nil, tokener, stateHasher(), nil, nil, nil, idps, nil, fedCfg())
+		if _, err := s.CompleteFederatedLogin(ctx, "corp", "state", "code", dctx); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("complete: concurrent link resolved by stored identity", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		pending(repo)
+		repo.On("GetIdentity", mock.Anything, "corp", "sub-1").Return(domain.Identity{}, domain.ErrNotFound).Once()
+		repo.On("GetIdentity", mock.Anything, "corp", "sub-1").Return(domain.Identity{UserID: "u1"}, nil).Once()
+		repo.On("GetUserInfoByEmail", mock.Anything, "alice@example.com").Return(stored, nil)
+		repo.On("NewIdentity", mock.Anything, mock.Anything).Return(domain.ErrDuplicate)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(domain.UserMfa{}, domain.ErrNotFound)
+		tokener := sessionOpened(repo)
+		_, idps := exchanging(ident)
+
+		s := New(repo, nil, nil, nil, tokener, stateHasher(), nil, nil, nil, idps, nil, fedCfg())
+		if _, err := s.CompleteFederatedLogin(ctx, "corp", "state", "code", dctx); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertNotCalled(t, "MarkEmailVerified", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: email not verified by idp — not linked", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		pending(repo)
+		repo.On("GetIdentity", mock.Anything, "corp", "sub-1").Return(domain.Identity{}, domain.ErrNotFound)
+		_, idps := exchanging(domain.ExternalIdentity{Subject: "sub-1", Email: "alice@example.com"})
+
+		s := New(repo, nil, nil, nil, nil, stateHasher(), nil, nil, nil, idps, nil, fedCfg())
+		if _, err := s.CompleteFederatedLogin(ctx, "corp", "state", "code", dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "GetUserInfoByEmail", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "NewIdentity", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: no local account", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		pending(repo)
+		repo.On("GetIdentity", mock.Anything, "corp", "sub-1").Return(domain.Identity{}, domain.ErrNotFound)
+		repo.On("GetUserInfoByEmail", mock.Anything, "alice@example.com").Return(domain.User{}, domain.ErrNotFound)
+		_, idps := exchanging(ident)
+
+		s := New(repo, nil, nil, nil, nil, stateHasher(), nil, nil, nil, idps, nil, fedCfg())
+		if _, err := s.CompleteFederatedLogin(ctx, "corp", "state", "code", dctx); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected wrapped domain.ErrNotFound; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "NewUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: mfa still required", func(t *testing.T) {
+		confirmed := time.Now()
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		pending(repo)
+		repo.On("GetIdentity", mock.Anything, "corp", "sub-1").Return(domain.Identity{UserID: "u1"}, nil)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(domain.UserMfa{UserID: "u1", ConfirmedAt: &confirmed}, nil)
+		repo.On("SaveOneTimeToken", mock.Anything, mock.MatchedBy(func(ott domain.OneTimeToken) bool {
+			return ott.Purpose == domain.PurposeMfaChallenge && ott.UserID == "u1"
+		})).Return(nil)
+		_, idps := exchanging(ident)
+
+		s := New(repo, nil, nil, nil, nil, stateHasher(), nil, nil, nil, idps, nil, fedCfg())
+		res, err := s.CompleteFederatedLogin(ctx, "corp", "state", "code", dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if !res.MfaRequired() || res.Access != "" {
+			t.Fatalf("expected challenge only, got %+v", res)
+		}
+		repo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: app email domains apply", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		withApps(repo, domain.App{ID: 1, Status: domain.AppActive, GrantTypes: domain.AppGrantTypes, EmailDomains: []string{"corp.example.com"}})
+		pending(repo)
+		repo.On("GetIdentity", mock.Anything, "corp", "sub-1").Return(domain.Identity{UserID: "u1"}, nil)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		_, idps := exchanging(ident)
+
+		s := New(repo, nil, nil, nil, nil, stateHasher(), nil, nil, nil, idps, nil, fedCfg())
+		if _, err := s.CompleteFederatedLogin(ctx, "corp", "state", "code", dctx); !errors.Is(err, domain.ErrAppAccessDenied) {
+			t.Fatalf("expected wrapped domain.ErrAppAccessDenied; got: %v", err)
+		}
+	})
+
+	t.Run("complete: state consumed, for other provider or device", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			provider string
+			dctx     domain.DeviceCtx
+			consumed bool
+		}{
+			"consumed":       {provider: "corp", dctx: dctx, consumed: true},
+			"other provider": {provider: "google", dctx: dctx},
+			"other device":   {provider: "corp", dctx: domain.NewDeviceCtx(1, 7)},
+		} {
+			repo := &mocks_repo.Repository{}
+			anyApp(repo)
+			if tc.consumed {
+				repo.On("ConsumeFederationState", mock.Anything, "h-state").Return(domain.FederationState{}, domain.ErrNotFound)
+			} else {
+				pending(repo)
+			}
+			idp, idps := exchanging(ident)
+			idps["google"] = idp
+
+			s := New(repo, nil, nil, nil, nil, stateHasher(), nil, nil, nil, idps, nil, fedCfg())
+			if _, err := s.CompleteFederatedLogin(ctx, tc.provider, "state", "code", tc.dctx); !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("%s: expected wrapped domain.ErrValidation; got: %v", name, err)
+			}
+			idp.AssertNotCalled(t, "Exchange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("complete: idp rejected code", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		pending(repo)
+		idp := &mocks_idp.ExternalIdentityProvider{}
+		idp.On("Exchange", mock.Anything, "code", "verifier", "nonce").Return(domain.ExternalIdentity{}, domain.ErrValidation)
+
+		s := New(repo, nil, nil, nil, nil, stateHasher(), nil, nil, nil, map[string]ExternalIdentityProvider{"corp": idp}, nil, fedCfg())
+		if _, err := s.CompleteFederatedLogin(ctx, "corp", "state", "code", dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "GetIdentity", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestDirectoryLogin_AllCases(t *testing.T) {
+	ctx := context.Background()
+	dctx := domain.NewDeviceCtx(1, -1)
+	creds := domain.User{Email: "alice@corp.example", Password: "dir-pass"}
+	du := domain.DirectoryUser{DN: "uid=alice,dc=corp", Subject: "guid-1", Roles: []string{"developer"}}
+	managed := []string{"admin", "developer"}
+
+	directory := func(du domain.DirectoryUser, err error) *mocks_directory.DirectoryAuthenticator {
+		dir := &mocks_directory.DirectoryAuthenticator{}
+		dir.On("Authenticate", mock.Anything, creds.Email, creds.Password).Return(du, err)
+		dir.On("ManagedRoles").Return(managed).Maybe()
+		return dir
+	}
+	// sessionOpened — вход завершён выдачей токенов; пустой userID — id ещё не известен (JIT)
+	sessionOpened := func(repo *mocks_repo.Repository, userID string) *mocks_tokener.Tokener {
+		noRoles(repo)
+		repo.On("GetUserMfa", mock.Anything, mock.Anything).Return(domain.UserMfa{}, domain.ErrNotFound)
+		repo.On("SaveRefreshToken", mock.Anything, mock.MatchedBy(func(rt domain.RefreshToken) bool {
+			return rt.Meta.UserID != "" && (userID == "" || rt.Meta.UserID == userID)
+		})).Return(nil)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+		return tokener
+	}
+	tokenHasher := func() *mocks_tokenhasher.TokenHasher {
+		th := &mocks_tokenhasher.TokenHasher{}
+		th.On("Sum", mock.Anything).Return([]byte("h"), nil)
+		return th
+	}
+
+	t.Run("first login: user provisioned without password, linked, verified, roles synced", func(t *testing.T) {
+		var created domain.User
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, creds.Email).Return(domain.User{}, domain.ErrNotFound)
+		repo.On("GetIdentity", mock.Anything, domain.DirectoryProvider, "guid-1").Return(domain.Identity{}, domain.ErrNotFound)
+		repo.On("NewUser", mock.Anything, mock.MatchedBy(func(u domain.User) bool {
+			created = u
+			return u.ID != "" && u.Email == creds.Email && u.Password == ""
+		})).Return(func(_ context.Context, u domain.User) (domain.User, error) { return u, nil })
+		repo.On("NewIdentity", mock.Anything, mock.MatchedBy(func(id domain.Identity) bool {
+			return id.Provider == domain.DirectoryProvider && id.Subject == "guid-1" && id.UserID == created.ID
+		})).Return(nil)
+		repo.On("MarkEmailVerified", mock.Anything, mock.Anything).Return(nil)
+		repo.On("SyncUserRoles", mock.Anything, mock.Anything, managed, []string{"developer"}).Return(nil)
+		tokener := sessionOpened(repo, "")
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher(), nil, nil, nil, nil, directory(du, nil), baseCfg())
+		res, err := s.Login(ctx, creds, dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if res.Access != "acc" || res.Refresh != "ref" {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+		repo.AssertCalled(t, "MarkEmailVerified", mock.Anything, created.ID)
+		repo.AssertCalled(t, "SyncUserRoles", mock.Anything, created.ID, managed, []string{"developer"})
+		repo.AssertNotCalled(t, "RegisterLoginFailure", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("linked identity: user found by directory id, email change does not matter", func(t *testing.T) {
+		verifiedAt := time.Now()
+		linked := domain.User{ID: "u1", Email: "old@corp.example", EmailVerifiedAt: &verifiedAt}
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, creds.Email).Return(domain.User{}, domain.ErrNotFound)
+		repo.On("GetIdentity", mock.Anything, domain.DirectoryProvider, "guid-1").Return(domain.Identity{UserID: "u1"}, nil)
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(linked, nil)
+		repo.On("SyncUserRoles", mock.Anything, "u1", managed, []string{"developer"}).Return(nil)
+		tokener := sessionOpened(repo, "u1")
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher(), nil, nil, nil, nil, directory(du, nil), baseCfg())
+		if _, err := s.Login(ctx, creds, dctx); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertNotCalled(t, "NewUser", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "MarkEmailVerified", mock.Anything, mock.Anything)
+	})
+
+	t.Run("existing user without password is linked on first directory login", func(t *testing.T) {
+		existing := domain.User{ID: "u2", Email: creds.Email}
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, creds.Email).Return(existing, nil)
+		repo.On("GetIdentity", mock.Anything, domain.DirectoryProvider, "guid-1").Return(domain.Identity{}, domain.ErrNotFound)
+		repo.On("NewIdentity", mock.Anything, mock.MatchedBy(func(id domain.Identity) bool { return id.UserID == "u2" })).Return(nil)
+		repo.On("MarkEmailVerified", mock.Anything, "u2").Return(nil)
+		repo.On("SyncUserRoles", mock.Anything, "u2", managed, []string{"developer"}).Return(nil)
+		tokener := sessionOpened(repo, "u2")
+
+		s := New(repo, nil, nil, nil, tokener, tokenHasher(), nil, nil, nil, nil, directory(du, nil), baseCfg())
+		if _, err := s.Login(ctx, creds, dctx); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertNotCalled(t, "NewUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("local password wins: directory is not asked", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, creds.Email).Return(domain.User{ID: "u3", Email: creds.Email, Password: "stored-hash"}, nil)
+		repo.On("RegisterLoginFailure", mock.Anything, mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+		hasher := &mocks_hasher.PasswordHasher{}
+		hasher.On("Compare", mock.Anything, mock.Anything).Return(false, nil)
+		dir := &mocks_directory.DirectoryAuthenticator{}
+
+		s := New(repo, hasher, nil, nil, nil, nil, nil, nil, nil, nil, dir, baseCfg())
+		if _, err := s.Login(ctx, creds, dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		dir.AssertNotCalled(t, "Authenticate", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejected by directory counts as login failure", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, creds.Email).Return(domain.User{}, domain.ErrNotFound)
+		repo.On("RegisterLoginFailure", mock.Anything, "email:"+creds.Email, mock.Anything).Return(time.Duration(0), nil)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, directory(domain.DirectoryUser{}, domain.ErrValidation), baseCfg())
+		if _, err := s.Login(ctx, creds, dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "NewUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("directory unavailable is internal, not a login failure", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, creds.Email).Return(domain.User{}, domain.ErrNotFound)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, directory(domain.DirectoryUser{}, errors.New("dial tcp: refused")), baseCfg())
+		_, err := s.Login(ctx, creds, dctx)
+		if err == nil || errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected internal error; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "RegisterLoginFailure", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("email taken by local account concurrently: not captured", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		anyDevice(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, creds.Email).Return(domain.User{}, domain.ErrNotFound).Once()
+		repo.On("GetIdentity", mock.Anything, domain.DirectoryProvider, "guid-1").Return(domain.Identity{}, domain.ErrNotFound)
+		repo.On("NewUser", mock.Anything, mock.Anything).Return(domain.User{}, domain.ErrDuplicate)
+		repo.On("GetUserInfoByEmail", mock.Anything, creds.Email).Return(domain.User{ID: "u4", Password: "stored-hash"}, nil)
+
+		s := New(repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, directory(du, nil), baseCfg())
+		if _, err := s.Login(ctx, creds, dctx); !errors.Is(err, domain.ErrDuplicate) {
+			t.Fatalf("expected wrapped domain.ErrDuplicate; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "NewIdentity", mock.Anything, mock.Anything)
+	})
+
+	t.Run("password reset: directory user gets no mail and cannot confirm", func(t *testing.T) {
+		dirUser := domain.User{ID: "u5", Email: creds.Email}
+		repo := &mocks_repo.Repository{}
+		repo.On("GetUserInfoByEmail", mock.Anything, creds.Email).Return(dirUser, nil)
+		notifier := &mocks_notifier.Notifier{}
+
+		s := New(repo, nil, nil, nil, nil, nil, notifier, nil, nil, nil, nil, baseCfg())
+		if err := s.RequestPasswordReset(ctx, creds.Email); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		repo.AssertNotCalled(t, "SaveOneTimeToken", mock.Anything, mock.Anything)
+		notifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+
+		// токен, выданный до перевода пользователя в каталог
+		repo.On("ConsumeOneTimeToken", mock.Anything, domain.PurposePasswordReset, "h").Return(domain.OneTimeToken{UserID: "u5"}, nil)
+		repo.On("GetUserInfoByID", mock.Anything, "u5").Return(dirUser, nil)
+		s = New(repo, nil, nil, nil, nil, tokenHasher(), nil, nil, nil, nil, nil, baseCfg())
+		if err := s.ConfirmPasswordReset(ctx, "tok", "new-pass", 1); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "UpdateUserPassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPasswordless_AllCases(t *testing.T) {
+	ctx := context.Background()
+	dctx := domain.NewDeviceCtx(1, domain.UnregisteredDevice)
+	verifiedAt := time.Now()
+	confirmedAt := time.Now()
+	stored := domain.User{ID: "u1", Email: "alice@example.com", Password: "stored-hash", EmailVerifiedAt: &verifiedAt}
+	directoryUser := domain.User{ID: "u1", Email: "alice@example.com", EmailVerifiedAt: &verifiedAt}
+
+	pwlCfg := func() *configs.BussinesLogic {
+		cfg := baseCfg()
+		cfg.PasswordlessTTL = 10 * time.Minute
+		cfg.PasswordlessMaxAttempts = 5
+		cfg.MfaChallengeTTL = 5 * time.Minute
+		return cfg
+	}
+	// prefixHasher — хэш виден в тесте: h-<значение>
+	prefixHasher := func() *mocks_tokenhasher.TokenHasher {
+		th := &mocks_tokenhasher.TokenHasher{}
+		th.On("Sum", mock.Anything).Return(func(b []byte) ([]byte, error) {
+			return append([]byte("h-"), b...), nil
+		})
+		return th
+	}
+	// pending — в redis лежит challenge, выданный u1 для dctx
+	pending := func(repo *mocks_repo.Repository, codeHash string) {
+		repo.On("ConsumePasswordlessChallenge", mock.Anything, "h-ch", codeHash).Return(domain.PasswordlessChallenge{
+			Hash: "h-ch", UserID: "u1", Email: "alice@example.com", Ctx: dctx, Exp: time.Now().Add(time.Minute),
+		}, nil)
+	}
+	sessionOpened := func(repo *mocks_repo.Repository) *mocks_tokener.Tokener {
+		noRoles(repo)
+		repo.On("SaveRefreshToken", mock.Anything, mock.MatchedBy(func(rt domain.RefreshToken) bool {
+			return rt.Meta.UserID == "u1" && rt.Meta.Ctx.Compare(dctx)
+		})).Return(nil)
+		tokener := &mocks_tokener.Tokener{}
+		tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+		return tokener
+	}
+
+	t.Run("start link: token mailed, stored hashed, no challenge in response", func(t *testing.T) {
+		var saved domain.PasswordlessChallenge
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, "alice@example.com").Return(stored, nil)
+		repo.On("SavePasswordlessChallenge", mock.Anything, mock.MatchedBy(func(c domain.PasswordlessChallenge) bool {
+			saved = c
+			return c.UserID == "u1" && c.CodeHash == "" && c.Attempts == 5 && c.Ctx.Compare(dctx) &&
+				time.Until(c.Exp) > 9*time.Minute
+		})).Return(nil)
+		var sent domain.Notification
+		notifier := &mocks_notifier.Notifier{}
+		notifier.On("Notify", mock.Anything, mock.MatchedBy(func(n domain.Notification) bool {
+			sent = n
+			return n.Purpose == domain.PurposePasswordlessLink && n.To == "alice@example.com"
+		})).Return(nil)
+
+		s := New(repo, nil, nil, nil, nil, prefixHasher(), notifier, nil, nil, nil, nil, pwlCfg())
+		res, err := s.StartPasswordlessLogin(ctx, "alice@example.com", domain.PasswordlessLink, dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if res.Challenge != "" || time.Until(res.ExpiresAt) < 9*time.Minute {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+		if sent.Token == "" || saved.Hash != "h-"+sent.Token {
+			t.Fatalf("link token must be stored hashed: %q / %q", saved.Hash, sent.Token)
+		}
+	})
+
+	t.Run("start code: 6 digits mailed, code stored hashed, challenge returned", func(t *testing.T) {
+		var saved domain.PasswordlessChallenge
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, "alice@example.com").Return(stored, nil)
+		repo.On("SavePasswordlessChallenge", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(1).(domain.PasswordlessChallenge)
+		}).Return(nil)
+		var sent domain.Notification
+		notifier := &mocks_notifier.Notifier{}
+		notifier.On("Notify", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			sent = args.Get(1).(domain.Notification)
+		}).Return(nil)
+
+		s := New(repo, nil, nil, nil, nil, prefixHasher(), notifier, nil, nil, nil, nil, pwlCfg())
+		res, err := s.StartPasswordlessLogin(ctx, "alice@example.com", domain.PasswordlessCode, dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if sent.Purpose != domain.PurposePasswordlessCode || len(sent.Token) != 6 || strings.Trim(sent.Token, "0123456789") != "" {
+			t.Fatalf("expected 6-digit code notification; got: %+v", sent)
+		}
+		if res.Challenge == "" || saved.Hash != "h-"+res.Challenge || saved.CodeHash != "h-"+sent.Token {
+			t.Fatalf("challenge and code must be stored hashed: %+v / %+v", saved, res)
+		}
+	})
+
+	t.Run("start: unknown email answers the same without mail", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, mock.Anything).Return(domain.User{}, domain.ErrNotFound)
+		notifier := &mocks_notifier.Notifier{}
+
+		s := New(repo, nil, nil, nil, nil, prefixHasher(), notifier, nil, nil, nil, nil, pwlCfg())
+		res, err := s.StartPasswordlessLogin(ctx, "ghost@example.com", domain.PasswordlessCode, dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if res.Challenge == "" {
+			t.Fatalf("response must not reveal missing account: %+v", res)
+		}
+		repo.AssertNotCalled(t, "SavePasswordlessChallenge", mock.Anything, mock.Anything)
+		notifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+	})
+
+	t.Run("start: directory user answers the same without mail", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		noLockout(repo)
+		repo.On("GetUserInfoByEmail", mock.Anything, "alice@example.com").Return(directoryUser, nil)
+		notifier := &mocks_notifier.Notifier{}
+
+		s := New(repo, nil, nil, nil, nil, prefixHasher(), notifier, nil, nil, nil, nil, pwlCfg())
+		res, err := s.StartPasswordlessLogin(ctx, "alice@example.com", domain.PasswordlessCode, dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if res.Challenge == "" {
+			t.Fatalf("response must not differ from a local account: %+v", res)
+		}
+		repo.AssertNotCalled(t, "SavePasswordlessChallenge", mock.Anything, mock.Anything)
+		notifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+	})
+
+	t.Run("start: rejected before any lookup", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			app    domain.App
+			method domain.PasswordlessMethod
+			locked time.Duration
+			want   error
+		}{
+			"grant missing": {domain.App{ID: 1, Status: domain.AppActive, GrantTypes: []string{domain.GrantPassword}}, domain.PasswordlessLink, 0, domain.ErrAppAccessDenied},
+			"bad method":    {domain.App{ID: 1, Status: domain.AppActive, GrantTypes: domain.AppGrantTypes}, "sms", 0, domain.ErrValidation},
+			"email domain":  {domain.App{ID: 1, Status: domain.AppActive, GrantTypes: domain.AppGrantTypes, EmailDomains: []string{"corp.example"}}, domain.PasswordlessLink, 0, domain.ErrAppAccessDenied},
+		} {
+			repo := &mocks_repo.Repository{}
+			withApps(repo, tc.app)
+			repo.On("LoginLockedFor", mock.Anything, mock.Anything).Return(tc.locked, nil).Maybe()
+
+			s := New(repo, nil, nil, nil, nil, prefixHasher(), nil, nil, nil, nil, nil, pwlCfg())
+			if _, err := s.StartPasswordlessLogin(ctx, "alice@example.com", tc.method, dctx); !errors.Is(err, tc.want) {
+				t.Fatalf("%s: expected wrapped %v; got: %v", name, tc.want, err)
+			}
+			repo.AssertNotCalled(t, "GetUserInfoByEmail", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("start: locked email gets no new mail", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		repo.On("LoginLockedFor", mock.Anything, mock.Anything).Return(time.Minute, nil)
+
+		s := New(repo, nil, nil, nil, nil, prefixHasher(), nil, nil, nil, nil, nil, pwlCfg())
+		_, err := s.StartPasswordlessLogin(ctx, "alice@example.com", domain.PasswordlessCode, dctx)
+		var lockErr *domain.LockoutError
+		if !errors.As(err, &lockErr) || lockErr.RetryAfter != time.Minute {
+			t.Fatalf("expected LockoutError; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "GetUserInfoByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete link: tokens, email marked verified", func(t *testing.T) {
+		unverified := stored
+		unverified.EmailVerifiedAt = nil
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		pending(repo, "")
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(unverified, nil)
+		repo.On("ResetLoginFailures", mock.Anything, []string{emailAttemptKey("alice@example.com")}).Return(nil)
+		repo.On("MarkEmailVerified", mock.Anything, "u1").Return(nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(domain.UserMfa{}, domain.ErrNotFound)
+		tokener := sessionOpened(repo)
+
+		s := New(repo, nil, nil, nil, tokener, prefixHasher(), nil, nil, nil, nil, nil, pwlCfg())
+		res, err := s.CompletePasswordlessLogin(ctx, "ch", "", dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if res.MfaRequired() || res.Access != "acc" || res.Refresh != "ref" {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("complete code: mfa enabled -> challenge instead of tokens", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		noLockout(repo)
+		pending(repo, "h-012345")
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(stored, nil)
+		repo.On("GetUserMfa", mock.Anything, "u1").Return(domain.UserMfa{UserID: "u1", EncryptedSecret: []byte("sealed"), ConfirmedAt: &confirmedAt}, nil)
+		repo.On("SaveOneTimeToken", mock.Anything, mock.MatchedBy(func(ott domain.OneTimeToken) bool {
+			return ott.Purpose == domain.PurposeMfaChallenge
+		})).Return(nil)
+
+		s := New(repo, nil, nil, nil, nil, prefixHasher(), nil, nil, nil, nil, nil, pwlCfg())
+		res, err := s.CompletePasswordlessLogin(ctx, "ch", "012345", dctx)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if !res.MfaRequired() {
+			t.Fatalf("expected mfa challenge; got: %+v", res)
+		}
+		repo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: wrong code counts as failed login", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		repo.On("ConsumePasswordlessChallenge", mock.Anything, "h-ch", "h-000000").
+			Return(domain.PasswordlessChallenge{Email: "alice@example.com", Attempts: 4}, domain.ErrValidation)
+		repo.On("RegisterLoginFailure", mock.Anything, emailAttemptKey("alice@example.com"), mock.Anything).Return(time.Duration(0), nil)
+
+		s := New(repo, nil, nil, nil, nil, prefixHasher(), nil, nil, nil, nil, nil, pwlCfg())
+		if _, err := s.CompletePasswordlessLogin(ctx, "ch", "000000", dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "GetUserInfoByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: unknown, used or expired challenge", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		repo.On("ConsumePasswordlessChallenge", mock.Anything, mock.Anything, mock.Anything).
+			Return(domain.PasswordlessChallenge{}, domain.ErrNotFound)
+
+		s := New(repo, nil, nil, nil, nil, prefixHasher(), nil, nil, nil, nil, nil, pwlCfg())
+		if _, err := s.CompletePasswordlessLogin(ctx, "ch", "", dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "RegisterLoginFailure", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: directory user gets no session", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		pending(repo, "")
+		repo.On("GetUserInfoByID", mock.Anything, "u1").Return(directoryUser, nil)
+
+		s := New(repo, nil, nil, nil, nil, prefixHasher(), nil, nil, nil, nil, nil, pwlCfg())
+		if _, err := s.CompletePasswordlessLogin(ctx, "ch", "", dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "ResetLoginFailures", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete: other device", func(t *testing.T) {
+		repo := &mocks_repo.Repository{}
+		anyApp(repo)
+		pending(repo, "")
+
+		s := New(repo, nil, nil, nil, nil, prefixHasher(), nil, nil, nil, nil, nil, pwlCfg())
+		if _, err := s.CompletePasswordlessLogin(ctx, "ch", "", domain.NewDeviceCtx(2, domain.UnregisteredDevice)); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrapped domain.ErrValidation; got: %v", err)
+		}
+		repo.AssertNotCalled(t, "GetUserInfoByID", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_internalSanity(t *testing.T) {
+	// simple sanity: genTokensFlow + verificationToken round-ish checks
+	ctx := context.Background()
+	userDctx := domain.NewDeviceCtx(int32(2), int32(-3))
+	tokener := &mocks_tokener.Tokener{}
+	tokener.On("GenPair", mock.Anything, mock.Anything).Return([]byte("acc"), []byte("ref"), nil)
+	tokener.On("VerifyRefresh", mock.Anything).Return(domain.NewRefreshMeta(time.Minute, "u1", userDctx.AppId, userDctx.DeviceID), nil)
+
+	tokenHasher := &mocks_tokenhasher.TokenHasher{}
+	tokenHasher.On("Sum", mock.Anything).Return([]byte("h"), nil)
+
+	repo := &mocks_repo.Repository{}
+	noRoles(repo)
+
+	s := New(repo, nil, nil, nil, tokener, tokenHasher, nil, nil, nil, nil, nil, baseCfg())
+
+	tok, rt, err := s.genTokensFlow(ctx, domain.App{}, "u1", "fam", userDctx, nil)
+	if err != nil {
+		t.Fatalf("genTokensFlow err: %v", err)
+	}
+	if reflect.DeepEqual(tok, (*domain.Token)(nil)) || reflect.DeepEqual(rt, (*domain.RefreshToken)(nil)) {
+		t.Fatalf("unexpected nil results")
+	}
+	// access-токен пары привязан к refresh: по нему отзыв сессии заполняет denylist
+	if rt.AccessID == "" || rt.AccessExp.IsZero() {
+		t.Fatalf("access token not bound to refresh: %+v", rt)
 	}
 
 	// verificationToken will use tokener.VerifyRefresh mocked above
-	_, err = s.verificationToken("some", userDctx)
+	_, err = s.verificationToken(ctx, "some", userDctx)
 	if err != nil {
 		t.Fatalf("verificationToken unexpected err: %v", err)
 	}
@@ -1,21 +1,43 @@
 package authservice
 
-import "github.com/eragon-mdi/sso/internal/common/configs"
+import (
+	"sync"
+
+	"github.com/eragon-mdi/sso/internal/common/configs"
+)
 
 type Auth struct {
-	r           Repository
-	passHasher  PasswordHasher
-	tokener     Tokener
-	tokenHasher TokenHasher
-	cfg         *configs.BussinesLogic
+	r            Repository
+	passHasher   PasswordHasher
+	passPolicy   PasswordPolicy
+	breach       BreachChecker
+	tokener      Tokener
+	tokenHasher  TokenHasher
+	notifier     Notifier
+	totp         Totp
+	secretCipher SecretCipher
+	idps         map[string]ExternalIdentityProvider
+	directory    DirectoryAuthenticator
+	cfg          *configs.BussinesLogic
+
+	// хэш-заглушка для неизвестных email: считается лениво текущим хэшером
+	dummyOnce sync.Once
+	dummyHash []byte
 }
 
-func New(r Repository, ph PasswordHasher, t Tokener, th TokenHasher, c *configs.BussinesLogic) *Auth {
+func New(r Repository, ph PasswordHasher, pp PasswordPolicy, bc BreachChecker, t Tokener, th TokenHasher, n Notifier, tp Totp, sc SecretCipher, idps map[string]ExternalIdentityProvider, dir DirectoryAuthenticator, c *configs.BussinesLogic) *Auth {
 	return &Auth{
-		r:           r,
-		passHasher:  ph,
-		tokener:     t,
-		tokenHasher: th,
-		cfg:         c,
+		r:            r,
+		passHasher:   ph,
+		passPolicy:   pp,
+		breach:       bc,
+		tokener:      t,
+		tokenHasher:  th,
+		notifier:     n,
+		totp:         tp,
+		secretCipher: sc,
+		idps:         idps,
+		directory:    dir,
+		cfg:          c,
 	}
 }
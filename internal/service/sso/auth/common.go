@@ -1,8 +1,14 @@
 package authservice
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
 	"github.com/eragon-mdi/sso/internal/domain"
 	"github.com/go-faster/errors"
+	"github.com/google/uuid"
 )
 
 const (
@@ -10,25 +16,113 @@ const (
 	ErrUnauthenticatedCtx = "unauthenticated: ctx mismatch"
 	ErrFailedGenJWT       = "failed generate jwt"
 	ErrFailedHashRefresh  = "failed hashing refresh token"
+	ErrFailedRandToken    = "failed read random bytes"
+	ErrFailedHashOneTime  = "failed hashing one-time token"
+	ErrFailedGenOneTime   = "failed generate one-time token"
+	ErrFailedSaveOneTime  = "failed save one-time token"
+	ErrFailedNotify       = "failed notify user"
+	ErrTotpCodeReused     = "totp code already used"
+	ErrFailedUseTotp      = "failed store used totp step"
+	ErrFailedCheckRefresh = "failed check refresh token is active"
+	ErrFailedCheckLegacy  = "failed check legacy refresh tokens revoked"
+	ErrFailedRevokeLegacy = "failed revoke legacy refresh tokens"
 )
 
-func (s *Auth) verificationToken(refresh string, userDctx domain.DeviceCtx) (domain.Meta, error) {
+const oneTimeTokenLen = 32
+
+func (s *Auth) verificationToken(ctx context.Context, refresh string, userDctx domain.DeviceCtx) (domain.Meta, error) {
+	// неверный токен или чужое устройство — ошибка клиента (ErrValidation), а не сбой
 	m, err := s.tokener.VerifyRefresh([]byte(refresh))
 	if err != nil {
-		return domain.Meta{}, errors.Wrap(err, ErrInvalidToken)
+		return domain.Meta{}, errors.Wrapf(domain.ErrValidation, "%s: %v", ErrInvalidToken, err)
 	}
 
 	if !userDctx.Compare(m.Ctx) {
-		return domain.Meta{}, errors.New(ErrUnauthenticatedCtx)
+		return domain.Meta{}, errors.Wrap(domain.ErrValidation, ErrUnauthenticatedCtx)
+	}
+
+	if m.Legacy() {
+		active, err := s.legacyActive(ctx, m)
+		if err != nil {
+			return domain.Meta{}, err
+		}
+		if !active {
+			return domain.Meta{}, errors.Wrap(domain.ErrValidation, ErrRefreshNotActive)
+		}
 	}
 
 	return m, nil
 }
 
-func (s *Auth) genTokensFlow(userId string, dctx domain.DeviceCtx) (*domain.Token, *domain.RefreshToken, error) {
-	m := domain.NewMeta(s.cfg.TokenTTL, userId, dctx.AppId, dctx.DeviceID)
+// legacyActive — refresh, выданный до семейств, принимается не дольше REFRESH_TOKEN_TTL
+// и не переживает LogoutAll и смену пароля: в индексе сессий его нет, поэтому отзыв помечается отдельно
+func (s *Auth) legacyActive(ctx context.Context, m domain.Meta) (bool, error) {
+	if m.Exp.After(time.Now().Add(s.cfg.RefreshTokenTTL)) {
+		return false, nil
+	}
+
+	revoked, err := s.r.LegacyTokensRevoked(ctx, m.UserID)
+	if err != nil {
+		return false, errors.Wrap(err, ErrFailedCheckLegacy)
+	}
+	return !revoked, nil
+}
+
+// revokeUserTokens — все сессии пользователя, кроме exceptFamilyID, и его legacy refresh-токены
+func (s *Auth) revokeUserTokens(ctx context.Context, userID, exceptFamilyID string) error {
+	if _, err := s.r.RevokeUserTokens(ctx, userID, exceptFamilyID); err != nil {
+		return errors.Wrap(err, ErrFailedRevokeAll)
+	}
+	if err := s.r.RevokeLegacyTokens(ctx, userID, s.cfg.RefreshTokenTTL); err != nil {
+		return errors.Wrap(err, ErrFailedRevokeLegacy)
+	}
+
+	return nil
+}
 
-	access, refresh, err := s.tokener.GenPair(m)
+// activeRefresh — verificationToken плюс проверка по хранилищу: подпись и ctx ничего не знают
+// об отзыве и ротации, а refresh используется как доказательство владения аккаунтом
+func (s *Auth) activeRefresh(ctx context.Context, refresh string, userDctx domain.DeviceCtx) (domain.Meta, error) {
+	m, err := s.verificationToken(ctx, refresh, userDctx)
+	if err != nil {
+		return domain.Meta{}, err
+	}
+
+	hash, err := s.tokenHasher.Sum([]byte(refresh))
+	if err != nil {
+		return domain.Meta{}, errors.Wrap(err, ErrFailedHashToken)
+	}
+	active, err := s.r.RefreshTokenExists(ctx, string(hash))
+	if err != nil {
+		return domain.Meta{}, errors.Wrap(err, ErrFailedCheckRefresh)
+	}
+	if !active {
+		return domain.Meta{}, errors.Wrap(domain.ErrValidation, ErrRefreshNotActive)
+	}
+
+	return m, nil
+}
+
+// familyID - семейство refresh-токенов: новое на login, наследуется при refresh
+// scopes попадают в оба токена: при Refresh они переносятся из refresh в новую пару;
+// TTL — из настроек приложения app, если заданы
+func (s *Auth) genTokensFlow(ctx context.Context, app domain.App, userId, familyID string, dctx domain.DeviceCtx, scopes []string) (*domain.Token, *domain.RefreshToken, error) {
+	// роли кладутся в access, чтобы resource-серверы проверяли доступ без запроса к SSO;
+	// актуальны на момент выдачи, обновляются при Refresh
+	roles, err := s.r.GetUserRoles(ctx, userId)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, ErrFailedGetRoles)
+	}
+
+	am := domain.NewAccessMeta(app.AccessTTL(s.cfg.AccessTokenTTL), userId, dctx.AppId, dctx.DeviceID)
+	am.SetFamily(familyID) // sid: по нему интроспекция узнаёт об отзыве сессии
+	am.SetRoles(roles)
+	am.Scopes = scopes
+	rm := domain.NewRefreshMeta(app.RefreshTTL(s.cfg.RefreshTokenTTL), userId, dctx.AppId, dctx.DeviceID)
+	rm.SetFamily(familyID)
+	rm.Scopes = scopes
+
+	access, refresh, err := s.tokener.GenPair(am, rm)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, ErrFailedGenJWT)
 	}
@@ -39,7 +133,217 @@ func (s *Auth) genTokensFlow(userId string, dctx domain.DeviceCtx) (*domain.Toke
 	}
 
 	token := domain.NewToken(string(access), string(refresh))
-	rt := domain.NewRefreshTorken(string(refreshHash), m)
+	rt := domain.NewRefreshTorken(string(refreshHash), rm)
+	rt.BindAccess(am)
 
 	return &token, &rt, nil
 }
+
+// genOneTimeToken возвращает случайный токен для пользователя и его хэш для хранения
+func (s *Auth) genOneTimeToken() (token, hash string, _ error) {
+	b := make([]byte, oneTimeTokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", errors.Wrap(err, ErrFailedRandToken)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+
+	h, err := s.tokenHasher.Sum([]byte(token))
+	if err != nil {
+		return "", "", errors.Wrap(err, ErrFailedHashOneTime)
+	}
+
+	return token, string(h), nil
+}
+
+// sendOneTimeToken выпускает одноразовый токен, сохраняет его хэш и отправляет сам токен пользователю
+func (s *Auth) sendOneTimeToken(ctx context.Context, u domain.User, purpose domain.OneTimePurpose, ttl time.Duration) error {
+	token, hash, err := s.genOneTimeToken()
+	if err != nil {
+		return errors.Wrap(err, ErrFailedGenOneTime)
+	}
+
+	ott := domain.NewOneTimeToken(hash, u.ID, purpose, ttl)
+	if err := s.r.SaveOneTimeToken(ctx, ott); err != nil {
+		return errors.Wrap(err, ErrFailedSaveOneTime)
+	}
+
+	if err := s.notifier.Notify(ctx, domain.Notification{
+		Purpose:   purpose,
+		To:        u.Email,
+		Token:     token,
+		ExpiresAt: ott.Exp,
+	}); err != nil {
+		return errors.Wrap(err, ErrFailedNotify)
+	}
+
+	return nil
+}
+
+// openSession открывает новую сессию (семейство refresh-токенов) и выдаёт пару токенов
+func (s *Auth) openSession(ctx context.Context, app domain.App, userID string, dctx domain.DeviceCtx, scopes []string) (domain.Token, error) {
+	token, newRt, err := s.genTokensFlow(ctx, app, userID, uuid.NewString(), dctx, scopes)
+	if err != nil {
+		return domain.Token{}, errors.Wrap(err, ErrFailedGenerateToken)
+	}
+
+	if err := s.r.SaveRefreshToken(ctx, *newRt); err != nil {
+		return domain.Token{}, errors.Wrap(err, ErrFailedSaveToken)
+	}
+
+	return *token, nil
+}
+
+// sessionOrMfa — завершение первого шага входа: при включённой MFA челлендж для CompleteMfaLogin, иначе сессия
+func (s *Auth) sessionOrMfa(ctx context.Context, app domain.App, userID string, dctx domain.DeviceCtx) (domain.LoginResult, error) {
+	mfa, err := s.r.GetUserMfa(ctx, userID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return domain.LoginResult{}, errors.Wrap(err, ErrFailedGetMfa)
+	}
+	if err == nil && mfa.Enabled() {
+		challenge, err := s.issueMfaChallenge(ctx, userID, dctx)
+		if err != nil {
+			return domain.LoginResult{}, errors.Wrap(err, ErrFailedIssueMfa)
+		}
+		return domain.LoginResult{MfaChallenge: challenge}, nil
+	}
+
+	token, err := s.openSession(ctx, app, userID, dctx, nil)
+	if err != nil {
+		return domain.LoginResult{}, err
+	}
+
+	return domain.LoginResult{Token: token}, nil
+}
+
+// issueMfaChallenge — одноразовый токен второго шага входа, привязанный к устройству
+func (s *Auth) issueMfaChallenge(ctx context.Context, userID string, dctx domain.DeviceCtx) (string, error) {
+	token, hash, err := s.genOneTimeToken()
+	if err != nil {
+		return "", errors.Wrap(err, ErrFailedGenOneTime)
+	}
+
+	ott := domain.NewOneTimeToken(hash, userID, domain.PurposeMfaChallenge, s.cfg.MfaChallengeTTL)
+	ott.SetCtx(dctx)
+	if err := s.r.SaveOneTimeToken(ctx, ott); err != nil {
+		return "", errors.Wrap(err, ErrFailedSaveOneTime)
+	}
+
+	return token, nil
+}
+
+// matchTotp расшифровывает секрет и проверяет код; возвращает принятый временной шаг
+func (s *Auth) matchTotp(mfa domain.UserMfa, code string) (int64, error) {
+	secret, err := s.secretCipher.Open(mfa.EncryptedSecret)
+	if err != nil {
+		return 0, errors.Wrap(err, ErrFailedOpenSecret)
+	}
+
+	step, ok := s.totp.Validate(string(secret), code, time.Now())
+	if !ok {
+		return 0, errors.Wrap(domain.ErrValidation, ErrWrongTotpCode)
+	}
+
+	return step, nil
+}
+
+// checkTotp — matchTotp для включённой MFA плюс защита от повторного использования кода
+func (s *Auth) checkTotp(ctx context.Context, mfa domain.UserMfa, code string) error {
+	step, err := s.matchTotp(mfa, code)
+	if err != nil {
+		return err
+	}
+
+	if err := s.r.UseTotpStep(ctx, mfa.UserID, step); err != nil {
+		if errors.Is(err, domain.ErrTokenReuse) {
+			return errors.Wrap(domain.ErrValidation, ErrTotpCodeReused)
+		}
+		return errors.Wrap(err, ErrFailedUseTotp)
+	}
+
+	return nil
+}
+
+// authenticatePassword — проверка email и пароля с защитой от перебора и требованиями приложения
+// (домен email, подтверждённый email); общая для Login и формы /authorize
+func (s *Auth) authenticatePassword(ctx context.Context, creds domain.User, app domain.App) (domain.User, error) {
+	// чужой домен отклоняется до проверки пароля: ответ не зависит от наличия аккаунта
+	if !app.AllowsEmail(creds.Email) {
+		return domain.User{}, errors.Wrap(domain.ErrAppAccessDenied, ErrEmailNotAllowed)
+	}
+
+	// защита от перебора: ключи по email и по адресу клиента
+	emailKey := emailAttemptKey(creds.Email)
+	keys := loginAttemptKeys(ctx, creds.Email)
+	if err := s.checkLoginLock(ctx, keys); err != nil {
+		return domain.User{}, err
+	}
+
+	// авторизация: неизвестный email и неверный пароль неразличимы для клиента
+	u, err := s.r.GetUserInfoByEmail(ctx, creds.Email)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return domain.User{}, errors.Wrap(err, ErrFailedGetUserInfo)
+	}
+	found := err == nil
+
+	switch {
+	case found && u.HasLocalPassword():
+		isCorrect, err := s.passHasher.Compare([]byte(u.Password), []byte(creds.Password))
+		if err != nil || !isCorrect {
+			return domain.User{}, s.loginFailed(ctx, keys)
+		}
+		s.rehashIfNeeded(ctx, u, []byte(creds.Password))
+
+	// локального пароля нет — пароль проверяет каталог; неизвестный email может быть в каталоге
+	case s.directory != nil:
+		var existing *domain.User
+		if found {
+			existing = &u
+		}
+		u, err = s.authenticateDirectory(ctx, creds, existing)
+		if errors.Is(err, domain.ErrValidation) {
+			return domain.User{}, s.loginFailed(ctx, keys)
+		}
+		if err != nil {
+			return domain.User{}, err
+		}
+
+	default:
+		// пароль всё равно хэшируется: иначе время ответа выдаёт наличие аккаунта
+		s.compareDummy([]byte(creds.Password))
+		return domain.User{}, s.loginFailed(ctx, keys)
+	}
+	// сбрасываем только счётчик email: сброс ip позволил бы обнулять его входом в свой аккаунт
+	if err := s.r.ResetLoginFailures(ctx, []string{emailKey}); err != nil {
+		return domain.User{}, errors.Wrap(err, ErrFailedResetAttempts)
+	}
+	if s.cfg.RequiresVerifiedEmail(app.ID) && !u.IsEmailVerified() {
+		return domain.User{}, errors.Wrap(domain.ErrEmailNotVerified, ErrEmailNotVerified)
+	}
+
+	return u, nil
+}
+
+// dummyPassword — пароль хэша-заглушки для неизвестных email
+const dummyPassword = "dummy-password"
+
+// compareDummy тратит на проверку пароля столько же, сколько проверка реального хэша
+func (s *Auth) compareDummy(pass []byte) {
+	s.dummyOnce.Do(func() {
+		s.dummyHash, _ = s.passHasher.Gen([]byte(dummyPassword))
+	})
+	_, _ = s.passHasher.Compare(s.dummyHash, pass)
+}
+
+// rehashIfNeeded переводит хэш пароля на текущий алгоритм/параметры после успешной проверки.
+// Ошибка не мешает входу: старый хэш остаётся рабочим, попытка повторится при следующем входе
+func (s *Auth) rehashIfNeeded(ctx context.Context, u domain.User, pass []byte) {
+	if !s.passHasher.NeedsRehash([]byte(u.Password)) {
+		return
+	}
+
+	hashedPass, err := s.passHasher.Gen(pass)
+	if err != nil {
+		return
+	}
+	_ = s.r.UpdateUserPassword(ctx, u.ID, string(hashedPass))
+}
@@ -0,0 +1,126 @@
+package authservice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+const (
+	ErrFailedGenDeviceID   = "failed generate device id"
+	ErrFailedSaveDevice    = "failed save device"
+	ErrFailedTouchDevice   = "failed check device owner"
+	ErrDeviceNotRegistered = "device not registered for user"
+	ErrDeviceUnregistered  = "session on unregistered device: call RegisterDevice"
+	ErrRefreshNotActive    = "refresh token already rotated or revoked"
+)
+
+// при коллизии случайного id — новая попытка; занятых id несравнимо меньше 2^31
+const deviceIDAttempts = 3
+
+// RegisterDevice выдаёт устройству id, привязанный к владельцу refresh, и переносит сессию на него:
+// предъявленный refresh отзывается (вместе с парным access), новая пара выдаётся уже с новым device_id.
+// Если после отзыва открыть сессию не удалось, клиент входит заново
+func (s *Auth) RegisterDevice(ctx context.Context, refresh string, dctx domain.DeviceCtx, platform, name string) (domain.Device, domain.Token, error) {
+	m, err := s.verificationToken(ctx, refresh, dctx)
+	if err != nil {
+		return domain.Device{}, domain.Token{}, errors.Wrap(err, ErrFailedVerifyToken)
+	}
+	app, err := s.allowedApp(ctx, dctx.AppId, domain.GrantRefreshToken)
+	if err != nil {
+		return domain.Device{}, domain.Token{}, err
+	}
+	if err := s.deviceOwned(ctx, m.UserID, dctx); err != nil {
+		return domain.Device{}, domain.Token{}, err
+	}
+
+	// отзыв первым: один refresh — одно устройство, повтор запроса получает ошибку
+	hash, err := s.tokenHasher.Sum([]byte(refresh))
+	if err != nil {
+		return domain.Device{}, domain.Token{}, errors.Wrap(err, ErrFailedHashToken)
+	}
+	if err := s.r.RevokeTokenByHash(ctx, string(hash)); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.Device{}, domain.Token{}, errors.Wrap(domain.ErrValidation, ErrRefreshNotActive)
+		}
+		return domain.Device{}, domain.Token{}, errors.Wrap(err, ErrFailedRevokeToken)
+	}
+
+	device, err := s.newDevice(ctx, domain.Device{UserID: m.UserID, Platform: platform, Name: name})
+	if err != nil {
+		return domain.Device{}, domain.Token{}, err
+	}
+
+	token, err := s.openSession(ctx, app, m.UserID, domain.NewDeviceCtx(dctx.AppId, device.ID), m.Scopes)
+	if err != nil {
+		return domain.Device{}, domain.Token{}, err
+	}
+
+	return device, token, nil
+}
+
+func (s *Auth) newDevice(ctx context.Context, d domain.Device) (domain.Device, error) {
+	for range deviceIDAttempts {
+		id, err := newDeviceID()
+		if err != nil {
+			return domain.Device{}, err
+		}
+		d.ID = id
+
+		created, err := s.r.NewDevice(ctx, d)
+		if errors.Is(err, domain.ErrDuplicate) {
+			continue
+		}
+		if err != nil {
+			return domain.Device{}, errors.Wrap(err, ErrFailedSaveDevice)
+		}
+		return created, nil
+	}
+
+	return domain.Device{}, errors.Wrap(domain.ErrDuplicate, ErrFailedSaveDevice)
+}
+
+// deviceOwned — устройство выдано сервером этому пользователю; заодно обновляет last_seen.
+// UnregisteredDevice не проверяется: с ним входят до RegisterDevice, а Refresh его ограничивает (sessionDevice).
+// Legacy device_id (положительный, выбран клиентом) принимается без реестра только в переходный период
+func (s *Auth) deviceOwned(ctx context.Context, userID string, dctx domain.DeviceCtx) error {
+	if dctx.DeviceID == domain.UnregisteredDevice {
+		return nil
+	}
+	if !domain.IssuedDevice(dctx.DeviceID) {
+		if s.cfg.DeviceGrace(time.Now()) {
+			return nil
+		}
+		return errors.Wrapf(domain.ErrValidation, "%s: %d", ErrDeviceNotRegistered, dctx.DeviceID)
+	}
+	if err := s.r.TouchDevice(ctx, dctx.DeviceID, userID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return errors.Wrapf(domain.ErrValidation, "%s: %d", ErrDeviceNotRegistered, dctx.DeviceID)
+		}
+		return errors.Wrap(err, ErrFailedTouchDevice)
+	}
+	return nil
+}
+
+// sessionDevice — сессия на UnregisteredDevice продлевается только в переходный период:
+// дальше её refresh годится лишь для RegisterDevice, который переносит сессию на выданный id
+func (s *Auth) sessionDevice(dctx domain.DeviceCtx) error {
+	if dctx.DeviceID == domain.UnregisteredDevice && !s.cfg.DeviceGrace(time.Now()) {
+		return errors.Wrap(domain.ErrValidation, ErrDeviceUnregistered)
+	}
+	return nil
+}
+
+// newDeviceID — случайный отрицательный int32: id не подбирается по порядку выдачи
+// и не совпадает с legacy device_id, которые клиенты выбирали сами (только положительные)
+func newDeviceID() (int32, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return 0, errors.Wrap(err, ErrFailedGenDeviceID)
+	}
+	return -1 - int32(binary.BigEndian.Uint32(b)&0x7fffffff), nil
+}
@@ -0,0 +1,113 @@
+package authservice
+
+import (
+	"context"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+)
+
+const (
+	ErrFailedDirectoryAuth   = "failed authenticate in directory"
+	ErrFailedProvisionUser   = "failed provision directory user"
+	ErrFailedSyncRoles       = "failed sync directory roles"
+	ErrDirectoryPassword     = "password of directory user is managed by directory"
+	ErrDirectoryEmailInUse   = "email belongs to local account"
+	ErrFailedLinkDirectoryID = "failed link directory identity"
+)
+
+// authenticateDirectory — вход через каталог: пароль проверяет bind, при первом входе пользователь
+// создаётся без локального пароля (identities, provider ldap); роли по группам обновляются на каждом входе.
+// existing — найденный по email пользователь без локального пароля
+func (s *Auth) authenticateDirectory(ctx context.Context, creds domain.User, existing *domain.User) (domain.User, error) {
+	du, err := s.directory.Authenticate(ctx, creds.Email, creds.Password)
+	if err != nil {
+		if errors.Is(err, domain.ErrValidation) {
+			return domain.User{}, err
+		}
+		return domain.User{}, errors.Wrap(err, ErrFailedDirectoryAuth)
+	}
+
+	u, err := s.directoryUser(ctx, du, creds.Email, existing)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if managed := s.directory.ManagedRoles(); len(managed) > 0 {
+		if err := s.r.SyncUserRoles(ctx, u.ID, managed, du.Roles); err != nil {
+			return domain.User{}, errors.Wrap(err, ErrFailedSyncRoles)
+		}
+	}
+
+	return u, nil
+}
+
+func (s *Auth) directoryUser(ctx context.Context, du domain.DirectoryUser, email string, existing *domain.User) (domain.User, error) {
+	linked, err := s.r.GetIdentity(ctx, domain.DirectoryProvider, du.Subject)
+	if err == nil {
+		u, err := s.r.GetUserInfoByID(ctx, linked.UserID)
+		if err != nil {
+			return domain.User{}, errors.Wrap(err, ErrFailedGetUserInfo)
+		}
+		return u, nil
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return domain.User{}, errors.Wrap(err, ErrFailedGetIdentity)
+	}
+
+	u, err := s.provisionDirectoryUser(ctx, email, existing)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	err = s.r.NewIdentity(ctx, domain.Identity{
+		Provider: domain.DirectoryProvider,
+		Subject:  du.Subject,
+		UserID:   u.ID,
+		Email:    email,
+	})
+	if errors.Is(err, domain.ErrDuplicate) {
+		// параллельный первый вход уже привязал запись каталога
+		return s.directoryUser(ctx, du, email, &u)
+	}
+	if err != nil {
+		return domain.User{}, errors.Wrap(err, ErrFailedLinkDirectoryID)
+	}
+
+	// email подтверждён каталогом
+	if !u.IsEmailVerified() {
+		if err := s.r.MarkEmailVerified(ctx, u.ID); err != nil {
+			return domain.User{}, errors.Wrap(err, ErrFailedMarkVerified)
+		}
+	}
+
+	return u, nil
+}
+
+// provisionDirectoryUser — JIT: пользователь без локального пароля. Локальная учётная запись
+// с паролем каталогом не захватывается
+func (s *Auth) provisionDirectoryUser(ctx context.Context, email string, existing *domain.User) (domain.User, error) {
+	if existing != nil {
+		return *existing, nil
+	}
+
+	u, err := s.r.NewUser(ctx, domain.User{ID: uuid.NewString(), Email: email})
+	if err == nil {
+		return u, nil
+	}
+	if !errors.Is(err, domain.ErrDuplicate) {
+		return domain.User{}, errors.Wrap(err, ErrFailedProvisionUser)
+	}
+
+	// email заняли между поиском и созданием
+	u, err = s.r.GetUserInfoByEmail(ctx, email)
+	if err != nil {
+		return domain.User{}, errors.Wrap(err, ErrFailedGetUserInfo)
+	}
+	if u.HasLocalPassword() {
+		return domain.User{}, errors.Wrap(domain.ErrDuplicate, ErrDirectoryEmailInUse)
+	}
+
+	return u, nil
+}
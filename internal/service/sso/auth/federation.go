@@ -0,0 +1,181 @@
+package authservice
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+const (
+	ErrUnknownProvider       = "unknown identity provider"
+	ErrFailedAuthURL         = "failed build identity provider auth url"
+	ErrFailedSaveFedState    = "failed save federation state"
+	ErrInvalidFedState       = "federation state invalid or expired"
+	ErrFailedConsumeFedState = "failed consume federation state"
+	ErrFedStateMismatch      = "federation state issued for another provider or device"
+	ErrFailedExchange        = "failed exchange code at identity provider"
+	ErrFailedGetIdentity     = "failed get identity"
+	ErrFailedLinkIdentity    = "failed link identity"
+	ErrEmailUnverifiedByIdP  = "provider did not verify email: cannot link account"
+	ErrNoLocalAccount        = "no local account for identity"
+)
+
+// verifier PKCE — 32 случайных байта, в base64url 43 символа (минимум RFC 7636)
+const pkceVerifierLen = 32
+
+// BeginFederatedLogin — первый шаг входа через внешний IdP: state, nonce и PKCE verifier
+// сохраняются на FederationStateTTL, клиент открывает AuthURL в браузере
+func (s *Auth) BeginFederatedLogin(ctx context.Context, provider string, dctx domain.DeviceCtx) (domain.FederatedLogin, error) {
+	if _, err := s.allowedApp(ctx, dctx.AppId, domain.GrantFederated); err != nil {
+		return domain.FederatedLogin{}, err
+	}
+	idp, ok := s.idps[provider]
+	if !ok {
+		return domain.FederatedLogin{}, errors.Wrapf(domain.ErrNotFound, "%s: %q", ErrUnknownProvider, provider)
+	}
+
+	state, stateHash, err := s.genOneTimeToken()
+	if err != nil {
+		return domain.FederatedLogin{}, errors.Wrap(err, ErrFailedGenOneTime)
+	}
+	nonce, err := randomURLSafe(pkceVerifierLen)
+	if err != nil {
+		return domain.FederatedLogin{}, err
+	}
+	verifier, err := randomURLSafe(pkceVerifierLen)
+	if err != nil {
+		return domain.FederatedLogin{}, err
+	}
+
+	st := domain.FederationState{
+		Hash:         stateHash,
+		Provider:     provider,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		Ctx:          dctx,
+		Exp:          time.Now().Add(s.cfg.FederationStateTTL),
+	}
+	if err := s.r.SaveFederationState(ctx, st); err != nil {
+		return domain.FederatedLogin{}, errors.Wrap(err, ErrFailedSaveFedState)
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	authURL, err := idp.AuthCodeURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return domain.FederatedLogin{}, errors.Wrap(err, ErrFailedAuthURL)
+	}
+
+	return domain.FederatedLogin{AuthURL: authURL, State: state}, nil
+}
+
+// CompleteFederatedLogin — code и state из redirect IdP меняются на сессию, как при Login:
+// MFA пользователя действует и здесь (вместо токенов — челлендж для CompleteMfaLogin)
+func (s *Auth) CompleteFederatedLogin(ctx context.Context, provider, state, code string, dctx domain.DeviceCtx) (domain.LoginResult, error) {
+	app, err := s.allowedApp(ctx, dctx.AppId, domain.GrantFederated)
+	if err != nil {
+		return domain.LoginResult{}, err
+	}
+	idp, ok := s.idps[provider]
+	if !ok {
+		return domain.LoginResult{}, errors.Wrapf(domain.ErrNotFound, "%s: %q", ErrUnknownProvider, provider)
+	}
+
+	hash, err := s.tokenHasher.Sum([]byte(state))
+	if err != nil {
+		return domain.LoginResult{}, errors.Wrap(err, ErrFailedHashToken)
+	}
+	// state погашен при первом предъявлении, даже если дальше что-то не совпало
+	st, err := s.r.ConsumeFederationState(ctx, string(hash))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.LoginResult{}, errors.Wrap(domain.ErrValidation, ErrInvalidFedState)
+		}
+		return domain.LoginResult{}, errors.Wrap(err, ErrFailedConsumeFedState)
+	}
+	if st.Provider != provider || !dctx.Compare(st.Ctx) {
+		return domain.LoginResult{}, errors.Wrap(domain.ErrValidation, ErrFedStateMismatch)
+	}
+
+	ident, err := idp.Exchange(ctx, code, st.CodeVerifier, st.Nonce)
+	if err != nil {
+		return domain.LoginResult{}, errors.Wrap(err, ErrFailedExchange)
+	}
+	ident.Provider = provider
+
+	u, err := s.linkedUser(ctx, ident)
+	if err != nil {
+		return domain.LoginResult{}, err
+	}
+	// ограничения приложения — по email локального пользователя, как при входе по паролю
+	if !app.AllowsEmail(u.Email) {
+		return domain.LoginResult{}, errors.Wrap(domain.ErrAppAccessDenied, ErrEmailNotAllowed)
+	}
+	if err := s.deviceOwned(ctx, u.ID, dctx); err != nil {
+		return domain.LoginResult{}, err
+	}
+
+	return s.sessionOrMfa(ctx, app, u.ID, dctx)
+}
+
+// linkedUser — пользователь по привязке (provider, sub). Без привязки учётная запись IdP
+// привязывается к пользователю с тем же email, только если IdP email подтвердил
+func (s *Auth) linkedUser(ctx context.Context, ident domain.ExternalIdentity) (domain.User, error) {
+	linked, err := s.r.GetIdentity(ctx, ident.Provider, ident.Subject)
+	if err == nil {
+		u, err := s.r.GetUserInfoByID(ctx, linked.UserID)
+		if err != nil {
+			return domain.User{}, errors.Wrap(err, ErrFailedGetUserInfo)
+		}
+		return u, nil
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return domain.User{}, errors.Wrap(err, ErrFailedGetIdentity)
+	}
+
+	if ident.Email == "" || !ident.EmailVerified {
+		return domain.User{}, errors.Wrap(domain.ErrValidation, ErrEmailUnverifiedByIdP)
+	}
+	u, err := s.r.GetUserInfoByEmail(ctx, ident.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.User{}, errors.Wrap(domain.ErrNotFound, ErrNoLocalAccount)
+		}
+		return domain.User{}, errors.Wrap(err, ErrFailedGetUserInfo)
+	}
+
+	err = s.r.NewIdentity(ctx, domain.Identity{
+		Provider: ident.Provider,
+		Subject:  ident.Subject,
+		UserID:   u.ID,
+		Email:    ident.Email,
+	})
+	if err != nil && !errors.Is(err, domain.ErrDuplicate) {
+		return domain.User{}, errors.Wrap(err, ErrFailedLinkIdentity)
+	}
+	if errors.Is(err, domain.ErrDuplicate) {
+		// параллельный вход уже привязал учётную запись — к кому, решает сохранённая привязка
+		return s.linkedUser(ctx, ident)
+	}
+
+	// IdP подтвердил владение адресом — это и есть подтверждение email
+	if !u.IsEmailVerified() {
+		if err := s.r.MarkEmailVerified(ctx, u.ID); err != nil {
+			return domain.User{}, errors.Wrap(err, ErrFailedMarkVerified)
+		}
+	}
+
+	return u, nil
+}
+
+func randomURLSafe(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, ErrFailedRandToken)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,176 @@
+package federation
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+// errKeysUnavailable — JWKS IdP не получен: сбой, а не ошибка клиента
+var errKeysUnavailable = errors.New("identity provider keys unavailable")
+
+type verificationKey struct {
+	alg string
+	pub crypto.PublicKey
+}
+
+// keySet — ключи IdP по kid. Перечитываются по интервалу и на незнакомый kid (ротация у IdP);
+// при ошибке чтения остаются прежние ключи
+type keySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	byKid     map[string]verificationKey
+	checkedAt time.Time
+	lastErr   error
+}
+
+func newKeySet(url string, client *http.Client) *keySet {
+	return &keySet{url: url, client: client}
+}
+
+func (ks *keySet) get(ctx context.Context, kid string) (verificationKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if time.Since(ks.checkedAt) > keysRefreshInterval {
+		ks.refresh(ctx)
+	}
+	k, ok := ks.lookup(kid)
+	if !ok && time.Since(ks.checkedAt) > minKeysRefetch {
+		ks.refresh(ctx)
+		k, ok = ks.lookup(kid)
+	}
+	if !ok {
+		if ks.lastErr != nil {
+			return verificationKey{}, ks.lastErr
+		}
+		return verificationKey{}, errors.Errorf("unknown signing key id %q", kid)
+	}
+	return k, nil
+}
+
+// lookup — без kid подходит только единственный ключ: выбирать наугад нельзя
+func (ks *keySet) lookup(kid string) (verificationKey, bool) {
+	if kid == "" && len(ks.byKid) == 1 {
+		for _, k := range ks.byKid {
+			return k, true
+		}
+	}
+	k, ok := ks.byKid[kid]
+	return k, ok
+}
+
+func (ks *keySet) refresh(ctx context.Context) {
+	ks.checkedAt = time.Now()
+
+	set, err := ks.fetch(ctx)
+	if err != nil {
+		ks.lastErr = errors.Wrap(errKeysUnavailable, err.Error())
+		return
+	}
+
+	byKid := make(map[string]verificationKey, len(set.Keys))
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		k, err := parseJWK(j)
+		if err != nil {
+			// ключ неподдерживаемого типа не должен ломать проверку остальных
+			continue
+		}
+		byKid[j.Kid] = k
+	}
+
+	ks.byKid, ks.lastErr = byKid, nil
+}
+
+func (ks *keySet) fetch(ctx context.Context) (domain.JWKS, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, nil)
+	if err != nil {
+		return domain.JWKS{}, err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return domain.JWKS{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return domain.JWKS{}, errors.Errorf("jwks: status %d", resp.StatusCode)
+	}
+
+	var set domain.JWKS
+	if err := json.NewDecoder(limitBody(resp.Body)).Decode(&set); err != nil {
+		return domain.JWKS{}, errors.Wrap(err, "decode jwks")
+	}
+	return set, nil
+}
+
+// parseJWK — RSA (RS256) и EC P-256 (ES256): то, чем подписывают id_token распространённые IdP
+func parseJWK(j domain.JWK) (verificationKey, error) {
+	switch {
+	case j.Kty == "RSA" && (j.Alg == "" || j.Alg == "RS256"):
+		n, err := b64Int(j.N)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		e, err := b64Int(j.E)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return verificationKey{}, errors.New("rsa exponent too large")
+		}
+		return verificationKey{alg: "RS256", pub: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+
+	case j.Kty == "EC" && j.Crv == "P-256" && (j.Alg == "" || j.Alg == "ES256"):
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return verificationKey{}, errors.New("ec coordinates must be 32 bytes")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return verificationKey{}, errors.Wrap(err, "ec point")
+		}
+		return verificationKey{alg: "ES256", pub: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+	}
+
+	return verificationKey{}, errors.Errorf("unsupported jwk kty=%q crv=%q alg=%q", j.Kty, j.Crv, j.Alg)
+}
+
+func b64Int(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,330 @@
+package federation
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
+	"github.com/go-faster/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	discoveryPath       = "/.well-known/openid-configuration"
+	metadataTTL         = time.Hour
+	keysRefreshInterval = time.Hour
+	minKeysRefetch      = 10 * time.Second // незнакомый kid не должен превращаться в запрос к IdP на каждый вход
+	httpTimeout         = 10 * time.Second
+	clockSkew           = time.Minute
+	maxResponseBody     = 1 << 20
+)
+
+// DefaultScopes — openid обязателен, email нужен для привязки к локальной учётной записи
+var DefaultScopes = []string{"openid", "email"}
+
+// Config — один внешний OpenID Connect провайдер
+type Config struct {
+	Issuer       string   `json:"issuer"` // https; discovery — issuer + /.well-known/openid-configuration
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"` // пусто — публичный клиент, только PKCE
+	RedirectURI  string   `json:"redirect_uri"`
+	Scopes       []string `json:"scopes"`
+}
+
+// file — формат BUSSINES_LOGIC_IDENTITY_PROVIDERS_PATH; ключ — имя провайдера в API
+type file struct {
+	Providers map[string]Config `json:"providers"`
+}
+
+// NewFromPath читает провайдеров из json. Пустой путь — вход через внешние IdP выключен
+func NewFromPath(path string) (map[string]authservice.ExternalIdentityProvider, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read identity providers")
+	}
+	var f file
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, errors.Wrap(err, "parse identity providers")
+	}
+
+	idps := make(map[string]authservice.ExternalIdentityProvider, len(f.Providers))
+	for name, cfg := range f.Providers {
+		if name == "" {
+			return nil, errors.New("identity provider without name")
+		}
+		idp, err := New(cfg, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "identity provider %q", name)
+		}
+		idps[name] = idp
+	}
+
+	return idps, nil
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu     sync.Mutex
+	meta   metadata
+	metaAt time.Time
+	keys   *keySet
+}
+
+// New — провайдер по конфигу; discovery и JWKS запрашиваются при первом входе, а не на старте,
+// чтобы недоступный IdP не мешал запуску сервиса. client nil — http.Client с таймаутом
+func New(cfg Config, client *http.Client) (authservice.ExternalIdentityProvider, error) {
+	u, err := url.Parse(cfg.Issuer)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, errors.Errorf("issuer must be https url, got %q", cfg.Issuer)
+	}
+	if cfg.ClientID == "" {
+		return nil, errors.New("client_id required")
+	}
+	if _, err := url.ParseRequestURI(cfg.RedirectURI); err != nil {
+		return nil, errors.Errorf("invalid redirect_uri %q", cfg.RedirectURI)
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultScopes
+	}
+	if client == nil {
+		client = &http.Client{Timeout: httpTimeout}
+	}
+
+	return &provider{cfg: cfg, client: client}, nil
+}
+
+func (p *provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	meta, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", errors.Wrap(err, "parse authorization_endpoint")
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURI)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// Exchange меняет code на id_token и проверяет его. Отказ IdP и непрошедший проверку токен —
+// domain.ErrValidation; недоступность IdP — обычная ошибка
+func (p *provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (domain.ExternalIdentity, error) {
+	meta, keys, err := p.discover(ctx)
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+
+	rawIDToken, err := p.redeem(ctx, meta.TokenEndpoint, code, codeVerifier)
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+
+	return p.verify(ctx, keys, rawIDToken, nonce)
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p *provider) redeem(ctx context.Context, endpoint, code, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURI},
+		"code_verifier": {codeVerifier},
+		"client_id":     {p.cfg.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		// client_secret_basic: RFC 6749 2.3.1 требует form-кодирования id и secret
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "token endpoint")
+	}
+	defer resp.Body.Close()
+
+	var tr tokenResponse
+	decodeErr := json.NewDecoder(limitBody(resp.Body)).Decode(&tr)
+
+	switch {
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized:
+		return "", errors.Wrapf(domain.ErrValidation, "token endpoint: %s %s", tr.Error, tr.ErrorDescription)
+	case resp.StatusCode != http.StatusOK:
+		return "", errors.Errorf("token endpoint: status %d", resp.StatusCode)
+	case decodeErr != nil:
+		return "", errors.Wrap(decodeErr, "decode token response")
+	case tr.IDToken == "":
+		return "", errors.Wrap(domain.ErrValidation, "token endpoint returned no id_token")
+	}
+
+	return tr.IDToken, nil
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string   `json:"nonce"`
+	AuthorizedParty string   `json:"azp"`
+	Email           string   `json:"email"`
+	EmailVerified   flexBool `json:"email_verified"`
+}
+
+func (p *provider) verify(ctx context.Context, keys *keySet, raw, nonce string) (domain.ExternalIdentity, error) {
+	var c idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &c, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, err := keys.get(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if k.alg != t.Method.Alg() {
+			return nil, errors.Errorf("key %q is not for %s", kid, t.Method.Alg())
+		}
+		return k.pub, nil
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		if errors.Is(err, errKeysUnavailable) {
+			return domain.ExternalIdentity{}, err
+		}
+		return domain.ExternalIdentity{}, errors.Wrap(domain.ErrValidation, "id_token: "+err.Error())
+	}
+
+	if c.Subject == "" {
+		return domain.ExternalIdentity{}, errors.Wrap(domain.ErrValidation, "id_token without sub")
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Nonce), []byte(nonce)) != 1 {
+		return domain.ExternalIdentity{}, errors.Wrap(domain.ErrValidation, "id_token nonce mismatch")
+	}
+	// при нескольких audience токен должен быть выдан именно нам (OIDC Core 3.1.3.7)
+	if (len(c.Audience) > 1 || c.AuthorizedParty != "") && c.AuthorizedParty != p.cfg.ClientID {
+		return domain.ExternalIdentity{}, errors.Wrap(domain.ErrValidation, "id_token azp mismatch")
+	}
+
+	return domain.ExternalIdentity{
+		Subject:       c.Subject,
+		Email:         c.Email,
+		EmailVerified: bool(c.EmailVerified),
+	}, nil
+}
+
+// discover — метаданные IdP, кэш на metadataTTL. issuer в ответе обязан совпасть с настроенным
+func (p *provider) discover(ctx context.Context) (metadata, *keySet, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil && time.Since(p.metaAt) < metadataTTL {
+		return p.meta, p.keys, nil
+	}
+
+	meta, err := p.fetchMetadata(ctx)
+	if err != nil {
+		if p.keys != nil {
+			// IdP временно недоступен — работаем на прежних метаданных
+			return p.meta, p.keys, nil
+		}
+		return metadata{}, nil, err
+	}
+
+	if p.keys == nil || p.keys.url != meta.JWKSURI {
+		p.keys = newKeySet(meta.JWKSURI, p.client)
+	}
+	p.meta, p.metaAt = meta, time.Now()
+
+	return p.meta, p.keys, nil
+}
+
+func (p *provider) fetchMetadata(ctx context.Context) (metadata, error) {
+	endpoint := strings.TrimSuffix(p.cfg.Issuer, "/") + discoveryPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return metadata{}, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return metadata{}, errors.Wrap(err, "discovery")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return metadata{}, errors.Errorf("discovery: status %d", resp.StatusCode)
+	}
+
+	var meta metadata
+	if err := json.NewDecoder(limitBody(resp.Body)).Decode(&meta); err != nil {
+		return metadata{}, errors.Wrap(err, "decode discovery")
+	}
+	if meta.Issuer != p.cfg.Issuer {
+		return metadata{}, errors.Errorf("discovery issuer %q does not match %q", meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return metadata{}, errors.New("discovery: missing endpoints")
+	}
+
+	return meta, nil
+}
+
+// flexBool — email_verified часть IdP отдаёт строкой "true"
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", `"true"`:
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+func limitBody(r io.Reader) io.Reader {
+	return io.LimitReader(r, maxResponseBody)
+}
@@ -0,0 +1,436 @@
+package federation
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID    = "sso-client"
+	testSecret      = "s3cr/et"
+	testRedirectURI = "https://sso.example.com/federation/callback"
+)
+
+// fakeIdP — OIDC провайдер в процессе: discovery, authorize (сразу redirect с code), token и jwks
+type fakeIdP struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	issuer   string
+	key      crypto.Signer
+	kid      string
+	jwks     domain.JWKS
+	codes    map[string]fakeGrant
+	claims   jwt.MapClaims         // личность пользователя, вошедшего у IdP
+	mutate   func(c jwt.MapClaims) // порча id_token для негативных случаев
+	signWith func() (crypto.Signer, string)
+	jwksHits int
+}
+
+type fakeGrant struct {
+	challenge string
+	nonce     string
+}
+
+func newFakeIdP(t *testing.T, key crypto.Signer) *fakeIdP {
+	t.Helper()
+	f := &fakeIdP{
+		codes: map[string]fakeGrant{},
+		claims: jwt.MapClaims{
+			"sub":            "idp-user-1",
+			"email":          "alice@example.com",
+			"email_verified": true,
+		},
+	}
+	f.srv = httptest.NewTLSServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.srv.Close)
+	f.issuer = f.srv.URL
+	f.setKey(key, "k1")
+	return f
+}
+
+func (f *fakeIdP) setKey(key crypto.Signer, kid string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.key, f.kid = key, kid
+	f.jwks = domain.JWKS{Keys: []domain.JWK{toJWK(key.Public(), kid)}}
+}
+
+func (f *fakeIdP) provider(t *testing.T) *provider {
+	t.Helper()
+	p, err := New(Config{
+		Issuer:       f.srv.URL,
+		ClientID:     testClientID,
+		ClientSecret: testSecret,
+		RedirectURI:  testRedirectURI,
+	}, f.srv.Client())
+	require.NoError(t, err)
+	return p.(*provider)
+}
+
+func (f *fakeIdP) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case discoveryPath:
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 f.issuer,
+			"authorization_endpoint": f.srv.URL + "/authorize?tenant=acme",
+			"token_endpoint":         f.srv.URL + "/token",
+			"jwks_uri":               f.srv.URL + "/jwks",
+		})
+	case "/jwks":
+		f.jwksHits++
+		writeJSON(w, http.StatusOK, f.jwks)
+	case "/authorize":
+		q := r.URL.Query()
+		if q.Get("client_id") != testClientID || q.Get("response_type") != "code" ||
+			q.Get("code_challenge_method") != "S256" || q.Get("tenant") != "acme" {
+			http.Error(w, "bad authorize request", http.StatusBadRequest)
+			return
+		}
+		code := rand.Text()
+		f.codes[code] = fakeGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+		http.Redirect(w, r, q.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+	case "/token":
+		f.token(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	id, _ = url.QueryUnescape(id)
+	secret, _ = url.QueryUnescape(secret)
+	if !ok || id != testClientID || secret != testSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("redirect_uri") != testRedirectURI {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	g, ok := f.codes[r.PostForm.Get("code")]
+	delete(f.codes, r.PostForm.Get("code"))
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code or verifier mismatch"})
+		return
+	}
+
+	now := time.Now()
+	c := jwt.MapClaims{
+		"iss":   f.issuer,
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": g.nonce,
+	}
+	for k, v := range f.claims {
+		c[k] = v
+	}
+	if f.mutate != nil {
+		f.mutate(c)
+	}
+
+	key, kid := f.key, f.kid
+	if f.signWith != nil {
+		key, kid = f.signWith()
+	}
+	method := jwt.SigningMethod(jwt.SigningMethodRS256)
+	if _, isEC := key.(*ecdsa.PrivateKey); isEC {
+		method = jwt.SigningMethodES256
+	}
+	tok := jwt.NewWithClaims(method, c)
+	tok.Header["kid"] = kid
+	signed, err := tok.SignedString(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"access_token": "opaque", "token_type": "Bearer", "id_token": signed})
+}
+
+// login — браузерная часть: открыть AuthCodeURL и забрать code и state из redirect
+func (f *fakeIdP) login(t *testing.T, p *provider, state, nonce, verifier string) (code string) {
+	t.Helper()
+	sum := sha256.Sum256([]byte(verifier))
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, base64.RawURLEncoding.EncodeToString(sum[:]))
+	require.NoError(t, err)
+
+	browser := f.srv.Client()
+	browser.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := browser.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, state, loc.Query().Get("state"))
+	return loc.Query().Get("code")
+}
+
+func TestOIDC_AuthCodeURL(t *testing.T) {
+	f := newFakeIdP(t, rsaKey(t))
+	p := f.provider(t)
+
+	raw, err := p.AuthCodeURL(context.Background(), "st", "nn", "ch")
+	require.NoError(t, err)
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+
+	q := u.Query()
+	require.Equal(t, "/authorize", u.Path)
+	require.Equal(t, "acme", q.Get("tenant"), "query authorization_endpoint сохраняется")
+	require.Equal(t, "code", q.Get("response_type"))
+	require.Equal(t, testClientID, q.Get("client_id"))
+	require.Equal(t, testRedirectURI, q.Get("redirect_uri"))
+	require.Equal(t, "openid email", q.Get("scope"))
+	require.Equal(t, "st", q.Get("state"))
+	require.Equal(t, "nn", q.Get("nonce"))
+	require.Equal(t, "ch", q.Get("code_challenge"))
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+}
+
+func TestOIDC_Exchange(t *testing.T) {
+	t.Run("rsa key", func(t *testing.T) {
+		f := newFakeIdP(t, rsaKey(t))
+		p := f.provider(t)
+
+		code := f.login(t, p, "state-1", "nonce-1", "verifier-verifier-verifier-verifier-verifier")
+		ident, err := p.Exchange(context.Background(), code, "verifier-verifier-verifier-verifier-verifier", "nonce-1")
+		require.NoError(t, err)
+		require.Equal(t, domain.ExternalIdentity{Subject: "idp-user-1", Email: "alice@example.com", EmailVerified: true}, ident)
+
+		// code одноразовый
+		_, err = p.Exchange(context.Background(), code, "verifier-verifier-verifier-verifier-verifier", "nonce-1")
+		require.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("ec key and string email_verified", func(t *testing.T) {
+		f := newFakeIdP(t, ecKey(t))
+		f.claims["email_verified"] = "true"
+		p := f.provider(t)
+
+		code := f.login(t, p, "s", "n", "v")
+		ident, err := p.Exchange(context.Background(), code, "v", "n")
+		require.NoError(t, err)
+		require.True(t, ident.EmailVerified)
+	})
+
+	t.Run("unverified email is reported as is", func(t *testing.T) {
+		f := newFakeIdP(t, ecKey(t))
+		f.claims["email_verified"] = false
+		p := f.provider(t)
+
+		code := f.login(t, p, "s", "n", "v")
+		ident, err := p.Exchange(context.Background(), code, "v", "n")
+		require.NoError(t, err)
+		require.False(t, ident.EmailVerified)
+	})
+}
+
+func TestOIDC_ExchangeRejected(t *testing.T) {
+	foreign := rsaKey(t)
+
+	tests := []struct {
+		name     string
+		verifier string
+		nonce    string
+		setup    func(f *fakeIdP)
+	}{
+		{name: "wrong code verifier", verifier: "other"},
+		{name: "nonce mismatch", nonce: "replayed"},
+		{name: "another audience", setup: func(f *fakeIdP) {
+			f.mutate = func(c jwt.MapClaims) { c["aud"] = "someone-else" }
+		}},
+		{name: "several audiences without azp", setup: func(f *fakeIdP) {
+			f.mutate = func(c jwt.MapClaims) { c["aud"] = []string{testClientID, "someone-else"} }
+		}},
+		{name: "azp of another client", setup: func(f *fakeIdP) {
+			f.mutate = func(c jwt.MapClaims) { c["azp"] = "someone-else" }
+		}},
+		{name: "expired", setup: func(f *fakeIdP) {
+			f.mutate = func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }
+		}},
+		{name: "no exp", setup: func(f *fakeIdP) {
+			f.mutate = func(c jwt.MapClaims) { delete(c, "exp") }
+		}},
+		{name: "another issuer", setup: func(f *fakeIdP) {
+			f.mutate = func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }
+		}},
+		{name: "no sub", setup: func(f *fakeIdP) {
+			f.mutate = func(c jwt.MapClaims) { delete(c, "sub") }
+		}},
+		{name: "signed by unknown key", setup: func(f *fakeIdP) {
+			f.signWith = func() (crypto.Signer, string) { return foreign, "k-foreign" }
+		}},
+		{name: "signed by foreign key under known kid", setup: func(f *fakeIdP) {
+			f.signWith = func() (crypto.Signer, string) { return foreign, "k1" }
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeIdP(t, rsaKey(t))
+			if tt.setup != nil {
+				tt.setup(f)
+			}
+			p := f.provider(t)
+
+			code := f.login(t, p, "s", "n", "verifier")
+			verifier, nonce := "verifier", "n"
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+
+			_, err := p.Exchange(context.Background(), code, verifier, nonce)
+			require.ErrorIs(t, err, domain.ErrValidation)
+		})
+	}
+}
+
+func TestOIDC_KeyRotation(t *testing.T) {
+	f := newFakeIdP(t, rsaKey(t))
+	p := f.provider(t)
+
+	code := f.login(t, p, "s", "n", "v")
+	_, err := p.Exchange(context.Background(), code, "v", "n")
+	require.NoError(t, err)
+	require.Equal(t, 1, f.jwksHits)
+
+	// повторный вход — ключи из кэша
+	code = f.login(t, p, "s", "n", "v")
+	_, err = p.Exchange(context.Background(), code, "v", "n")
+	require.NoError(t, err)
+	require.Equal(t, 1, f.jwksHits)
+
+	f.setKey(ecKey(t), "k2")
+	p.keys.mu.Lock()
+	p.keys.checkedAt = time.Now().Add(-2 * minKeysRefetch)
+	p.keys.mu.Unlock()
+
+	code = f.login(t, p, "s", "n", "v")
+	_, err = p.Exchange(context.Background(), code, "v", "n")
+	require.NoError(t, err, "незнакомый kid перечитывает jwks")
+	require.Equal(t, 2, f.jwksHits)
+}
+
+func TestOIDC_IdPUnavailable(t *testing.T) {
+	f := newFakeIdP(t, rsaKey(t))
+	f.issuer = "https://evil.example.com"
+	p := f.provider(t)
+
+	_, err := p.AuthCodeURL(context.Background(), "s", "n", "c")
+	require.Error(t, err, "issuer в discovery не совпал с настроенным")
+	require.NotErrorIs(t, err, domain.ErrValidation)
+
+	f.srv.Close()
+	_, err = p.Exchange(context.Background(), "code", "v", "n")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, domain.ErrValidation, "недоступный IdP — сбой, а не ошибка клиента")
+}
+
+func TestNewFromPath(t *testing.T) {
+	idps, err := NewFromPath("")
+	require.NoError(t, err)
+	require.Nil(t, idps)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "idps.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"providers": {
+		"corp": {"issuer": "https://login.corp.example.com", "client_id": "sso", "redirect_uri": "https://sso.example.com/cb"},
+		"google": {"issuer": "https://accounts.google.com", "client_id": "x.apps", "client_secret": "y",
+			"redirect_uri": "https://sso.example.com/cb", "scopes": ["openid", "email", "profile"]}
+	}}`), 0o600))
+	idps, err = NewFromPath(path)
+	require.NoError(t, err)
+	require.Len(t, idps, 2)
+	require.Equal(t, []string{"openid", "email", "profile"}, idps["google"].(*provider).cfg.Scopes)
+	require.Equal(t, DefaultScopes, idps["corp"].(*provider).cfg.Scopes)
+
+	for name, body := range map[string]string{
+		"plain http issuer": `{"providers": {"a": {"issuer": "http://idp.example.com", "client_id": "x", "redirect_uri": "https://sso.example.com/cb"}}}`,
+		"no client_id":      `{"providers": {"a": {"issuer": "https://idp.example.com", "redirect_uri": "https://sso.example.com/cb"}}}`,
+		"no redirect_uri":   `{"providers": {"a": {"issuer": "https://idp.example.com", "client_id": "x"}}}`,
+		"broken json":       `{"providers": [`,
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+		_, err := NewFromPath(path)
+		require.Error(t, err, name)
+	}
+
+	_, err = NewFromPath(filepath.Join(dir, "missing.json"))
+	require.Error(t, err)
+}
+
+func TestParseJWK_SkipsUnsupported(t *testing.T) {
+	_, err := parseJWK(domain.JWK{Kty: "OKP", Crv: "Ed25519", X: "AA"})
+	require.Error(t, err)
+	_, err = parseJWK(domain.JWK{Kty: "RSA", Alg: "RS512", N: "AQAB", E: "AQAB"})
+	require.Error(t, err)
+	_, err = parseJWK(domain.JWK{Kty: "EC", Crv: "P-256", X: b64(make([]byte, 32)), Y: b64(make([]byte, 32))})
+	require.Error(t, err, "точка не на кривой")
+}
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return k
+}
+
+func ecKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return k
+}
+
+func toJWK(pub crypto.PublicKey, kid string) domain.JWK {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return domain.JWK{Kty: "RSA", Use: "sig", Alg: "RS256", Kid: kid, N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return domain.JWK{Kty: "EC", Use: "sig", Alg: "ES256", Kid: kid, Crv: "P-256", X: b64(k.X.FillBytes(make([]byte, 32))), Y: b64(k.Y.FillBytes(make([]byte, 32)))}
+	}
+	panic(errors.Errorf("unexpected key %T", pub))
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
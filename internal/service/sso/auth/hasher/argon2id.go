@@ -0,0 +1,120 @@
+package hasher
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
+	"github.com/go-faster/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+	argon2Prefix  = "$argon2id$"
+)
+
+var errBadArgon2Hash = errors.New("malformed argon2id hash")
+
+// Argon2Params — параметры argon2id; Memory в KiB
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+type argon2Hasher struct {
+	p Argon2Params
+}
+
+// NewArgon2id — argon2id в формате PHC: $argon2id$v=19$m=<KiB>,t=<iter>,p=<par>$<salt>$<hash>.
+// Проверяет и bcrypt-хэши: старые пароли переводятся на argon2id при входе
+func NewArgon2id(p Argon2Params) authservice.PasswordHasher {
+	return &argon2Hasher{
+		p: p,
+	}
+}
+
+func (h *argon2Hasher) Gen(origin []byte) ([]byte, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "read salt")
+	}
+
+	key := argon2.IDKey(origin, salt, h.p.Iterations, h.p.Memory, h.p.Parallelism, argon2KeyLen)
+
+	return fmt.Appendf(nil, "%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix, argon2.Version, h.p.Memory, h.p.Iterations, h.p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *argon2Hasher) Compare(hash []byte, origin []byte) (bool, error) {
+	return compare(hash, origin)
+}
+
+func (h *argon2Hasher) NeedsRehash(hash []byte) bool {
+	phc, err := parseArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return phc.p != h.p || len(phc.key) != argon2KeyLen
+}
+
+type argon2Hash struct {
+	p    Argon2Params
+	salt []byte
+	key  []byte
+}
+
+func isArgon2id(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte(argon2Prefix))
+}
+
+func parseArgon2id(hash []byte) (argon2Hash, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return argon2Hash{}, errBadArgon2Hash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return argon2Hash{}, errors.Wrap(errBadArgon2Hash, "version")
+	}
+	if version != argon2.Version {
+		return argon2Hash{}, errors.Wrapf(errBadArgon2Hash, "unsupported version %d", version)
+	}
+
+	var h argon2Hash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.p.Memory, &h.p.Iterations, &h.p.Parallelism); err != nil {
+		return argon2Hash{}, errors.Wrap(errBadArgon2Hash, "params")
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return argon2Hash{}, errors.Wrap(errBadArgon2Hash, "salt")
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return argon2Hash{}, errors.Wrap(errBadArgon2Hash, "key")
+	}
+
+	return h, nil
+}
+
+func compareArgon2id(hash, origin []byte) (bool, error) {
+	h, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey(origin, h.salt, h.p.Iterations, h.p.Memory, h.p.Parallelism, uint32(len(h.key)))
+
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
@@ -1,14 +1,26 @@
 package hasher
 
 import (
+	"bytes"
+
 	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// алгоритмы для BUSSINES_LOGIC_PASS_HASHER_ALGO
+const (
+	AlgoBcrypt   = "bcrypt"
+	AlgoArgon2id = "argon2id"
+)
+
+// BcryptMaxBytes — bcrypt не принимает пароли длиннее (bcrypt.ErrPasswordTooLong)
+const BcryptMaxBytes = 72
+
 type hasher struct {
 	cost int
 }
 
+// New — bcrypt. Проверяет и argon2id-хэши, поэтому переключение алгоритма обратимо
 func New(cost int) authservice.PasswordHasher {
 	return &hasher{
 		cost: cost,
@@ -25,9 +37,32 @@ func (h *hasher) Gen(origin []byte) ([]byte, error) {
 }
 
 func (h *hasher) Compare(hash []byte, origin []byte) (bool, error) {
+	return compare(hash, origin)
+}
+
+func (h *hasher) NeedsRehash(hash []byte) bool {
+	if !isBcrypt(hash) {
+		return true
+	}
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost != h.cost
+}
+
+// compare определяет алгоритм по префиксу хэша
+func compare(hash, origin []byte) (bool, error) {
+	if isArgon2id(hash) {
+		return compareArgon2id(hash, origin)
+	}
+
 	if err := bcrypt.CompareHashAndPassword(hash, origin); err != nil {
 		return false, err
 	}
 
 	return true, nil
 }
+
+func isBcrypt(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$2a$")) ||
+		bytes.HasPrefix(hash, []byte("$2b$")) ||
+		bytes.HasPrefix(hash, []byte("$2y$"))
+}
@@ -0,0 +1,95 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// минимальные параметры, чтобы тесты не тратили 64 MiB на каждый хэш
+var testParams = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestArgon2id_GenCompare(t *testing.T) {
+	h := NewArgon2id(testParams)
+
+	hash, err := h.Gen([]byte("secret"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(hash), "$argon2id$v=19$m=64,t=1,p=1$"))
+
+	ok, err := h.Compare(hash, []byte("secret"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = h.Compare(hash, []byte("wrong"))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// соль случайная: одинаковые пароли дают разные хэши
+	again, err := h.Gen([]byte("secret"))
+	require.NoError(t, err)
+	require.NotEqual(t, hash, again)
+}
+
+func TestArgon2id_LongPasswordNotTruncated(t *testing.T) {
+	h := NewArgon2id(testParams)
+	long := []byte(strings.Repeat("a", 100))
+
+	hash, err := h.Gen(long)
+	require.NoError(t, err)
+
+	ok, err := h.Compare(hash, long[:72])
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestBcrypt_MaxBytes(t *testing.T) {
+	h := New(bcrypt.MinCost)
+
+	_, err := h.Gen([]byte(strings.Repeat("a", BcryptMaxBytes)))
+	require.NoError(t, err)
+
+	_, err = h.Gen([]byte(strings.Repeat("a", 100)))
+	require.ErrorIs(t, err, bcrypt.ErrPasswordTooLong)
+}
+
+func TestCompare_CrossFormat(t *testing.T) {
+	bc := New(bcrypt.MinCost)
+	ar := NewArgon2id(testParams)
+
+	bcHash, err := bc.Gen([]byte("secret"))
+	require.NoError(t, err)
+	arHash, err := ar.Gen([]byte("secret"))
+	require.NoError(t, err)
+
+	ok, err := ar.Compare(bcHash, []byte("secret"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = bc.Compare(arHash, []byte("secret"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = ar.Compare([]byte("$argon2id$v=19$broken"), []byte("secret"))
+	require.Error(t, err)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bc := New(bcrypt.MinCost)
+	ar := NewArgon2id(testParams)
+
+	bcHash, err := bc.Gen([]byte("secret"))
+	require.NoError(t, err)
+	arHash, err := ar.Gen([]byte("secret"))
+	require.NoError(t, err)
+
+	require.False(t, bc.NeedsRehash(bcHash))
+	require.True(t, New(bcrypt.MinCost+1).NeedsRehash(bcHash))
+	require.True(t, bc.NeedsRehash(arHash))
+
+	require.False(t, ar.NeedsRehash(arHash))
+	require.True(t, NewArgon2id(Argon2Params{Memory: 128, Iterations: 1, Parallelism: 1}).NeedsRehash(arHash))
+	require.True(t, ar.NeedsRehash(bcHash))
+	require.True(t, ar.NeedsRehash([]byte("garbage")))
+}
@@ -0,0 +1,85 @@
+package authservice
+
+import (
+	"context"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+// Introspect — RFC 7662: активен ли токен с учётом отзыва в хранилище, а не только подписи.
+// Неверный, истёкший или отозванный токен — не ошибка, а Active: false;
+// ошибка возвращается только при сбое хранилища
+func (s *Auth) Introspect(ctx context.Context, token string) (domain.Introspection, error) {
+	m, err := s.tokener.Verify([]byte(token))
+	if err != nil {
+		return domain.Introspection{}, nil
+	}
+
+	active, err := s.tokenActive(ctx, token, m)
+	if err != nil {
+		return domain.Introspection{}, errors.Wrap(err, ErrFailedCheckRevoked)
+	}
+	if !active {
+		return domain.Introspection{}, nil
+	}
+
+	// у машинного клиента ролей нет, зато он мог быть отключён после выдачи токена
+	if m.ClientID != "" {
+		client, err := s.r.GetMachineClient(ctx, m.ClientID)
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.Introspection{}, nil
+		}
+		if err != nil {
+			return domain.Introspection{}, errors.Wrap(err, ErrFailedGetMachineClient)
+		}
+		if client.Disabled() {
+			return domain.Introspection{}, nil
+		}
+		return domain.NewActiveIntrospection(m, nil), nil
+	}
+
+	roles, err := s.r.GetUserRoles(ctx, m.UserID)
+	if err != nil {
+		return domain.Introspection{}, errors.Wrap(err, ErrFailedGetRoles)
+	}
+
+	return domain.NewActiveIntrospection(m, roles), nil
+}
+
+// tokenActive — refresh жив, пока хранится его хэш (ротация и logout его удаляют);
+// access — пока его jti нет в denylist и жива его сессия (sid)
+func (s *Auth) tokenActive(ctx context.Context, token string, m domain.Meta) (bool, error) {
+	if m.Type == domain.TokenTypeRefresh {
+		hash, err := s.tokenHasher.Sum([]byte(token))
+		if err != nil {
+			return false, errors.Wrap(err, ErrFailedHashToken)
+		}
+		if m.Legacy() {
+			if active, err := s.legacyActive(ctx, m); err != nil || !active {
+				return false, err
+			}
+		}
+		return s.r.RefreshTokenExists(ctx, string(hash))
+	}
+
+	revoked, err := s.r.AccessTokenRevoked(ctx, m.ID)
+	if err != nil || revoked {
+		return false, err
+	}
+	// access-токены, выданные до появления sid, проверяются только по denylist
+	if m.FamilyID == "" {
+		return true, nil
+	}
+	return s.r.SessionExists(ctx, m.FamilyID)
+}
+
+// IsAccessTokenRevoked — для resource-серверов, которые проверяют подпись сами:
+// отозван ли access-токен с этим jti (Logout, отзыв сессии, LogoutAll, смена пароля, reuse)
+func (s *Auth) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	revoked, err := s.r.AccessTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, errors.Wrap(err, ErrFailedCheckDenylist)
+	}
+	return revoked, nil
+}
@@ -0,0 +1,314 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
+	"github.com/go-faster/errors"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// loginPlaceholder — место логина (email из Login) в user_dn_template и user_filter
+const loginPlaceholder = "{login}"
+
+const defaultTimeout = 5 * time.Second
+
+// searchSizeLimit — вызывающему нужно отличить «одна запись» от «несколько»
+const searchSizeLimit = 2
+
+// Config — каталог для BUSSINES_LOGIC_LDAP_CONFIG_PATH.
+// Два способа найти пользователя:
+// bind_dn задан — служебная учётка ищет запись по user_filter, затем bind под найденным DN с паролем пользователя;
+// иначе bind под user_dn_template (для AD подходит "{login}" — вход по userPrincipalName), запись читается уже от имени пользователя
+type Config struct {
+	URL            string `json:"url"`             // ldaps://host[:636] или ldap://host[:389]
+	StartTLS       bool   `json:"start_tls"`       // для ldap://
+	AllowPlaintext bool   `json:"allow_plaintext"` // ldap:// без TLS: пароль уходит открытым текстом, только для стендов
+	CAFile         string `json:"ca_file"`         // PEM; пусто — системные корневые сертификаты
+	TimeoutSeconds int    `json:"timeout_seconds"` // на всю аутентификацию, по умолчанию 5
+
+	BindDN         string `json:"bind_dn"`
+	BindPassword   string `json:"bind_password"`
+	UserDNTemplate string `json:"user_dn_template"`
+
+	BaseDN     string `json:"base_dn"`
+	UserFilter string `json:"user_filter"` // например (&(objectClass=person)(mail={login}))
+
+	IDAttribute    string `json:"id_attribute"`    // стабильный id записи (entryUUID, objectGUID); пусто — DN
+	GroupAttribute string `json:"group_attribute"` // DN групп пользователя, по умолчанию memberOf
+
+	// GroupRoles — DN группы (без учёта регистра и пробелов вокруг , и =) → роли SSO
+	GroupRoles map[string][]string `json:"group_roles"`
+}
+
+type directory struct {
+	cfg     Config
+	tls     *tls.Config
+	timeout time.Duration
+	roles   map[string][]string // нормализованный DN группы → роли
+	managed []string
+
+	// dial — соединение на одну аутентификацию; в тестах подменяется
+	dial func(deadline time.Time) (ldap.Client, error)
+}
+
+// NewFromPath читает конфигурацию каталога (json). Пустой путь — вход через каталог выключен
+func NewFromPath(path string) (authservice.DirectoryAuthenticator, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read ldap config")
+	}
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, errors.Wrap(err, "parse ldap config")
+	}
+
+	return New(cfg)
+}
+
+func New(cfg Config) (authservice.DirectoryAuthenticator, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, errors.Errorf("invalid ldap url %q", cfg.URL)
+	}
+
+	d := &directory{cfg: cfg, timeout: defaultTimeout, roles: map[string][]string{}}
+	d.dial = d.connect
+	switch u.Scheme {
+	case "ldaps":
+		if cfg.StartTLS {
+			return nil, errors.New("start_tls is for ldap:// urls")
+		}
+	case "ldap":
+		if !cfg.StartTLS && !cfg.AllowPlaintext {
+			return nil, errors.New("ldap:// without start_tls sends passwords in plaintext: set allow_plaintext to confirm")
+		}
+	default:
+		return nil, errors.Errorf("unsupported ldap url scheme %q", u.Scheme)
+	}
+
+	d.tls = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read ldap ca_file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("ldap ca_file has no certificates")
+		}
+		d.tls.RootCAs = pool
+	}
+	if cfg.TimeoutSeconds > 0 {
+		d.timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+
+	if (cfg.BindDN == "") == (cfg.UserDNTemplate == "") {
+		return nil, errors.New("exactly one of bind_dn and user_dn_template required")
+	}
+	if cfg.UserDNTemplate != "" && !strings.Contains(cfg.UserDNTemplate, loginPlaceholder) {
+		return nil, errors.Errorf("user_dn_template must contain %s", loginPlaceholder)
+	}
+	if cfg.BaseDN == "" {
+		return nil, errors.New("base_dn required")
+	}
+	if !strings.Contains(cfg.UserFilter, loginPlaceholder) {
+		return nil, errors.Errorf("user_filter must contain %s", loginPlaceholder)
+	}
+	if _, err := ldap.CompileFilter(d.userFilter("probe@example.com")); err != nil {
+		return nil, errors.Wrap(err, "user_filter")
+	}
+	if d.cfg.GroupAttribute == "" {
+		d.cfg.GroupAttribute = "memberOf"
+	}
+
+	for group, roles := range cfg.GroupRoles {
+		key := normalizeDN(group)
+		d.roles[key] = append(d.roles[key], roles...)
+		d.managed = append(d.managed, roles...)
+	}
+	slices.Sort(d.managed)
+	d.managed = slices.Compact(d.managed)
+
+	return d, nil
+}
+
+func (d *directory) ManagedRoles() []string {
+	return d.managed
+}
+
+func (d *directory) Authenticate(ctx context.Context, login, password string) (domain.DirectoryUser, error) {
+	// пустой пароль — unauthenticated bind (RFC 4513 5.1.2): сервер ответит успехом без проверки
+	if login == "" || password == "" {
+		return domain.DirectoryUser{}, errors.Wrap(domain.ErrValidation, "empty login or password")
+	}
+
+	// дедлайн — ближайший из ctx и timeout на весь обмен
+	deadline := time.Now().Add(d.timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	c, err := d.dial(deadline)
+	if err != nil {
+		return domain.DirectoryUser{}, err
+	}
+	defer c.Close()
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+
+	var e *ldap.Entry
+	if d.cfg.BindDN != "" {
+		e, err = d.searchThenBind(c, login, password)
+	} else {
+		e, err = d.bindThenRead(c, login, password)
+	}
+	if err != nil {
+		return domain.DirectoryUser{}, err
+	}
+
+	return domain.DirectoryUser{
+		DN:      e.DN,
+		Subject: d.subject(e),
+		Roles:   d.mapRoles(e),
+	}, nil
+}
+
+// connect: ldaps:// — сразу TLS, ldap:// + start_tls — TLS после StartTLS
+func (d *directory) connect(deadline time.Time) (ldap.Client, error) {
+	c, err := ldap.DialURL(d.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Deadline: deadline}),
+		ldap.DialWithTLSConfig(d.tls),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "ldap dial")
+	}
+	c.SetTimeout(time.Until(deadline))
+
+	if d.cfg.StartTLS {
+		if err := c.StartTLS(d.tls); err != nil {
+			c.Close()
+			return nil, errors.Wrap(err, "ldap StartTLS")
+		}
+	}
+	return c, nil
+}
+
+func (d *directory) searchThenBind(c ldap.Client, login, password string) (*ldap.Entry, error) {
+	if err := c.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+		return nil, errors.Wrap(err, "service account bind")
+	}
+	e, err := d.findUser(c, login)
+	if err != nil {
+		return nil, err
+	}
+	if err := userBind(c, e.DN, password); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (d *directory) bindThenRead(c ldap.Client, login, password string) (*ldap.Entry, error) {
+	dn := strings.ReplaceAll(d.cfg.UserDNTemplate, loginPlaceholder, ldap.EscapeDN(login))
+	if err := userBind(c, dn, password); err != nil {
+		return nil, err
+	}
+	e, err := d.findUser(c, login)
+	if errors.Is(err, domain.ErrValidation) {
+		// пароль верный, но своя запись не видна — ошибка настройки, а не клиента
+		return nil, errors.Errorf("user %q bound but not found by user_filter", login)
+	}
+	return e, err
+}
+
+// userBind — bind с паролем пользователя; неверный DN или пароль — ErrValidation
+func userBind(c ldap.Client, dn, password string) error {
+	err := c.Bind(dn, password)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return errors.Wrap(domain.ErrValidation, err.Error())
+	}
+	return err
+}
+
+func (d *directory) findUser(c ldap.Client, login string) (*ldap.Entry, error) {
+	attrs := []string{d.cfg.GroupAttribute}
+	if d.cfg.IDAttribute != "" {
+		attrs = append(attrs, d.cfg.IDAttribute)
+	}
+
+	res, err := c.Search(ldap.NewSearchRequest(
+		d.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		searchSizeLimit, int(d.timeout/time.Second), false,
+		d.userFilter(login), attrs, nil,
+	))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		err = nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "search user")
+	}
+	switch len(res.Entries) {
+	case 0:
+		return nil, errors.Wrap(domain.ErrValidation, "user not found in directory")
+	case 1:
+		return res.Entries[0], nil
+	default:
+		// вход под одной из нескольких записей — угадывание; user_filter должен быть однозначным
+		return nil, errors.Errorf("user_filter matched several entries for %q", login)
+	}
+}
+
+func (d *directory) userFilter(login string) string {
+	return strings.ReplaceAll(d.cfg.UserFilter, loginPlaceholder, ldap.EscapeFilter(login))
+}
+
+// subject — id_attribute; двоичные значения (objectGUID) в hex
+func (d *directory) subject(e *ldap.Entry) string {
+	if d.cfg.IDAttribute == "" {
+		return normalizeDN(e.DN)
+	}
+	v := e.GetEqualFoldRawAttributeValue(d.cfg.IDAttribute)
+	if len(v) == 0 {
+		return normalizeDN(e.DN)
+	}
+	if utf8.Valid(v) {
+		return string(v)
+	}
+	return hex.EncodeToString(v)
+}
+
+func (d *directory) mapRoles(e *ldap.Entry) []string {
+	var roles []string
+	for _, g := range e.GetEqualFoldAttributeValues(d.cfg.GroupAttribute) {
+		roles = append(roles, d.roles[normalizeDN(g)]...)
+	}
+	slices.Sort(roles)
+	return slices.Compact(roles)
+}
+
+// normalizeDN — для сравнения: нижний регистр, без пробелов вокруг , и =
+func normalizeDN(dn string) string {
+	rdns := strings.Split(dn, ",")
+	for i, rdn := range rdns {
+		if k, v, ok := strings.Cut(rdn, "="); ok {
+			rdn = strings.TrimSpace(k) + "=" + strings.TrimSpace(v)
+		}
+		rdns[i] = strings.ToLower(strings.TrimSpace(rdn))
+	}
+	return strings.Join(rdns, ",")
+}
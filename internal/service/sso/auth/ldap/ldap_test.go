@@ -0,0 +1,413 @@
+package ldap
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-faster/errors"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testBaseDN      = "dc=corp,dc=example"
+	testServiceDN   = "cn=sso,ou=services,dc=corp,dc=example"
+	testServicePass = "svc-pass"
+	testAdminsDN    = "CN=Admins, OU=Groups, DC=corp, DC=example"
+	testDevsDN      = "cn=devs,ou=groups,dc=corp,dc=example"
+)
+
+type fakeEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string // имена в нижнем регистре
+}
+
+// fakeDirectory — каталог в процессе: bind и search (фильтр вычисляется по записям)
+type fakeDirectory struct {
+	mu      sync.Mutex
+	entries []fakeEntry
+	filters []string // фильтры полученных search
+	binds   []string
+}
+
+func newFakeDirectory() *fakeDirectory {
+	twin := func(uid string) fakeEntry {
+		return fakeEntry{
+			dn:       "uid=" + uid + ",ou=people,dc=corp,dc=example",
+			password: "twin-pass",
+			attrs:    map[string][]string{"objectclass": {"person"}, "mail": {"twin@corp.example"}},
+		}
+	}
+
+	return &fakeDirectory{entries: []fakeEntry{
+		{dn: testServiceDN, password: testServicePass, attrs: map[string][]string{}},
+		{
+			dn:       "uid=alice,ou=people,dc=corp,dc=example",
+			password: "alice-pass",
+			attrs: map[string][]string{
+				"objectclass":       {"person"},
+				"mail":              {"alice@corp.example"},
+				"userprincipalname": {"alice@corp.example"},
+				"entryuuid":         {"5b0f1c9e-alice"},
+				"objectguid":        {"\x01\x02\xff\xfe"},
+				"memberof":          {"cn=admins,ou=groups,dc=corp,dc=example", testDevsDN, "cn=unmapped,dc=corp,dc=example"},
+			},
+		},
+		{
+			dn:       "uid=bob,ou=people,dc=corp,dc=example",
+			password: "bob-pass",
+			attrs: map[string][]string{
+				"objectclass":       {"person"},
+				"mail":              {"bob@corp.example"},
+				"userprincipalname": {"bob@corp.example"},
+			},
+		},
+		twin("twin1"), twin("twin2"), twin("twin3"),
+	}}
+}
+
+func (f *fakeDirectory) dial(time.Time) (ldap.Client, error) {
+	return &fakeConn{dir: f}, nil
+}
+
+// fakeConn — соединение с fakeDirectory; остальные методы ldap.Client не вызываются
+type fakeConn struct {
+	ldap.Client
+	dir   *fakeDirectory
+	bound *fakeEntry
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Bind(dn, password string) error {
+	c.dir.mu.Lock()
+	defer c.dir.mu.Unlock()
+	c.dir.binds = append(c.dir.binds, dn)
+
+	c.bound = c.dir.bind(dn, password)
+	if c.bound == nil {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("fake: invalid credentials"))
+	}
+	return nil
+}
+
+func (c *fakeConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	c.dir.mu.Lock()
+	defer c.dir.mu.Unlock()
+	c.dir.filters = append(c.dir.filters, req.Filter)
+
+	if c.bound == nil {
+		return nil, ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("fake: anonymous search"))
+	}
+	filter, err := ldap.CompileFilter(req.Filter)
+	if err != nil {
+		return nil, ldap.NewError(ldap.LDAPResultProtocolError, err)
+	}
+
+	res := &ldap.SearchResult{}
+	for _, e := range c.dir.entries {
+		if !match(filter, e) {
+			continue
+		}
+		if req.SizeLimit > 0 && len(res.Entries) == req.SizeLimit {
+			return res, ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("fake: size limit"))
+		}
+		attrs := map[string][]string{}
+		for _, a := range req.Attributes {
+			if vs := e.attrs[strings.ToLower(a)]; len(vs) > 0 {
+				attrs[a] = vs
+			}
+		}
+		res.Entries = append(res.Entries, ldap.NewEntry(e.dn, attrs))
+	}
+	return res, nil
+}
+
+// bind: DN записи (без учёта регистра) или userPrincipalName, как в AD
+func (f *fakeDirectory) bind(dn, password string) *fakeEntry {
+	for i, e := range f.entries {
+		upn := e.attrs["userprincipalname"]
+		if normalizeDN(e.dn) == normalizeDN(dn) || len(upn) > 0 && upn[0] == dn {
+			if e.password == password {
+				return &f.entries[i]
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// match вычисляет фильтр, разобранный ldap.CompileFilter; сравнение значений без учёта регистра
+func match(f *ber.Packet, e fakeEntry) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, k := range f.Children {
+			if !match(k, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, k := range f.Children {
+			if match(k, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !match(f.Children[0], e)
+	case ldap.FilterPresent:
+		return len(e.attrs[strings.ToLower(f.Data.String())]) > 0
+	case ldap.FilterEqualityMatch:
+		for _, v := range e.attrs[strings.ToLower(f.Children[0].Data.String())] {
+			if strings.EqualFold(v, f.Children[1].Data.String()) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterSubstrings:
+		for _, v := range e.attrs[strings.ToLower(f.Children[0].Data.String())] {
+			if matchSubstrings(strings.ToLower(v), f.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func matchSubstrings(v string, subs []*ber.Packet) bool {
+	for _, s := range subs {
+		p := strings.ToLower(s.Data.String())
+		switch s.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(v, p) {
+				return false
+			}
+			v = v[len(p):]
+		case ldap.FilterSubstringsFinal:
+			return strings.HasSuffix(v, p)
+		default:
+			i := strings.Index(v, p)
+			if i < 0 {
+				return false
+			}
+			v = v[i+len(p):]
+		}
+	}
+	return true
+}
+
+func (f *fakeDirectory) lastFilter() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.filters[len(f.filters)-1]
+}
+
+func searchConfig() Config {
+	return Config{
+		URL:            "ldap://dc.corp.example",
+		AllowPlaintext: true,
+		BindDN:         testServiceDN,
+		BindPassword:   testServicePass,
+		BaseDN:         testBaseDN,
+		UserFilter:     "(&(objectClass=person)(mail={login}))",
+		IDAttribute:    "entryUUID",
+		GroupRoles: map[string][]string{
+			testAdminsDN: {"admin", "auditor"},
+			testDevsDN:   {"developer", "auditor"},
+		},
+	}
+}
+
+func newDirectory(t *testing.T, f *fakeDirectory, cfg Config) *directory {
+	t.Helper()
+	d, err := New(cfg)
+	require.NoError(t, err)
+	d.(*directory).dial = f.dial
+	return d.(*directory)
+}
+
+func TestAuthenticate_SearchThenBind(t *testing.T) {
+	f := newFakeDirectory()
+	d := newDirectory(t, f, searchConfig())
+	ctx := context.Background()
+
+	t.Run("ok: группы сопоставлены ролям, регистр и пробелы в DN не важны", func(t *testing.T) {
+		u, err := d.Authenticate(ctx, "alice@corp.example", "alice-pass")
+		require.NoError(t, err)
+		require.Equal(t, domain.DirectoryUser{
+			DN:      "uid=alice,ou=people,dc=corp,dc=example",
+			Subject: "5b0f1c9e-alice",
+			Roles:   []string{"admin", "auditor", "developer"},
+		}, u)
+		require.Equal(t, []string{"admin", "auditor", "developer"}, d.ManagedRoles())
+	})
+
+	t.Run("ok: без id_attribute subject — нормализованный DN, без групп ролей нет", func(t *testing.T) {
+		u, err := d.Authenticate(ctx, "BOB@corp.example", "bob-pass")
+		require.NoError(t, err)
+		require.Equal(t, "uid=bob,ou=people,dc=corp,dc=example", u.Subject) // entryUUID у bob нет
+		require.Empty(t, u.Roles)
+	})
+
+	t.Run("двоичный id_attribute в hex", func(t *testing.T) {
+		cfg := searchConfig()
+		cfg.IDAttribute = "objectGUID"
+		u, err := newDirectory(t, f, cfg).Authenticate(ctx, "alice@corp.example", "alice-pass")
+		require.NoError(t, err)
+		require.Equal(t, "0102fffe", u.Subject)
+	})
+
+	t.Run("неверный пароль — ErrValidation", func(t *testing.T) {
+		_, err := d.Authenticate(ctx, "alice@corp.example", "wrong")
+		require.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("неизвестный пользователь — ErrValidation", func(t *testing.T) {
+		_, err := d.Authenticate(ctx, "nobody@corp.example", "x")
+		require.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("пустой пароль не уходит в каталог", func(t *testing.T) {
+		f.mu.Lock()
+		before := len(f.binds)
+		f.mu.Unlock()
+
+		_, err := d.Authenticate(ctx, "alice@corp.example", "")
+		require.ErrorIs(t, err, domain.ErrValidation)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		require.Len(t, f.binds, before)
+	})
+
+	t.Run("несколько записей — ошибка настройки, не ErrValidation", func(t *testing.T) {
+		_, err := d.Authenticate(ctx, "twin@corp.example", "twin-pass")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("логин с метасимволами фильтра экранируется", func(t *testing.T) {
+		_, err := d.Authenticate(ctx, "*)(mail=*", "alice-pass")
+		require.ErrorIs(t, err, domain.ErrValidation)
+
+		require.Equal(t, `(&(objectClass=person)(mail=\2a\29\28mail=\2a))`, f.lastFilter())
+	})
+
+	t.Run("неверный пароль служебной учётки — не ErrValidation", func(t *testing.T) {
+		cfg := searchConfig()
+		cfg.BindPassword = "wrong"
+		_, err := newDirectory(t, f, cfg).Authenticate(ctx, "alice@corp.example", "alice-pass")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("каталог недоступен", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		cfg := searchConfig()
+		cfg.URL = "ldap://" + ln.Addr().String()
+		ln.Close()
+
+		d, err := New(cfg) // настоящее соединение
+		require.NoError(t, err)
+		_, err = d.Authenticate(ctx, "alice@corp.example", "alice-pass")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, domain.ErrValidation)
+	})
+}
+
+func TestAuthenticate_BindThenRead(t *testing.T) {
+	f := newFakeDirectory()
+	cfg := searchConfig()
+	cfg.BindDN, cfg.BindPassword = "", ""
+	cfg.UserDNTemplate = "{login}"
+	cfg.UserFilter = "(userPrincipalName={login})"
+	d := newDirectory(t, f, cfg)
+	ctx := context.Background()
+
+	u, err := d.Authenticate(ctx, "alice@corp.example", "alice-pass")
+	require.NoError(t, err)
+	require.Equal(t, "5b0f1c9e-alice", u.Subject)
+	require.Equal(t, []string{"admin", "auditor", "developer"}, u.Roles)
+
+	f.mu.Lock()
+	require.Equal(t, []string{"alice@corp.example"}, f.binds) // служебной учётки нет
+	f.mu.Unlock()
+
+	_, err = d.Authenticate(ctx, "alice@corp.example", "wrong")
+	require.ErrorIs(t, err, domain.ErrValidation)
+
+	t.Run("логин экранируется в DN", func(t *testing.T) {
+		cfg := cfg
+		cfg.UserDNTemplate = "uid={login},ou=people,dc=corp,dc=example"
+		_, err := newDirectory(t, f, cfg).Authenticate(ctx, "alice,ou=people", "alice-pass")
+		require.ErrorIs(t, err, domain.ErrValidation)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		require.Equal(t, `uid=alice\,ou=people,ou=people,dc=corp,dc=example`, f.binds[len(f.binds)-1])
+	})
+}
+
+func TestNewFromPath(t *testing.T) {
+	d, err := NewFromPath("")
+	require.NoError(t, err)
+	require.Nil(t, d)
+
+	_, err = NewFromPath(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "ldap.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"url": "ldaps://dc.corp.example",
+		"bind_dn": "cn=sso,dc=corp,dc=example",
+		"bind_password": "x",
+		"base_dn": "dc=corp,dc=example",
+		"user_filter": "(mail={login})",
+		"group_roles": {"cn=admins,dc=corp,dc=example": ["admin"]}
+	}`), 0o600))
+	d, err = NewFromPath(path)
+	require.NoError(t, err)
+	require.Equal(t, "dc.corp.example", d.(*directory).tls.ServerName)
+	require.Equal(t, "memberOf", d.(*directory).cfg.GroupAttribute)
+
+	base := Config{
+		URL:        "ldaps://dc.corp.example:3269",
+		BindDN:     "cn=sso,dc=corp,dc=example",
+		BaseDN:     "dc=corp,dc=example",
+		UserFilter: "(mail={login})",
+	}
+	for name, mutate := range map[string]func(c *Config){
+		"plaintext без подтверждения": func(c *Config) { c.URL = "ldap://dc.corp.example" },
+		"start_tls с ldaps":           func(c *Config) { c.StartTLS = true },
+		"чужая схема":                 func(c *Config) { c.URL = "http://dc.corp.example" },
+		"оба способа bind":            func(c *Config) { c.UserDNTemplate = "{login}" },
+		"ни одного способа bind":      func(c *Config) { c.BindDN = "" },
+		"template без {login}":        func(c *Config) { c.BindDN, c.UserDNTemplate = "", "cn=x" },
+		"без base_dn":                 func(c *Config) { c.BaseDN = "" },
+		"filter без {login}":          func(c *Config) { c.UserFilter = "(mail=x)" },
+		"битый filter":                func(c *Config) { c.UserFilter = "(mail={login}" },
+		"нет ca_file":                 func(c *Config) { c.CAFile = filepath.Join(t.TempDir(), "ca.pem") },
+	} {
+		cfg := base
+		mutate(&cfg)
+		_, err := New(cfg)
+		require.Error(t, err, name)
+	}
+
+	cfg := base
+	cfg.URL, cfg.StartTLS = "ldap://dc.corp.example", true
+	_, err = New(cfg)
+	require.NoError(t, err)
+}
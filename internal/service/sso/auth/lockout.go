@@ -0,0 +1,70 @@
+package authservice
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+const (
+	ErrFailedCheckLock     = "failed check login lock"
+	ErrFailedRegisterFail  = "failed register login failure"
+	ErrFailedResetAttempts = "failed reset login failures"
+	emailAttemptKeyPrefix  = "email:"
+	ipAttemptKeyPrefix     = "ip:"
+)
+
+func emailAttemptKey(email string) string {
+	return emailAttemptKeyPrefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptKey(ip string) string {
+	return ipAttemptKeyPrefix + ip
+}
+
+// loginAttemptKeys — ключ email всегда, ключ ip — если транспорт определил адрес клиента
+func loginAttemptKeys(ctx context.Context, email string) []string {
+	keys := []string{emailAttemptKey(email)}
+	if ip := domain.ClientIP(ctx); ip != "" {
+		keys = append(keys, ipAttemptKey(ip))
+	}
+	return keys
+}
+
+func (s *Auth) policyFor(key string) domain.LockoutPolicy {
+	if strings.HasPrefix(key, ipAttemptKeyPrefix) {
+		return s.cfg.IPLockoutPolicy()
+	}
+	return s.cfg.EmailLockoutPolicy()
+}
+
+func (s *Auth) checkLoginLock(ctx context.Context, keys []string) error {
+	left, err := s.r.LoginLockedFor(ctx, keys)
+	if err != nil {
+		return errors.Wrap(err, ErrFailedCheckLock)
+	}
+	if left > 0 {
+		return &domain.LockoutError{RetryAfter: left}
+	}
+	return nil
+}
+
+// loginFailed учитывает неудачный вход по всем ключам; если сработал порог — LockoutError, иначе ErrValidation
+func (s *Auth) loginFailed(ctx context.Context, keys []string) error {
+	var lock time.Duration
+	for _, k := range keys {
+		d, err := s.r.RegisterLoginFailure(ctx, k, s.policyFor(k))
+		if err != nil {
+			return errors.Wrap(err, ErrFailedRegisterFail)
+		}
+		lock = max(lock, d)
+	}
+
+	if lock > 0 {
+		return &domain.LockoutError{RetryAfter: lock}
+	}
+	return errors.Wrap(domain.ErrValidation, ErrWrongCredentials)
+}
@@ -0,0 +1,109 @@
+package authservice
+
+import (
+	"context"
+	"slices"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+)
+
+const (
+	ErrFailedGetMachineClient  = "failed get machine client"
+	ErrFailedSaveMachineClient = "failed save machine client"
+	ErrFailedRotateSecret      = "failed rotate machine client secret"
+	ErrFailedDisableClient     = "failed disable machine client"
+	ErrFailedGenClientSecret   = "failed generate machine client secret"
+	ErrFailedClientCredentials = "failed issue client credentials token"
+	ErrInvalidScope            = "invalid scope"
+	errDescInvalidClient       = "client authentication failed"
+	errDescClientScope         = "scope is not allowed for client"
+)
+
+// CreateMachineClient — админская операция; секрет возвращается один раз, хранится только его хэш
+func (s *Auth) CreateMachineClient(ctx context.Context, name string, scopes []string) (domain.MachineClientSecret, error) {
+	for _, scope := range scopes {
+		if !domain.ValidScope(scope) {
+			return domain.MachineClientSecret{}, errors.Wrapf(domain.ErrValidation, "%s: %q", ErrInvalidScope, scope)
+		}
+	}
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	secret, hash, err := s.genOneTimeToken()
+	if err != nil {
+		return domain.MachineClientSecret{}, errors.Wrap(err, ErrFailedGenClientSecret)
+	}
+
+	c := domain.NewMachineClient(uuid.NewString(), name, []byte(hash), scopes)
+	if err := s.r.NewMachineClient(ctx, c); err != nil {
+		return domain.MachineClientSecret{}, errors.Wrap(err, ErrFailedSaveMachineClient)
+	}
+
+	return domain.MachineClientSecret{ClientID: c.ID, ClientSecret: secret}, nil
+}
+
+// RotateMachineClientSecret — прежний секрет перестаёт работать сразу; выданные токены живут до exp
+func (s *Auth) RotateMachineClientSecret(ctx context.Context, clientID string) (domain.MachineClientSecret, error) {
+	secret, hash, err := s.genOneTimeToken()
+	if err != nil {
+		return domain.MachineClientSecret{}, errors.Wrap(err, ErrFailedGenClientSecret)
+	}
+
+	if err := s.r.UpdateMachineClientSecret(ctx, clientID, []byte(hash)); err != nil {
+		return domain.MachineClientSecret{}, errors.Wrap(err, ErrFailedRotateSecret)
+	}
+
+	return domain.MachineClientSecret{ClientID: clientID, ClientSecret: secret}, nil
+}
+
+// DisableMachineClient — новые токены не выдаются, Introspect выданные считает неактивными
+func (s *Auth) DisableMachineClient(ctx context.Context, clientID string) error {
+	if err := s.r.DisableMachineClient(ctx, clientID); err != nil {
+		return errors.Wrap(err, ErrFailedDisableClient)
+	}
+	return nil
+}
+
+// ClientCredentialsToken — grant_type=client_credentials (RFC 6749, 4.4): только access, без refresh и сессии;
+// sub и client_id — id клиента. Неизвестный, отключённый клиент и неверный секрет неразличимы: invalid_client
+func (s *Auth) ClientCredentialsToken(ctx context.Context, clientID, secret string, scopes []string) (domain.OAuthToken, error) {
+	invalidClient := domain.NewOAuthError(domain.OAuthInvalidClient, errDescInvalidClient)
+	if _, err := uuid.Parse(clientID); err != nil || secret == "" {
+		return domain.OAuthToken{}, invalidClient
+	}
+
+	client, err := s.r.GetMachineClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.OAuthToken{}, invalidClient
+		}
+		return domain.OAuthToken{}, errors.Wrap(err, ErrFailedGetMachineClient)
+	}
+	hash, err := s.tokenHasher.Sum([]byte(secret))
+	if err != nil {
+		return domain.OAuthToken{}, errors.Wrap(err, ErrFailedHashToken)
+	}
+	if !client.SecretMatches(hash) || client.Disabled() {
+		return domain.OAuthToken{}, invalidClient
+	}
+
+	granted, ok := client.GrantScopes(scopes)
+	if !ok {
+		return domain.OAuthToken{}, domain.NewOAuthError(domain.OAuthInvalidScope, errDescClientScope)
+	}
+
+	am := domain.NewClientAccessMeta(s.cfg.AccessTokenTTL, client.ID, granted)
+	access, err := s.tokener.GenAccess(am)
+	if err != nil {
+		return domain.OAuthToken{}, errors.Wrap(err, ErrFailedClientCredentials)
+	}
+
+	return domain.OAuthToken{
+		Token:     domain.Token{Access: string(access)},
+		ExpiresIn: s.cfg.AccessTokenTTL,
+		Scopes:    granted,
+	}, nil
+}
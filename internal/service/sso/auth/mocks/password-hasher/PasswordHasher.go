@@ -2,7 +2,9 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	mock "github.com/stretchr/testify/mock"
+)
 
 // PasswordHasher is an autogenerated mock type for the PasswordHasher type
 type PasswordHasher struct {
@@ -132,6 +134,52 @@ func (_c *PasswordHasher_Gen_Call) RunAndReturn(run func([]byte) ([]byte, error)
 	return _c
 }
 
+// NeedsRehash provides a mock function with given fields: hash
+func (_m *PasswordHasher) NeedsRehash(hash []byte) bool {
+	ret := _m.Called(hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NeedsRehash")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func([]byte) bool); ok {
+		r0 = rf(hash)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// PasswordHasher_NeedsRehash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NeedsRehash'
+type PasswordHasher_NeedsRehash_Call struct {
+	*mock.Call
+}
+
+// NeedsRehash is a helper method to define mock.On call
+//   - hash []byte
+func (_e *PasswordHasher_Expecter) NeedsRehash(hash interface{}) *PasswordHasher_NeedsRehash_Call {
+	return &PasswordHasher_NeedsRehash_Call{Call: _e.mock.On("NeedsRehash", hash)}
+}
+
+func (_c *PasswordHasher_NeedsRehash_Call) Run(run func(hash []byte)) *PasswordHasher_NeedsRehash_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]byte))
+	})
+	return _c
+}
+
+func (_c *PasswordHasher_NeedsRehash_Call) Return(_a0 bool) *PasswordHasher_NeedsRehash_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *PasswordHasher_NeedsRehash_Call) RunAndReturn(run func([]byte) bool) *PasswordHasher_NeedsRehash_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewPasswordHasher creates a new instance of PasswordHasher. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewPasswordHasher(t interface {
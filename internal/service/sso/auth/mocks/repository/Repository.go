@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	domain "github.com/eragon-mdi/sso/internal/domain"
 	mock "github.com/stretchr/testify/mock"
@@ -22,27 +23,2049 @@ func (_m *Repository) EXPECT() *Repository_Expecter {
 	return &Repository_Expecter{mock: &_m.Mock}
 }
 
+// AccessTokenRevoked provides a mock function with given fields: _a0, jti
+func (_m *Repository) AccessTokenRevoked(_a0 context.Context, jti string) (bool, error) {
+	ret := _m.Called(_a0, jti)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AccessTokenRevoked")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(_a0, jti)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(_a0, jti)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, jti)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_AccessTokenRevoked_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AccessTokenRevoked'
+type Repository_AccessTokenRevoked_Call struct {
+	*mock.Call
+}
+
+// AccessTokenRevoked is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - jti string
+func (_e *Repository_Expecter) AccessTokenRevoked(_a0 interface{}, jti interface{}) *Repository_AccessTokenRevoked_Call {
+	return &Repository_AccessTokenRevoked_Call{Call: _e.mock.On("AccessTokenRevoked", _a0, jti)}
+}
+
+func (_c *Repository_AccessTokenRevoked_Call) Run(run func(_a0 context.Context, jti string)) *Repository_AccessTokenRevoked_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_AccessTokenRevoked_Call) Return(_a0 bool, _a1 error) *Repository_AccessTokenRevoked_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_AccessTokenRevoked_Call) RunAndReturn(run func(context.Context, string) (bool, error)) *Repository_AccessTokenRevoked_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ConfirmTotp provides a mock function with given fields: _a0, userID, step
+func (_m *Repository) ConfirmTotp(_a0 context.Context, userID string, step int64) error {
+	ret := _m.Called(_a0, userID, step)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTotp")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(_a0, userID, step)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_ConfirmTotp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ConfirmTotp'
+type Repository_ConfirmTotp_Call struct {
+	*mock.Call
+}
+
+// ConfirmTotp is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+//   - step int64
+func (_e *Repository_Expecter) ConfirmTotp(_a0 interface{}, userID interface{}, step interface{}) *Repository_ConfirmTotp_Call {
+	return &Repository_ConfirmTotp_Call{Call: _e.mock.On("ConfirmTotp", _a0, userID, step)}
+}
+
+func (_c *Repository_ConfirmTotp_Call) Run(run func(_a0 context.Context, userID string, step int64)) *Repository_ConfirmTotp_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(int64))
+	})
+	return _c
+}
+
+func (_c *Repository_ConfirmTotp_Call) Return(_a0 error) *Repository_ConfirmTotp_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_ConfirmTotp_Call) RunAndReturn(run func(context.Context, string, int64) error) *Repository_ConfirmTotp_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ConsumeAuthorizationCode provides a mock function with given fields: _a0, hash
+func (_m *Repository) ConsumeAuthorizationCode(_a0 context.Context, hash string) (domain.AuthorizationCode, error) {
+	ret := _m.Called(_a0, hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConsumeAuthorizationCode")
+	}
+
+	var r0 domain.AuthorizationCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.AuthorizationCode, error)); ok {
+		return rf(_a0, hash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.AuthorizationCode); ok {
+		r0 = rf(_a0, hash)
+	} else {
+		r0 = ret.Get(0).(domain.AuthorizationCode)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_ConsumeAuthorizationCode_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ConsumeAuthorizationCode'
+type Repository_ConsumeAuthorizationCode_Call struct {
+	*mock.Call
+}
+
+// ConsumeAuthorizationCode is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - hash string
+func (_e *Repository_Expecter) ConsumeAuthorizationCode(_a0 interface{}, hash interface{}) *Repository_ConsumeAuthorizationCode_Call {
+	return &Repository_ConsumeAuthorizationCode_Call{Call: _e.mock.On("ConsumeAuthorizationCode", _a0, hash)}
+}
+
+func (_c *Repository_ConsumeAuthorizationCode_Call) Run(run func(_a0 context.Context, hash string)) *Repository_ConsumeAuthorizationCode_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_ConsumeAuthorizationCode_Call) Return(_a0 domain.AuthorizationCode, _a1 error) *Repository_ConsumeAuthorizationCode_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_ConsumeAuthorizationCode_Call) RunAndReturn(run func(context.Context, string) (domain.AuthorizationCode, error)) *Repository_ConsumeAuthorizationCode_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ConsumeFederationState provides a mock function with given fields: _a0, hash
+func (_m *Repository) ConsumeFederationState(_a0 context.Context, hash string) (domain.FederationState, error) {
+	ret := _m.Called(_a0, hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConsumeFederationState")
+	}
+
+	var r0 domain.FederationState
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.FederationState, error)); ok {
+		return rf(_a0, hash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.FederationState); ok {
+		r0 = rf(_a0, hash)
+	} else {
+		r0 = ret.Get(0).(domain.FederationState)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_ConsumeFederationState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ConsumeFederationState'
+type Repository_ConsumeFederationState_Call struct {
+	*mock.Call
+}
+
+// ConsumeFederationState is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - hash string
+func (_e *Repository_Expecter) ConsumeFederationState(_a0 interface{}, hash interface{}) *Repository_ConsumeFederationState_Call {
+	return &Repository_ConsumeFederationState_Call{Call: _e.mock.On("ConsumeFederationState", _a0, hash)}
+}
+
+func (_c *Repository_ConsumeFederationState_Call) Run(run func(_a0 context.Context, hash string)) *Repository_ConsumeFederationState_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_ConsumeFederationState_Call) Return(_a0 domain.FederationState, _a1 error) *Repository_ConsumeFederationState_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_ConsumeFederationState_Call) RunAndReturn(run func(context.Context, string) (domain.FederationState, error)) *Repository_ConsumeFederationState_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ConsumeOneTimeToken provides a mock function with given fields: _a0, purpose, hash
+func (_m *Repository) ConsumeOneTimeToken(_a0 context.Context, purpose domain.OneTimePurpose, hash string) (domain.OneTimeToken, error) {
+	ret := _m.Called(_a0, purpose, hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConsumeOneTimeToken")
+	}
+
+	var r0 domain.OneTimeToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.OneTimePurpose, string) (domain.OneTimeToken, error)); ok {
+		return rf(_a0, purpose, hash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.OneTimePurpose, string) domain.OneTimeToken); ok {
+		r0 = rf(_a0, purpose, hash)
+	} else {
+		r0 = ret.Get(0).(domain.OneTimeToken)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.OneTimePurpose, string) error); ok {
+		r1 = rf(_a0, purpose, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_ConsumeOneTimeToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ConsumeOneTimeToken'
+type Repository_ConsumeOneTimeToken_Call struct {
+	*mock.Call
+}
+
+// ConsumeOneTimeToken is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - purpose domain.OneTimePurpose
+//   - hash string
+func (_e *Repository_Expecter) ConsumeOneTimeToken(_a0 interface{}, purpose interface{}, hash interface{}) *Repository_ConsumeOneTimeToken_Call {
+	return &Repository_ConsumeOneTimeToken_Call{Call: _e.mock.On("ConsumeOneTimeToken", _a0, purpose, hash)}
+}
+
+func (_c *Repository_ConsumeOneTimeToken_Call) Run(run func(_a0 context.Context, purpose domain.OneTimePurpose, hash string)) *Repository_ConsumeOneTimeToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.OneTimePurpose), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_ConsumeOneTimeToken_Call) Return(_a0 domain.OneTimeToken, _a1 error) *Repository_ConsumeOneTimeToken_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_ConsumeOneTimeToken_Call) RunAndReturn(run func(context.Context, domain.OneTimePurpose, string) (domain.OneTimeToken, error)) *Repository_ConsumeOneTimeToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ConsumePasswordlessChallenge provides a mock function with given fields: _a0, hash, codeHash
+func (_m *Repository) ConsumePasswordlessChallenge(_a0 context.Context, hash string, codeHash string) (domain.PasswordlessChallenge, error) {
+	ret := _m.Called(_a0, hash, codeHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConsumePasswordlessChallenge")
+	}
+
+	var r0 domain.PasswordlessChallenge
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (domain.PasswordlessChallenge, error)); ok {
+		return rf(_a0, hash, codeHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) domain.PasswordlessChallenge); ok {
+		r0 = rf(_a0, hash, codeHash)
+	} else {
+		r0 = ret.Get(0).(domain.PasswordlessChallenge)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, hash, codeHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_ConsumePasswordlessChallenge_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ConsumePasswordlessChallenge'
+type Repository_ConsumePasswordlessChallenge_Call struct {
+	*mock.Call
+}
+
+// ConsumePasswordlessChallenge is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - hash string
+//   - codeHash string
+func (_e *Repository_Expecter) ConsumePasswordlessChallenge(_a0 interface{}, hash interface{}, codeHash interface{}) *Repository_ConsumePasswordlessChallenge_Call {
+	return &Repository_ConsumePasswordlessChallenge_Call{Call: _e.mock.On("ConsumePasswordlessChallenge", _a0, hash, codeHash)}
+}
+
+func (_c *Repository_ConsumePasswordlessChallenge_Call) Run(run func(_a0 context.Context, hash string, codeHash string)) *Repository_ConsumePasswordlessChallenge_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_ConsumePasswordlessChallenge_Call) Return(_a0 domain.PasswordlessChallenge, _a1 error) *Repository_ConsumePasswordlessChallenge_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_ConsumePasswordlessChallenge_Call) RunAndReturn(run func(context.Context, string, string) (domain.PasswordlessChallenge, error)) *Repository_ConsumePasswordlessChallenge_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DisableMachineClient provides a mock function with given fields: _a0, clientID
+func (_m *Repository) DisableMachineClient(_a0 context.Context, clientID string) error {
+	ret := _m.Called(_a0, clientID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DisableMachineClient")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, clientID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_DisableMachineClient_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DisableMachineClient'
+type Repository_DisableMachineClient_Call struct {
+	*mock.Call
+}
+
+// DisableMachineClient is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - clientID string
+func (_e *Repository_Expecter) DisableMachineClient(_a0 interface{}, clientID interface{}) *Repository_DisableMachineClient_Call {
+	return &Repository_DisableMachineClient_Call{Call: _e.mock.On("DisableMachineClient", _a0, clientID)}
+}
+
+func (_c *Repository_DisableMachineClient_Call) Run(run func(_a0 context.Context, clientID string)) *Repository_DisableMachineClient_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_DisableMachineClient_Call) Return(_a0 error) *Repository_DisableMachineClient_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_DisableMachineClient_Call) RunAndReturn(run func(context.Context, string) error) *Repository_DisableMachineClient_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetApp provides a mock function with given fields: _a0, appID
+func (_m *Repository) GetApp(_a0 context.Context, appID int32) (domain.App, error) {
+	ret := _m.Called(_a0, appID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetApp")
+	}
+
+	var r0 domain.App
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32) (domain.App, error)); ok {
+		return rf(_a0, appID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32) domain.App); ok {
+		r0 = rf(_a0, appID)
+	} else {
+		r0 = ret.Get(0).(domain.App)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32) error); ok {
+		r1 = rf(_a0, appID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_GetApp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetApp'
+type Repository_GetApp_Call struct {
+	*mock.Call
+}
+
+// GetApp is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - appID int32
+func (_e *Repository_Expecter) GetApp(_a0 interface{}, appID interface{}) *Repository_GetApp_Call {
+	return &Repository_GetApp_Call{Call: _e.mock.On("GetApp", _a0, appID)}
+}
+
+func (_c *Repository_GetApp_Call) Run(run func(_a0 context.Context, appID int32)) *Repository_GetApp_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int32))
+	})
+	return _c
+}
+
+func (_c *Repository_GetApp_Call) Return(_a0 domain.App, _a1 error) *Repository_GetApp_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_GetApp_Call) RunAndReturn(run func(context.Context, int32) (domain.App, error)) *Repository_GetApp_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetIdentity provides a mock function with given fields: _a0, provider, subject
+func (_m *Repository) GetIdentity(_a0 context.Context, provider string, subject string) (domain.Identity, error) {
+	ret := _m.Called(_a0, provider, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetIdentity")
+	}
+
+	var r0 domain.Identity
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (domain.Identity, error)); ok {
+		return rf(_a0, provider, subject)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) domain.Identity); ok {
+		r0 = rf(_a0, provider, subject)
+	} else {
+		r0 = ret.Get(0).(domain.Identity)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, provider, subject)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_GetIdentity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetIdentity'
+type Repository_GetIdentity_Call struct {
+	*mock.Call
+}
+
+// GetIdentity is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - provider string
+//   - subject string
+func (_e *Repository_Expecter) GetIdentity(_a0 interface{}, provider interface{}, subject interface{}) *Repository_GetIdentity_Call {
+	return &Repository_GetIdentity_Call{Call: _e.mock.On("GetIdentity", _a0, provider, subject)}
+}
+
+func (_c *Repository_GetIdentity_Call) Run(run func(_a0 context.Context, provider string, subject string)) *Repository_GetIdentity_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_GetIdentity_Call) Return(_a0 domain.Identity, _a1 error) *Repository_GetIdentity_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_GetIdentity_Call) RunAndReturn(run func(context.Context, string, string) (domain.Identity, error)) *Repository_GetIdentity_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetMachineClient provides a mock function with given fields: _a0, clientID
+func (_m *Repository) GetMachineClient(_a0 context.Context, clientID string) (domain.MachineClient, error) {
+	ret := _m.Called(_a0, clientID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMachineClient")
+	}
+
+	var r0 domain.MachineClient
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.MachineClient, error)); ok {
+		return rf(_a0, clientID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.MachineClient); ok {
+		r0 = rf(_a0, clientID)
+	} else {
+		r0 = ret.Get(0).(domain.MachineClient)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, clientID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_GetMachineClient_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMachineClient'
+type Repository_GetMachineClient_Call struct {
+	*mock.Call
+}
+
+// GetMachineClient is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - clientID string
+func (_e *Repository_Expecter) GetMachineClient(_a0 interface{}, clientID interface{}) *Repository_GetMachineClient_Call {
+	return &Repository_GetMachineClient_Call{Call: _e.mock.On("GetMachineClient", _a0, clientID)}
+}
+
+func (_c *Repository_GetMachineClient_Call) Run(run func(_a0 context.Context, clientID string)) *Repository_GetMachineClient_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_GetMachineClient_Call) Return(_a0 domain.MachineClient, _a1 error) *Repository_GetMachineClient_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_GetMachineClient_Call) RunAndReturn(run func(context.Context, string) (domain.MachineClient, error)) *Repository_GetMachineClient_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetPasswordHistory provides a mock function with given fields: _a0, userID, limit
+func (_m *Repository) GetPasswordHistory(_a0 context.Context, userID string, limit int) ([]string, error) {
+	ret := _m.Called(_a0, userID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPasswordHistory")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]string, error)); ok {
+		return rf(_a0, userID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []string); ok {
+		r0 = rf(_a0, userID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(_a0, userID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_GetPasswordHistory_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetPasswordHistory'
+type Repository_GetPasswordHistory_Call struct {
+	*mock.Call
+}
+
+// GetPasswordHistory is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+//   - limit int
+func (_e *Repository_Expecter) GetPasswordHistory(_a0 interface{}, userID interface{}, limit interface{}) *Repository_GetPasswordHistory_Call {
+	return &Repository_GetPasswordHistory_Call{Call: _e.mock.On("GetPasswordHistory", _a0, userID, limit)}
+}
+
+func (_c *Repository_GetPasswordHistory_Call) Run(run func(_a0 context.Context, userID string, limit int)) *Repository_GetPasswordHistory_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *Repository_GetPasswordHistory_Call) Return(_a0 []string, _a1 error) *Repository_GetPasswordHistory_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_GetPasswordHistory_Call) RunAndReturn(run func(context.Context, string, int) ([]string, error)) *Repository_GetPasswordHistory_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetUserInfoByEmail provides a mock function with given fields: _a0, _a1
 func (_m *Repository) GetUserInfoByEmail(_a0 context.Context, _a1 string) (domain.User, error) {
 	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetUserInfoByEmail")
+		panic("no return value specified for GetUserInfoByEmail")
+	}
+
+	var r0 domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.User, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.User); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(domain.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_GetUserInfoByEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUserInfoByEmail'
+type Repository_GetUserInfoByEmail_Call struct {
+	*mock.Call
+}
+
+// GetUserInfoByEmail is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 string
+func (_e *Repository_Expecter) GetUserInfoByEmail(_a0 interface{}, _a1 interface{}) *Repository_GetUserInfoByEmail_Call {
+	return &Repository_GetUserInfoByEmail_Call{Call: _e.mock.On("GetUserInfoByEmail", _a0, _a1)}
+}
+
+func (_c *Repository_GetUserInfoByEmail_Call) Run(run func(_a0 context.Context, _a1 string)) *Repository_GetUserInfoByEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_GetUserInfoByEmail_Call) Return(_a0 domain.User, _a1 error) *Repository_GetUserInfoByEmail_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_GetUserInfoByEmail_Call) RunAndReturn(run func(context.Context, string) (domain.User, error)) *Repository_GetUserInfoByEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUserInfoByID provides a mock function with given fields: _a0, _a1
+func (_m *Repository) GetUserInfoByID(_a0 context.Context, _a1 string) (domain.User, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserInfoByID")
+	}
+
+	var r0 domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.User, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.User); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(domain.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_GetUserInfoByID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUserInfoByID'
+type Repository_GetUserInfoByID_Call struct {
+	*mock.Call
+}
+
+// GetUserInfoByID is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 string
+func (_e *Repository_Expecter) GetUserInfoByID(_a0 interface{}, _a1 interface{}) *Repository_GetUserInfoByID_Call {
+	return &Repository_GetUserInfoByID_Call{Call: _e.mock.On("GetUserInfoByID", _a0, _a1)}
+}
+
+func (_c *Repository_GetUserInfoByID_Call) Run(run func(_a0 context.Context, _a1 string)) *Repository_GetUserInfoByID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_GetUserInfoByID_Call) Return(_a0 domain.User, _a1 error) *Repository_GetUserInfoByID_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_GetUserInfoByID_Call) RunAndReturn(run func(context.Context, string) (domain.User, error)) *Repository_GetUserInfoByID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUserMfa provides a mock function with given fields: _a0, userID
+func (_m *Repository) GetUserMfa(_a0 context.Context, userID string) (domain.UserMfa, error) {
+	ret := _m.Called(_a0, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserMfa")
+	}
+
+	var r0 domain.UserMfa
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.UserMfa, error)); ok {
+		return rf(_a0, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.UserMfa); ok {
+		r0 = rf(_a0, userID)
+	} else {
+		r0 = ret.Get(0).(domain.UserMfa)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_GetUserMfa_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUserMfa'
+type Repository_GetUserMfa_Call struct {
+	*mock.Call
+}
+
+// GetUserMfa is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+func (_e *Repository_Expecter) GetUserMfa(_a0 interface{}, userID interface{}) *Repository_GetUserMfa_Call {
+	return &Repository_GetUserMfa_Call{Call: _e.mock.On("GetUserMfa", _a0, userID)}
+}
+
+func (_c *Repository_GetUserMfa_Call) Run(run func(_a0 context.Context, userID string)) *Repository_GetUserMfa_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_GetUserMfa_Call) Return(_a0 domain.UserMfa, _a1 error) *Repository_GetUserMfa_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_GetUserMfa_Call) RunAndReturn(run func(context.Context, string) (domain.UserMfa, error)) *Repository_GetUserMfa_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUserRoles provides a mock function with given fields: _a0, userID
+func (_m *Repository) GetUserRoles(_a0 context.Context, userID string) ([]string, error) {
+	ret := _m.Called(_a0, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserRoles")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]string, error)); ok {
+		return rf(_a0, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []string); ok {
+		r0 = rf(_a0, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_GetUserRoles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUserRoles'
+type Repository_GetUserRoles_Call struct {
+	*mock.Call
+}
+
+// GetUserRoles is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+func (_e *Repository_Expecter) GetUserRoles(_a0 interface{}, userID interface{}) *Repository_GetUserRoles_Call {
+	return &Repository_GetUserRoles_Call{Call: _e.mock.On("GetUserRoles", _a0, userID)}
+}
+
+func (_c *Repository_GetUserRoles_Call) Run(run func(_a0 context.Context, userID string)) *Repository_GetUserRoles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_GetUserRoles_Call) Return(_a0 []string, _a1 error) *Repository_GetUserRoles_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_GetUserRoles_Call) RunAndReturn(run func(context.Context, string) ([]string, error)) *Repository_GetUserRoles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LegacyTokensRevoked provides a mock function with given fields: _a0, userID
+func (_m *Repository) LegacyTokensRevoked(_a0 context.Context, userID string) (bool, error) {
+	ret := _m.Called(_a0, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LegacyTokensRevoked")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(_a0, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(_a0, userID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_LegacyTokensRevoked_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LegacyTokensRevoked'
+type Repository_LegacyTokensRevoked_Call struct {
+	*mock.Call
+}
+
+// LegacyTokensRevoked is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+func (_e *Repository_Expecter) LegacyTokensRevoked(_a0 interface{}, userID interface{}) *Repository_LegacyTokensRevoked_Call {
+	return &Repository_LegacyTokensRevoked_Call{Call: _e.mock.On("LegacyTokensRevoked", _a0, userID)}
+}
+
+func (_c *Repository_LegacyTokensRevoked_Call) Run(run func(_a0 context.Context, userID string)) *Repository_LegacyTokensRevoked_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_LegacyTokensRevoked_Call) Return(_a0 bool, _a1 error) *Repository_LegacyTokensRevoked_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_LegacyTokensRevoked_Call) RunAndReturn(run func(context.Context, string) (bool, error)) *Repository_LegacyTokensRevoked_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListApps provides a mock function with given fields: _a0
+func (_m *Repository) ListApps(_a0 context.Context) ([]domain.App, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListApps")
+	}
+
+	var r0 []domain.App
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]domain.App, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []domain.App); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.App)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_ListApps_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListApps'
+type Repository_ListApps_Call struct {
+	*mock.Call
+}
+
+// ListApps is a helper method to define mock.On call
+//   - _a0 context.Context
+func (_e *Repository_Expecter) ListApps(_a0 interface{}) *Repository_ListApps_Call {
+	return &Repository_ListApps_Call{Call: _e.mock.On("ListApps", _a0)}
+}
+
+func (_c *Repository_ListApps_Call) Run(run func(_a0 context.Context)) *Repository_ListApps_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Repository_ListApps_Call) Return(_a0 []domain.App, _a1 error) *Repository_ListApps_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_ListApps_Call) RunAndReturn(run func(context.Context) ([]domain.App, error)) *Repository_ListApps_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LoginLockedFor provides a mock function with given fields: _a0, keys
+func (_m *Repository) LoginLockedFor(_a0 context.Context, keys []string) (time.Duration, error) {
+	ret := _m.Called(_a0, keys)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LoginLockedFor")
+	}
+
+	var r0 time.Duration
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (time.Duration, error)); ok {
+		return rf(_a0, keys)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) time.Duration); ok {
+		r0 = rf(_a0, keys)
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(_a0, keys)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_LoginLockedFor_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LoginLockedFor'
+type Repository_LoginLockedFor_Call struct {
+	*mock.Call
+}
+
+// LoginLockedFor is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - keys []string
+func (_e *Repository_Expecter) LoginLockedFor(_a0 interface{}, keys interface{}) *Repository_LoginLockedFor_Call {
+	return &Repository_LoginLockedFor_Call{Call: _e.mock.On("LoginLockedFor", _a0, keys)}
+}
+
+func (_c *Repository_LoginLockedFor_Call) Run(run func(_a0 context.Context, keys []string)) *Repository_LoginLockedFor_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *Repository_LoginLockedFor_Call) Return(_a0 time.Duration, _a1 error) *Repository_LoginLockedFor_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_LoginLockedFor_Call) RunAndReturn(run func(context.Context, []string) (time.Duration, error)) *Repository_LoginLockedFor_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MarkEmailVerified provides a mock function with given fields: _a0, userID
+func (_m *Repository) MarkEmailVerified(_a0 context.Context, userID string) error {
+	ret := _m.Called(_a0, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkEmailVerified")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_MarkEmailVerified_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MarkEmailVerified'
+type Repository_MarkEmailVerified_Call struct {
+	*mock.Call
+}
+
+// MarkEmailVerified is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+func (_e *Repository_Expecter) MarkEmailVerified(_a0 interface{}, userID interface{}) *Repository_MarkEmailVerified_Call {
+	return &Repository_MarkEmailVerified_Call{Call: _e.mock.On("MarkEmailVerified", _a0, userID)}
+}
+
+func (_c *Repository_MarkEmailVerified_Call) Run(run func(_a0 context.Context, userID string)) *Repository_MarkEmailVerified_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_MarkEmailVerified_Call) Return(_a0 error) *Repository_MarkEmailVerified_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_MarkEmailVerified_Call) RunAndReturn(run func(context.Context, string) error) *Repository_MarkEmailVerified_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewApp provides a mock function with given fields: _a0, _a1
+func (_m *Repository) NewApp(_a0 context.Context, _a1 domain.App) (domain.App, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NewApp")
+	}
+
+	var r0 domain.App
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.App) (domain.App, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.App) domain.App); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(domain.App)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.App) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_NewApp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NewApp'
+type Repository_NewApp_Call struct {
+	*mock.Call
+}
+
+// NewApp is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.App
+func (_e *Repository_Expecter) NewApp(_a0 interface{}, _a1 interface{}) *Repository_NewApp_Call {
+	return &Repository_NewApp_Call{Call: _e.mock.On("NewApp", _a0, _a1)}
+}
+
+func (_c *Repository_NewApp_Call) Run(run func(_a0 context.Context, _a1 domain.App)) *Repository_NewApp_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.App))
+	})
+	return _c
+}
+
+func (_c *Repository_NewApp_Call) Return(_a0 domain.App, _a1 error) *Repository_NewApp_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_NewApp_Call) RunAndReturn(run func(context.Context, domain.App) (domain.App, error)) *Repository_NewApp_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewDevice provides a mock function with given fields: _a0, _a1
+func (_m *Repository) NewDevice(_a0 context.Context, _a1 domain.Device) (domain.Device, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NewDevice")
+	}
+
+	var r0 domain.Device
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Device) (domain.Device, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Device) domain.Device); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(domain.Device)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Device) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_NewDevice_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NewDevice'
+type Repository_NewDevice_Call struct {
+	*mock.Call
+}
+
+// NewDevice is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.Device
+func (_e *Repository_Expecter) NewDevice(_a0 interface{}, _a1 interface{}) *Repository_NewDevice_Call {
+	return &Repository_NewDevice_Call{Call: _e.mock.On("NewDevice", _a0, _a1)}
+}
+
+func (_c *Repository_NewDevice_Call) Run(run func(_a0 context.Context, _a1 domain.Device)) *Repository_NewDevice_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.Device))
+	})
+	return _c
+}
+
+func (_c *Repository_NewDevice_Call) Return(_a0 domain.Device, _a1 error) *Repository_NewDevice_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_NewDevice_Call) RunAndReturn(run func(context.Context, domain.Device) (domain.Device, error)) *Repository_NewDevice_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewIdentity provides a mock function with given fields: _a0, _a1
+func (_m *Repository) NewIdentity(_a0 context.Context, _a1 domain.Identity) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NewIdentity")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Identity) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_NewIdentity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NewIdentity'
+type Repository_NewIdentity_Call struct {
+	*mock.Call
+}
+
+// NewIdentity is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.Identity
+func (_e *Repository_Expecter) NewIdentity(_a0 interface{}, _a1 interface{}) *Repository_NewIdentity_Call {
+	return &Repository_NewIdentity_Call{Call: _e.mock.On("NewIdentity", _a0, _a1)}
+}
+
+func (_c *Repository_NewIdentity_Call) Run(run func(_a0 context.Context, _a1 domain.Identity)) *Repository_NewIdentity_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.Identity))
+	})
+	return _c
+}
+
+func (_c *Repository_NewIdentity_Call) Return(_a0 error) *Repository_NewIdentity_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_NewIdentity_Call) RunAndReturn(run func(context.Context, domain.Identity) error) *Repository_NewIdentity_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMachineClient provides a mock function with given fields: _a0, _a1
+func (_m *Repository) NewMachineClient(_a0 context.Context, _a1 domain.MachineClient) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NewMachineClient")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.MachineClient) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_NewMachineClient_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NewMachineClient'
+type Repository_NewMachineClient_Call struct {
+	*mock.Call
+}
+
+// NewMachineClient is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.MachineClient
+func (_e *Repository_Expecter) NewMachineClient(_a0 interface{}, _a1 interface{}) *Repository_NewMachineClient_Call {
+	return &Repository_NewMachineClient_Call{Call: _e.mock.On("NewMachineClient", _a0, _a1)}
+}
+
+func (_c *Repository_NewMachineClient_Call) Run(run func(_a0 context.Context, _a1 domain.MachineClient)) *Repository_NewMachineClient_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.MachineClient))
+	})
+	return _c
+}
+
+func (_c *Repository_NewMachineClient_Call) Return(_a0 error) *Repository_NewMachineClient_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_NewMachineClient_Call) RunAndReturn(run func(context.Context, domain.MachineClient) error) *Repository_NewMachineClient_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewUser provides a mock function with given fields: _a0, _a1
+func (_m *Repository) NewUser(_a0 context.Context, _a1 domain.User) (domain.User, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NewUser")
+	}
+
+	var r0 domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.User) (domain.User, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.User) domain.User); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(domain.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.User) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_NewUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NewUser'
+type Repository_NewUser_Call struct {
+	*mock.Call
+}
+
+// NewUser is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.User
+func (_e *Repository_Expecter) NewUser(_a0 interface{}, _a1 interface{}) *Repository_NewUser_Call {
+	return &Repository_NewUser_Call{Call: _e.mock.On("NewUser", _a0, _a1)}
+}
+
+func (_c *Repository_NewUser_Call) Run(run func(_a0 context.Context, _a1 domain.User)) *Repository_NewUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.User))
+	})
+	return _c
+}
+
+func (_c *Repository_NewUser_Call) Return(_a0 domain.User, _a1 error) *Repository_NewUser_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_NewUser_Call) RunAndReturn(run func(context.Context, domain.User) (domain.User, error)) *Repository_NewUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RefreshTokenExists provides a mock function with given fields: _a0, hash
+func (_m *Repository) RefreshTokenExists(_a0 context.Context, hash string) (bool, error) {
+	ret := _m.Called(_a0, hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefreshTokenExists")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(_a0, hash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(_a0, hash)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_RefreshTokenExists_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RefreshTokenExists'
+type Repository_RefreshTokenExists_Call struct {
+	*mock.Call
+}
+
+// RefreshTokenExists is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - hash string
+func (_e *Repository_Expecter) RefreshTokenExists(_a0 interface{}, hash interface{}) *Repository_RefreshTokenExists_Call {
+	return &Repository_RefreshTokenExists_Call{Call: _e.mock.On("RefreshTokenExists", _a0, hash)}
+}
+
+func (_c *Repository_RefreshTokenExists_Call) Run(run func(_a0 context.Context, hash string)) *Repository_RefreshTokenExists_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_RefreshTokenExists_Call) Return(_a0 bool, _a1 error) *Repository_RefreshTokenExists_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_RefreshTokenExists_Call) RunAndReturn(run func(context.Context, string) (bool, error)) *Repository_RefreshTokenExists_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RegisterLoginFailure provides a mock function with given fields: _a0, key, p
+func (_m *Repository) RegisterLoginFailure(_a0 context.Context, key string, p domain.LockoutPolicy) (time.Duration, error) {
+	ret := _m.Called(_a0, key, p)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RegisterLoginFailure")
+	}
+
+	var r0 time.Duration
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.LockoutPolicy) (time.Duration, error)); ok {
+		return rf(_a0, key, p)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.LockoutPolicy) time.Duration); ok {
+		r0 = rf(_a0, key, p)
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, domain.LockoutPolicy) error); ok {
+		r1 = rf(_a0, key, p)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_RegisterLoginFailure_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RegisterLoginFailure'
+type Repository_RegisterLoginFailure_Call struct {
+	*mock.Call
+}
+
+// RegisterLoginFailure is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - key string
+//   - p domain.LockoutPolicy
+func (_e *Repository_Expecter) RegisterLoginFailure(_a0 interface{}, key interface{}, p interface{}) *Repository_RegisterLoginFailure_Call {
+	return &Repository_RegisterLoginFailure_Call{Call: _e.mock.On("RegisterLoginFailure", _a0, key, p)}
+}
+
+func (_c *Repository_RegisterLoginFailure_Call) Run(run func(_a0 context.Context, key string, p domain.LockoutPolicy)) *Repository_RegisterLoginFailure_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(domain.LockoutPolicy))
+	})
+	return _c
+}
+
+func (_c *Repository_RegisterLoginFailure_Call) Return(_a0 time.Duration, _a1 error) *Repository_RegisterLoginFailure_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_RegisterLoginFailure_Call) RunAndReturn(run func(context.Context, string, domain.LockoutPolicy) (time.Duration, error)) *Repository_RegisterLoginFailure_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResetLoginFailures provides a mock function with given fields: _a0, keys
+func (_m *Repository) ResetLoginFailures(_a0 context.Context, keys []string) error {
+	ret := _m.Called(_a0, keys)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetLoginFailures")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) error); ok {
+		r0 = rf(_a0, keys)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_ResetLoginFailures_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResetLoginFailures'
+type Repository_ResetLoginFailures_Call struct {
+	*mock.Call
+}
+
+// ResetLoginFailures is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - keys []string
+func (_e *Repository_Expecter) ResetLoginFailures(_a0 interface{}, keys interface{}) *Repository_ResetLoginFailures_Call {
+	return &Repository_ResetLoginFailures_Call{Call: _e.mock.On("ResetLoginFailures", _a0, keys)}
+}
+
+func (_c *Repository_ResetLoginFailures_Call) Run(run func(_a0 context.Context, keys []string)) *Repository_ResetLoginFailures_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *Repository_ResetLoginFailures_Call) Return(_a0 error) *Repository_ResetLoginFailures_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_ResetLoginFailures_Call) RunAndReturn(run func(context.Context, []string) error) *Repository_ResetLoginFailures_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RevokeLegacyTokens provides a mock function with given fields: _a0, userID, ttl
+func (_m *Repository) RevokeLegacyTokens(_a0 context.Context, userID string, ttl time.Duration) error {
+	ret := _m.Called(_a0, userID, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeLegacyTokens")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) error); ok {
+		r0 = rf(_a0, userID, ttl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_RevokeLegacyTokens_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeLegacyTokens'
+type Repository_RevokeLegacyTokens_Call struct {
+	*mock.Call
+}
+
+// RevokeLegacyTokens is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+//   - ttl time.Duration
+func (_e *Repository_Expecter) RevokeLegacyTokens(_a0 interface{}, userID interface{}, ttl interface{}) *Repository_RevokeLegacyTokens_Call {
+	return &Repository_RevokeLegacyTokens_Call{Call: _e.mock.On("RevokeLegacyTokens", _a0, userID, ttl)}
+}
+
+func (_c *Repository_RevokeLegacyTokens_Call) Run(run func(_a0 context.Context, userID string, ttl time.Duration)) *Repository_RevokeLegacyTokens_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *Repository_RevokeLegacyTokens_Call) Return(_a0 error) *Repository_RevokeLegacyTokens_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_RevokeLegacyTokens_Call) RunAndReturn(run func(context.Context, string, time.Duration) error) *Repository_RevokeLegacyTokens_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RevokeTokenByHash provides a mock function with given fields: _a0, _a1
+func (_m *Repository) RevokeTokenByHash(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeTokenByHash")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_RevokeTokenByHash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeTokenByHash'
+type Repository_RevokeTokenByHash_Call struct {
+	*mock.Call
+}
+
+// RevokeTokenByHash is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 string
+func (_e *Repository_Expecter) RevokeTokenByHash(_a0 interface{}, _a1 interface{}) *Repository_RevokeTokenByHash_Call {
+	return &Repository_RevokeTokenByHash_Call{Call: _e.mock.On("RevokeTokenByHash", _a0, _a1)}
+}
+
+func (_c *Repository_RevokeTokenByHash_Call) Run(run func(_a0 context.Context, _a1 string)) *Repository_RevokeTokenByHash_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_RevokeTokenByHash_Call) Return(_a0 error) *Repository_RevokeTokenByHash_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_RevokeTokenByHash_Call) RunAndReturn(run func(context.Context, string) error) *Repository_RevokeTokenByHash_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RevokeUserTokens provides a mock function with given fields: _a0, userID, exceptFamilyID
+func (_m *Repository) RevokeUserTokens(_a0 context.Context, userID string, exceptFamilyID string) (int, error) {
+	ret := _m.Called(_a0, userID, exceptFamilyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeUserTokens")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (int, error)); ok {
+		return rf(_a0, userID, exceptFamilyID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) int); ok {
+		r0 = rf(_a0, userID, exceptFamilyID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, userID, exceptFamilyID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Repository_RevokeUserTokens_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeUserTokens'
+type Repository_RevokeUserTokens_Call struct {
+	*mock.Call
+}
+
+// RevokeUserTokens is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+//   - exceptFamilyID string
+func (_e *Repository_Expecter) RevokeUserTokens(_a0 interface{}, userID interface{}, exceptFamilyID interface{}) *Repository_RevokeUserTokens_Call {
+	return &Repository_RevokeUserTokens_Call{Call: _e.mock.On("RevokeUserTokens", _a0, userID, exceptFamilyID)}
+}
+
+func (_c *Repository_RevokeUserTokens_Call) Run(run func(_a0 context.Context, userID string, exceptFamilyID string)) *Repository_RevokeUserTokens_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_RevokeUserTokens_Call) Return(_a0 int, _a1 error) *Repository_RevokeUserTokens_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Repository_RevokeUserTokens_Call) RunAndReturn(run func(context.Context, string, string) (int, error)) *Repository_RevokeUserTokens_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RotateToken provides a mock function with given fields: _a0, oldHash, newRT
+func (_m *Repository) RotateToken(_a0 context.Context, oldHash string, newRT domain.RefreshToken) error {
+	ret := _m.Called(_a0, oldHash, newRT)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.RefreshToken) error); ok {
+		r0 = rf(_a0, oldHash, newRT)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_RotateToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RotateToken'
+type Repository_RotateToken_Call struct {
+	*mock.Call
+}
+
+// RotateToken is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - oldHash string
+//   - newRT domain.RefreshToken
+func (_e *Repository_Expecter) RotateToken(_a0 interface{}, oldHash interface{}, newRT interface{}) *Repository_RotateToken_Call {
+	return &Repository_RotateToken_Call{Call: _e.mock.On("RotateToken", _a0, oldHash, newRT)}
+}
+
+func (_c *Repository_RotateToken_Call) Run(run func(_a0 context.Context, oldHash string, newRT domain.RefreshToken)) *Repository_RotateToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(domain.RefreshToken))
+	})
+	return _c
+}
+
+func (_c *Repository_RotateToken_Call) Return(_a0 error) *Repository_RotateToken_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_RotateToken_Call) RunAndReturn(run func(context.Context, string, domain.RefreshToken) error) *Repository_RotateToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveAuthorizationCode provides a mock function with given fields: _a0, _a1
+func (_m *Repository) SaveAuthorizationCode(_a0 context.Context, _a1 domain.AuthorizationCode) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveAuthorizationCode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.AuthorizationCode) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_SaveAuthorizationCode_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveAuthorizationCode'
+type Repository_SaveAuthorizationCode_Call struct {
+	*mock.Call
+}
+
+// SaveAuthorizationCode is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.AuthorizationCode
+func (_e *Repository_Expecter) SaveAuthorizationCode(_a0 interface{}, _a1 interface{}) *Repository_SaveAuthorizationCode_Call {
+	return &Repository_SaveAuthorizationCode_Call{Call: _e.mock.On("SaveAuthorizationCode", _a0, _a1)}
+}
+
+func (_c *Repository_SaveAuthorizationCode_Call) Run(run func(_a0 context.Context, _a1 domain.AuthorizationCode)) *Repository_SaveAuthorizationCode_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.AuthorizationCode))
+	})
+	return _c
+}
+
+func (_c *Repository_SaveAuthorizationCode_Call) Return(_a0 error) *Repository_SaveAuthorizationCode_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_SaveAuthorizationCode_Call) RunAndReturn(run func(context.Context, domain.AuthorizationCode) error) *Repository_SaveAuthorizationCode_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveFederationState provides a mock function with given fields: _a0, _a1
+func (_m *Repository) SaveFederationState(_a0 context.Context, _a1 domain.FederationState) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveFederationState")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.FederationState) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_SaveFederationState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveFederationState'
+type Repository_SaveFederationState_Call struct {
+	*mock.Call
+}
+
+// SaveFederationState is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.FederationState
+func (_e *Repository_Expecter) SaveFederationState(_a0 interface{}, _a1 interface{}) *Repository_SaveFederationState_Call {
+	return &Repository_SaveFederationState_Call{Call: _e.mock.On("SaveFederationState", _a0, _a1)}
+}
+
+func (_c *Repository_SaveFederationState_Call) Run(run func(_a0 context.Context, _a1 domain.FederationState)) *Repository_SaveFederationState_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.FederationState))
+	})
+	return _c
+}
+
+func (_c *Repository_SaveFederationState_Call) Return(_a0 error) *Repository_SaveFederationState_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_SaveFederationState_Call) RunAndReturn(run func(context.Context, domain.FederationState) error) *Repository_SaveFederationState_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveOneTimeToken provides a mock function with given fields: _a0, _a1
+func (_m *Repository) SaveOneTimeToken(_a0 context.Context, _a1 domain.OneTimeToken) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveOneTimeToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.OneTimeToken) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_SaveOneTimeToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveOneTimeToken'
+type Repository_SaveOneTimeToken_Call struct {
+	*mock.Call
+}
+
+// SaveOneTimeToken is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.OneTimeToken
+func (_e *Repository_Expecter) SaveOneTimeToken(_a0 interface{}, _a1 interface{}) *Repository_SaveOneTimeToken_Call {
+	return &Repository_SaveOneTimeToken_Call{Call: _e.mock.On("SaveOneTimeToken", _a0, _a1)}
+}
+
+func (_c *Repository_SaveOneTimeToken_Call) Run(run func(_a0 context.Context, _a1 domain.OneTimeToken)) *Repository_SaveOneTimeToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.OneTimeToken))
+	})
+	return _c
+}
+
+func (_c *Repository_SaveOneTimeToken_Call) Return(_a0 error) *Repository_SaveOneTimeToken_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_SaveOneTimeToken_Call) RunAndReturn(run func(context.Context, domain.OneTimeToken) error) *Repository_SaveOneTimeToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SavePasswordHistory provides a mock function with given fields: _a0, userID, passwordHash
+func (_m *Repository) SavePasswordHistory(_a0 context.Context, userID string, passwordHash string) error {
+	ret := _m.Called(_a0, userID, passwordHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SavePasswordHistory")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, userID, passwordHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_SavePasswordHistory_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SavePasswordHistory'
+type Repository_SavePasswordHistory_Call struct {
+	*mock.Call
+}
+
+// SavePasswordHistory is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+//   - passwordHash string
+func (_e *Repository_Expecter) SavePasswordHistory(_a0 interface{}, userID interface{}, passwordHash interface{}) *Repository_SavePasswordHistory_Call {
+	return &Repository_SavePasswordHistory_Call{Call: _e.mock.On("SavePasswordHistory", _a0, userID, passwordHash)}
+}
+
+func (_c *Repository_SavePasswordHistory_Call) Run(run func(_a0 context.Context, userID string, passwordHash string)) *Repository_SavePasswordHistory_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_SavePasswordHistory_Call) Return(_a0 error) *Repository_SavePasswordHistory_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_SavePasswordHistory_Call) RunAndReturn(run func(context.Context, string, string) error) *Repository_SavePasswordHistory_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SavePasswordlessChallenge provides a mock function with given fields: _a0, _a1
+func (_m *Repository) SavePasswordlessChallenge(_a0 context.Context, _a1 domain.PasswordlessChallenge) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SavePasswordlessChallenge")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PasswordlessChallenge) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_SavePasswordlessChallenge_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SavePasswordlessChallenge'
+type Repository_SavePasswordlessChallenge_Call struct {
+	*mock.Call
+}
+
+// SavePasswordlessChallenge is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.PasswordlessChallenge
+func (_e *Repository_Expecter) SavePasswordlessChallenge(_a0 interface{}, _a1 interface{}) *Repository_SavePasswordlessChallenge_Call {
+	return &Repository_SavePasswordlessChallenge_Call{Call: _e.mock.On("SavePasswordlessChallenge", _a0, _a1)}
+}
+
+func (_c *Repository_SavePasswordlessChallenge_Call) Run(run func(_a0 context.Context, _a1 domain.PasswordlessChallenge)) *Repository_SavePasswordlessChallenge_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.PasswordlessChallenge))
+	})
+	return _c
+}
+
+func (_c *Repository_SavePasswordlessChallenge_Call) Return(_a0 error) *Repository_SavePasswordlessChallenge_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_SavePasswordlessChallenge_Call) RunAndReturn(run func(context.Context, domain.PasswordlessChallenge) error) *Repository_SavePasswordlessChallenge_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveRefreshToken provides a mock function with given fields: _a0, _a1
+func (_m *Repository) SaveRefreshToken(_a0 context.Context, _a1 domain.RefreshToken) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveRefreshToken")
 	}
 
-	var r0 domain.User
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.RefreshToken) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_SaveRefreshToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveRefreshToken'
+type Repository_SaveRefreshToken_Call struct {
+	*mock.Call
+}
+
+// SaveRefreshToken is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.RefreshToken
+func (_e *Repository_Expecter) SaveRefreshToken(_a0 interface{}, _a1 interface{}) *Repository_SaveRefreshToken_Call {
+	return &Repository_SaveRefreshToken_Call{Call: _e.mock.On("SaveRefreshToken", _a0, _a1)}
+}
+
+func (_c *Repository_SaveRefreshToken_Call) Run(run func(_a0 context.Context, _a1 domain.RefreshToken)) *Repository_SaveRefreshToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.RefreshToken))
+	})
+	return _c
+}
+
+func (_c *Repository_SaveRefreshToken_Call) Return(_a0 error) *Repository_SaveRefreshToken_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_SaveRefreshToken_Call) RunAndReturn(run func(context.Context, domain.RefreshToken) error) *Repository_SaveRefreshToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveTotpSecret provides a mock function with given fields: _a0, userID, encryptedSecret
+func (_m *Repository) SaveTotpSecret(_a0 context.Context, userID string, encryptedSecret []byte) error {
+	ret := _m.Called(_a0, userID, encryptedSecret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveTotpSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte) error); ok {
+		r0 = rf(_a0, userID, encryptedSecret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_SaveTotpSecret_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveTotpSecret'
+type Repository_SaveTotpSecret_Call struct {
+	*mock.Call
+}
+
+// SaveTotpSecret is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+//   - encryptedSecret []byte
+func (_e *Repository_Expecter) SaveTotpSecret(_a0 interface{}, userID interface{}, encryptedSecret interface{}) *Repository_SaveTotpSecret_Call {
+	return &Repository_SaveTotpSecret_Call{Call: _e.mock.On("SaveTotpSecret", _a0, userID, encryptedSecret)}
+}
+
+func (_c *Repository_SaveTotpSecret_Call) Run(run func(_a0 context.Context, userID string, encryptedSecret []byte)) *Repository_SaveTotpSecret_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]byte))
+	})
+	return _c
+}
+
+func (_c *Repository_SaveTotpSecret_Call) Return(_a0 error) *Repository_SaveTotpSecret_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_SaveTotpSecret_Call) RunAndReturn(run func(context.Context, string, []byte) error) *Repository_SaveTotpSecret_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SessionExists provides a mock function with given fields: _a0, familyID
+func (_m *Repository) SessionExists(_a0 context.Context, familyID string) (bool, error) {
+	ret := _m.Called(_a0, familyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SessionExists")
+	}
+
+	var r0 bool
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.User, error)); ok {
-		return rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(_a0, familyID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) domain.User); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(_a0, familyID)
 	} else {
-		r0 = ret.Get(0).(domain.User)
+		r0 = ret.Get(0).(bool)
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(_a0, _a1)
+		r1 = rf(_a0, familyID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -50,55 +2073,200 @@ func (_m *Repository) GetUserInfoByEmail(_a0 context.Context, _a1 string) (domai
 	return r0, r1
 }
 
-// Repository_GetUserInfoByEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUserInfoByEmail'
-type Repository_GetUserInfoByEmail_Call struct {
+// Repository_SessionExists_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SessionExists'
+type Repository_SessionExists_Call struct {
 	*mock.Call
 }
 
-// GetUserInfoByEmail is a helper method to define mock.On call
+// SessionExists is a helper method to define mock.On call
 //   - _a0 context.Context
-//   - _a1 string
-func (_e *Repository_Expecter) GetUserInfoByEmail(_a0 interface{}, _a1 interface{}) *Repository_GetUserInfoByEmail_Call {
-	return &Repository_GetUserInfoByEmail_Call{Call: _e.mock.On("GetUserInfoByEmail", _a0, _a1)}
+//   - familyID string
+func (_e *Repository_Expecter) SessionExists(_a0 interface{}, familyID interface{}) *Repository_SessionExists_Call {
+	return &Repository_SessionExists_Call{Call: _e.mock.On("SessionExists", _a0, familyID)}
 }
 
-func (_c *Repository_GetUserInfoByEmail_Call) Run(run func(_a0 context.Context, _a1 string)) *Repository_GetUserInfoByEmail_Call {
+func (_c *Repository_SessionExists_Call) Run(run func(_a0 context.Context, familyID string)) *Repository_SessionExists_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		run(args[0].(context.Context), args[1].(string))
 	})
 	return _c
 }
 
-func (_c *Repository_GetUserInfoByEmail_Call) Return(_a0 domain.User, _a1 error) *Repository_GetUserInfoByEmail_Call {
+func (_c *Repository_SessionExists_Call) Return(_a0 bool, _a1 error) *Repository_SessionExists_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *Repository_GetUserInfoByEmail_Call) RunAndReturn(run func(context.Context, string) (domain.User, error)) *Repository_GetUserInfoByEmail_Call {
+func (_c *Repository_SessionExists_Call) RunAndReturn(run func(context.Context, string) (bool, error)) *Repository_SessionExists_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// NewUser provides a mock function with given fields: _a0, _a1
-func (_m *Repository) NewUser(_a0 context.Context, _a1 domain.User) (domain.User, error) {
+// SetAppStatus provides a mock function with given fields: _a0, appID, status
+func (_m *Repository) SetAppStatus(_a0 context.Context, appID int32, status domain.AppStatus) error {
+	ret := _m.Called(_a0, appID, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetAppStatus")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32, domain.AppStatus) error); ok {
+		r0 = rf(_a0, appID, status)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_SetAppStatus_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetAppStatus'
+type Repository_SetAppStatus_Call struct {
+	*mock.Call
+}
+
+// SetAppStatus is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - appID int32
+//   - status domain.AppStatus
+func (_e *Repository_Expecter) SetAppStatus(_a0 interface{}, appID interface{}, status interface{}) *Repository_SetAppStatus_Call {
+	return &Repository_SetAppStatus_Call{Call: _e.mock.On("SetAppStatus", _a0, appID, status)}
+}
+
+func (_c *Repository_SetAppStatus_Call) Run(run func(_a0 context.Context, appID int32, status domain.AppStatus)) *Repository_SetAppStatus_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int32), args[2].(domain.AppStatus))
+	})
+	return _c
+}
+
+func (_c *Repository_SetAppStatus_Call) Return(_a0 error) *Repository_SetAppStatus_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_SetAppStatus_Call) RunAndReturn(run func(context.Context, int32, domain.AppStatus) error) *Repository_SetAppStatus_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SyncUserRoles provides a mock function with given fields: _a0, userID, managed, roles
+func (_m *Repository) SyncUserRoles(_a0 context.Context, userID string, managed []string, roles []string) error {
+	ret := _m.Called(_a0, userID, managed, roles)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SyncUserRoles")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string, []string) error); ok {
+		r0 = rf(_a0, userID, managed, roles)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_SyncUserRoles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SyncUserRoles'
+type Repository_SyncUserRoles_Call struct {
+	*mock.Call
+}
+
+// SyncUserRoles is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+//   - managed []string
+//   - roles []string
+func (_e *Repository_Expecter) SyncUserRoles(_a0 interface{}, userID interface{}, managed interface{}, roles interface{}) *Repository_SyncUserRoles_Call {
+	return &Repository_SyncUserRoles_Call{Call: _e.mock.On("SyncUserRoles", _a0, userID, managed, roles)}
+}
+
+func (_c *Repository_SyncUserRoles_Call) Run(run func(_a0 context.Context, userID string, managed []string, roles []string)) *Repository_SyncUserRoles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]string), args[3].([]string))
+	})
+	return _c
+}
+
+func (_c *Repository_SyncUserRoles_Call) Return(_a0 error) *Repository_SyncUserRoles_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_SyncUserRoles_Call) RunAndReturn(run func(context.Context, string, []string, []string) error) *Repository_SyncUserRoles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TouchDevice provides a mock function with given fields: _a0, deviceID, userID
+func (_m *Repository) TouchDevice(_a0 context.Context, deviceID int32, userID string) error {
+	ret := _m.Called(_a0, deviceID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TouchDevice")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32, string) error); ok {
+		r0 = rf(_a0, deviceID, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Repository_TouchDevice_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TouchDevice'
+type Repository_TouchDevice_Call struct {
+	*mock.Call
+}
+
+// TouchDevice is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - deviceID int32
+//   - userID string
+func (_e *Repository_Expecter) TouchDevice(_a0 interface{}, deviceID interface{}, userID interface{}) *Repository_TouchDevice_Call {
+	return &Repository_TouchDevice_Call{Call: _e.mock.On("TouchDevice", _a0, deviceID, userID)}
+}
+
+func (_c *Repository_TouchDevice_Call) Run(run func(_a0 context.Context, deviceID int32, userID string)) *Repository_TouchDevice_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int32), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Repository_TouchDevice_Call) Return(_a0 error) *Repository_TouchDevice_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Repository_TouchDevice_Call) RunAndReturn(run func(context.Context, int32, string) error) *Repository_TouchDevice_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateApp provides a mock function with given fields: _a0, _a1
+func (_m *Repository) UpdateApp(_a0 context.Context, _a1 domain.App) (domain.App, error) {
 	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
-		panic("no return value specified for NewUser")
+		panic("no return value specified for UpdateApp")
 	}
 
-	var r0 domain.User
+	var r0 domain.App
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, domain.User) (domain.User, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, domain.App) (domain.App, error)); ok {
 		return rf(_a0, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, domain.User) domain.User); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, domain.App) domain.App); ok {
 		r0 = rf(_a0, _a1)
 	} else {
-		r0 = ret.Get(0).(domain.User)
+		r0 = ret.Get(0).(domain.App)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, domain.User) error); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, domain.App) error); ok {
 		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
@@ -107,46 +2275,46 @@ func (_m *Repository) NewUser(_a0 context.Context, _a1 domain.User) (domain.User
 	return r0, r1
 }
 
-// Repository_NewUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NewUser'
-type Repository_NewUser_Call struct {
+// Repository_UpdateApp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateApp'
+type Repository_UpdateApp_Call struct {
 	*mock.Call
 }
 
-// NewUser is a helper method to define mock.On call
+// UpdateApp is a helper method to define mock.On call
 //   - _a0 context.Context
-//   - _a1 domain.User
-func (_e *Repository_Expecter) NewUser(_a0 interface{}, _a1 interface{}) *Repository_NewUser_Call {
-	return &Repository_NewUser_Call{Call: _e.mock.On("NewUser", _a0, _a1)}
+//   - _a1 domain.App
+func (_e *Repository_Expecter) UpdateApp(_a0 interface{}, _a1 interface{}) *Repository_UpdateApp_Call {
+	return &Repository_UpdateApp_Call{Call: _e.mock.On("UpdateApp", _a0, _a1)}
 }
 
-func (_c *Repository_NewUser_Call) Run(run func(_a0 context.Context, _a1 domain.User)) *Repository_NewUser_Call {
+func (_c *Repository_UpdateApp_Call) Run(run func(_a0 context.Context, _a1 domain.App)) *Repository_UpdateApp_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(domain.User))
+		run(args[0].(context.Context), args[1].(domain.App))
 	})
 	return _c
 }
 
-func (_c *Repository_NewUser_Call) Return(_a0 domain.User, _a1 error) *Repository_NewUser_Call {
+func (_c *Repository_UpdateApp_Call) Return(_a0 domain.App, _a1 error) *Repository_UpdateApp_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *Repository_NewUser_Call) RunAndReturn(run func(context.Context, domain.User) (domain.User, error)) *Repository_NewUser_Call {
+func (_c *Repository_UpdateApp_Call) RunAndReturn(run func(context.Context, domain.App) (domain.App, error)) *Repository_UpdateApp_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// RevokeTokenByHash provides a mock function with given fields: _a0, _a1
-func (_m *Repository) RevokeTokenByHash(_a0 context.Context, _a1 string) error {
-	ret := _m.Called(_a0, _a1)
+// UpdateMachineClientSecret provides a mock function with given fields: _a0, clientID, secretHash
+func (_m *Repository) UpdateMachineClientSecret(_a0 context.Context, clientID string, secretHash []byte) error {
+	ret := _m.Called(_a0, clientID, secretHash)
 
 	if len(ret) == 0 {
-		panic("no return value specified for RevokeTokenByHash")
+		panic("no return value specified for UpdateMachineClientSecret")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte) error); ok {
+		r0 = rf(_a0, clientID, secretHash)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -154,46 +2322,47 @@ func (_m *Repository) RevokeTokenByHash(_a0 context.Context, _a1 string) error {
 	return r0
 }
 
-// Repository_RevokeTokenByHash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeTokenByHash'
-type Repository_RevokeTokenByHash_Call struct {
+// Repository_UpdateMachineClientSecret_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateMachineClientSecret'
+type Repository_UpdateMachineClientSecret_Call struct {
 	*mock.Call
 }
 
-// RevokeTokenByHash is a helper method to define mock.On call
+// UpdateMachineClientSecret is a helper method to define mock.On call
 //   - _a0 context.Context
-//   - _a1 string
-func (_e *Repository_Expecter) RevokeTokenByHash(_a0 interface{}, _a1 interface{}) *Repository_RevokeTokenByHash_Call {
-	return &Repository_RevokeTokenByHash_Call{Call: _e.mock.On("RevokeTokenByHash", _a0, _a1)}
+//   - clientID string
+//   - secretHash []byte
+func (_e *Repository_Expecter) UpdateMachineClientSecret(_a0 interface{}, clientID interface{}, secretHash interface{}) *Repository_UpdateMachineClientSecret_Call {
+	return &Repository_UpdateMachineClientSecret_Call{Call: _e.mock.On("UpdateMachineClientSecret", _a0, clientID, secretHash)}
 }
 
-func (_c *Repository_RevokeTokenByHash_Call) Run(run func(_a0 context.Context, _a1 string)) *Repository_RevokeTokenByHash_Call {
+func (_c *Repository_UpdateMachineClientSecret_Call) Run(run func(_a0 context.Context, clientID string, secretHash []byte)) *Repository_UpdateMachineClientSecret_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].([]byte))
 	})
 	return _c
 }
 
-func (_c *Repository_RevokeTokenByHash_Call) Return(_a0 error) *Repository_RevokeTokenByHash_Call {
+func (_c *Repository_UpdateMachineClientSecret_Call) Return(_a0 error) *Repository_UpdateMachineClientSecret_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *Repository_RevokeTokenByHash_Call) RunAndReturn(run func(context.Context, string) error) *Repository_RevokeTokenByHash_Call {
+func (_c *Repository_UpdateMachineClientSecret_Call) RunAndReturn(run func(context.Context, string, []byte) error) *Repository_UpdateMachineClientSecret_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// RotateToken provides a mock function with given fields: _a0, oldHash, newRT
-func (_m *Repository) RotateToken(_a0 context.Context, oldHash string, newRT domain.RefreshToken) error {
-	ret := _m.Called(_a0, oldHash, newRT)
+// UpdateUserPassword provides a mock function with given fields: _a0, userID, passwordHash
+func (_m *Repository) UpdateUserPassword(_a0 context.Context, userID string, passwordHash string) error {
+	ret := _m.Called(_a0, userID, passwordHash)
 
 	if len(ret) == 0 {
-		panic("no return value specified for RotateToken")
+		panic("no return value specified for UpdateUserPassword")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, domain.RefreshToken) error); ok {
-		r0 = rf(_a0, oldHash, newRT)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, userID, passwordHash)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -201,47 +2370,47 @@ func (_m *Repository) RotateToken(_a0 context.Context, oldHash string, newRT dom
 	return r0
 }
 
-// Repository_RotateToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RotateToken'
-type Repository_RotateToken_Call struct {
+// Repository_UpdateUserPassword_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateUserPassword'
+type Repository_UpdateUserPassword_Call struct {
 	*mock.Call
 }
 
-// RotateToken is a helper method to define mock.On call
+// UpdateUserPassword is a helper method to define mock.On call
 //   - _a0 context.Context
-//   - oldHash string
-//   - newRT domain.RefreshToken
-func (_e *Repository_Expecter) RotateToken(_a0 interface{}, oldHash interface{}, newRT interface{}) *Repository_RotateToken_Call {
-	return &Repository_RotateToken_Call{Call: _e.mock.On("RotateToken", _a0, oldHash, newRT)}
+//   - userID string
+//   - passwordHash string
+func (_e *Repository_Expecter) UpdateUserPassword(_a0 interface{}, userID interface{}, passwordHash interface{}) *Repository_UpdateUserPassword_Call {
+	return &Repository_UpdateUserPassword_Call{Call: _e.mock.On("UpdateUserPassword", _a0, userID, passwordHash)}
 }
 
-func (_c *Repository_RotateToken_Call) Run(run func(_a0 context.Context, oldHash string, newRT domain.RefreshToken)) *Repository_RotateToken_Call {
+func (_c *Repository_UpdateUserPassword_Call) Run(run func(_a0 context.Context, userID string, passwordHash string)) *Repository_UpdateUserPassword_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string), args[2].(domain.RefreshToken))
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
 	})
 	return _c
 }
 
-func (_c *Repository_RotateToken_Call) Return(_a0 error) *Repository_RotateToken_Call {
+func (_c *Repository_UpdateUserPassword_Call) Return(_a0 error) *Repository_UpdateUserPassword_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *Repository_RotateToken_Call) RunAndReturn(run func(context.Context, string, domain.RefreshToken) error) *Repository_RotateToken_Call {
+func (_c *Repository_UpdateUserPassword_Call) RunAndReturn(run func(context.Context, string, string) error) *Repository_UpdateUserPassword_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// SaveRefreshToken provides a mock function with given fields: _a0, _a1
-func (_m *Repository) SaveRefreshToken(_a0 context.Context, _a1 domain.RefreshToken) error {
-	ret := _m.Called(_a0, _a1)
+// UseTotpStep provides a mock function with given fields: _a0, userID, step
+func (_m *Repository) UseTotpStep(_a0 context.Context, userID string, step int64) error {
+	ret := _m.Called(_a0, userID, step)
 
 	if len(ret) == 0 {
-		panic("no return value specified for SaveRefreshToken")
+		panic("no return value specified for UseTotpStep")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, domain.RefreshToken) error); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(_a0, userID, step)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -249,31 +2418,32 @@ func (_m *Repository) SaveRefreshToken(_a0 context.Context, _a1 domain.RefreshTo
 	return r0
 }
 
-// Repository_SaveRefreshToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveRefreshToken'
-type Repository_SaveRefreshToken_Call struct {
+// Repository_UseTotpStep_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UseTotpStep'
+type Repository_UseTotpStep_Call struct {
 	*mock.Call
 }
 
-// SaveRefreshToken is a helper method to define mock.On call
+// UseTotpStep is a helper method to define mock.On call
 //   - _a0 context.Context
-//   - _a1 domain.RefreshToken
-func (_e *Repository_Expecter) SaveRefreshToken(_a0 interface{}, _a1 interface{}) *Repository_SaveRefreshToken_Call {
-	return &Repository_SaveRefreshToken_Call{Call: _e.mock.On("SaveRefreshToken", _a0, _a1)}
+//   - userID string
+//   - step int64
+func (_e *Repository_Expecter) UseTotpStep(_a0 interface{}, userID interface{}, step interface{}) *Repository_UseTotpStep_Call {
+	return &Repository_UseTotpStep_Call{Call: _e.mock.On("UseTotpStep", _a0, userID, step)}
 }
 
-func (_c *Repository_SaveRefreshToken_Call) Run(run func(_a0 context.Context, _a1 domain.RefreshToken)) *Repository_SaveRefreshToken_Call {
+func (_c *Repository_UseTotpStep_Call) Run(run func(_a0 context.Context, userID string, step int64)) *Repository_UseTotpStep_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(domain.RefreshToken))
+		run(args[0].(context.Context), args[1].(string), args[2].(int64))
 	})
 	return _c
 }
 
-func (_c *Repository_SaveRefreshToken_Call) Return(_a0 error) *Repository_SaveRefreshToken_Call {
+func (_c *Repository_UseTotpStep_Call) Return(_a0 error) *Repository_UseTotpStep_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *Repository_SaveRefreshToken_Call) RunAndReturn(run func(context.Context, domain.RefreshToken) error) *Repository_SaveRefreshToken_Call {
+func (_c *Repository_UseTotpStep_Call) RunAndReturn(run func(context.Context, string, int64) error) *Repository_UseTotpStep_Call {
 	_c.Call.Return(run)
 	return _c
 }
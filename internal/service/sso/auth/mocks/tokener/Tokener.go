@@ -20,18 +20,17 @@ func (_m *Tokener) EXPECT() *Tokener_Expecter {
 	return &Tokener_Expecter{mock: &_m.Mock}
 }
 
-// GenPair provides a mock function with given fields: _a0
-func (_m *Tokener) GenPair(_a0 domain.Meta) ([]byte, []byte, error) {
+// GenAccess provides a mock function with given fields: _a0
+func (_m *Tokener) GenAccess(_a0 domain.Meta) ([]byte, error) {
 	ret := _m.Called(_a0)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GenPair")
+		panic("no return value specified for GenAccess")
 	}
 
 	var r0 []byte
-	var r1 []byte
-	var r2 error
-	if rf, ok := ret.Get(0).(func(domain.Meta) ([]byte, []byte, error)); ok {
+	var r1 error
+	if rf, ok := ret.Get(0).(func(domain.Meta) ([]byte, error)); ok {
 		return rf(_a0)
 	}
 	if rf, ok := ret.Get(0).(func(domain.Meta) []byte); ok {
@@ -42,16 +41,133 @@ func (_m *Tokener) GenPair(_a0 domain.Meta) ([]byte, []byte, error) {
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(domain.Meta) []byte); ok {
+	if rf, ok := ret.Get(1).(func(domain.Meta) error); ok {
 		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Tokener_GenAccess_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GenAccess'
+type Tokener_GenAccess_Call struct {
+	*mock.Call
+}
+
+// GenAccess is a helper method to define mock.On call
+//   - _a0 domain.Meta
+func (_e *Tokener_Expecter) GenAccess(_a0 interface{}) *Tokener_GenAccess_Call {
+	return &Tokener_GenAccess_Call{Call: _e.mock.On("GenAccess", _a0)}
+}
+
+func (_c *Tokener_GenAccess_Call) Run(run func(_a0 domain.Meta)) *Tokener_GenAccess_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(domain.Meta))
+	})
+	return _c
+}
+
+func (_c *Tokener_GenAccess_Call) Return(_a0 []byte, _a1 error) *Tokener_GenAccess_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Tokener_GenAccess_Call) RunAndReturn(run func(domain.Meta) ([]byte, error)) *Tokener_GenAccess_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GenIDToken provides a mock function with given fields: _a0
+func (_m *Tokener) GenIDToken(_a0 domain.IDToken) ([]byte, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GenIDToken")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(domain.IDToken) ([]byte, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(domain.IDToken) []byte); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(domain.IDToken) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Tokener_GenIDToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GenIDToken'
+type Tokener_GenIDToken_Call struct {
+	*mock.Call
+}
+
+// GenIDToken is a helper method to define mock.On call
+//   - _a0 domain.IDToken
+func (_e *Tokener_Expecter) GenIDToken(_a0 interface{}) *Tokener_GenIDToken_Call {
+	return &Tokener_GenIDToken_Call{Call: _e.mock.On("GenIDToken", _a0)}
+}
+
+func (_c *Tokener_GenIDToken_Call) Run(run func(_a0 domain.IDToken)) *Tokener_GenIDToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(domain.IDToken))
+	})
+	return _c
+}
+
+func (_c *Tokener_GenIDToken_Call) Return(_a0 []byte, _a1 error) *Tokener_GenIDToken_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Tokener_GenIDToken_Call) RunAndReturn(run func(domain.IDToken) ([]byte, error)) *Tokener_GenIDToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GenPair provides a mock function with given fields: access, refresh
+func (_m *Tokener) GenPair(access domain.Meta, refresh domain.Meta) ([]byte, []byte, error) {
+	ret := _m.Called(access, refresh)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GenPair")
+	}
+
+	var r0 []byte
+	var r1 []byte
+	var r2 error
+	if rf, ok := ret.Get(0).(func(domain.Meta, domain.Meta) ([]byte, []byte, error)); ok {
+		return rf(access, refresh)
+	}
+	if rf, ok := ret.Get(0).(func(domain.Meta, domain.Meta) []byte); ok {
+		r0 = rf(access, refresh)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(domain.Meta, domain.Meta) []byte); ok {
+		r1 = rf(access, refresh)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).([]byte)
 		}
 	}
 
-	if rf, ok := ret.Get(2).(func(domain.Meta) error); ok {
-		r2 = rf(_a0)
+	if rf, ok := ret.Get(2).(func(domain.Meta, domain.Meta) error); ok {
+		r2 = rf(access, refresh)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -65,24 +181,126 @@ type Tokener_GenPair_Call struct {
 }
 
 // GenPair is a helper method to define mock.On call
-//   - _a0 domain.Meta
-func (_e *Tokener_Expecter) GenPair(_a0 interface{}) *Tokener_GenPair_Call {
-	return &Tokener_GenPair_Call{Call: _e.mock.On("GenPair", _a0)}
+//   - access domain.Meta
+//   - refresh domain.Meta
+func (_e *Tokener_Expecter) GenPair(access interface{}, refresh interface{}) *Tokener_GenPair_Call {
+	return &Tokener_GenPair_Call{Call: _e.mock.On("GenPair", access, refresh)}
 }
 
-func (_c *Tokener_GenPair_Call) Run(run func(_a0 domain.Meta)) *Tokener_GenPair_Call {
+func (_c *Tokener_GenPair_Call) Run(run func(access domain.Meta, refresh domain.Meta)) *Tokener_GenPair_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(domain.Meta))
+		run(args[0].(domain.Meta), args[1].(domain.Meta))
+	})
+	return _c
+}
+
+func (_c *Tokener_GenPair_Call) Return(_a0 []byte, _a1 []byte, _a2 error) *Tokener_GenPair_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *Tokener_GenPair_Call) RunAndReturn(run func(domain.Meta, domain.Meta) ([]byte, []byte, error)) *Tokener_GenPair_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// JWKS provides a mock function with no fields
+func (_m *Tokener) JWKS() domain.JWKS {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for JWKS")
+	}
+
+	var r0 domain.JWKS
+	if rf, ok := ret.Get(0).(func() domain.JWKS); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(domain.JWKS)
+	}
+
+	return r0
+}
+
+// Tokener_JWKS_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'JWKS'
+type Tokener_JWKS_Call struct {
+	*mock.Call
+}
+
+// JWKS is a helper method to define mock.On call
+func (_e *Tokener_Expecter) JWKS() *Tokener_JWKS_Call {
+	return &Tokener_JWKS_Call{Call: _e.mock.On("JWKS")}
+}
+
+func (_c *Tokener_JWKS_Call) Run(run func()) *Tokener_JWKS_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Tokener_JWKS_Call) Return(_a0 domain.JWKS) *Tokener_JWKS_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Tokener_JWKS_Call) RunAndReturn(run func() domain.JWKS) *Tokener_JWKS_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Verify provides a mock function with given fields: _a0
+func (_m *Tokener) Verify(_a0 []byte) (domain.Meta, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Verify")
+	}
+
+	var r0 domain.Meta
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]byte) (domain.Meta, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func([]byte) domain.Meta); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(domain.Meta)
+	}
+
+	if rf, ok := ret.Get(1).(func([]byte) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Tokener_Verify_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Verify'
+type Tokener_Verify_Call struct {
+	*mock.Call
+}
+
+// Verify is a helper method to define mock.On call
+//   - _a0 []byte
+func (_e *Tokener_Expecter) Verify(_a0 interface{}) *Tokener_Verify_Call {
+	return &Tokener_Verify_Call{Call: _e.mock.On("Verify", _a0)}
+}
+
+func (_c *Tokener_Verify_Call) Run(run func(_a0 []byte)) *Tokener_Verify_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]byte))
 	})
 	return _c
 }
 
-func (_c *Tokener_GenPair_Call) Return(access []byte, refresh []byte, err error) *Tokener_GenPair_Call {
-	_c.Call.Return(access, refresh, err)
+func (_c *Tokener_Verify_Call) Return(_a0 domain.Meta, _a1 error) *Tokener_Verify_Call {
+	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *Tokener_GenPair_Call) RunAndReturn(run func(domain.Meta) ([]byte, []byte, error)) *Tokener_GenPair_Call {
+func (_c *Tokener_Verify_Call) RunAndReturn(run func([]byte) (domain.Meta, error)) *Tokener_Verify_Call {
 	_c.Call.Return(run)
 	return _c
 }
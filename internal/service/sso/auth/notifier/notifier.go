@@ -0,0 +1,68 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
+	"github.com/go-faster/errors"
+)
+
+// notifier пишет уведомления json-строками в io.Writer (stdout/файл) — для dev и тестов
+type notifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func New(w io.Writer) authservice.Notifier {
+	return &notifier{
+		w: w,
+	}
+}
+
+// NewFromPath — пустой путь означает stdout, иначе файл открывается на дозапись
+func NewFromPath(path string) (authservice.Notifier, error) {
+	if path == "" {
+		return New(os.Stdout), nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, errors.Wrap(err, "open notifications file")
+	}
+
+	return New(f), nil
+}
+
+type message struct {
+	Purpose   string    `json:"purpose"`
+	To        string    `json:"to"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (n *notifier) Notify(_ context.Context, msg domain.Notification) error {
+	b, err := json.Marshal(message{
+		Purpose:   string(msg.Purpose),
+		To:        msg.To,
+		Token:     msg.Token,
+		ExpiresAt: msg.ExpiresAt,
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshal notification")
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, err := n.w.Write(append(b, '\n')); err != nil {
+		return errors.Wrap(err, "write notification")
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier_WritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	n := New(&buf)
+
+	exp := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	require.NoError(t, n.Notify(context.Background(), domain.Notification{
+		Purpose: domain.PurposePasswordReset, To: "a@b.c", Token: "tok-1", ExpiresAt: exp,
+	}))
+	require.NoError(t, n.Notify(context.Background(), domain.Notification{
+		Purpose: domain.PurposePasswordReset, To: "a@b.c", Token: "tok-2", ExpiresAt: exp,
+	}))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var got message
+	require.NoError(t, json.Unmarshal(lines[1], &got))
+	require.Equal(t, message{Purpose: "password_reset", To: "a@b.c", Token: "tok-2", ExpiresAt: exp}, got)
+}
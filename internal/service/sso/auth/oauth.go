@@ -0,0 +1,195 @@
+package authservice
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+const (
+	ErrFailedSaveCode      = "failed save authorization code"
+	ErrFailedConsumeCode   = "failed consume authorization code"
+	ErrFailedGenCode       = "failed generate authorization code"
+	ErrFailedExchangeCode  = "failed exchange authorization code"
+	ErrFailedOAuthRefresh  = "failed refresh oauth token"
+	ErrMfaCodeRequired     = "totp code required"
+	oauthDeviceID          = 0 // OAuth-сессии не привязаны к устройству клиента
+	pkceChallengeS256Len   = 43
+	pkceVerifierMinLen     = 43
+	pkceVerifierMaxLen     = 128
+	oidcNonceMaxLen        = 512
+	errDescUnknownClient   = "unknown client_id"
+	errDescBadRedirect     = "redirect_uri is not registered for client"
+	errDescInvalidGrant    = "authorization code is invalid, expired or was issued to another client"
+	errDescInvalidRefresh  = "refresh token is invalid, expired or revoked"
+	errDescInvalidVerifier = "code_verifier does not match code_challenge"
+	errDescUnauthorized    = "client is disabled or not allowed to use this grant"
+)
+
+// code_verifier: unreserved-символы RFC 3986 (RFC 7636, 4.1); code_challenge S256 — base64url без паддинга
+var (
+	pkceVerifierRe  = regexp.MustCompile(`^[A-Za-z0-9\-._~]+$`)
+	pkceChallengeRe = regexp.MustCompile(`^[A-Za-z0-9\-_]+$`)
+)
+
+// CheckAuthorizeRequest — проверка /authorize до показа формы входа.
+// Ошибки клиента и redirect_uri нельзя отправлять на redirect_uri (Redirect = false), остальные — можно
+func (s *Auth) CheckAuthorizeRequest(ctx context.Context, req domain.AuthorizeRequest) error {
+	_, err := s.authorizeClient(ctx, req)
+	return err
+}
+
+// authorizeClient — CheckAuthorizeRequest, возвращающий приложение-клиент
+func (s *Auth) authorizeClient(ctx context.Context, req domain.AuthorizeRequest) (domain.App, error) {
+	// неизвестный и отключённый клиент для /authorize неразличимы
+	client, err := s.allowedApp(ctx, req.ClientID, domain.GrantAuthorizationCode)
+	if err != nil {
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			return domain.App{}, domain.NewOAuthError(domain.OAuthInvalidClient, errDescUnknownClient)
+		}
+		return domain.App{}, err
+	}
+	if !client.RedirectAllowed(req.RedirectURI) {
+		return domain.App{}, domain.NewOAuthError(domain.OAuthInvalidRequest, errDescBadRedirect)
+	}
+
+	redirectable := func(code, desc string) error {
+		e := domain.NewOAuthError(code, desc)
+		e.Redirect = true
+		return e
+	}
+	if req.ResponseType != domain.ResponseTypeCode {
+		return domain.App{}, redirectable(domain.OAuthUnsupportedResponseType, "only response_type=code is supported")
+	}
+	// PKCE обязателен для всех клиентов, plain не принимается
+	if req.CodeChallengeMethod != domain.PKCEMethodS256 {
+		return domain.App{}, redirectable(domain.OAuthInvalidRequest, "code_challenge_method must be S256")
+	}
+	if len(req.CodeChallenge) != pkceChallengeS256Len || !pkceChallengeRe.MatchString(req.CodeChallenge) {
+		return domain.App{}, redirectable(domain.OAuthInvalidRequest, "code_challenge must be base64url of sha256")
+	}
+	if !client.ScopesAllowed(req.Scopes) {
+		return domain.App{}, redirectable(domain.OAuthInvalidScope, "scope is not allowed for client")
+	}
+	if len(req.Nonce) > oidcNonceMaxLen {
+		return domain.App{}, redirectable(domain.OAuthInvalidRequest, "nonce is too long")
+	}
+
+	return client, nil
+}
+
+// Authorize — отправка формы входа на /authorize: проверяет запрос и учётные данные,
+// при включённой MFA требует TOTP-код (без кода — ErrMfaRequired) и выпускает одноразовый код
+func (s *Auth) Authorize(ctx context.Context, req domain.AuthorizeRequest, creds domain.User, totpCode string) (string, error) {
+	client, err := s.authorizeClient(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := s.authenticatePassword(ctx, creds, client)
+	if err != nil {
+		return "", err
+	}
+
+	amr := []string{domain.AMRPassword}
+	mfa, err := s.r.GetUserMfa(ctx, u.ID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return "", errors.Wrap(err, ErrFailedGetMfa)
+	}
+	if err == nil && mfa.Enabled() {
+		if totpCode == "" {
+			return "", errors.Wrap(domain.ErrMfaRequired, ErrMfaCodeRequired)
+		}
+		if err := s.checkTotp(ctx, mfa, totpCode); err != nil {
+			return "", err
+		}
+		amr = append(amr, domain.AMROtp, domain.AMRMfa)
+	}
+
+	code, hash, err := s.genOneTimeToken()
+	if err != nil {
+		return "", errors.Wrap(err, ErrFailedGenCode)
+	}
+	if err := s.r.SaveAuthorizationCode(ctx, domain.NewAuthorizationCode(hash, u.ID, req, amr, s.cfg.OAuthCodeTTL)); err != nil {
+		return "", errors.Wrap(err, ErrFailedSaveCode)
+	}
+
+	return code, nil
+}
+
+// ExchangeAuthorizationCode — grant_type=authorization_code: код погашается при первом предъявлении,
+// даже если дальнейшие проверки не прошли; сессия открывается тем же путём, что и Login
+func (s *Auth) ExchangeAuthorizationCode(ctx context.Context, ex domain.CodeExchange) (domain.OAuthToken, error) {
+	if len(ex.CodeVerifier) < pkceVerifierMinLen || len(ex.CodeVerifier) > pkceVerifierMaxLen ||
+		!pkceVerifierRe.MatchString(ex.CodeVerifier) {
+		return domain.OAuthToken{}, domain.NewOAuthError(domain.OAuthInvalidRequest, "code_verifier must be 43-128 unreserved characters")
+	}
+
+	hash, err := s.tokenHasher.Sum([]byte(ex.Code))
+	if err != nil {
+		return domain.OAuthToken{}, errors.Wrap(err, ErrFailedHashToken)
+	}
+	code, err := s.r.ConsumeAuthorizationCode(ctx, string(hash))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.OAuthToken{}, domain.NewOAuthError(domain.OAuthInvalidGrant, errDescInvalidGrant)
+		}
+		return domain.OAuthToken{}, errors.Wrap(err, ErrFailedConsumeCode)
+	}
+	if code.ClientID != ex.ClientID || code.RedirectURI != ex.RedirectURI {
+		return domain.OAuthToken{}, domain.NewOAuthError(domain.OAuthInvalidGrant, errDescInvalidGrant)
+	}
+	if !code.VerifyPKCE(ex.CodeVerifier) {
+		return domain.OAuthToken{}, domain.NewOAuthError(domain.OAuthInvalidGrant, errDescInvalidVerifier)
+	}
+
+	// клиент могли отключить, пока пользователь входил
+	client, err := s.allowedApp(ctx, code.ClientID, domain.GrantAuthorizationCode)
+	if err != nil {
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			return domain.OAuthToken{}, domain.NewOAuthError(domain.OAuthUnauthorizedClient, errDescUnauthorized)
+		}
+		return domain.OAuthToken{}, err
+	}
+
+	// id_token до сессии: при ошибке не остаётся сессии, о которой клиент не узнает
+	var idToken string
+	if domain.HasScope(code.Scopes, domain.ScopeOpenID) {
+		if idToken, err = s.genIDToken(ctx, client, code); err != nil {
+			return domain.OAuthToken{}, err
+		}
+	}
+
+	token, err := s.openSession(ctx, client, code.UserID, domain.NewDeviceCtx(code.ClientID, oauthDeviceID), code.Scopes)
+	if err != nil {
+		return domain.OAuthToken{}, errors.Wrap(err, ErrFailedExchangeCode)
+	}
+
+	return domain.OAuthToken{
+		Token:     token,
+		IDToken:   idToken,
+		ExpiresIn: client.AccessTTL(s.cfg.AccessTokenTTL),
+		Scopes:    code.Scopes,
+	}, nil
+}
+
+// RefreshOAuthToken — grant_type=refresh_token: та же ротация, что у Refresh, scopes сохраняются
+func (s *Auth) RefreshOAuthToken(ctx context.Context, refresh string, clientID int32) (domain.OAuthToken, error) {
+	token, client, err := s.rotateRefresh(ctx, refresh, domain.NewDeviceCtx(clientID, oauthDeviceID))
+	if err != nil {
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			return domain.OAuthToken{}, domain.NewOAuthError(domain.OAuthUnauthorizedClient, errDescUnauthorized)
+		}
+		if errors.Is(err, domain.ErrValidation) || errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrTokenReuse) {
+			return domain.OAuthToken{}, domain.NewOAuthError(domain.OAuthInvalidGrant, errDescInvalidRefresh)
+		}
+		return domain.OAuthToken{}, errors.Wrap(err, ErrFailedOAuthRefresh)
+	}
+
+	return domain.OAuthToken{
+		Token:     token,
+		ExpiresIn: client.AccessTTL(s.cfg.AccessTokenTTL),
+	}, nil
+}
@@ -0,0 +1,134 @@
+package authservice
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+const (
+	ErrFailedGenIDToken = "failed generate id_token"
+	ErrNotOpenIDToken   = "access token has no openid scope"
+	ErrMachineToken     = "token of machine client has no user"
+	ErrInactiveToken    = "token is invalid, expired or revoked"
+	ErrOIDCNotEnabled   = "token issuer is not an http(s) url: oidc discovery disabled"
+)
+
+// пути HTTP-транспорта относительно issuer
+const (
+	pathAuthorize = "/oauth2/authorize"
+	pathToken     = "/oauth2/token"
+	pathUserinfo  = "/oauth2/userinfo"
+	pathJwks      = "/.well-known/jwks.json"
+)
+
+// OpenIDConfiguration — discovery строится от issuer, поэтому для OIDC BUSSINES_LOGIC_TOKEN_ISSUER —
+// публичный адрес SSO; иначе клиенты не сойдутся в проверке iss, и discovery отдаёт ErrNotFound
+func (s *Auth) OpenIDConfiguration() (domain.OpenIDConfiguration, error) {
+	u, err := url.Parse(s.cfg.TokenIssuer)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return domain.OpenIDConfiguration{}, errors.Wrap(domain.ErrNotFound, ErrOIDCNotEnabled)
+	}
+	base := strings.TrimRight(s.cfg.TokenIssuer, "/")
+
+	var algs []string
+	for _, k := range s.tokener.JWKS().Keys {
+		if !slices.Contains(algs, k.Alg) {
+			algs = append(algs, k.Alg)
+		}
+	}
+
+	return domain.OpenIDConfiguration{
+		Issuer:                            s.cfg.TokenIssuer,
+		AuthorizationEndpoint:             base + pathAuthorize,
+		TokenEndpoint:                     base + pathToken,
+		UserinfoEndpoint:                  base + pathUserinfo,
+		JwksURI:                           base + pathJwks,
+		ResponseTypesSupported:            []string{domain.ResponseTypeCode},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algs,
+		ScopesSupported:                   []string{domain.ScopeOpenID, domain.ScopeEmail},
+		GrantTypesSupported:               []string{domain.GrantAuthorizationCode, domain.GrantRefreshToken, domain.GrantClientCredentials},
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{domain.PKCEMethodS256},
+		ClaimsSupported: []string{"iss", "sub", "aud", "iat", "exp", "auth_time", "nonce", "amr",
+			"email", "email_verified"},
+	}, nil
+}
+
+// genIDToken — для кода со scope openid; email — только при scope email. Живёт столько же, сколько access
+func (s *Auth) genIDToken(ctx context.Context, client domain.App, code domain.AuthorizationCode) (string, error) {
+	now := time.Now()
+	id := domain.IDToken{
+		Subject:  code.UserID,
+		Audience: strconv.Itoa(int(code.ClientID)),
+		IssuedAt: now,
+		Exp:      now.Add(client.AccessTTL(s.cfg.AccessTokenTTL)),
+		AuthTime: code.AuthTime,
+		Nonce:    code.Nonce,
+		AMR:      code.AMR,
+	}
+	if domain.HasScope(code.Scopes, domain.ScopeEmail) {
+		u, err := s.r.GetUserInfoByID(ctx, code.UserID)
+		if err != nil {
+			return "", errors.Wrap(err, ErrFailedGetUserInfo)
+		}
+		id.Email = u.Email
+		id.EmailVerified = u.IsEmailVerified()
+	}
+
+	signed, err := s.tokener.GenIDToken(id)
+	if err != nil {
+		return "", errors.Wrap(err, ErrFailedGenIDToken)
+	}
+	return string(signed), nil
+}
+
+// UserInfo — OIDC userinfo по access-токену пользователя со scope openid.
+// Недействительный или отозванный токен и токен машинного клиента — ErrValidation, нет openid — ErrInsufficientScope
+func (s *Auth) UserInfo(ctx context.Context, access string) (domain.UserInfo, error) {
+	m, err := s.tokener.Verify([]byte(access))
+	if err != nil {
+		return domain.UserInfo{}, errors.Wrapf(domain.ErrValidation, "%s: %v", ErrInactiveToken, err)
+	}
+	if m.Type != domain.TokenTypeAccess {
+		return domain.UserInfo{}, errors.Wrap(domain.ErrValidation, ErrInactiveToken)
+	}
+	if m.UserID == "" {
+		return domain.UserInfo{}, errors.Wrap(domain.ErrValidation, ErrMachineToken)
+	}
+
+	active, err := s.tokenActive(ctx, access, m)
+	if err != nil {
+		return domain.UserInfo{}, errors.Wrap(err, ErrFailedCheckRevoked)
+	}
+	if !active {
+		return domain.UserInfo{}, errors.Wrap(domain.ErrValidation, ErrInactiveToken)
+	}
+	if !domain.HasScope(m.Scopes, domain.ScopeOpenID) {
+		return domain.UserInfo{}, errors.Wrap(domain.ErrInsufficientScope, ErrNotOpenIDToken)
+	}
+
+	info := domain.UserInfo{Subject: m.UserID}
+	if !domain.HasScope(m.Scopes, domain.ScopeEmail) {
+		return info, nil
+	}
+
+	u, err := s.r.GetUserInfoByID(ctx, m.UserID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.UserInfo{}, errors.Wrap(domain.ErrValidation, ErrInactiveToken)
+		}
+		return domain.UserInfo{}, errors.Wrap(err, ErrFailedGetUserInfo)
+	}
+	info.Email = u.Email
+	info.EmailVerified = u.IsEmailVerified()
+
+	return info, nil
+}
@@ -0,0 +1,207 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
+	"github.com/go-faster/errors"
+)
+
+// минимальная длина local-part email, начиная с которой его вхождение в пароль запрещено
+const minEmailPartLen = 3
+
+//go:embed common.txt
+var embeddedCommon string
+
+// Rules — правила для одного приложения
+type Rules struct {
+	MinLength     int  `json:"min_length"`
+	MaxLength     int  `json:"max_length"` // 0 — без ограничения
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	ForbidCommon  bool `json:"forbid_common"`
+	ForbidEmail   bool `json:"forbid_email"`  // запрет на вхождение local-part email
+	HistoryDepth  int  `json:"history_depth"` // сколько последних паролей нельзя переиспользовать
+}
+
+// DefaultRules — политика без файла конфигурации
+var DefaultRules = Rules{
+	MinLength:    8,
+	MaxLength:    128,
+	ForbidCommon: true,
+	ForbidEmail:  true,
+	HistoryDepth: 5,
+}
+
+type policy struct {
+	def      Rules
+	apps     map[int32]Rules
+	common   map[string]struct{}
+	maxBytes int
+}
+
+// New — политика def для всех приложений, кроме перечисленных в apps.
+// common — дополнительные распространённые пароли к встроенному списку.
+// maxBytes — предел длины в байтах от хэшера (bcrypt — 72), действует поверх правил; 0 — без предела
+func New(def Rules, apps map[int32]Rules, common []string, maxBytes int) authservice.PasswordPolicy {
+	p := &policy{
+		def:      def,
+		apps:     apps,
+		common:   make(map[string]struct{}),
+		maxBytes: maxBytes,
+	}
+	for _, w := range strings.Split(embeddedCommon, "\n") {
+		p.addCommon(w)
+	}
+	for _, w := range common {
+		p.addCommon(w)
+	}
+
+	return p
+}
+
+// file — формат BUSSINES_LOGIC_PASSWORD_POLICY_PATH; правила приложения накладываются поверх default
+type file struct {
+	Default *Rules                     `json:"default"`
+	Apps    map[string]json.RawMessage `json:"apps"`
+}
+
+// NewFromFiles читает политику (json) и список запрещённых паролей (по одному в строке).
+// Пустой путь политики — DefaultRules для всех, пустой путь списка — только встроенный список
+func NewFromFiles(policyPath, blocklistPath string, maxBytes int) (authservice.PasswordPolicy, error) {
+	def := DefaultRules
+	apps := make(map[int32]Rules)
+
+	if policyPath != "" {
+		raw, err := os.ReadFile(policyPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "read password policy")
+		}
+		var f file
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return nil, errors.Wrap(err, "parse password policy")
+		}
+		if f.Default != nil {
+			def = *f.Default
+		}
+		for id, rawRules := range f.Apps {
+			appID, err := strconv.ParseInt(id, 10, 32)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse app id %q", id)
+			}
+			rules := def
+			if err := json.Unmarshal(rawRules, &rules); err != nil {
+				return nil, errors.Wrapf(err, "parse rules of app %d", appID)
+			}
+			apps[int32(appID)] = rules
+		}
+	}
+
+	var common []string
+	if blocklistPath != "" {
+		f, err := os.Open(blocklistPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "open password blocklist")
+		}
+		defer f.Close()
+
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			common = append(common, sc.Text())
+		}
+		if err := sc.Err(); err != nil {
+			return nil, errors.Wrap(err, "read password blocklist")
+		}
+	}
+
+	return New(def, apps, common, maxBytes), nil
+}
+
+func (p *policy) addCommon(w string) {
+	if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+		p.common[w] = struct{}{}
+	}
+}
+
+func (p *policy) rules(appID int32) Rules {
+	if r, ok := p.apps[appID]; ok {
+		return r
+	}
+	return p.def
+}
+
+func (p *policy) HistoryDepth(appID int32) int {
+	return p.rules(appID).HistoryDepth
+}
+
+func (p *policy) Check(appID int32, email, pass string) []domain.PasswordViolation {
+	r := p.rules(appID)
+	var vs []domain.PasswordViolation
+	add := func(rule, msg string) {
+		vs = append(vs, domain.PasswordViolation{Rule: rule, Message: msg})
+	}
+
+	n := utf8.RuneCountInString(pass)
+	if n < r.MinLength {
+		add(domain.RuleMinLength, fmt.Sprintf("must be at least %d characters", r.MinLength))
+	}
+	switch {
+	case r.MaxLength > 0 && n > r.MaxLength:
+		add(domain.RuleMaxLength, fmt.Sprintf("must be at most %d characters", r.MaxLength))
+	// хэшер отбрасывает или отвергает байты сверх предела
+	case p.maxBytes > 0 && len(pass) > p.maxBytes:
+		add(domain.RuleMaxLength, fmt.Sprintf("must be at most %d bytes", p.maxBytes))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, c := range pass {
+		switch {
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsDigit(c):
+			digit = true
+		case !unicode.IsLetter(c):
+			symbol = true
+		}
+	}
+	if r.RequireUpper && !upper {
+		add(domain.RuleUpper, "must contain an uppercase letter")
+	}
+	if r.RequireLower && !lower {
+		add(domain.RuleLower, "must contain a lowercase letter")
+	}
+	if r.RequireDigit && !digit {
+		add(domain.RuleDigit, "must contain a digit")
+	}
+	if r.RequireSymbol && !symbol {
+		add(domain.RuleSymbol, "must contain a symbol")
+	}
+
+	lowerPass := strings.ToLower(pass)
+	if r.ForbidCommon {
+		if _, ok := p.common[lowerPass]; ok {
+			add(domain.RuleCommon, "is too common")
+		}
+	}
+	if r.ForbidEmail {
+		local, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+		if utf8.RuneCountInString(local) >= minEmailPartLen && strings.Contains(lowerPass, local) {
+			add(domain.RuleContainsEmail, "must not contain the email name")
+		}
+	}
+
+	return vs
+}
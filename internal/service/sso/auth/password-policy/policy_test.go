@@ -0,0 +1,95 @@
+package passwordpolicy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func rules(vs []domain.PasswordViolation) []string {
+	out := make([]string, 0, len(vs))
+	for _, v := range vs {
+		out = append(out, v.Rule)
+	}
+	return out
+}
+
+func TestCheck_Default(t *testing.T) {
+	p := New(DefaultRules, nil, nil, 0)
+
+	require.Empty(t, p.Check(0, "alice@example.com", "correct horse battery staple"))
+	require.Equal(t, []string{domain.RuleMinLength}, rules(p.Check(0, "alice@example.com", "x7#kq")))
+	require.Equal(t, []string{domain.RuleCommon}, rules(p.Check(0, "alice@example.com", "Password123")))
+	require.Equal(t, []string{domain.RuleContainsEmail}, rules(p.Check(0, "Alice@example.com", "my-ALICE-pass")))
+	// короткий local-part не проверяется
+	require.Empty(t, p.Check(0, "al@example.com", "always-blue-sky"))
+	// длина считается в символах, а не байтах
+	require.Empty(t, p.Check(0, "a@b.c", "пароль-кириллицей"))
+	require.Equal(t, []string{domain.RuleMaxLength}, rules(p.Check(0, "a@b.c", string(make([]byte, 129)))))
+	require.Equal(t, 5, p.HistoryDepth(0))
+}
+
+func TestCheck_MaxBytes(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	cyrillic := strings.Repeat("ж", 40) // 40 символов, 80 байт
+
+	// без предела хэшера действует только max_length в символах
+	require.Empty(t, New(DefaultRules, nil, nil, 0).Check(0, "a@b.c", long))
+
+	p := New(DefaultRules, nil, nil, 72)
+	require.Equal(t, []string{domain.RuleMaxLength}, rules(p.Check(0, "a@b.c", long)))
+	require.Equal(t, []string{domain.RuleMaxLength}, rules(p.Check(0, "a@b.c", cyrillic)))
+	require.Empty(t, p.Check(0, "a@b.c", strings.Repeat("a", 72)))
+	// превышение обоих пределов — одно нарушение
+	require.Len(t, p.Check(0, "a@b.c", string(make([]byte, 129))), 1)
+}
+
+func TestCheck_CharacterClassesPerApp(t *testing.T) {
+	strict := DefaultRules
+	strict.RequireUpper, strict.RequireLower, strict.RequireDigit, strict.RequireSymbol = true, true, true, true
+	p := New(DefaultRules, map[int32]Rules{7: strict}, []string{"Corp-Secret-2024"}, 0)
+
+	require.Empty(t, p.Check(1, "a@b.c", "only lowercase words"))
+	require.Equal(t,
+		[]string{domain.RuleUpper, domain.RuleDigit, domain.RuleSymbol},
+		rules(p.Check(7, "a@b.c", "onlylowercase")),
+	)
+	require.Empty(t, p.Check(7, "a@b.c", "Tr0ub4dor&3x"))
+	// дополнительный список сравнивается без учёта регистра
+	require.Equal(t, []string{domain.RuleCommon}, rules(p.Check(1, "a@b.c", "corp-secret-2024")))
+}
+
+func TestNewFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	policyPath := filepath.Join(dir, "policy.json")
+	blocklistPath := filepath.Join(dir, "blocklist.txt")
+
+	require.NoError(t, os.WriteFile(policyPath, []byte(`{
+		"default": {"min_length": 10, "max_length": 64, "forbid_common": true, "history_depth": 3},
+		"apps": {"2": {"require_digit": true, "history_depth": 0}}
+	}`), 0o600))
+	require.NoError(t, os.WriteFile(blocklistPath, []byte("acme-rocks-1\n\n  Widget2025  \n"), 0o600))
+
+	p, err := NewFromFiles(policyPath, blocklistPath, 0)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{domain.RuleMinLength}, rules(p.Check(1, "a@b.c", "nine-char")))
+	require.Equal(t, []string{domain.RuleCommon}, rules(p.Check(1, "a@b.c", "widget2025")))
+	require.Equal(t, []string{domain.RuleCommon}, rules(p.Check(1, "a@b.c", "acme-rocks-1")))
+	require.Equal(t, 3, p.HistoryDepth(1))
+
+	// приложение наследует default и переопределяет только указанные поля
+	require.Equal(t, []string{domain.RuleMinLength, domain.RuleDigit}, rules(p.Check(2, "a@b.c", "no-digits")))
+	require.Equal(t, 0, p.HistoryDepth(2))
+
+	_, err = NewFromFiles(filepath.Join(dir, "missing.json"), "", 0)
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(policyPath, []byte(`{"apps": {"x": {}}}`), 0o600))
+	_, err = NewFromFiles(policyPath, "", 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,79 @@
+package authservice
+
+import (
+	"context"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+const (
+	ErrFailedGetHistory  = "failed get password history"
+	ErrFailedCheckBreach = "failed check breached passwords"
+)
+
+// checkPassword — политика приложения, корпус утёкших паролей и запрет повторного использования последних паролей.
+// Для ещё не созданного пользователя (пустой ID) история не проверяется
+func (s *Auth) checkPassword(ctx context.Context, appID int32, u domain.User, pass string) error {
+	violations := s.passPolicy.Check(appID, u.Email, pass)
+
+	n, err := s.breach.Breached(pass)
+	if err != nil {
+		return errors.Wrap(err, ErrFailedCheckBreach)
+	}
+	if n > 0 {
+		violations = append(violations, domain.PasswordViolation{
+			Rule:    domain.RuleBreached,
+			Message: "password found in breach",
+		})
+	}
+
+	if depth := s.passPolicy.HistoryDepth(appID); depth > 0 && u.ID != "" {
+		reused, err := s.passwordReused(ctx, u.ID, pass, depth)
+		if err != nil {
+			return errors.Wrap(err, ErrFailedCheckPolicy)
+		}
+		if reused {
+			violations = append(violations, domain.PasswordViolation{
+				Rule:    domain.RuleReused,
+				Message: "must differ from recently used passwords",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &domain.PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func (s *Auth) passwordReused(ctx context.Context, userID, pass string, depth int) (bool, error) {
+	hashes, err := s.r.GetPasswordHistory(ctx, userID, depth)
+	if err != nil {
+		return false, errors.Wrap(err, ErrFailedGetHistory)
+	}
+
+	for _, h := range hashes {
+		// bcrypt сообщает о несовпадении ошибкой — считаем это просто «не совпал»
+		if ok, _ := s.passHasher.Compare([]byte(h), []byte(pass)); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// setPassword хэширует и сохраняет новый пароль, добавляя его в историю
+func (s *Auth) setPassword(ctx context.Context, userID, pass string) error {
+	hashedPass, err := s.passHasher.Gen([]byte(pass))
+	if err != nil {
+		return errors.Wrap(err, ErrFailedHashPass)
+	}
+	if err := s.r.UpdateUserPassword(ctx, userID, string(hashedPass)); err != nil {
+		return errors.Wrap(err, ErrFailedUpdatePass)
+	}
+	if err := s.r.SavePasswordHistory(ctx, userID, string(hashedPass)); err != nil {
+		return errors.Wrap(err, ErrFailedSaveHistory)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package authservice
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+const (
+	ErrUnknownPasswordless       = "unknown passwordless method"
+	ErrFailedGenLoginCode        = "failed generate passwordless code"
+	ErrFailedSavePasswordless    = "failed save passwordless challenge"
+	ErrFailedSendPasswordless    = "failed send passwordless link or code"
+	ErrInvalidPasswordless       = "passwordless link or code invalid or expired"
+	ErrFailedConsumePasswordless = "failed consume passwordless challenge"
+)
+
+// passwordlessCodeMax — код из 6 цифр, 000000–999999
+var passwordlessCodeMax = big.NewInt(1_000_000)
+
+// StartPasswordlessLogin отправляет на email ссылку или код для входа без пароля.
+// Неизвестный email и пользователь каталога — тот же ответ без письма: по ответу нельзя узнать, есть ли аккаунт
+func (s *Auth) StartPasswordlessLogin(ctx context.Context, email string, method domain.PasswordlessMethod, dctx domain.DeviceCtx) (domain.PasswordlessLogin, error) {
+	app, err := s.allowedApp(ctx, dctx.AppId, domain.GrantPasswordless)
+	if err != nil {
+		return domain.PasswordlessLogin{}, err
+	}
+	if method != domain.PasswordlessLink && method != domain.PasswordlessCode {
+		return domain.PasswordlessLogin{}, errors.Wrapf(domain.ErrValidation, "%s: %q", ErrUnknownPasswordless, method)
+	}
+	if !app.AllowsEmail(email) {
+		return domain.PasswordlessLogin{}, errors.Wrap(domain.ErrAppAccessDenied, ErrEmailNotAllowed)
+	}
+	// код подбирается так же, как пароль: под блокировкой новые письма не отправляются
+	if err := s.checkLoginLock(ctx, loginAttemptKeys(ctx, email)); err != nil {
+		return domain.PasswordlessLogin{}, err
+	}
+
+	token, hash, err := s.genOneTimeToken()
+	if err != nil {
+		return domain.PasswordlessLogin{}, errors.Wrap(err, ErrFailedGenOneTime)
+	}
+	res := domain.PasswordlessLogin{ExpiresAt: time.Now().Add(s.cfg.PasswordlessTTL)}
+	if method == domain.PasswordlessCode {
+		// код без challenge бесполезен: перебирать 6 цифр можно только в пределах одного challenge
+		res.Challenge = token
+	}
+
+	u, err := s.r.GetUserInfoByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return res, nil
+		}
+		return domain.PasswordlessLogin{}, errors.Wrap(err, ErrFailedGetUserInfo)
+	}
+	// пользователь каталога входит только через каталог: письмо обошло бы его блокировку
+	if !u.HasLocalPassword() {
+		return res, nil
+	}
+
+	c := domain.PasswordlessChallenge{
+		Hash:     hash,
+		UserID:   u.ID,
+		Email:    u.Email,
+		Ctx:      dctx,
+		Attempts: s.cfg.PasswordlessMaxAttempts,
+		Exp:      res.ExpiresAt,
+	}
+	n := domain.Notification{
+		Purpose:   domain.PurposePasswordlessLink,
+		To:        u.Email,
+		Token:     token,
+		ExpiresAt: res.ExpiresAt,
+	}
+	if method == domain.PasswordlessCode {
+		code, err := genPasswordlessCode()
+		if err != nil {
+			return domain.PasswordlessLogin{}, err
+		}
+		codeHash, err := s.tokenHasher.Sum([]byte(code))
+		if err != nil {
+			return domain.PasswordlessLogin{}, errors.Wrap(err, ErrFailedHashOneTime)
+		}
+		c.CodeHash = string(codeHash)
+		n.Purpose, n.Token = domain.PurposePasswordlessCode, code
+	}
+
+	if err := s.r.SavePasswordlessChallenge(ctx, c); err != nil {
+		return domain.PasswordlessLogin{}, errors.Wrap(err, ErrFailedSavePasswordless)
+	}
+	if err := s.notifier.Notify(ctx, n); err != nil {
+		return domain.PasswordlessLogin{}, errors.Wrap(err, ErrFailedSendPasswordless)
+	}
+
+	return res, nil
+}
+
+// CompletePasswordlessLogin — токен ссылки (code пустой) или challenge с кодом из письма меняются на сессию.
+// Ответ как у Login: при включённой MFA — челлендж для CompleteMfaLogin
+func (s *Auth) CompletePasswordlessLogin(ctx context.Context, challenge, code string, dctx domain.DeviceCtx) (domain.LoginResult, error) {
+	app, err := s.allowedApp(ctx, dctx.AppId, domain.GrantPasswordless)
+	if err != nil {
+		return domain.LoginResult{}, err
+	}
+
+	hash, err := s.tokenHasher.Sum([]byte(challenge))
+	if err != nil {
+		return domain.LoginResult{}, errors.Wrap(err, ErrFailedHashToken)
+	}
+	var codeHash []byte
+	if code != "" {
+		if codeHash, err = s.tokenHasher.Sum([]byte(code)); err != nil {
+			return domain.LoginResult{}, errors.Wrap(err, ErrFailedHashToken)
+		}
+	}
+
+	c, err := s.r.ConsumePasswordlessChallenge(ctx, string(hash), string(codeHash))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.LoginResult{}, errors.Wrap(domain.ErrValidation, ErrInvalidPasswordless)
+		}
+		// неверный код считается неудачным входом: число challenge на email ограничено блокировкой
+		if errors.Is(err, domain.ErrValidation) {
+			return domain.LoginResult{}, s.loginFailed(ctx, loginAttemptKeys(ctx, c.Email))
+		}
+		return domain.LoginResult{}, errors.Wrap(err, ErrFailedConsumePasswordless)
+	}
+	if !dctx.Compare(c.Ctx) {
+		return domain.LoginResult{}, errors.Wrap(domain.ErrValidation, ErrUnauthenticatedCtx)
+	}
+
+	u, err := s.r.GetUserInfoByID(ctx, c.UserID)
+	if err != nil {
+		return domain.LoginResult{}, errors.Wrap(err, ErrFailedGetUserInfo)
+	}
+	// challenge мог быть выдан до привязки к каталогу
+	if !u.HasLocalPassword() {
+		return domain.LoginResult{}, errors.Wrap(domain.ErrValidation, ErrInvalidPasswordless)
+	}
+	// домены приложения могли смениться после письма
+	if !app.AllowsEmail(u.Email) {
+		return domain.LoginResult{}, errors.Wrap(domain.ErrAppAccessDenied, ErrEmailNotAllowed)
+	}
+	if err := s.r.ResetLoginFailures(ctx, []string{emailAttemptKey(u.Email)}); err != nil {
+		return domain.LoginResult{}, errors.Wrap(err, ErrFailedResetAttempts)
+	}
+	// письмо дошло — email подтверждён
+	if !u.IsEmailVerified() {
+		if err := s.r.MarkEmailVerified(ctx, u.ID); err != nil {
+			return domain.LoginResult{}, errors.Wrap(err, ErrFailedMarkVerified)
+		}
+	}
+	if err := s.deviceOwned(ctx, u.ID, dctx); err != nil {
+		return domain.LoginResult{}, err
+	}
+
+	return s.sessionOrMfa(ctx, app, u.ID, dctx)
+}
+
+func genPasswordlessCode() (string, error) {
+	n, err := rand.Int(rand.Reader, passwordlessCodeMax)
+	if err != nil {
+		return "", errors.Wrap(err, ErrFailedGenLoginCode)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,178 @@
+package pwned
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/go-faster/errors"
+)
+
+// Индекс отсортированного корпуса: для каждого из 16^5 префиксов — смещение первой строки с этим
+// (или большим) префиксом. Поиск читает 16 байт индекса и только строки своего префикса.
+//
+// Формат <file>.idx: magic, размер и mtime корпуса (индекс перестраивается, если корпус изменился),
+// затем 16^5+1 смещений uint64 little-endian; последнее — размер корпуса.
+const (
+	indexMagic   = "PWNIDX01"
+	indexHeader  = len(indexMagic) + 8 + 8
+	prefixCount  = 1 << 20
+	indexSuffix  = ".idx"
+	offsetSize   = 8
+	maxRangeSize = 16 << 20 // защита от битого индекса
+)
+
+var errUnsorted = errors.New("pwned corpus is not sorted by hash")
+
+type sorted struct {
+	corpus *os.File
+	index  *os.File
+}
+
+func openSorted(path string) (*sorted, error) {
+	corpus, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open pwned corpus")
+	}
+	fi, err := corpus.Stat()
+	if err != nil {
+		corpus.Close()
+		return nil, errors.Wrap(err, "stat pwned corpus")
+	}
+
+	idxPath := path + indexSuffix
+	if !indexValid(idxPath, fi) {
+		if err := buildIndex(corpus, idxPath, fi); err != nil {
+			corpus.Close()
+			return nil, errors.Wrap(err, "build pwned index")
+		}
+	}
+
+	index, err := os.Open(idxPath)
+	if err != nil {
+		corpus.Close()
+		return nil, errors.Wrap(err, "open pwned index")
+	}
+
+	return &sorted{corpus: corpus, index: index}, nil
+}
+
+func header(fi os.FileInfo) []byte {
+	h := make([]byte, 0, indexHeader)
+	h = append(h, indexMagic...)
+	h = binary.LittleEndian.AppendUint64(h, uint64(fi.Size()))
+	h = binary.LittleEndian.AppendUint64(h, uint64(fi.ModTime().UnixNano()))
+	return h
+}
+
+func indexValid(idxPath string, fi os.FileInfo) bool {
+	f, err := os.Open(idxPath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil || st.Size() != int64(indexHeader+(prefixCount+1)*offsetSize) {
+		return false
+	}
+	got := make([]byte, indexHeader)
+	if _, err := io.ReadFull(f, got); err != nil {
+		return false
+	}
+
+	return string(got) == string(header(fi))
+}
+
+// buildIndex — один проход по корпусу; пишет во временный файл и атомарно переименовывает
+func buildIndex(corpus *os.File, idxPath string, fi os.FileInfo) error {
+	offsets := make([]uint64, prefixCount+1)
+	next := 0 // первый префикс, смещение которого ещё не заполнено
+
+	r := bufio.NewReaderSize(io.NewSectionReader(corpus, 0, fi.Size()), 1<<20)
+	var pos uint64
+	for {
+		line, err := r.ReadSlice('\n')
+		if len(line) >= prefixLen {
+			p, perr := strconv.ParseUint(string(line[:prefixLen]), 16, 32)
+			if perr != nil {
+				return errors.Wrapf(perr, "bad line at offset %d", pos)
+			}
+			if int(p) < next-1 {
+				return errors.Wrapf(errUnsorted, "at offset %d", pos)
+			}
+			for ; next <= int(p); next++ {
+				offsets[next] = pos
+			}
+		}
+		pos += uint64(len(line))
+
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return errors.Errorf("line too long at offset %d", pos)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "read corpus")
+		}
+	}
+	for ; next <= prefixCount; next++ {
+		offsets[next] = pos
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(idxPath), "pwned-idx-*")
+	if err != nil {
+		return errors.Wrap(err, "create index")
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if _, err := w.Write(header(fi)); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "write index")
+	}
+	buf := make([]byte, offsetSize)
+	for _, off := range offsets {
+		binary.LittleEndian.PutUint64(buf, off)
+		if _, err := w.Write(buf); err != nil {
+			tmp.Close()
+			return errors.Wrap(err, "write index")
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "flush index")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "close index")
+	}
+
+	return os.Rename(tmp.Name(), idxPath)
+}
+
+func (s *sorted) Breached(pass string) (int, error) {
+	h := sha1Hex(pass)
+	p, err := strconv.ParseUint(h[:prefixLen], 16, 32)
+	if err != nil {
+		return 0, errors.Wrap(err, "parse prefix")
+	}
+
+	buf := make([]byte, 2*offsetSize)
+	if _, err := s.index.ReadAt(buf, int64(indexHeader)+int64(p)*offsetSize); err != nil {
+		return 0, errors.Wrap(err, "read pwned index")
+	}
+	from := binary.LittleEndian.Uint64(buf[:offsetSize])
+	to := binary.LittleEndian.Uint64(buf[offsetSize:])
+	if to <= from {
+		return 0, nil
+	}
+	if to-from > maxRangeSize {
+		return 0, errors.Errorf("pwned index range too large: %d bytes", to-from)
+	}
+
+	return scan(io.NewSectionReader(s.corpus, int64(from), int64(to-from)), h)
+}
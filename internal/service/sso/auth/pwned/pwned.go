@@ -0,0 +1,93 @@
+package pwned
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
+	"github.com/go-faster/errors"
+)
+
+const (
+	prefixLen = 5  // hex-символов в префиксе k-anonymity (как в range API HIBP)
+	hashLen   = 40 // hex-символов в SHA-1
+)
+
+// NewFromPath открывает локальный корпус Pwned Passwords (SHA-1):
+//   - каталог: файлы <PREFIX>.txt со строками "<SUFFIX>:<COUNT>" (формат range API, как у pwned-passwords-downloader);
+//   - файл: строки "<HASH>:<COUNT>", отсортированные по хэшу; рядом строится индекс <file>.idx.
+//
+// Пустой путь — проверка выключена
+func NewFromPath(path string) (authservice.BreachChecker, error) {
+	if path == "" {
+		return disabled{}, nil
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "stat pwned corpus")
+	}
+	if fi.IsDir() {
+		return &rangeDir{dir: path}, nil
+	}
+
+	return openSorted(path)
+}
+
+type disabled struct{}
+
+func (disabled) Breached(string) (int, error) { return 0, nil }
+
+// sha1Hex — SHA-1 пароля в верхнем регистре, как в корпусе HIBP
+func sha1Hex(pass string) string {
+	sum := sha1.Sum([]byte(pass))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// rangeDir — корпус в формате range API: один файл на префикс
+type rangeDir struct {
+	dir string
+}
+
+func (d *rangeDir) Breached(pass string) (int, error) {
+	h := sha1Hex(pass)
+
+	f, err := os.Open(filepath.Join(d.dir, h[:prefixLen]+".txt"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, errors.Wrap(err, "open pwned range file")
+	}
+	defer f.Close()
+
+	return scan(f, h[prefixLen:])
+}
+
+// scan ищет строку "<want>:<COUNT>" (регистр хэша не важен)
+func scan(r io.Reader, want string) (int, error) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		hash, count, ok := bytes.Cut(bytes.TrimSpace(sc.Bytes()), []byte(":"))
+		if !ok || !strings.EqualFold(string(hash), want) {
+			continue
+		}
+		n, err := strconv.Atoi(string(count))
+		if err != nil {
+			return 0, errors.Wrap(err, "parse pwned count")
+		}
+		return n, nil
+	}
+	if err := sc.Err(); err != nil {
+		return 0, errors.Wrap(err, "read pwned corpus")
+	}
+
+	return 0, nil
+}
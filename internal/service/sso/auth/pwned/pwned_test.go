@@ -0,0 +1,98 @@
+package pwned
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// корпус из известных паролей и «шума» с соседними префиксами
+func corpusLines(t *testing.T, known map[string]int) []string {
+	t.Helper()
+	lines := []string{
+		"0000000000000000000000000000000000000000:1",
+		"5BAA600000000000000000000000000000000000:7",
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:3",
+	}
+	for pass, n := range known {
+		lines = append(lines, sha1Hex(pass)+":"+strconv.Itoa(n))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestDisabled(t *testing.T) {
+	c, err := NewFromPath("")
+	require.NoError(t, err)
+	n, err := c.Breached("password")
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestRangeDir(t *testing.T) {
+	dir := t.TempDir()
+	h := sha1Hex("password") // 5BAA6...
+	require.NoError(t, os.WriteFile(filepath.Join(dir, h[:5]+".txt"), []byte(
+		"003D68EB55068C33ACE09247EE4C639306B:3\r\n"+strings.ToLower(h[5:])+":9545824\r\n",
+	), 0o600))
+
+	c, err := NewFromPath(dir)
+	require.NoError(t, err)
+
+	n, err := c.Breached("password")
+	require.NoError(t, err)
+	require.Equal(t, 9545824, n)
+
+	// файла префикса нет — пароль не найден
+	n, err = c.Breached("correct horse battery staple")
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestSortedFileWithIndex(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pwned.txt")
+	lines := corpusLines(t, map[string]int{"password": 100, "qwerty": 5})
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+
+	c, err := NewFromPath(path)
+	require.NoError(t, err)
+	_, err = os.Stat(path + indexSuffix)
+	require.NoError(t, err, "index must be created next to corpus")
+
+	for pass, want := range map[string]int{"password": 100, "qwerty": 5, "not-in-corpus-1": 0} {
+		n, err := c.Breached(pass)
+		require.NoError(t, err)
+		require.Equal(t, want, n, pass)
+	}
+
+	// корпус изменился — индекс перестраивается при следующем открытии
+	lines = corpusLines(t, map[string]int{"letmein": 11})
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	c, err = NewFromPath(path)
+	require.NoError(t, err)
+	n, err := c.Breached("letmein")
+	require.NoError(t, err)
+	require.Equal(t, 11, n)
+	n, err = c.Breached("password")
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestSortedFileUnsorted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	require.NoError(t, os.WriteFile(path, []byte(
+		"FFFFF00000000000000000000000000000000000:1\n00000000000000000000000000000000000000AA:1\n",
+	), 0o600))
+
+	_, err := NewFromPath(path)
+	require.ErrorIs(t, err, errUnsorted)
+}
@@ -0,0 +1,59 @@
+package secretcipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+
+	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
+	"github.com/go-faster/errors"
+)
+
+const keyLen = 32 // AES-256
+
+// secretCipher — AES-256-GCM; на выходе nonce||ciphertext
+type secretCipher struct {
+	aead cipher.AEAD
+}
+
+func New(key []byte) (authservice.SecretCipher, error) {
+	if len(key) != keyLen {
+		return nil, errors.Errorf("key must be %d bytes, got %d", keyLen, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "new aes cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "new gcm")
+	}
+
+	return &secretCipher{
+		aead: aead,
+	}, nil
+}
+
+func (c *secretCipher) Seal(plain []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "read nonce")
+	}
+
+	return c.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func (c *secretCipher) Open(sealed []byte) ([]byte, error) {
+	ns := c.aead.NonceSize()
+	if len(sealed) < ns {
+		return nil, errors.New("sealed data too short")
+	}
+
+	plain, err := c.aead.Open(nil, sealed[:ns], sealed[ns:], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "open sealed data")
+	}
+
+	return plain, nil
+}
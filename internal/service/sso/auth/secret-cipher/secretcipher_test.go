@@ -0,0 +1,42 @@
+package secretcipher
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretCipher_SealOpen(t *testing.T) {
+	c, err := New(bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+
+	sealed, err := c.Seal([]byte("JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+	require.NotContains(t, string(sealed), "JBSWY3DPEHPK3PXP")
+
+	plain, err := c.Open(sealed)
+	require.NoError(t, err)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", string(plain))
+
+	t.Run("tampered rejected", func(t *testing.T) {
+		sealed[len(sealed)-1] ^= 0xff
+		_, err := c.Open(sealed)
+		require.Error(t, err)
+	})
+
+	t.Run("foreign key rejected", func(t *testing.T) {
+		other, err := New(bytes.Repeat([]byte{8}, 32))
+		require.NoError(t, err)
+
+		sealed, err := c.Seal([]byte("x"))
+		require.NoError(t, err)
+		_, err = other.Open(sealed)
+		require.Error(t, err)
+	})
+
+	t.Run("bad key length", func(t *testing.T) {
+		_, err := New([]byte("short"))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,198 @@
+package tokeneradapter
+
+import (
+	"crypto"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// KeySetManifest — файл в каталоге ключей со статусами: {"active": "<kid>", "retiring": ["<kid>", ...]}
+	KeySetManifest = "keyset.json"
+	keyFileExt     = ".pem"
+	keyUseSig      = "sig"
+)
+
+var ErrUnknownKid = errors.New("unknown signing key id")
+
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	priv   crypto.Signer // nil у retiring-ключа, от которого остался только открытый
+	pub    crypto.PublicKey
+}
+
+// KeySet — ключи подписи: active подписывает новые токены,
+// retiring только проверяет ранее выданные, пока они не истекут
+type KeySet struct {
+	active signingKey
+	byKid  map[string]signingKey
+	order  []string // порядок публикации в JWKS: active первым
+}
+
+type keySetManifest struct {
+	Active   string   `json:"active"`
+	Retiring []string `json:"retiring"`
+}
+
+// LoadKeySet — ключи из каталога: <kid>.pem на каждый ключ из KeySetManifest.
+// Для retiring-ключа достаточно открытого ключа, active обязан быть закрытым.
+// alg (AlgRS256, AlgES256, AlgEdDSA) фиксирует алгоритм active-ключа; пусто — любой поддерживаемый
+func LoadKeySet(dir, alg string) (*KeySet, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, KeySetManifest))
+	if err != nil {
+		return nil, errors.Wrap(err, "read keyset manifest")
+	}
+	var m keySetManifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, errors.Wrap(err, "parse keyset manifest")
+	}
+	if m.Active == "" {
+		return nil, errors.New("keyset manifest: no active key")
+	}
+
+	ks := &KeySet{byKid: make(map[string]signingKey, 1+len(m.Retiring))}
+	for i, kid := range append([]string{m.Active}, m.Retiring...) {
+		if err := validKid(kid); err != nil {
+			return nil, err
+		}
+		if _, ok := ks.byKid[kid]; ok {
+			return nil, errors.Errorf("keyset manifest: duplicate kid %q", kid)
+		}
+
+		k, err := loadKeyFile(filepath.Join(dir, kid+keyFileExt))
+		if err != nil {
+			return nil, errors.Wrapf(err, "key %q", kid)
+		}
+		k.kid = kid
+
+		if i == 0 {
+			if k.priv == nil {
+				return nil, errors.Errorf("active key %q: private key required", kid)
+			}
+			if err := checkAlg(k, alg); err != nil {
+				return nil, err
+			}
+			ks.active = k
+		}
+		ks.byKid[kid] = k
+		ks.order = append(ks.order, kid)
+	}
+
+	return ks, nil
+}
+
+// LoadKeyPair — один ключ из пары файлов (прежний формат конфига); kid — JWK thumbprint (RFC 7638)
+func LoadKeyPair(pathPrivate, pathPublic, alg string) (*KeySet, error) {
+	k, err := loadKeyFile(pathPrivate)
+	if err != nil {
+		return nil, errors.Wrap(err, "private key")
+	}
+	if k.priv == nil {
+		return nil, errors.New("private key: got public key")
+	}
+	pub, err := loadKeyFile(pathPublic)
+	if err != nil {
+		return nil, errors.Wrap(err, "public key")
+	}
+	if !publicKeysEqual(k.pub, pub.pub) {
+		return nil, errors.New("public key does not match private key")
+	}
+
+	k.kid = thumbprint(k.pub)
+	if err := checkAlg(k, alg); err != nil {
+		return nil, err
+	}
+	return &KeySet{
+		active: k,
+		byKid:  map[string]signingKey{k.kid: k},
+		order:  []string{k.kid},
+	}, nil
+}
+
+// verificationKey — ключ по заголовку kid. Токены без kid выпущены до ротации ключей
+// и подписаны тем, что стало active-ключом. alg токена обязан совпадать с алгоритмом ключа,
+// иначе подпись под одним алгоритмом могла бы проверяться ключом другого
+func (ks *KeySet) verificationKey(tk *jwt.Token) (crypto.PublicKey, error) {
+	k := ks.active
+	if raw, ok := tk.Header["kid"]; ok {
+		kid, _ := raw.(string)
+		if k, ok = ks.byKid[kid]; !ok {
+			return nil, errors.Wrapf(ErrUnknownKid, "kid %q", kid)
+		}
+	}
+
+	if tk.Method.Alg() != k.method.Alg() {
+		return nil, errors.Errorf("kid %q: token alg %s, key alg %s", k.kid, tk.Method.Alg(), k.method.Alg())
+	}
+	return k.pub, nil
+}
+
+// algs — алгоритмы всех ключей набора, для jwt.WithValidMethods
+func (ks *KeySet) algs() []string {
+	algs := make([]string, 0, len(ks.order))
+	for _, kid := range ks.order {
+		if alg := ks.byKid[kid].method.Alg(); !slices.Contains(algs, alg) {
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// JWKS — открытые части всех ключей, которыми могут быть подписаны действующие токены
+func (ks *KeySet) JWKS() domain.JWKS {
+	set := domain.JWKS{Keys: make([]domain.JWK, 0, len(ks.order))}
+	for _, kid := range ks.order {
+		k := ks.byKid[kid]
+		j := publicJWK(k.pub)
+		j.Kid, j.Use, j.Alg = kid, keyUseSig, k.method.Alg()
+		set.Keys = append(set.Keys, j)
+	}
+	return set
+}
+
+func checkAlg(k signingKey, alg string) error {
+	if alg != "" && k.method.Alg() != alg {
+		return errors.Errorf("signing key %q is %s, config requires %s", k.kid, k.method.Alg(), alg)
+	}
+	return nil
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
+
+// kid попадает в имя файла — никаких путей
+func validKid(kid string) error {
+	if kid == "" || kid == "." || kid == ".." || strings.ContainsAny(kid, `/\`) {
+		return errors.Errorf("keyset manifest: invalid kid %q", kid)
+	}
+	return nil
+}
+
+// loadKeyFile — закрытый ключ или, если в файле только открытый, открытый; алгоритм — по типу ключа
+func loadKeyFile(path string) (signingKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return signingKey{}, errors.Wrap(err, "read key")
+	}
+
+	priv, pub, err := parseKeyPEM(raw)
+	if err != nil {
+		return signingKey{}, err
+	}
+	method, err := methodFor(pub)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	return signingKey{method: method, priv: priv, pub: pub}, nil
+}
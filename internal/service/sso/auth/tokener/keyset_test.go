@@ -0,0 +1,189 @@
+package tokeneradapter
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKey(t *testing.T, dir, kid string, publicOnly bool) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	if publicOnly {
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+keyFileExt), pem.EncodeToMemory(block), 0o600))
+
+	return key
+}
+
+func writeManifest(t *testing.T, dir, manifest string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, KeySetManifest), []byte(manifest), 0o600))
+}
+
+func refreshMeta() domain.Meta {
+	m := domain.NewRefreshMeta(time.Hour, "user-1", 1, 2)
+	m.SetFamily("family-1")
+	return m
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "k1", false)
+	writeKey(t, dir, "k2", false)
+
+	writeManifest(t, dir, `{"active": "k1"}`)
+	ks1, err := LoadKeySet(dir, "")
+	require.NoError(t, err)
+	_, oldRefresh, err := New(ks1, "sso", "aud").GenPair(refreshMeta(), refreshMeta())
+	require.NoError(t, err)
+
+	// ротация: k2 подписывает, k1 только проверяет
+	writeManifest(t, dir, `{"active": "k2", "retiring": ["k1"]}`)
+	ks2, err := LoadKeySet(dir, "")
+	require.NoError(t, err)
+	tk := New(ks2, "sso", "aud")
+
+	_, newRefresh, err := tk.GenPair(refreshMeta(), refreshMeta())
+	require.NoError(t, err)
+	tok, _, err := jwt.NewParser().ParseUnverified(string(newRefresh), jwt.MapClaims{})
+	require.NoError(t, err)
+	require.Equal(t, "k2", tok.Header["kid"])
+
+	_, err = tk.VerifyRefresh(oldRefresh)
+	require.NoError(t, err, "token signed by retiring key must stay valid")
+	_, err = tk.VerifyRefresh(newRefresh)
+	require.NoError(t, err)
+
+	kids := []string{}
+	for _, k := range tk.JWKS().Keys {
+		kids = append(kids, k.Kid)
+	}
+	require.Equal(t, []string{"k2", "k1"}, kids)
+
+	// k1 выведен из набора — его токены больше не принимаются
+	writeManifest(t, dir, `{"active": "k2"}`)
+	ks3, err := LoadKeySet(dir, "")
+	require.NoError(t, err)
+	_, err = New(ks3, "sso", "aud").VerifyRefresh(oldRefresh)
+	require.ErrorIs(t, err, ErrUnknownKid)
+}
+
+func TestKeySet_RetiringPublicOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "new", false)
+	old := writeKey(t, dir, "old", true)
+	writeManifest(t, dir, `{"active": "new", "retiring": ["old"]}`)
+
+	ks, err := LoadKeySet(dir, "")
+	require.NoError(t, err)
+
+	// токен, подписанный старым ключом до ротации
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims(func() domain.Meta {
+		m := refreshMeta()
+		m.Issuer = "sso"
+		return m
+	}().Claims()))
+	tok.Header["kid"] = "old"
+	signed, err := tok.SignedString(old)
+	require.NoError(t, err)
+
+	_, err = New(ks, "sso", "aud").VerifyRefresh([]byte(signed))
+	require.NoError(t, err)
+}
+
+func TestKeySet_TokenWithoutKidUsesActive(t *testing.T) {
+	dir := t.TempDir()
+	key := writeKey(t, dir, "k1", false)
+	writeManifest(t, dir, `{"active": "k1"}`)
+	ks, err := LoadKeySet(dir, "")
+	require.NoError(t, err)
+
+	m := refreshMeta()
+	m.Issuer = "sso"
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims(m.Claims())).SignedString(key)
+	require.NoError(t, err)
+
+	_, err = New(ks, "sso", "aud").VerifyRefresh([]byte(signed))
+	require.NoError(t, err)
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	dir := t.TempDir()
+	key := writeKey(t, dir, "k1", false)
+	writeManifest(t, dir, `{"active": "k1"}`)
+	ks, err := LoadKeySet(dir, "")
+	require.NoError(t, err)
+
+	set := ks.JWKS()
+	require.Len(t, set.Keys, 1)
+	k := set.Keys[0]
+	require.Equal(t, "RSA", k.Kty)
+	require.Equal(t, "sig", k.Use)
+	require.Equal(t, "RS256", k.Alg)
+	require.Equal(t, "k1", k.Kid)
+	require.Equal(t, "AQAB", k.E)
+
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	require.NoError(t, err)
+	require.Zero(t, new(big.Int).SetBytes(n).Cmp(key.N))
+}
+
+func TestKeySet_InvalidManifest(t *testing.T) {
+	for name, tc := range map[string]struct {
+		manifest string
+		public   bool
+	}{
+		"no active":          {manifest: `{"retiring": ["k1"]}`},
+		"missing key file":   {manifest: `{"active": "k2"}`},
+		"duplicate kid":      {manifest: `{"active": "k1", "retiring": ["k1"]}`},
+		"path in kid":        {manifest: `{"active": "../k1"}`},
+		"public-only active": {manifest: `{"active": "k1"}`, public: true},
+		"broken json":        {manifest: `{"active":`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeKey(t, dir, "k1", tc.public)
+			writeManifest(t, dir, tc.manifest)
+
+			_, err := LoadKeySet(dir, "")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestLoadKeyPair(t *testing.T) {
+	privPath, pubPath := writeRSAPair(t)
+
+	ks, err := LoadKeyPair(privPath, pubPath, "")
+	require.NoError(t, err)
+	// RFC 7638: одинаковый ключ — одинаковый kid между перезапусками
+	ks2, err := LoadKeyPair(privPath, pubPath, "")
+	require.NoError(t, err)
+	require.Equal(t, ks.JWKS().Keys[0].Kid, ks2.JWKS().Keys[0].Kid)
+	require.Len(t, ks.JWKS().Keys[0].Kid, 43)
+
+	t.Run("mismatched pair", func(t *testing.T) {
+		_, otherPub := writeRSAPair(t)
+		_, err := LoadKeyPair(privPath, otherPub, "")
+		require.Error(t, err)
+	})
+}
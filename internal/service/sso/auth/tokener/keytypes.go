@@ -0,0 +1,142 @@
+package tokeneradapter
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Поддерживаемые алгоритмы подписи; выбираются по типу ключа в PEM
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported signing key type")
+
+// parseKeyPEM — закрытый ключ (PKCS#8, PKCS#1, SEC1) или открытый (PKIX, PKCS#1);
+// у открытого priv == nil
+func parseKeyPEM(raw []byte) (priv crypto.Signer, pub crypto.PublicKey, _ error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, nil, errors.New("no pem block")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "parse pkcs8 key")
+		}
+		signer, ok := k.(crypto.Signer)
+		if !ok {
+			return nil, nil, ErrUnsupportedKey
+		}
+		return signer, signer.Public(), nil
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "parse pkcs1 key")
+		}
+		return k, &k.PublicKey, nil
+	case "EC PRIVATE KEY":
+		k, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "parse ec key")
+		}
+		return k, &k.PublicKey, nil
+	case "PUBLIC KEY":
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "parse pkix key")
+		}
+		return nil, k, nil
+	case "RSA PUBLIC KEY":
+		k, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "parse pkcs1 public key")
+		}
+		return nil, k, nil
+	default:
+		return nil, nil, errors.Errorf("unexpected pem block %q", block.Type)
+	}
+}
+
+// methodFor — алгоритм по типу открытого ключа: RSA → RS256, P-256 → ES256, Ed25519 → EdDSA
+func methodFor(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, errors.Wrapf(ErrUnsupportedKey, "ecdsa curve %s", k.Curve.Params().Name)
+		}
+		return jwt.SigningMethodES256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, errors.Wrapf(ErrUnsupportedKey, "%T", pub)
+	}
+}
+
+// publicJWK — обязательные поля ключа по RFC 7518/8037, без kid/use/alg
+func publicJWK(pub crypto.PublicKey) domain.JWK {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return domain.JWK{
+			Kty: "RSA",
+			N:   b64(k.N.Bytes()),
+			E:   b64(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		// координаты фиксированной длины, с ведущими нулями
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return domain.JWK{
+			Kty: "EC",
+			Crv: k.Curve.Params().Name,
+			X:   b64(k.X.FillBytes(make([]byte, size))),
+			Y:   b64(k.Y.FillBytes(make([]byte, size))),
+		}
+	case ed25519.PublicKey:
+		return domain.JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   b64(k),
+		}
+	}
+	return domain.JWK{}
+}
+
+// thumbprint — RFC 7638: SHA-256 от JSON обязательных полей ключа в лексикографическом порядке
+func thumbprint(pub crypto.PublicKey) string {
+	j := publicJWK(pub)
+
+	var canonical string
+	switch j.Kty {
+	case "RSA":
+		canonical = `{"e":"` + j.E + `","kty":"RSA","n":"` + j.N + `"}`
+	case "EC":
+		canonical = `{"crv":"` + j.Crv + `","kty":"EC","x":"` + j.X + `","y":"` + j.Y + `"}`
+	case "OKP":
+		canonical = `{"crv":"` + j.Crv + `","kty":"OKP","x":"` + j.X + `"}`
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return b64(sum[:])
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
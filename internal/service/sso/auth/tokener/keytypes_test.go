@@ -0,0 +1,174 @@
+package tokeneradapter
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func writePKCS8(t *testing.T, dir, kid string, key crypto.Signer) {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+keyFileExt),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+}
+
+func TestKeySet_Algorithms(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for alg, tc := range map[string]struct {
+		key crypto.Signer
+		kty string
+		crv string
+	}{
+		AlgES256: {key: ecKey, kty: "EC", crv: "P-256"},
+		AlgEdDSA: {key: edKey, kty: "OKP", crv: "Ed25519"},
+	} {
+		t.Run(alg, func(t *testing.T) {
+			dir := t.TempDir()
+			writePKCS8(t, dir, "k1", tc.key)
+			writeManifest(t, dir, `{"active": "k1"}`)
+
+			ks, err := LoadKeySet(dir, alg)
+			require.NoError(t, err)
+			tk := New(ks, "sso", "aud")
+
+			access, refresh, err := tk.GenPair(refreshMeta(), refreshMeta())
+			require.NoError(t, err)
+			_, err = tk.VerifyRefresh(refresh)
+			require.NoError(t, err)
+
+			tok, _, err := jwt.NewParser().ParseUnverified(string(access), jwt.MapClaims{})
+			require.NoError(t, err)
+			require.Equal(t, alg, tok.Method.Alg())
+			require.Equal(t, "k1", tok.Header["kid"])
+
+			jwk := tk.JWKS().Keys[0]
+			require.Equal(t, tc.kty, jwk.Kty)
+			require.Equal(t, tc.crv, jwk.Crv)
+			require.Equal(t, alg, jwk.Alg)
+			require.Empty(t, jwk.N)
+			x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+			require.NoError(t, err)
+			require.Len(t, x, 32)
+			if tc.kty == "EC" {
+				y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+				require.NoError(t, err)
+				require.Len(t, y, 32)
+			}
+		})
+	}
+}
+
+func TestKeySet_MixedAlgorithmsRotation(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "rsa", false)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	writePKCS8(t, dir, "ed", edKey)
+
+	writeManifest(t, dir, `{"active": "rsa"}`)
+	rsaSet, err := LoadKeySet(dir, "")
+	require.NoError(t, err)
+	_, rsaRefresh, err := New(rsaSet, "sso", "aud").GenPair(refreshMeta(), refreshMeta())
+	require.NoError(t, err)
+
+	// переход RSA → EdDSA: старые RS256-токены принимаются до вывода ключа из набора
+	writeManifest(t, dir, `{"active": "ed", "retiring": ["rsa"]}`)
+	mixed, err := LoadKeySet(dir, AlgEdDSA)
+	require.NoError(t, err)
+	tk := New(mixed, "sso", "aud")
+
+	_, err = tk.VerifyRefresh(rsaRefresh)
+	require.NoError(t, err)
+	_, edRefresh, err := tk.GenPair(refreshMeta(), refreshMeta())
+	require.NoError(t, err)
+	_, err = tk.VerifyRefresh(edRefresh)
+	require.NoError(t, err)
+	require.Less(t, len(edRefresh), len(rsaRefresh))
+}
+
+func TestKeySet_AlgMismatch(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "rsa", false)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	writePKCS8(t, dir, "ec", ecKey)
+	writeManifest(t, dir, `{"active": "rsa", "retiring": ["ec"]}`)
+
+	t.Run("config pins another alg", func(t *testing.T) {
+		_, err := LoadKeySet(dir, AlgES256)
+		require.Error(t, err)
+	})
+
+	t.Run("token alg differs from key alg", func(t *testing.T) {
+		ks, err := LoadKeySet(dir, "")
+		require.NoError(t, err)
+
+		// ES256-подпись своим ключом, но с kid RSA-ключа
+		m := refreshMeta()
+		m.Issuer = "sso"
+		tok := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims(m.Claims()))
+		tok.Header["kid"] = "rsa"
+		signed, err := tok.SignedString(ecKey)
+		require.NoError(t, err)
+
+		_, err = New(ks, "sso", "aud").VerifyRefresh([]byte(signed))
+		require.Error(t, err)
+	})
+}
+
+func TestParseKeyPEM(t *testing.T) {
+	t.Run("sec1 ec key", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		der, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+
+		priv, pub, err := parseKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+		require.NoError(t, err)
+		require.NotNil(t, priv)
+		m, err := methodFor(pub)
+		require.NoError(t, err)
+		require.Equal(t, AlgES256, m.Alg())
+	})
+
+	t.Run("unsupported curve", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		require.NoError(t, err)
+		_, err = methodFor(&key.PublicKey)
+		require.ErrorIs(t, err, ErrUnsupportedKey)
+	})
+
+	t.Run("not pem", func(t *testing.T) {
+		_, _, err := parseKeyPEM([]byte("garbage"))
+		require.Error(t, err)
+	})
+}
+
+func TestThumbprint_RFC7638Example(t *testing.T) {
+	// пример из RFC 7638, раздел 3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	require.NoError(t, err)
+
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+	require.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint(pub))
+}
@@ -1,72 +1,130 @@
 package tokeneradapter
 
 import (
-	"crypto/rsa"
-	"os"
-
 	"github.com/eragon-mdi/sso/internal/domain"
 	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
 	"github.com/go-faster/errors"
 	"github.com/golang-jwt/jwt/v5"
-
-	"github.com/eragon-mdi/go-playground/tokener"
 )
 
-func New(privateRSAFilePath, publicRSAFilePath string) (authservice.Tokener, error) {
-	priv, pub, err := loadKeys(privateRSAFilePath, publicRSAFilePath)
-	if err != nil {
-		return nil, errors.Wrap(err, "load keys")
-	}
-
+func New(keys *KeySet, issuer, audience string) authservice.Tokener {
 	return &tokenerAdapter{
-		Tokener: tokener.NewRSA(priv, pub),
-	}, nil
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+	}
 }
 
 type tokenerAdapter struct {
-	tokener.Tokener
+	keys     *KeySet
+	issuer   string
+	audience string
 }
 
-func (t *tokenerAdapter) GenPair(m domain.Meta) (access, refresh []byte, _ error) {
-	return t.Tokener.GenPair(tokener.Claims(m), "access", "refresh")
+func (t *tokenerAdapter) GenPair(access, refresh domain.Meta) ([]byte, []byte, error) {
+	a, err := t.sign(access)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "sign access")
+	}
+
+	r, err := t.sign(refresh)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "sign refresh")
+	}
+
+	return a, r, nil
+}
+
+func (t *tokenerAdapter) GenAccess(access domain.Meta) ([]byte, error) {
+	a, err := t.sign(access)
+	if err != nil {
+		return nil, errors.Wrap(err, "sign access")
+	}
+
+	return a, nil
 }
 
 type UnClaims interface {
 	UnClaims(map[string]any) error
 }
 
-func (t *tokenerAdapter) VerifyRefresh(refresh []byte) (m domain.Meta, _ error) {
-	claims, err := t.Tokener.VerifyRefresh(refresh)
+func (t *tokenerAdapter) VerifyRefresh(refresh []byte) (domain.Meta, error) {
+	m, err := t.verify(refresh)
 	if err != nil {
-		return domain.Meta{}, errors.Wrap(err, "failed verify")
+		return domain.Meta{}, err
 	}
 
-	if err := m.UnClaims(claims); err != nil {
-		return domain.Meta{}, errors.Wrap(err, "failed unclaim")
+	// access-токен не должен приниматься вместо refresh
+	if m.Type != domain.TokenTypeRefresh {
+		return domain.Meta{}, errors.Errorf("unexpected token type: %s", m.Type)
 	}
 
 	return m, nil
 }
 
-func loadKeys(pathPrivate, pathPublic string) (*rsa.PrivateKey, *rsa.PublicKey, error) {
-	privBytes, err := os.ReadFile(pathPrivate)
+// Verify — подпись, iss и срок токена любого типа
+func (t *tokenerAdapter) Verify(token []byte) (domain.Meta, error) {
+	return t.verify(token)
+}
+
+// GenIDToken — id_token тем же active-ключом (kid в заголовке), aud — client_id из токена
+func (t *tokenerAdapter) GenIDToken(id domain.IDToken) ([]byte, error) {
+	id.Issuer = t.issuer
+
+	signed, err := t.signClaims(id.Claims())
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "read private key")
+		return nil, errors.Wrap(err, "sign id_token")
 	}
-	privKey, err := jwt.ParseRSAPrivateKeyFromPEM(privBytes)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "parse private key")
+
+	return signed, nil
+}
+
+func (t *tokenerAdapter) sign(m domain.Meta) ([]byte, error) {
+	m.Issuer = t.issuer
+	if m.Type == domain.TokenTypeAccess {
+		m.Audience = t.audience
 	}
 
-	pubBytes, err := os.ReadFile(pathPublic)
+	return t.signClaims(m.Claims())
+}
+
+func (t *tokenerAdapter) signClaims(claims map[string]any) ([]byte, error) {
+	token := jwt.NewWithClaims(t.keys.active.method, jwt.MapClaims(claims))
+	token.Header["kid"] = t.keys.active.kid
+
+	signed, err := token.SignedString(t.keys.active.priv)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "read public key")
+		return nil, err
 	}
-	pubKey, err := jwt.ParseRSAPublicKeyFromPEM(pubBytes)
+
+	return []byte(signed), nil
+}
+
+func (t *tokenerAdapter) verify(token []byte) (m domain.Meta, _ error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(string(token), claims,
+		func(tk *jwt.Token) (any, error) {
+			return t.keys.verificationKey(tk)
+		},
+		jwt.WithValidMethods(t.keys.algs()),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "parse public key")
+		return domain.Meta{}, errors.Wrap(err, "failed verify")
+	}
+
+	if err := m.UnClaims(claims); err != nil {
+		return domain.Meta{}, errors.Wrap(err, "failed unclaim")
+	}
+	// iss появился вместе с typ и jti: у legacy refresh его нет
+	if m.Issuer != t.issuer && !m.Legacy() {
+		return domain.Meta{}, errors.Errorf("failed verify: invalid issuer %q", m.Issuer)
 	}
 
-	return privKey,
-		pubKey, nil
+	return m, nil
+}
+
+func (t *tokenerAdapter) JWKS() domain.JWKS {
+	return t.keys.JWKS()
 }
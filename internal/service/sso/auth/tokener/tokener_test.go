@@ -0,0 +1,187 @@
+package tokeneradapter
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRSAPair(t *testing.T) (privPath, pubPath string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privPath = filepath.Join(dir, "private.pem")
+	pubPath = filepath.Join(dir, "public.pem")
+
+	require.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}), 0o600))
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: pubDER,
+	}), 0o600))
+
+	return privPath, pubPath
+}
+
+func TestTokenerAdapter_GenPairAndVerify(t *testing.T) {
+	privPath, pubPath := writeRSAPair(t)
+
+	keys, err := LoadKeyPair(privPath, pubPath, "")
+	require.NoError(t, err)
+	tk := New(keys, "sso-test", "clients-test")
+
+	am := domain.NewAccessMeta(time.Minute, "user-1", 1, 2)
+	am.SetFamily("family-1")
+	rm := domain.NewRefreshMeta(time.Hour, "user-1", 1, 2)
+	rm.SetFamily("family-1")
+
+	access, refresh, err := tk.GenPair(am, rm)
+	require.NoError(t, err)
+
+	t.Run("refresh verified", func(t *testing.T) {
+		m, err := tk.VerifyRefresh(refresh)
+		require.NoError(t, err)
+		require.Equal(t, domain.TokenTypeRefresh, m.Type)
+		require.Equal(t, rm.ID, m.ID)
+		require.Equal(t, "user-1", m.UserID)
+		require.Equal(t, "family-1", m.FamilyID)
+		require.Equal(t, "sso-test", m.Issuer)
+		require.True(t, rm.Ctx.Compare(m.Ctx))
+	})
+
+	t.Run("access rejected as refresh", func(t *testing.T) {
+		_, err := tk.VerifyRefresh(access)
+		require.Error(t, err)
+	})
+
+	t.Run("access carries standard claims", func(t *testing.T) {
+		claims := jwt.MapClaims{}
+		_, _, err := jwt.NewParser().ParseUnverified(string(access), claims)
+		require.NoError(t, err)
+
+		tok, _, err := jwt.NewParser().ParseUnverified(string(access), jwt.MapClaims{})
+		require.NoError(t, err)
+		require.Equal(t, keys.JWKS().Keys[0].Kid, tok.Header["kid"])
+
+		for _, c := range []string{"iss", "aud", "sub", "iat", "nbf", "exp", "jti", "typ"} {
+			require.Contains(t, claims, c)
+		}
+		require.Equal(t, "access", claims["typ"])
+		require.Equal(t, "family-1", claims["sid"])
+		require.Equal(t, "clients-test", claims["aud"])
+		require.NotEqual(t, rm.ID, claims["jti"])
+	})
+
+	t.Run("access verified with session id", func(t *testing.T) {
+		m, err := tk.Verify(access)
+		require.NoError(t, err)
+		require.Equal(t, domain.TokenTypeAccess, m.Type)
+		require.Equal(t, "family-1", m.FamilyID)
+		require.Equal(t, "clients-test", m.Audience)
+	})
+
+	t.Run("scopes round-trip", func(t *testing.T) {
+		sm := domain.NewAccessMeta(time.Minute, "user-1", 1, 2)
+		sm.Scopes = []string{"profile", "email"}
+		a, _, err := tk.GenPair(sm, rm)
+		require.NoError(t, err)
+
+		m, err := tk.Verify(a)
+		require.NoError(t, err)
+		require.Equal(t, []string{"profile", "email"}, m.Scopes)
+	})
+
+	t.Run("roles round-trip", func(t *testing.T) {
+		sm := domain.NewAccessMeta(time.Minute, "user-1", 1, 2)
+		sm.SetRoles([]string{"admin", "user"})
+		a, _, err := tk.GenPair(sm, rm)
+		require.NoError(t, err)
+
+		m, err := tk.Verify(a)
+		require.NoError(t, err)
+		require.Equal(t, []string{"admin", "user"}, m.Roles)
+	})
+
+	t.Run("client access round-trip", func(t *testing.T) {
+		a, err := tk.GenAccess(domain.NewClientAccessMeta(time.Minute, "client-1", []string{"orders:read"}))
+		require.NoError(t, err)
+
+		m, err := tk.Verify(a)
+		require.NoError(t, err)
+		require.Equal(t, domain.TokenTypeAccess, m.Type)
+		require.Empty(t, m.UserID)
+		require.Equal(t, "client-1", m.ClientID)
+		require.Equal(t, "client-1", m.Subject())
+		require.Equal(t, []string{"orders:read"}, m.Scopes)
+
+		_, err = tk.VerifyRefresh(a)
+		require.Error(t, err, "access is never accepted as refresh")
+	})
+
+	t.Run("id_token signed by active key, not accepted as access", func(t *testing.T) {
+		authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+		idt, err := tk.GenIDToken(domain.IDToken{
+			Subject: "user-1", Audience: "7", IssuedAt: time.Now(), Exp: time.Now().Add(time.Minute),
+			AuthTime: authTime, Nonce: "n-1", AMR: []string{domain.AMRPassword},
+			Email: "u@e.x", EmailVerified: true,
+		})
+		require.NoError(t, err)
+
+		claims := jwt.MapClaims{}
+		parsed, err := jwt.ParseWithClaims(string(idt), claims,
+			func(tk *jwt.Token) (any, error) { return keys.verificationKey(tk) },
+			jwt.WithIssuer("sso-test"), jwt.WithAudience("7"))
+		require.NoError(t, err)
+		require.Equal(t, keys.active.kid, parsed.Header["kid"])
+		require.Equal(t, "user-1", claims["sub"])
+		require.Equal(t, "n-1", claims["nonce"])
+		require.Equal(t, float64(authTime.Unix()), claims["auth_time"])
+		require.Equal(t, []any{"pwd"}, claims["amr"])
+		require.Equal(t, true, claims["email_verified"])
+
+		_, err = tk.Verify(idt)
+		require.Error(t, err, "id_token has no typ and is never a session token")
+	})
+
+	t.Run("legacy refresh without typ, jti and kid", func(t *testing.T) {
+		// формат до разделения access/refresh: ни iss, ни kid
+		tok := jwt.NewWithClaims(keys.active.method, jwt.MapClaims{
+			"user_id": "user-1", "app_id": 1, "device_id": 2, "exp": time.Now().Add(time.Hour).Unix(),
+		})
+		legacy, err := tok.SignedString(keys.active.priv)
+		require.NoError(t, err)
+
+		m, err := tk.VerifyRefresh([]byte(legacy))
+		require.NoError(t, err)
+		require.True(t, m.Legacy())
+		require.Equal(t, "user-1", m.UserID)
+		require.Empty(t, m.FamilyID)
+		require.True(t, rm.Ctx.Compare(m.Ctx))
+
+		require.False(t, rm.Legacy())
+	})
+
+	t.Run("foreign issuer rejected", func(t *testing.T) {
+		other := New(keys, "other-issuer", "clients-test")
+
+		_, err := other.VerifyRefresh(refresh)
+		require.Error(t, err)
+	})
+}
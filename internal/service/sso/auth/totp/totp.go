@@ -0,0 +1,93 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
+	"github.com/go-faster/errors"
+)
+
+// RFC 6238 с параметрами по умолчанию, которые понимают все аутентификаторы
+const (
+	period     = 30 * time.Second
+	digits     = 6
+	secretLen  = 20 // 160 бит, как рекомендует RFC 4226
+	skewSteps  = 1  // допускаем соседний шаг: рассинхрон часов клиента
+	digitsMod  = 1_000_000
+	uriPattern = "otpauth://totp/%s?%s"
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type totp struct {
+	issuer string
+}
+
+func New(issuer string) authservice.Totp {
+	return &totp{
+		issuer: issuer,
+	}
+}
+
+func (t *totp) NewSecret() (string, error) {
+	b := make([]byte, secretLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "read random secret")
+	}
+
+	return b32.EncodeToString(b), nil
+}
+
+func (t *totp) URI(account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", t.issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(digits))
+	q.Set("period", fmt.Sprint(int(period.Seconds())))
+
+	label := url.PathEscape(t.issuer + ":" + account)
+	return fmt.Sprintf(uriPattern, label, q.Encode())
+}
+
+// Validate возвращает временной шаг, которому соответствует код
+func (t *totp) Validate(secret, code string, at time.Time) (int64, bool) {
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != digits {
+		return 0, false
+	}
+
+	step := at.Unix() / int64(period.Seconds())
+	for i := -skewSteps; i <= skewSteps; i++ {
+		s := step + int64(i)
+		if subtle.ConstantTimeCompare([]byte(hotp(key, s)), []byte(code)) == 1 {
+			return s, true
+		}
+	}
+
+	return 0, false
+}
+
+// hotp — RFC 4226, динамическое усечение HMAC-SHA1
+func hotp(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	off := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, bin%digitsMod)
+}
@@ -0,0 +1,66 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238, приложение B (SHA1): последние 6 цифр 8-значных эталонов
+func TestValidate_RFC6238Vectors(t *testing.T) {
+	secret := b32.EncodeToString([]byte("12345678901234567890"))
+	tp := New("sso")
+
+	for _, tc := range []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	} {
+		step, ok := tp.Validate(secret, tc.code, time.Unix(tc.unix, 0))
+		require.True(t, ok, "unix=%d", tc.unix)
+		require.Equal(t, tc.unix/30, step)
+	}
+}
+
+func TestValidate_SkewAndRejects(t *testing.T) {
+	tp := New("sso")
+	secret, err := tp.NewSecret()
+	require.NoError(t, err)
+
+	key, err := b32.DecodeString(secret)
+	require.NoError(t, err)
+
+	now := time.Unix(1_700_000_000, 0)
+	step := now.Unix() / 30
+
+	_, ok := tp.Validate(secret, hotp(key, step-1), now)
+	require.True(t, ok, "previous step must be accepted")
+
+	_, ok = tp.Validate(secret, hotp(key, step+2), now)
+	require.False(t, ok, "far future step must be rejected")
+
+	_, ok = tp.Validate(secret, "12345", now)
+	require.False(t, ok)
+
+	_, ok = tp.Validate("not base32!", "123456", now)
+	require.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := New("Acme SSO").URI("a@b.c", "JBSWY3DPEHPK3PXP")
+
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", u.Scheme)
+	require.Equal(t, "totp", u.Host)
+	require.Equal(t, "/Acme SSO:a@b.c", u.Path)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	require.Equal(t, "Acme SSO", u.Query().Get("issuer"))
+}
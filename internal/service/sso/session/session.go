@@ -0,0 +1,38 @@
+package sessionservice
+
+import (
+	"context"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+//go:generate mockery --name=SessionRepository --with-expecter --output=./mocks/sessionrepo --exported
+type SessionRepository interface {
+	ListUserSessions(_ context.Context, userID string) ([]domain.Session, error)
+	RevokeSession(_ context.Context, userID, sessionID string) error
+}
+
+const (
+	ErrFailedListSessions  = "failed list user sessions"
+	ErrFailedRevokeSession = "failed revoke session"
+)
+
+func (s Sessions) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	sessions, err := s.r.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrFailedListSessions)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession отзывает сессию (семейство refresh-токенов) по её id.
+// Сессия чужого пользователя считается не найденной.
+func (s Sessions) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if err := s.r.RevokeSession(ctx, userID, sessionID); err != nil {
+		return errors.Wrap(err, ErrFailedRevokeSession)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package sessionservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	mocks_sessionrepo "github.com/eragon-mdi/sso/internal/service/sso/session/mocks/sessionrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions_ListSessions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		now := time.Now()
+		want := []domain.Session{{
+			ID:              "fam-1",
+			UserID:          "user123",
+			Ctx:             domain.NewDeviceCtx(1, 2),
+			CreatedAt:       now.Add(-time.Hour),
+			LastRefreshedAt: now,
+			ExpiresAt:       now.Add(time.Hour),
+		}}
+
+		repo := &mocks_sessionrepo.SessionRepository{}
+		repo.On("ListUserSessions", mock.Anything, "user123").Return(want, nil)
+
+		s := New(repo)
+
+		got, err := s.ListSessions(ctx, "user123")
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("repo error", func(t *testing.T) {
+		repo := &mocks_sessionrepo.SessionRepository{}
+		repo.On("ListUserSessions", mock.Anything, "user123").Return(nil, assert.AnError)
+
+		s := New(repo)
+
+		got, err := s.ListSessions(ctx, "user123")
+		require.Error(t, err)
+		require.Nil(t, got)
+	})
+}
+
+func TestSessions_RevokeSession(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		repo := &mocks_sessionrepo.SessionRepository{}
+		repo.On("RevokeSession", mock.Anything, "user123", "fam-1").Return(nil)
+
+		s := New(repo)
+
+		require.NoError(t, s.RevokeSession(ctx, "user123", "fam-1"))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := &mocks_sessionrepo.SessionRepository{}
+		repo.On("RevokeSession", mock.Anything, "user123", "fam-1").Return(domain.ErrNotFound)
+
+		s := New(repo)
+
+		err := s.RevokeSession(ctx, "user123", "fam-1")
+		require.True(t, errors.Is(err, domain.ErrNotFound))
+	})
+}
@@ -0,0 +1,15 @@
+package sessionservice
+
+type Sessions struct {
+	r Repository
+}
+
+func New(r Repository) *Sessions {
+	return &Sessions{
+		r: r,
+	}
+}
+
+type Repository interface {
+	SessionRepository
+}
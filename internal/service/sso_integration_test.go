@@ -2,11 +2,19 @@ package service_test
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha1"
 	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -46,21 +54,49 @@ func TestAuth_FullFlow(t *testing.T) {
 	repo := repository.New(testStor)
 	bl := &configs.BussinesLogic{
 		PassHasherCost:       10,
-		TokenTTL:             72 * time.Hour,
+		AccessTokenTTL:       15 * time.Minute,
+		RefreshTokenTTL:      72 * time.Hour,
+		TokenIssuer:          "sso-test",
+		TokenAudience:        "sso-test-clients",
 		PathSecretPrivate:    filepath.Join(rootPATH, "secrets/private.pem"),
 		PathSecretPublic:     filepath.Join(rootPATH, "secrets/public.pem"),
 		SecretForTokerHasher: "test-secret",
+		PasswordResetTTL:     15 * time.Minute,
+		NotifierFilePath:     filepath.Join(t.TempDir(), "notifications.log"),
+		EmailVerificationTTL: time.Hour,
+		// app 100 — «строгое» приложение: вход только с подтверждённым email
+		RequireVerifiedEmailApps: []int32{100},
+		MfaChallengeTTL:          5 * time.Minute,
+		MfaSecretKey:             base64.StdEncoding.EncodeToString([]byte("integration-test-mfa-key-32bytes")),
+		LoginMaxFailuresPerEmail: 3,
+		LoginMaxFailuresPerIP:    100,
+		LoginFailureWindow:       time.Minute,
+		LoginLockoutBase:         time.Minute,
+		LoginLockoutMax:          time.Hour,
+		PasswordlessTTL:          10 * time.Minute,
+		PasswordlessMaxAttempts:  2,
+		AppsEnforced:             true,
+		// сценарии ниже продлевают сессии на device_id = 0; строгий режим проверяется в «RegisterDevice»
+		DevicesGraceUntil: time.Now().Add(time.Hour),
 	}
 	svc, err := service.New(repo, bl)
 	if err != nil {
 		t.Fatalf("service.New failed: %v", err)
 	}
 
-	user := domain.User{Email: "fullflow@test.local", Password: "secret123"}
+	// вход возможен только в приложения из реестра; app 99 не регистрируется
+	for _, id := range []int32{1, 2, 100} {
+		app := domain.App{ID: id, Name: fmt.Sprintf("app-%d", id), GrantTypes: []string{domain.GrantPassword, domain.GrantRefreshToken}}
+		if _, err := svc.CreateApp(ctx, app); err != nil {
+			t.Fatalf("CreateApp %d failed: %v", id, err)
+		}
+	}
+
+	user := domain.User{Email: "fullflow@test.local", Password: "horse-battery-9"}
 
 	// --- REGISTER ---
 	t.Run("Register success", func(t *testing.T) {
-		u, err := svc.Register(ctx, user)
+		u, err := svc.Register(ctx, user, 0)
 		if err != nil {
 			t.Fatalf("Register failed: %v", err)
 		}
@@ -70,16 +106,16 @@ func TestAuth_FullFlow(t *testing.T) {
 	})
 
 	t.Run("Register duplicate", func(t *testing.T) {
-		_, err := svc.Register(ctx, user)
+		_, err := svc.Register(ctx, user, 0)
 		if err == nil {
 			t.Fatal("expected duplicate email error")
 		}
 	})
 
 	// --- LOGIN ---
-	var tok domain.Token
+	var tok domain.LoginResult
 	t.Run("Login success", func(t *testing.T) {
-		tok, err = svc.Login(ctx, domain.User{Email: user.Email, Password: "secret123"}, domain.NewDeviceCtx(1, 1))
+		tok, err = svc.Login(ctx, domain.User{Email: user.Email, Password: "horse-battery-9"}, domain.NewDeviceCtx(1, domain.UnregisteredDevice))
 		if err != nil {
 			t.Fatalf("Login failed: %v", err)
 		}
@@ -89,15 +125,83 @@ func TestAuth_FullFlow(t *testing.T) {
 	})
 
 	t.Run("Login wrong password", func(t *testing.T) {
-		_, err := svc.Login(ctx, domain.User{Email: user.Email, Password: "wrongpass"}, domain.NewDeviceCtx(1, 1))
+		_, err := svc.Login(ctx, domain.User{Email: user.Email, Password: "wrongpass"}, domain.NewDeviceCtx(1, domain.UnregisteredDevice))
 		if err == nil {
 			t.Fatal("expected login failure")
 		}
 	})
 
+	t.Run("Login lockout after repeated failures", func(t *testing.T) {
+		victim := domain.User{Email: "lockout@test.local", Password: "horse-battery-9"}
+		if _, err := svc.Register(ctx, victim, 0); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		ipCtx := domain.WithClientIP(ctx, "192.0.2.10")
+		dctx := domain.NewDeviceCtx(1, domain.UnregisteredDevice)
+
+		for i := 1; i < bl.LoginMaxFailuresPerEmail; i++ {
+			_, err := svc.Login(ipCtx, domain.User{Email: victim.Email, Password: "wrongpass"}, dctx)
+			if !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("attempt %d: expected validation error, got %v", i, err)
+			}
+		}
+
+		var lockErr *domain.LockoutError
+		_, err := svc.Login(ipCtx, domain.User{Email: victim.Email, Password: "wrongpass"}, dctx)
+		if !errors.As(err, &lockErr) || lockErr.RetryAfter != bl.LoginLockoutBase {
+			t.Fatalf("expected lockout for %s, got %v", bl.LoginLockoutBase, err)
+		}
+
+		// во время блокировки не пускает даже с верным паролем
+		_, err = svc.Login(ipCtx, domain.User{Email: victim.Email, Password: victim.Password}, dctx)
+		if !errors.As(err, &lockErr) || lockErr.RetryAfter <= 0 {
+			t.Fatalf("expected lockout with correct password, got %v", err)
+		}
+
+		if err := svc.ClearLoginLockout(ctx, victim.Email, "192.0.2.10"); err != nil {
+			t.Fatalf("ClearLoginLockout failed: %v", err)
+		}
+		if _, err := svc.Login(ipCtx, domain.User{Email: victim.Email, Password: victim.Password}, dctx); err != nil {
+			t.Fatalf("Login after clear failed: %v", err)
+		}
+	})
+
+	t.Run("Login rehashes bcrypt password to argon2id", func(t *testing.T) {
+		legacy := domain.User{Email: "rehash@test.local", Password: "horse-battery-9"}
+		if _, err := svc.Register(ctx, legacy, 0); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+
+		argonCfg := *bl
+		argonCfg.PassHasherAlgo = "argon2id"
+		argonCfg.PassArgon2Memory = 1024
+		argonCfg.PassArgon2Iterations = 1
+		argonCfg.PassArgon2Parallelism = 1
+		argonSvc, err := service.New(repo, &argonCfg)
+		if err != nil {
+			t.Fatalf("service.New failed: %v", err)
+		}
+
+		if _, err := argonSvc.Login(ctx, legacy, domain.NewDeviceCtx(1, domain.UnregisteredDevice)); err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		u, err := repo.GetUserInfoByEmail(ctx, legacy.Email)
+		if err != nil {
+			t.Fatalf("GetUserInfoByEmail failed: %v", err)
+		}
+		if !strings.HasPrefix(u.Password, "$argon2id$v=19$m=1024,t=1,p=1$") {
+			t.Fatalf("expected argon2id hash after login, got %q", u.Password)
+		}
+
+		// старый сервис (bcrypt) по-прежнему пускает с новым хэшем
+		if _, err := svc.Login(ctx, legacy, domain.NewDeviceCtx(1, domain.UnregisteredDevice)); err != nil {
+			t.Fatalf("Login with bcrypt service failed: %v", err)
+		}
+	})
+
 	// --- REFRESH ---
 	t.Run("Refresh token success", func(t *testing.T) {
-		tok2, err := svc.Refresh(ctx, tok.Refresh, domain.NewDeviceCtx(1, 1))
+		tok2, err := svc.Refresh(ctx, tok.Refresh, domain.NewDeviceCtx(1, domain.UnregisteredDevice))
 		if err != nil {
 			t.Fatalf("Refresh failed: %v", err)
 		}
@@ -120,16 +224,496 @@ func TestAuth_FullFlow(t *testing.T) {
 		}
 	})
 
+	// --- REFRESH REUSE ---
+	t.Run("Refresh reuse revokes token family", func(t *testing.T) {
+		dctx := domain.NewDeviceCtx(1, domain.UnregisteredDevice)
+		first, err := svc.Login(ctx, domain.User{Email: user.Email, Password: "horse-battery-9"}, dctx)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		second, err := svc.Refresh(ctx, first.Refresh, dctx)
+		if err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+
+		// повторное предъявление уже ротированного токена
+		if _, err := svc.Refresh(ctx, first.Refresh, dctx); !errors.Is(err, domain.ErrTokenReuse) {
+			t.Fatalf("expected token reuse error, got: %v", err)
+		}
+		// актуальный токен семейства тоже отозван
+		if _, err := svc.Refresh(ctx, second.Refresh, dctx); err == nil {
+			t.Fatal("expected family to be revoked")
+		}
+	})
+
+	// --- SESSIONS ---
+	t.Run("List and revoke sessions", func(t *testing.T) {
+		u, err := svc.Register(ctx, domain.User{Email: "sessions@test.local", Password: "horse-battery-9"}, 0)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		dctx := domain.NewDeviceCtx(2, domain.UnregisteredDevice)
+		tk, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, dctx)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+
+		sessions, err := svc.ListSessions(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("ListSessions failed: %v", err)
+		}
+		if len(sessions) != 1 || !sessions[0].Ctx.Compare(dctx) {
+			t.Fatalf("unexpected sessions: %+v", sessions)
+		}
+
+		if err := svc.RevokeSession(ctx, "00000000-0000-4000-8000-000000000000", sessions[0].ID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected foreign session to be not found, got: %v", err)
+		}
+		if err := svc.RevokeSession(ctx, u.ID, sessions[0].ID); err != nil {
+			t.Fatalf("RevokeSession failed: %v", err)
+		}
+		if _, err := svc.Refresh(ctx, tk.Refresh, dctx); err == nil {
+			t.Fatal("expected revoked session refresh to fail")
+		}
+	})
+
+	// --- CHANGE PASSWORD ---
+	t.Run("ChangePassword keeps only current session", func(t *testing.T) {
+		u, err := svc.Register(ctx, domain.User{Email: "changepass@test.local", Password: "horse-battery-9"}, 0)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		current, other := domain.NewDeviceCtx(1, domain.UnregisteredDevice), domain.NewDeviceCtx(1, domain.UnregisteredDevice)
+		curTok, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, current)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		otherTok, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, other)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+
+		if err := svc.ChangePassword(ctx, curTok.Refresh, current, "wrongpass", "newsecret1"); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrong password error, got: %v", err)
+		}
+		if err := svc.ChangePassword(ctx, curTok.Refresh, current, "horse-battery-9", "newsecret1"); err != nil {
+			t.Fatalf("ChangePassword failed: %v", err)
+		}
+
+		if _, err := svc.Refresh(ctx, otherTok.Refresh, other); err == nil {
+			t.Fatal("expected other session to be revoked")
+		}
+		if _, err := svc.Refresh(ctx, curTok.Refresh, current); err != nil {
+			t.Fatalf("current session must survive: %v", err)
+		}
+		if _, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "newsecret1"}, current); err != nil {
+			t.Fatalf("Login with new password failed: %v", err)
+		}
+	})
+
+	// --- EMAIL VERIFICATION ---
+	t.Run("Password policy rejects weak and reused passwords", func(t *testing.T) {
+		_, err := svc.Register(ctx, domain.User{Email: "policy@test.local", Password: "password"}, 0)
+		var policyErr *domain.PasswordPolicyError
+		if !errors.As(err, &policyErr) {
+			t.Fatalf("expected PasswordPolicyError, got %v", err)
+		}
+
+		u, err := svc.Register(ctx, domain.User{Email: "policy@test.local", Password: "horse-battery-9"}, 0)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		dctx := domain.NewDeviceCtx(1, domain.UnregisteredDevice)
+		tk, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, dctx)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		if err := svc.ChangePassword(ctx, tk.Refresh, dctx, "horse-battery-9", "staple-cart-77"); err != nil {
+			t.Fatalf("ChangePassword failed: %v", err)
+		}
+
+		// возврат к предыдущему паролю запрещён историей
+		err = svc.ChangePassword(ctx, tk.Refresh, dctx, "staple-cart-77", "horse-battery-9")
+		if !errors.As(err, &policyErr) || policyErr.Violations[0].Rule != domain.RuleReused {
+			t.Fatalf("expected reused violation, got %v", err)
+		}
+	})
+
+	t.Run("Email verification gates strict app", func(t *testing.T) {
+		u, err := svc.Register(ctx, domain.User{Email: "verify@test.local", Password: "horse-battery-9"}, 0)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		strict := domain.NewDeviceCtx(100, domain.UnregisteredDevice)
+
+		if _, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, strict); !errors.Is(err, domain.ErrEmailNotVerified) {
+			t.Fatalf("expected ErrEmailNotVerified, got: %v", err)
+		}
+		if _, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, domain.NewDeviceCtx(1, domain.UnregisteredDevice)); err != nil {
+			t.Fatalf("non-strict app must allow unverified login: %v", err)
+		}
+
+		n := lastNotification(t, bl.NotifierFilePath, domain.PurposeEmailVerification, u.Email)
+		if err := svc.VerifyEmail(ctx, n.Token); err != nil {
+			t.Fatalf("VerifyEmail failed: %v", err)
+		}
+		if err := svc.VerifyEmail(ctx, n.Token); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected verification token to be single-use, got: %v", err)
+		}
+		if _, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, strict); err != nil {
+			t.Fatalf("Login after verification failed: %v", err)
+		}
+	})
+
+	// --- MFA ---
+	t.Run("TOTP enrollment and two-step login", func(t *testing.T) {
+		u, err := svc.Register(ctx, domain.User{Email: "mfa@test.local", Password: "horse-battery-9"}, 0)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		dctx := domain.NewDeviceCtx(1, domain.UnregisteredDevice)
+		first, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, dctx)
+		if err != nil || first.MfaRequired() {
+			t.Fatalf("Login before enrollment must issue tokens: %+v, %v", first, err)
+		}
+
+		enr, err := svc.BeginTotpEnrollment(ctx, first.Refresh, dctx)
+		if err != nil {
+			t.Fatalf("BeginTotpEnrollment failed: %v", err)
+		}
+		if !strings.HasPrefix(enr.URI, "otpauth://totp/") {
+			t.Fatalf("unexpected otpauth uri: %s", enr.URI)
+		}
+		if err := svc.ConfirmTotpEnrollment(ctx, first.Refresh, dctx, "000000"); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrong code to be rejected, got: %v", err)
+		}
+		now := time.Now()
+		if err := svc.ConfirmTotpEnrollment(ctx, first.Refresh, dctx, totpCode(t, enr.Secret, now)); err != nil {
+			t.Fatalf("ConfirmTotpEnrollment failed: %v", err)
+		}
+
+		res, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, dctx)
+		if err != nil || !res.MfaRequired() || res.Refresh != "" {
+			t.Fatalf("expected mfa challenge, got %+v, %v", res, err)
+		}
+		// код, уже использованный при подтверждении, повторно не принимается (и челлендж сгорает)
+		if _, err := svc.CompleteMfaLogin(ctx, res.MfaChallenge, totpCode(t, enr.Secret, now), dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected replayed code to be rejected, got: %v", err)
+		}
+
+		res, err = svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, dctx)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		next := totpCode(t, enr.Secret, now.Add(30*time.Second))
+		if _, err := svc.CompleteMfaLogin(ctx, res.MfaChallenge, next, domain.NewDeviceCtx(1, 2)); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected challenge bound to device, got: %v", err)
+		}
+
+		res, err = svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, dctx)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		tk, err := svc.CompleteMfaLogin(ctx, res.MfaChallenge, next, dctx)
+		if err != nil {
+			t.Fatalf("CompleteMfaLogin failed: %v", err)
+		}
+		if _, err := svc.Refresh(ctx, tk.Refresh, dctx); err != nil {
+			t.Fatalf("Refresh after mfa login failed: %v", err)
+		}
+		if _, err := svc.CompleteMfaLogin(ctx, res.MfaChallenge, next, dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected challenge to be single-use, got: %v", err)
+		}
+	})
+
+	// --- PASSWORD RESET ---
+	t.Run("Password reset is single-use and revokes sessions", func(t *testing.T) {
+		u, err := svc.Register(ctx, domain.User{Email: "reset@test.local", Password: "horse-battery-9"}, 0)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		dctx := domain.NewDeviceCtx(1, domain.UnregisteredDevice)
+		tk, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, dctx)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+
+		if err := svc.RequestPasswordReset(ctx, "unknown@test.local"); err != nil {
+			t.Fatalf("unknown email must not be revealed: %v", err)
+		}
+		if n := lastNotification(t, bl.NotifierFilePath, domain.PurposePasswordReset, "unknown@test.local"); n.Token != "" {
+			t.Fatal("nothing must be sent to unknown email")
+		}
+		if err := svc.RequestPasswordReset(ctx, u.Email); err != nil {
+			t.Fatalf("RequestPasswordReset failed: %v", err)
+		}
+
+		n := lastNotification(t, bl.NotifierFilePath, domain.PurposePasswordReset, u.Email)
+		if err := svc.ConfirmPasswordReset(ctx, n.Token, "newsecret1", 0); err != nil {
+			t.Fatalf("ConfirmPasswordReset failed: %v", err)
+		}
+		if err := svc.ConfirmPasswordReset(ctx, n.Token, "newsecret2", 0); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected reset token to be single-use, got: %v", err)
+		}
+
+		if _, err := svc.Refresh(ctx, tk.Refresh, dctx); err == nil {
+			t.Fatal("expected sessions to be revoked after reset")
+		}
+		if _, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "newsecret1"}, dctx); err != nil {
+			t.Fatalf("Login with new password failed: %v", err)
+		}
+	})
+
+	// --- LOGOUT ALL ---
+	t.Run("Introspect follows rotation and logout", func(t *testing.T) {
+		dctx := domain.NewDeviceCtx(1, domain.UnregisteredDevice)
+		first, err := svc.Login(ctx, domain.User{Email: user.Email, Password: "horse-battery-9"}, dctx)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+
+		i, err := svc.Introspect(ctx, first.Access)
+		if err != nil || !i.Active || i.Type != domain.TokenTypeAccess || i.SessionID == "" {
+			t.Fatalf("access should be active: %+v, err %v", i, err)
+		}
+		if !i.Ctx.Compare(dctx) {
+			t.Fatalf("unexpected device ctx: %+v", i.Ctx)
+		}
+
+		second, err := svc.Refresh(ctx, first.Refresh, dctx)
+		if err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+		if i, _ := svc.Introspect(ctx, first.Refresh); i.Active {
+			t.Fatal("rotated refresh must be inactive")
+		}
+		if i, _ := svc.Introspect(ctx, second.Refresh); !i.Active {
+			t.Fatal("current refresh must be active")
+		}
+
+		secondAccess, err := svc.Introspect(ctx, second.Access)
+		if err != nil || !secondAccess.Active {
+			t.Fatalf("refreshed access should be active: %+v, err %v", secondAccess, err)
+		}
+
+		if err := svc.Logout(ctx, second.Refresh, dctx); err != nil {
+			t.Fatalf("Logout failed: %v", err)
+		}
+		for _, tk := range []string{first.Access, second.Access, second.Refresh} {
+			if i, _ := svc.Introspect(ctx, tk); i.Active {
+				t.Fatal("tokens of logged out session must be inactive")
+			}
+		}
+		// оба access-токена сессии (до и после ротации) — в denylist
+		for _, jti := range []string{i.ID, secondAccess.ID} {
+			revoked, err := svc.IsAccessTokenRevoked(ctx, jti)
+			if err != nil || !revoked {
+				t.Fatalf("access %s must be denylisted after logout, err %v", jti, err)
+			}
+		}
+		if i, err := svc.Introspect(ctx, "notarealtoken"); err != nil || i.Active {
+			t.Fatalf("garbage must be inactive without error: %+v, err %v", i, err)
+		}
+	})
+
+	t.Run("Machine client: client_credentials, rotate, disable", func(t *testing.T) {
+		created, err := svc.CreateMachineClient(ctx, "billing", []string{"orders:write", "orders:read"})
+		if err != nil {
+			t.Fatalf("CreateMachineClient failed: %v", err)
+		}
+
+		tok, err := svc.ClientCredentialsToken(ctx, created.ClientID, created.ClientSecret, []string{"orders:read"})
+		if err != nil || tok.Access == "" || tok.Refresh != "" {
+			t.Fatalf("client_credentials failed: %+v, err %v", tok, err)
+		}
+		i, err := svc.Introspect(ctx, tok.Access)
+		if err != nil || !i.Active || i.ClientID != created.ClientID || i.UserID != "" || i.SessionID != "" {
+			t.Fatalf("machine access should be active with client subject: %+v, err %v", i, err)
+		}
+
+		rotated, err := svc.RotateMachineClientSecret(ctx, created.ClientID)
+		if err != nil {
+			t.Fatalf("RotateMachineClientSecret failed: %v", err)
+		}
+		var oauthErr *domain.OAuthError
+		if _, err := svc.ClientCredentialsToken(ctx, created.ClientID, created.ClientSecret, nil); !errors.As(err, &oauthErr) || oauthErr.Code != domain.OAuthInvalidClient {
+			t.Fatalf("old secret must be rejected, got: %v", err)
+		}
+		if _, err := svc.ClientCredentialsToken(ctx, created.ClientID, rotated.ClientSecret, nil); err != nil {
+			t.Fatalf("new secret must work: %v", err)
+		}
+
+		if err := svc.DisableMachineClient(ctx, created.ClientID); err != nil {
+			t.Fatalf("DisableMachineClient failed: %v", err)
+		}
+		if _, err := svc.ClientCredentialsToken(ctx, created.ClientID, rotated.ClientSecret, nil); !errors.As(err, &oauthErr) || oauthErr.Code != domain.OAuthInvalidClient {
+			t.Fatalf("disabled client must be rejected, got: %v", err)
+		}
+		if i, err := svc.Introspect(ctx, tok.Access); err != nil || i.Active {
+			t.Fatalf("tokens of disabled client must be inactive: %+v, err %v", i, err)
+		}
+		if _, err := svc.RotateMachineClientSecret(ctx, created.ClientID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("disabled client secret must not rotate, got: %v", err)
+		}
+	})
+
+	t.Run("LogoutAll revokes every session", func(t *testing.T) {
+		u, err := svc.Register(ctx, domain.User{Email: "logoutall@test.local", Password: "horse-battery-9"}, 0)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+
+		var sessions []domain.Token
+		for range 3 {
+			tk, err := svc.Login(ctx, domain.User{Email: u.Email, Password: "horse-battery-9"}, domain.NewDeviceCtx(1, domain.UnregisteredDevice))
+			if err != nil {
+				t.Fatalf("Login failed: %v", err)
+			}
+			sessions = append(sessions, tk.Token)
+		}
+
+		if err := svc.LogoutAll(ctx, u.ID); err != nil {
+			t.Fatalf("LogoutAll failed: %v", err)
+		}
+		for i, tk := range sessions {
+			if _, err := svc.Refresh(ctx, tk.Refresh, domain.NewDeviceCtx(1, domain.UnregisteredDevice)); err == nil {
+				t.Fatalf("session %d still alive after LogoutAll", i+1)
+			}
+		}
+	})
+
+	// --- PASSWORDLESS ---
+	t.Run("Passwordless link and code", func(t *testing.T) {
+		u, err := svc.Register(ctx, domain.User{Email: "passwordless@test.local", Password: "horse-battery-9"}, 0)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		app := domain.App{ID: 101, Name: "app-101", GrantTypes: []string{domain.GrantPasswordless, domain.GrantRefreshToken}}
+		if _, err := svc.CreateApp(ctx, app); err != nil {
+			t.Fatalf("CreateApp failed: %v", err)
+		}
+		dctx := domain.NewDeviceCtx(101, domain.UnregisteredDevice)
+
+		if _, err := svc.StartPasswordlessLogin(ctx, u.Email, domain.PasswordlessLink, domain.NewDeviceCtx(1, domain.UnregisteredDevice)); !errors.Is(err, domain.ErrAppAccessDenied) {
+			t.Fatalf("expected app without passwordless grant rejected, got: %v", err)
+		}
+
+		if _, err := svc.StartPasswordlessLogin(ctx, u.Email, domain.PasswordlessLink, dctx); err != nil {
+			t.Fatalf("StartPasswordlessLogin link failed: %v", err)
+		}
+		link := lastNotification(t, bl.NotifierFilePath, domain.PurposePasswordlessLink, u.Email)
+		tk, err := svc.CompletePasswordlessLogin(ctx, link.Token, "", dctx)
+		if err != nil || tk.MfaRequired() || tk.Access == "" {
+			t.Fatalf("CompletePasswordlessLogin link failed: %+v, %v", tk, err)
+		}
+		if _, err := svc.CompletePasswordlessLogin(ctx, link.Token, "", dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected link to be single-use, got: %v", err)
+		}
+
+		start, err := svc.StartPasswordlessLogin(ctx, u.Email, domain.PasswordlessCode, dctx)
+		if err != nil {
+			t.Fatalf("StartPasswordlessLogin code failed: %v", err)
+		}
+		code := lastNotification(t, bl.NotifierFilePath, domain.PurposePasswordlessCode, u.Email)
+		wrong := "000000"
+		if code.Token == wrong {
+			wrong = "111111"
+		}
+		if _, err := svc.CompletePasswordlessLogin(ctx, start.Challenge, wrong, dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected wrong code rejected, got: %v", err)
+		}
+		if _, err := svc.CompletePasswordlessLogin(ctx, start.Challenge, code.Token, dctx); err != nil {
+			t.Fatalf("CompletePasswordlessLogin code failed: %v", err)
+		}
+
+		// последняя попытка неверна — challenge удалён, верный код уже не подходит
+		start, err = svc.StartPasswordlessLogin(ctx, u.Email, domain.PasswordlessCode, dctx)
+		if err != nil {
+			t.Fatalf("StartPasswordlessLogin code failed: %v", err)
+		}
+		code = lastNotification(t, bl.NotifierFilePath, domain.PurposePasswordlessCode, u.Email)
+		wrong = "000000"
+		if code.Token == wrong {
+			wrong = "111111"
+		}
+		for range bl.PasswordlessMaxAttempts {
+			if _, err := svc.CompletePasswordlessLogin(ctx, start.Challenge, wrong, dctx); !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("expected wrong code rejected, got: %v", err)
+			}
+		}
+		if _, err := svc.CompletePasswordlessLogin(ctx, start.Challenge, code.Token, dctx); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected challenge burned after attempts, got: %v", err)
+		}
+	})
+
+	// --- DEVICES ---
+	t.Run("RegisterDevice binds device to user", func(t *testing.T) {
+		owner, err := svc.Register(ctx, domain.User{Email: "device-owner@test.local", Password: "horse-battery-9"}, 0)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		intruder, err := svc.Register(ctx, domain.User{Email: "device-intruder@test.local", Password: "horse-battery-9"}, 0)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+
+		fresh := domain.NewDeviceCtx(1, domain.UnregisteredDevice)
+		first, err := svc.Login(ctx, domain.User{Email: owner.Email, Password: "horse-battery-9"}, fresh)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		device, tk, err := svc.RegisterDevice(ctx, first.Refresh, fresh, "ios", "iPhone")
+		if err != nil {
+			t.Fatalf("RegisterDevice failed: %v", err)
+		}
+		if !domain.IssuedDevice(device.ID) || device.UserID != owner.ID {
+			t.Fatalf("unexpected device: %+v", device)
+		}
+		if _, err := svc.Refresh(ctx, first.Refresh, fresh); err == nil {
+			t.Fatal("expected session on unregistered device to be moved")
+		}
+
+		registered := domain.NewDeviceCtx(1, device.ID)
+		if _, err := svc.Refresh(ctx, tk.Refresh, registered); err != nil {
+			t.Fatalf("Refresh on registered device failed: %v", err)
+		}
+
+		// чужой пользователь не может войти с выданным владельцу device_id
+		_, err = svc.Login(ctx, domain.User{Email: intruder.Email, Password: "horse-battery-9"}, registered)
+		if !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected foreign device rejected, got: %v", err)
+		}
+
+		// после переходного периода: сессию на device_id = 0 можно только перенести, legacy device_id не принимаются
+		graceUntil := bl.DevicesGraceUntil
+		bl.DevicesGraceUntil = time.Time{}
+		defer func() { bl.DevicesGraceUntil = graceUntil }()
+
+		second, err := svc.Login(ctx, domain.User{Email: owner.Email, Password: "horse-battery-9"}, fresh)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		if _, err := svc.Refresh(ctx, second.Refresh, fresh); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected refresh on unregistered device rejected, got: %v", err)
+		}
+		if _, _, err := svc.RegisterDevice(ctx, second.Refresh, fresh, "android", ""); err != nil {
+			t.Fatalf("RegisterDevice after grace failed: %v", err)
+		}
+		if _, err := svc.Login(ctx, domain.User{Email: owner.Email, Password: "horse-battery-9"}, domain.NewDeviceCtx(1, 7)); !errors.Is(err, domain.ErrValidation) {
+			t.Fatalf("expected legacy device rejected, got: %v", err)
+		}
+	})
+
 	// --- LOGOUT ---
 	t.Run("Logout success", func(t *testing.T) {
-		err := svc.Logout(ctx, tok.Refresh, domain.NewDeviceCtx(1, 1))
+		err := svc.Logout(ctx, tok.Refresh, domain.NewDeviceCtx(1, domain.UnregisteredDevice))
 		if err != nil {
 			t.Fatalf("Logout failed: %v", err)
 		}
 	})
 
 	t.Run("Logout invalid token", func(t *testing.T) {
-		err := svc.Logout(ctx, "notarealtoken", domain.NewDeviceCtx(1, 1))
+		err := svc.Logout(ctx, "notarealtoken", domain.NewDeviceCtx(1, domain.UnregisteredDevice))
 		if err == nil {
 			t.Fatal("expected logout failure")
 		}
@@ -146,6 +730,54 @@ func TestAuth_FullFlow(t *testing.T) {
 // /
 // /
 // /
+// totpCode — RFC 6238 (SHA1, 6 цифр, 30 с), независимая от сервиса реализация для проверки
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("bad totp secret: %v", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	off := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[off:off+4])&0x7fffffff)%1_000_000)
+}
+
+type notification struct {
+	Purpose string `json:"purpose"`
+	To      string `json:"to"`
+	Token   string `json:"token"`
+}
+
+// lastNotification — последнее уведомление с нужным назначением и адресатом из файла Notifier
+func lastNotification(t *testing.T, path string, purpose domain.OneTimePurpose, to string) notification {
+	t.Helper()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read notifications: %v", err)
+	}
+
+	var last notification
+	for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\n") {
+		var n notification
+		if err := json.Unmarshal([]byte(line), &n); err != nil {
+			t.Fatalf("bad notification %q: %v", line, err)
+		}
+		if n.Purpose == string(purpose) && n.To == to {
+			last = n
+		}
+	}
+
+	return last
+}
+
 func TestMain(m *testing.M) {
 	// увеличим таймаут старта контейнеров
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
@@ -0,0 +1,128 @@
+// Package clientip — адрес клиента для блокировки перебора по ip.
+// x-forwarded-for и x-real-ip учитываются, только если соединение пришло от доверенного прокси:
+// иначе клиент подставил бы в заголовок любой адрес — обошёл бы свою блокировку или заблокировал чужой
+package clientip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	headerForwardedFor = "X-Forwarded-For"
+	headerRealIP       = "X-Real-IP"
+)
+
+// Proxies — доверенные прокси перед сервисом; пустой список — заголовки не учитываются
+type Proxies struct {
+	nets []netip.Prefix
+}
+
+// ParseProxies — адреса или подсети (10.0.0.0/8, 192.0.2.1)
+func ParseProxies(list []string) (Proxies, error) {
+	var p Proxies
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return Proxies{}, errors.Wrapf(err, "trusted proxy %q", s)
+			}
+			p.nets = append(p.nets, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return Proxies{}, errors.Wrapf(err, "trusted proxy %q", s)
+		}
+		p.nets = append(p.nets, prefix.Masked())
+	}
+	return p, nil
+}
+
+func (p Proxies) trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, n := range p.nets {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve — remote: адрес соединения (host:port или host). За доверенным прокси x-forwarded-for
+// разбирается справа налево до первого недоверенного адреса: левые значения мог подставить клиент
+func (p Proxies) Resolve(remote, forwardedFor, realIP string) string {
+	ip := remote
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		ip = host
+	}
+	if !p.trusted(ip) {
+		return ip
+	}
+
+	if forwardedFor != "" {
+		hops := strings.Split(forwardedFor, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			ip = hop
+			if !p.trusted(hop) {
+				break
+			}
+		}
+		return ip
+	}
+	if v := strings.TrimSpace(realIP); v != "" {
+		return v
+	}
+	return ip
+}
+
+// UnaryServerInterceptor кладёт адрес клиента в контекст каждого gRPC-вызова (domain.ClientIP)
+func (p Proxies) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(domain.WithClientIP(ctx, p.fromGRPC(ctx)), req)
+	}
+}
+
+func (p Proxies) fromGRPC(ctx context.Context) string {
+	var remote, forwardedFor, realIP string
+	if pr, ok := peer.FromContext(ctx); ok && pr.Addr != nil {
+		remote = pr.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		// несколько заголовков x-forwarded-for — одна цепочка по порядку
+		forwardedFor = strings.Join(md.Get(headerForwardedFor), ",")
+		if v := md.Get(headerRealIP); len(v) > 0 {
+			realIP = v[0]
+		}
+	}
+	return p.Resolve(remote, forwardedFor, realIP)
+}
+
+// Middleware кладёт адрес клиента в контекст HTTP-запроса (domain.ClientIP)
+func (p Proxies) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := p.Resolve(r.RemoteAddr, strings.Join(r.Header.Values(headerForwardedFor), ","), r.Header.Get(headerRealIP))
+		next.ServeHTTP(w, r.WithContext(domain.WithClientIP(r.Context(), ip)))
+	})
+}
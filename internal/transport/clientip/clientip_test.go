@@ -0,0 +1,87 @@
+package clientip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestParseProxies(t *testing.T) {
+	_, err := ParseProxies([]string{"10.0.0.0/8", " 192.0.2.1 ", "", "::1"})
+	require.NoError(t, err)
+
+	for _, bad := range []string{"10.0.0.0/33", "proxy.local", "300.1.1.1"} {
+		_, err := ParseProxies([]string{bad})
+		require.Error(t, err, bad)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	p, err := ParseProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		remote, forwardedFor, realIP, want string
+	}{
+		"direct client, headers ignored": {"203.0.113.7:5555", "198.51.100.1", "198.51.100.2", "203.0.113.7"},
+		"direct client without headers":  {"203.0.113.7:5555", "", "", "203.0.113.7"},
+		"trusted proxy, forwarded for":   {"10.1.2.3:5555", "198.51.100.1", "", "198.51.100.1"},
+		"forged left values skipped":     {"10.1.2.3:5555", "1.2.3.4, 198.51.100.1, 192.0.2.1", "", "198.51.100.1"},
+		"all hops trusted":               {"10.1.2.3:5555", "10.9.9.9, 192.0.2.1", "", "10.9.9.9"},
+		"trusted proxy, real ip":         {"192.0.2.1:443", "", "198.51.100.2", "198.51.100.2"},
+		"trusted proxy without headers":  {"10.1.2.3:5555", "", "", "10.1.2.3"},
+		"ipv4-mapped remote":             {"[::ffff:10.1.2.3]:5555", "198.51.100.1", "", "198.51.100.1"},
+		"no remote":                      {"", "198.51.100.1", "", ""},
+	} {
+		require.Equal(t, tc.want, p.Resolve(tc.remote, tc.forwardedFor, tc.realIP), name)
+	}
+
+	var none Proxies
+	require.Equal(t, "203.0.113.7", none.Resolve("203.0.113.7:1", "198.51.100.1", ""))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	p, err := ParseProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	call := func(remote string, md metadata.MD) string {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(remote), Port: 5555}})
+		ctx = metadata.NewIncomingContext(ctx, md)
+		out, err := p.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+			return domain.ClientIP(ctx), nil
+		})
+		require.NoError(t, err)
+		return out.(string)
+	}
+
+	require.Equal(t, "203.0.113.7", call("10.0.0.1", metadata.Pairs("x-forwarded-for", "203.0.113.7")))
+	require.Equal(t, "198.51.100.9", call("198.51.100.9", metadata.Pairs("x-forwarded-for", "203.0.113.7")))
+}
+
+func TestMiddleware(t *testing.T) {
+	p, err := ParseProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	var got string
+	h := p.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = domain.ClientIP(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:5555"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.2")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, "203.0.113.9", got)
+
+	req.RemoteAddr = "198.51.100.9:5555"
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, "198.51.100.9", got)
+}
@@ -0,0 +1,126 @@
+package resttransportoauth
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+)
+
+// параметры /authorize и /token (RFC 6749, RFC 7636)
+const (
+	paramClientID            = "client_id"
+	paramRedirectURI         = "redirect_uri"
+	paramResponseType        = "response_type"
+	paramScope               = "scope"
+	paramState               = "state"
+	paramCodeChallenge       = "code_challenge"
+	paramCodeChallengeMethod = "code_challenge_method"
+	paramGrantType           = "grant_type"
+	paramCode                = "code"
+	paramCodeVerifier        = "code_verifier"
+	paramRefreshToken        = "refresh_token"
+	paramClientSecret        = "client_secret"
+	paramNonce               = "nonce"
+	paramError               = "error"
+	paramErrorDescription    = "error_description"
+
+	fieldEmail    = "email"
+	fieldPassword = "password"
+	fieldOtp      = "otp"
+)
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// userInfoResponse — OIDC Core 5.3.2; email-поля только при scope email
+type userInfoResponse struct {
+	Sub           string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func toTokenResponse(t domain.OAuthToken) tokenResponse {
+	return tokenResponse{
+		AccessToken:  t.Access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(t.ExpiresIn.Seconds()),
+		RefreshToken: t.Refresh,
+		IDToken:      t.IDToken,
+		Scope:        strings.Join(t.Scopes, " "),
+	}
+}
+
+func toUserInfoResponse(u domain.UserInfo) userInfoResponse {
+	resp := userInfoResponse{Sub: u.Subject}
+	if u.Email != "" {
+		resp.Email = u.Email
+		resp.EmailVerified = &u.EmailVerified
+	}
+	return resp
+}
+
+// clientIDFrom — client_id это app_id; нечисловой приравнивается к неизвестному (0)
+func clientIDFrom(r *http.Request) int32 {
+	id, err := strconv.ParseInt(r.Form.Get(paramClientID), 10, 32)
+	if err != nil || id <= 0 {
+		return 0
+	}
+	return int32(id)
+}
+
+func authorizeRequestFrom(r *http.Request) domain.AuthorizeRequest {
+	return domain.AuthorizeRequest{
+		ClientID:            clientIDFrom(r),
+		RedirectURI:         r.Form.Get(paramRedirectURI),
+		ResponseType:        r.Form.Get(paramResponseType),
+		Scopes:              strings.Fields(r.Form.Get(paramScope)),
+		State:               r.Form.Get(paramState),
+		CodeChallenge:       r.Form.Get(paramCodeChallenge),
+		CodeChallengeMethod: r.Form.Get(paramCodeChallengeMethod),
+		Nonce:               r.Form.Get(paramNonce),
+	}
+}
+
+func codeExchangeFrom(r *http.Request) domain.CodeExchange {
+	return domain.CodeExchange{
+		Code:         r.PostForm.Get(paramCode),
+		ClientID:     clientIDFrom(r),
+		RedirectURI:  r.PostForm.Get(paramRedirectURI),
+		CodeVerifier: r.PostForm.Get(paramCodeVerifier),
+	}
+}
+
+// clientCredentialsFrom — client_secret_basic или client_secret_post (RFC 6749, 2.3.1), но не оба сразу.
+// В Basic id и секрет дополнительно form-urlencoded
+func clientCredentialsFrom(r *http.Request) (clientID, secret string, _ error) {
+	basicID, basicSecret, hasBasic := r.BasicAuth()
+	postID, postSecret := r.PostForm.Get(paramClientID), r.PostForm.Get(paramClientSecret)
+
+	switch {
+	case hasBasic && (postID != "" || postSecret != ""):
+		return "", "", domain.NewOAuthError(domain.OAuthInvalidRequest, "use only one client authentication method")
+	case hasBasic:
+		id, errID := url.QueryUnescape(basicID)
+		sec, errSecret := url.QueryUnescape(basicSecret)
+		if errID != nil || errSecret != nil {
+			return "", "", domain.NewOAuthError(domain.OAuthInvalidClient, "malformed basic credentials")
+		}
+		return id, sec, nil
+	default:
+		return postID, postSecret, nil
+	}
+}
@@ -0,0 +1,86 @@
+package resttransportoauth
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+)
+
+const (
+	msgBadCredentials   = "Неверный email, пароль или код подтверждения"
+	msgTooManyAttempts  = "Слишком много попыток, повторите позже"
+	msgEmailNotVerified = "Подтвердите email, чтобы войти"
+	msgAppAccessDenied  = "Вход в это приложение для вашего аккаунта закрыт"
+	msgServerError      = "Не удалось выполнить вход, повторите позже"
+)
+
+type loginPage struct {
+	Req     domain.AuthorizeRequest
+	Scope   string
+	Email   string
+	NeedOtp bool
+	Message string
+}
+
+type errorPage struct {
+	Code        string
+	Description string
+}
+
+// параметры запроса уходят в скрытые поля и повторно проверяются сервисом при отправке формы
+var loginTmpl = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head><meta charset="utf-8"><title>Вход</title></head>
+<body>
+<form method="post" action="/oauth2/authorize">
+{{if .Message}}<p role="alert">{{.Message}}</p>{{end}}
+<input type="hidden" name="client_id" value="{{.Req.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Req.RedirectURI}}">
+<input type="hidden" name="response_type" value="{{.Req.ResponseType}}">
+<input type="hidden" name="scope" value="{{.Scope}}">
+<input type="hidden" name="state" value="{{.Req.State}}">
+<input type="hidden" name="code_challenge" value="{{.Req.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Req.CodeChallengeMethod}}">
+{{if .Req.Nonce}}<input type="hidden" name="nonce" value="{{.Req.Nonce}}">{{end}}
+<label>Email <input type="email" name="email" value="{{.Email}}" autocomplete="username" required></label>
+<label>Пароль <input type="password" name="password" autocomplete="current-password" required></label>
+{{if .NeedOtp}}<label>Код из приложения <input type="text" name="otp" inputmode="numeric" autocomplete="one-time-code" required></label>{{end}}
+<button type="submit">Войти</button>
+</form>
+</body>
+</html>
+`))
+
+var errorTmpl = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head><meta charset="utf-8"><title>Ошибка авторизации</title></head>
+<body><h1>{{.Code}}</h1><p>{{.Description}}</p></body>
+</html>
+`))
+
+// pageHeaders — страницу входа нельзя встраивать во фреймы и кэшировать
+func pageHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+}
+
+func (t oauthTransport) renderLogin(w http.ResponseWriter, status int, page loginPage) {
+	page.Scope = strings.Join(page.Req.Scopes, " ")
+	pageHeaders(w)
+	w.WriteHeader(status)
+	if err := loginTmpl.Execute(w, page); err != nil {
+		t.l.Errorw(ErrFailedRenderPage, err)
+	}
+}
+
+func (t oauthTransport) renderError(w http.ResponseWriter, status int, page errorPage) {
+	pageHeaders(w)
+	w.WriteHeader(status)
+	if err := errorTmpl.Execute(w, page); err != nil {
+		t.l.Errorw(ErrFailedRenderPage, err)
+	}
+}
@@ -0,0 +1,243 @@
+package resttransportoauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+//go:generate mockery --name=OAuthService --with-expecter --output=./mocks --exported
+type OAuthService interface {
+	CheckAuthorizeRequest(ctx context.Context, req domain.AuthorizeRequest) error
+	Authorize(ctx context.Context, req domain.AuthorizeRequest, creds domain.User, totpCode string) (string, error)
+	ExchangeAuthorizationCode(ctx context.Context, ex domain.CodeExchange) (domain.OAuthToken, error)
+	RefreshOAuthToken(ctx context.Context, refresh string, clientID int32) (domain.OAuthToken, error)
+	ClientCredentialsToken(ctx context.Context, clientID, secret string, scopes []string) (domain.OAuthToken, error)
+	UserInfo(ctx context.Context, access string) (domain.UserInfo, error)
+}
+
+const (
+	ErrFailedParseForm     = "failed parse form"
+	ErrFailedAuthorizeReq  = "failed authorize request"
+	ErrFailedTokenReq      = "failed token request"
+	ErrFailedRenderPage    = "failed render page"
+	ErrFailedWriteResponse = "failed write response"
+	ErrTooManyAttempts     = "too many failed login attempts"
+	ErrEmailNotVerified    = "email not verified"
+	ErrAppAccessDenied     = "app access denied"
+	ErrFailedUserInfoReq   = "failed userinfo request"
+
+	oauthServerError = "server_error"
+)
+
+// AuthorizeForm — GET /oauth2/authorize: проверка запроса и форма входа
+func (t oauthTransport) AuthorizeForm(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		t.renderError(w, http.StatusBadRequest, errorPage{Code: domain.OAuthInvalidRequest, Description: ErrFailedParseForm})
+		return
+	}
+
+	req := authorizeRequestFrom(r)
+	if err := t.s.CheckAuthorizeRequest(r.Context(), req); err != nil {
+		t.authorizeError(w, r, req, err)
+		return
+	}
+
+	t.renderLogin(w, http.StatusOK, loginPage{Req: req})
+}
+
+// Authorize — POST /oauth2/authorize: отправка формы входа; успех — redirect с code и state
+func (t oauthTransport) Authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		t.renderError(w, http.StatusBadRequest, errorPage{Code: domain.OAuthInvalidRequest, Description: ErrFailedParseForm})
+		return
+	}
+
+	req := authorizeRequestFrom(r)
+	creds := domain.User{
+		Email:    r.PostForm.Get(fieldEmail),
+		Password: r.PostForm.Get(fieldPassword),
+	}
+	otp := r.PostForm.Get(fieldOtp)
+	page := loginPage{Req: req, Email: creds.Email, NeedOtp: otp != ""}
+
+	// адрес клиента для блокировки перебора кладёт clientip.Middleware
+	code, err := t.s.Authorize(r.Context(), req, creds, otp)
+	if err != nil {
+		var oauthErr *domain.OAuthError
+		var lockErr *domain.LockoutError
+		switch {
+		case errors.As(err, &oauthErr):
+			t.authorizeError(w, r, req, err)
+		case errors.Is(err, domain.ErrMfaRequired):
+			page.NeedOtp = true
+			t.renderLogin(w, http.StatusOK, page)
+		case errors.As(err, &lockErr):
+			t.l.Errorw(ErrTooManyAttempts, err)
+			page.Message = msgTooManyAttempts
+			t.renderLogin(w, http.StatusTooManyRequests, page)
+		case errors.Is(err, domain.ErrEmailNotVerified):
+			t.l.Infow(ErrEmailNotVerified, "app_id", req.ClientID)
+			page.Message = msgEmailNotVerified
+			t.renderLogin(w, http.StatusForbidden, page)
+		case errors.Is(err, domain.ErrAppAccessDenied):
+			t.l.Infow(ErrAppAccessDenied, "app_id", req.ClientID, "cause", err)
+			page.Message = msgAppAccessDenied
+			t.renderLogin(w, http.StatusForbidden, page)
+		case errors.Is(err, domain.ErrValidation):
+			t.l.Errorw(ErrFailedAuthorizeReq, err)
+			page.Message = msgBadCredentials
+			t.renderLogin(w, http.StatusUnauthorized, page)
+		default:
+			t.l.Errorw(ErrFailedAuthorizeReq, err)
+			page.Message = msgServerError
+			t.renderLogin(w, http.StatusInternalServerError, page)
+		}
+		return
+	}
+
+	redirectTo(w, r, req.RedirectURI, url.Values{paramCode: {code}}, req.State)
+}
+
+// Token — POST /oauth2/token: authorization_code, refresh_token и client_credentials, ответ в JSON (RFC 6749, 5.1)
+func (t oauthTransport) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		t.tokenError(w, domain.NewOAuthError(domain.OAuthInvalidRequest, ErrFailedParseForm))
+		return
+	}
+
+	var (
+		token domain.OAuthToken
+		err   error
+	)
+	switch r.PostForm.Get(paramGrantType) {
+	case domain.GrantAuthorizationCode:
+		token, err = t.s.ExchangeAuthorizationCode(r.Context(), codeExchangeFrom(r))
+	case domain.GrantRefreshToken:
+		token, err = t.s.RefreshOAuthToken(r.Context(), r.PostForm.Get(paramRefreshToken), clientIDFrom(r))
+	case domain.GrantClientCredentials:
+		var clientID, secret string
+		if clientID, secret, err = clientCredentialsFrom(r); err == nil {
+			token, err = t.s.ClientCredentialsToken(r.Context(), clientID, secret, strings.Fields(r.PostForm.Get(paramScope)))
+		}
+	default:
+		err = domain.NewOAuthError(domain.OAuthUnsupportedGrantType,
+			"grant_type must be authorization_code, refresh_token or client_credentials")
+	}
+	if err != nil {
+		t.tokenError(w, err)
+		return
+	}
+
+	t.writeJSON(w, http.StatusOK, toTokenResponse(token))
+}
+
+// UserInfo — GET/POST /oauth2/userinfo с Authorization: Bearer <access> (OIDC Core 5.3).
+// Ошибки — по RFC 6750, 3: в WWW-Authenticate, без тела
+func (t oauthTransport) UserInfo(w http.ResponseWriter, r *http.Request) {
+	access, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	info, err := t.s.UserInfo(r.Context(), access)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrValidation):
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case errors.Is(err, domain.ErrInsufficientScope):
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			t.l.Errorw(ErrFailedUserInfoReq, err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	t.writeJSON(w, http.StatusOK, toUserInfoResponse(info))
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// authorizeError — ошибки до проверки client_id и redirect_uri показываются пользователю,
+// остальные уходят клиенту на redirect_uri (RFC 6749, 4.1.2.1)
+func (t oauthTransport) authorizeError(w http.ResponseWriter, r *http.Request, req domain.AuthorizeRequest, err error) {
+	var oauthErr *domain.OAuthError
+	if !errors.As(err, &oauthErr) {
+		t.l.Errorw(ErrFailedAuthorizeReq, err)
+		t.renderError(w, http.StatusInternalServerError, errorPage{Code: oauthServerError, Description: ErrFailedAuthorizeReq})
+		return
+	}
+	if !oauthErr.Redirect {
+		t.renderError(w, http.StatusBadRequest, errorPage{Code: oauthErr.Code, Description: oauthErr.Description})
+		return
+	}
+
+	redirectTo(w, r, req.RedirectURI, url.Values{
+		paramError:            {oauthErr.Code},
+		paramErrorDescription: {oauthErr.Description},
+	}, req.State)
+}
+
+func (t oauthTransport) tokenError(w http.ResponseWriter, err error) {
+	var oauthErr *domain.OAuthError
+	if errors.As(err, &oauthErr) {
+		status := http.StatusBadRequest
+		// RFC 6749, 5.2: неудачная аутентификация клиента — 401 с WWW-Authenticate
+		if oauthErr.Code == domain.OAuthInvalidClient {
+			status = http.StatusUnauthorized
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth2"`)
+		}
+		t.writeJSON(w, status, errorResponse{Error: oauthErr.Code, ErrorDescription: oauthErr.Description})
+		return
+	}
+
+	t.l.Errorw(ErrFailedTokenReq, err)
+	t.writeJSON(w, http.StatusInternalServerError, errorResponse{Error: oauthServerError})
+}
+
+// redirectTo — параметры добавляются к query зарегистрированного redirect_uri, state возвращается как есть
+func redirectTo(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values, state string) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	if state != "" {
+		q.Set(paramState, state)
+	}
+	u.RawQuery = q.Encode()
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// writeJSON — ответы /token не кэшируются (RFC 6749, 5.1)
+func (t oauthTransport) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		t.l.Errorw(ErrFailedWriteResponse, err)
+	}
+}
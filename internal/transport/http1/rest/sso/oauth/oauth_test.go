@@ -0,0 +1,363 @@
+package resttransportoauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	mocks "github.com/eragon-mdi/sso/internal/transport/http1/rest/sso/oauth/mocks"
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const authorizeQuery = "client_id=7&redirect_uri=https%3A%2F%2Fapp.example%2Fcb%3Fx%3D1&response_type=code" +
+	"&scope=profile+email&state=st%3C1%3E&code_challenge=chal&code_challenge_method=S256&nonce=n-1"
+
+func redirectable(code string) *domain.OAuthError {
+	e := domain.NewOAuthError(code, "desc")
+	e.Redirect = true
+	return e
+}
+
+func postForm(target string, form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestOAuthTransport_AuthorizeForm(t *testing.T) {
+	wantReq := domain.AuthorizeRequest{
+		ClientID:            7,
+		RedirectURI:         "https://app.example/cb?x=1",
+		ResponseType:        "code",
+		Scopes:              []string{"profile", "email"},
+		State:               "st<1>",
+		CodeChallenge:       "chal",
+		CodeChallengeMethod: "S256",
+		Nonce:               "n-1",
+	}
+
+	t.Run("valid request renders escaped login form", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("CheckAuthorizeRequest", mock.Anything, wantReq).Return(nil)
+
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).AuthorizeForm(rec, httptest.NewRequest(http.MethodGet, "/oauth2/authorize?"+authorizeQuery, nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+		require.Contains(t, rec.Header().Get("Content-Security-Policy"), "frame-ancestors 'none'")
+		require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+		body := rec.Body.String()
+		require.Contains(t, body, `name="state" value="st&lt;1&gt;"`)
+		require.Contains(t, body, `name="scope" value="profile email"`)
+		require.Contains(t, body, `name="nonce" value="n-1"`)
+		require.NotContains(t, body, `name="otp"`)
+	})
+
+	t.Run("bad client is shown, not redirected", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("CheckAuthorizeRequest", mock.Anything, mock.Anything).Return(domain.NewOAuthError(domain.OAuthInvalidClient, "unknown client_id"))
+
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).AuthorizeForm(rec, httptest.NewRequest(http.MethodGet, "/oauth2/authorize?"+authorizeQuery, nil))
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Empty(t, rec.Header().Get("Location"))
+		require.Contains(t, rec.Body.String(), domain.OAuthInvalidClient)
+	})
+
+	t.Run("redirectable error goes to client with state", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("CheckAuthorizeRequest", mock.Anything, mock.Anything).Return(redirectable(domain.OAuthInvalidScope))
+
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).AuthorizeForm(rec, httptest.NewRequest(http.MethodGet, "/oauth2/authorize?"+authorizeQuery, nil))
+
+		require.Equal(t, http.StatusFound, rec.Code)
+		loc, err := url.Parse(rec.Header().Get("Location"))
+		require.NoError(t, err)
+		require.Equal(t, "app.example", loc.Host)
+		require.Equal(t, domain.OAuthInvalidScope, loc.Query().Get("error"))
+		require.Equal(t, "st<1>", loc.Query().Get("state"))
+		require.Equal(t, "1", loc.Query().Get("x"), "registered query kept")
+	})
+}
+
+func TestOAuthTransport_Authorize(t *testing.T) {
+	form := func(extra url.Values) url.Values {
+		f, _ := url.ParseQuery(authorizeQuery)
+		f.Set("email", "u@e.x")
+		f.Set("password", "pass")
+		for k, v := range extra {
+			f[k] = v
+		}
+		return f
+	}
+
+	t.Run("success redirects with code and state", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("Authorize",
+			mock.MatchedBy(func(ctx context.Context) bool { return domain.ClientIP(ctx) == "203.0.113.9" }),
+			mock.MatchedBy(func(r domain.AuthorizeRequest) bool { return r.ClientID == 7 && r.State == "st<1>" }),
+			domain.User{Email: "u@e.x", Password: "pass"}, "",
+		).Return("the-code", nil)
+
+		req := postForm("/oauth2/authorize", form(nil))
+		req = req.WithContext(domain.WithClientIP(req.Context(), "203.0.113.9"))
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).Authorize(rec, req)
+
+		require.Equal(t, http.StatusFound, rec.Code)
+		loc, err := url.Parse(rec.Header().Get("Location"))
+		require.NoError(t, err)
+		require.Equal(t, "the-code", loc.Query().Get("code"))
+		require.Equal(t, "st<1>", loc.Query().Get("state"))
+		s.AssertExpectations(t)
+	})
+
+	t.Run("mfa required re-renders form with otp field", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("Authorize", mock.Anything, mock.Anything, mock.Anything, "").
+			Return("", errors.Wrap(domain.ErrMfaRequired, "totp code required"))
+
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).Authorize(rec, postForm("/oauth2/authorize", form(nil)))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `name="otp"`)
+		require.Contains(t, rec.Body.String(), `value="u@e.x"`)
+		require.NotContains(t, rec.Body.String(), "pass\"", "password is never echoed")
+	})
+
+	t.Run("login failures re-render form", func(t *testing.T) {
+		cases := map[string]struct {
+			err    error
+			status int
+		}{
+			"bad credentials": {errors.Wrap(domain.ErrValidation, "bad password"), http.StatusUnauthorized},
+			"locked out":      {&domain.LockoutError{RetryAfter: time.Minute}, http.StatusTooManyRequests},
+			"not verified":    {domain.ErrEmailNotVerified, http.StatusForbidden},
+			"email domain":    {errors.Wrap(domain.ErrAppAccessDenied, "email domain"), http.StatusForbidden},
+			"internal":        {errors.New("redis down"), http.StatusInternalServerError},
+		}
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				s := &mocks.OAuthService{}
+				s.On("Authorize", mock.Anything, mock.Anything, mock.Anything, "123456").Return("", tc.err)
+
+				rec := httptest.NewRecorder()
+				New(s, zap.NewNop().Sugar()).Authorize(rec, postForm("/oauth2/authorize", form(url.Values{"otp": {"123456"}})))
+
+				require.Equal(t, tc.status, rec.Code)
+				require.Empty(t, rec.Header().Get("Location"))
+				require.Contains(t, rec.Body.String(), `role="alert"`)
+				require.Contains(t, rec.Body.String(), `name="otp"`)
+			})
+		}
+	})
+
+	t.Run("tampered request is checked again", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("Authorize", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return("", domain.NewOAuthError(domain.OAuthInvalidRequest, "redirect_uri is not registered for client"))
+
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).Authorize(rec, postForm("/oauth2/authorize", form(url.Values{"redirect_uri": {"https://evil.example/cb"}})))
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Empty(t, rec.Header().Get("Location"))
+	})
+}
+
+func TestOAuthTransport_Token(t *testing.T) {
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+		t.Helper()
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		return got
+	}
+	token := domain.OAuthToken{
+		Token:     domain.Token{Access: "acc", Refresh: "ref"},
+		IDToken:   "idt",
+		ExpiresIn: 15 * time.Minute,
+		Scopes:    []string{"profile", "email"},
+	}
+
+	t.Run("authorization_code", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("ExchangeAuthorizationCode", mock.Anything, domain.CodeExchange{
+			Code: "c", ClientID: 7, RedirectURI: "https://app.example/cb", CodeVerifier: "v",
+		}).Return(token, nil)
+
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).Token(rec, postForm("/oauth2/token", url.Values{
+			"grant_type": {"authorization_code"}, "code": {"c"}, "client_id": {"7"},
+			"redirect_uri": {"https://app.example/cb"}, "code_verifier": {"v"},
+		}))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, map[string]any{
+			"access_token": "acc", "token_type": "Bearer", "expires_in": float64(900),
+			"refresh_token": "ref", "id_token": "idt", "scope": "profile email",
+		}, decode(t, rec))
+	})
+
+	t.Run("refresh_token", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("RefreshOAuthToken", mock.Anything, "ref", int32(7)).Return(domain.OAuthToken{Token: token.Token, ExpiresIn: token.ExpiresIn}, nil)
+
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).Token(rec, postForm("/oauth2/token", url.Values{
+			"grant_type": {"refresh_token"}, "refresh_token": {"ref"}, "client_id": {"7"},
+		}))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		got := decode(t, rec)
+		require.Equal(t, "acc", got["access_token"])
+		require.NotContains(t, got, "scope")
+	})
+
+	t.Run("client_credentials: basic and post auth", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("ClientCredentialsToken", mock.Anything, "c-1", "s&1", []string{"orders:read"}).
+			Return(domain.OAuthToken{Token: domain.Token{Access: "acc"}, ExpiresIn: time.Minute, Scopes: []string{"orders:read"}}, nil)
+		tr := New(s, zap.NewNop().Sugar())
+
+		req := postForm("/oauth2/token", url.Values{"grant_type": {"client_credentials"}, "scope": {"orders:read"}})
+		req.SetBasicAuth("c-1", url.QueryEscape("s&1"))
+		rec := httptest.NewRecorder()
+		tr.Token(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		got := decode(t, rec)
+		require.Equal(t, "acc", got["access_token"])
+		require.NotContains(t, got, "refresh_token")
+
+		rec = httptest.NewRecorder()
+		tr.Token(rec, postForm("/oauth2/token", url.Values{
+			"grant_type": {"client_credentials"}, "scope": {"orders:read"},
+			"client_id": {"c-1"}, "client_secret": {"s&1"},
+		}))
+		require.Equal(t, http.StatusOK, rec.Code)
+		s.AssertNumberOfCalls(t, "ClientCredentialsToken", 2)
+	})
+
+	t.Run("client_credentials: both auth methods rejected", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		req := postForm("/oauth2/token", url.Values{"grant_type": {"client_credentials"}, "client_id": {"c-1"}})
+		req.SetBasicAuth("c-1", "s")
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).Token(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Equal(t, domain.OAuthInvalidRequest, decode(t, rec)["error"])
+		s.AssertNotCalled(t, "ClientCredentialsToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("client_credentials: invalid client is 401", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("ClientCredentialsToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(domain.OAuthToken{}, domain.NewOAuthError(domain.OAuthInvalidClient, "client authentication failed"))
+
+		req := postForm("/oauth2/token", url.Values{"grant_type": {"client_credentials"}})
+		req.SetBasicAuth("c-1", "wrong")
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).Token(rec, req)
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+		require.Equal(t, domain.OAuthInvalidClient, decode(t, rec)["error"])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("ExchangeAuthorizationCode", mock.Anything, mock.Anything).
+			Return(domain.OAuthToken{}, domain.NewOAuthError(domain.OAuthInvalidGrant, "used")).Once()
+		s.On("ExchangeAuthorizationCode", mock.Anything, mock.Anything).
+			Return(domain.OAuthToken{}, errors.New("redis down")).Once()
+		tr := New(s, zap.NewNop().Sugar())
+
+		for _, tc := range []struct {
+			grant  string
+			status int
+			code   string
+		}{
+			{"authorization_code", http.StatusBadRequest, domain.OAuthInvalidGrant},
+			{"authorization_code", http.StatusInternalServerError, "server_error"},
+			{"password", http.StatusBadRequest, domain.OAuthUnsupportedGrantType},
+		} {
+			rec := httptest.NewRecorder()
+			tr.Token(rec, postForm("/oauth2/token", url.Values{"grant_type": {tc.grant}}))
+			require.Equal(t, tc.status, rec.Code, tc.grant)
+			require.Equal(t, tc.code, decode(t, rec)["error"], tc.grant)
+		}
+	})
+}
+
+func TestOAuthTransport_UserInfo(t *testing.T) {
+	withBearer := func(token string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/oauth2/userinfo", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return r
+	}
+
+	t.Run("email claims", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("UserInfo", mock.Anything, "acc").Return(domain.UserInfo{Subject: "u1", Email: "u@e.x", EmailVerified: false}, nil)
+
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).UserInfo(rec, withBearer("acc"))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		require.Equal(t, map[string]any{"sub": "u1", "email": "u@e.x", "email_verified": false}, got)
+	})
+
+	t.Run("without email scope only sub", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("UserInfo", mock.Anything, "acc").Return(domain.UserInfo{Subject: "u1"}, nil)
+
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).UserInfo(rec, withBearer("acc"))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"sub":"u1"}`, rec.Body.String())
+	})
+
+	t.Run("bearer errors", func(t *testing.T) {
+		s := &mocks.OAuthService{}
+		s.On("UserInfo", mock.Anything, "bad").Return(domain.UserInfo{}, errors.Wrap(domain.ErrValidation, "expired"))
+		s.On("UserInfo", mock.Anything, "noscope").Return(domain.UserInfo{}, errors.Wrap(domain.ErrInsufficientScope, "no openid"))
+		s.On("UserInfo", mock.Anything, "down").Return(domain.UserInfo{}, errors.New("redis down"))
+		tr := New(s, zap.NewNop().Sugar())
+
+		for _, tc := range []struct {
+			token  string
+			status int
+			header string
+		}{
+			{"", http.StatusUnauthorized, "Bearer"},
+			{"bad", http.StatusUnauthorized, `Bearer error="invalid_token"`},
+			{"noscope", http.StatusForbidden, `Bearer error="insufficient_scope", scope="openid"`},
+			{"down", http.StatusInternalServerError, ""},
+		} {
+			rec := httptest.NewRecorder()
+			tr.UserInfo(rec, withBearer(tc.token))
+			require.Equal(t, tc.status, rec.Code, tc.token)
+			require.Equal(t, tc.header, rec.Header().Get("WWW-Authenticate"), tc.token)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+package resttransportoauth
+
+import (
+	"github.com/eragon-mdi/sso/internal/common/api"
+	"go.uber.org/zap"
+)
+
+type oauthTransport struct {
+	s OAuthService
+	l *zap.SugaredLogger
+}
+
+func New(s OAuthService, l *zap.SugaredLogger) api.OAuthTransport {
+	return &oauthTransport{
+		s: s,
+		l: l,
+	}
+}
@@ -0,0 +1,18 @@
+package resttransportwellknown
+
+import (
+	"github.com/eragon-mdi/sso/internal/common/api"
+	"go.uber.org/zap"
+)
+
+type wellKnownTransport struct {
+	s WellKnownService
+	l *zap.SugaredLogger
+}
+
+func New(s WellKnownService, l *zap.SugaredLogger) api.WellKnownTransport {
+	return &wellKnownTransport{
+		s: s,
+		l: l,
+	}
+}
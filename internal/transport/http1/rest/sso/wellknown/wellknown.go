@@ -0,0 +1,56 @@
+package resttransportwellknown
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+//go:generate mockery --name=WellKnownService --with-expecter --output=./mocks --exported
+type WellKnownService interface {
+	JWKS() domain.JWKS
+	OpenIDConfiguration() (domain.OpenIDConfiguration, error)
+}
+
+const (
+	ErrFailedWriteJwks       = "failed to write jwks"
+	ErrFailedWriteOIDCConfig = "failed to write openid configuration"
+	ErrOIDCConfig            = "openid configuration unavailable"
+
+	// за это время resource-серверы увидят новый ключ; retiring-ключ держать дольше TTL access + jwksMaxAge
+	jwksMaxAge = 5 * time.Minute
+)
+
+func (t wellKnownTransport) JWKS(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+
+	if err := json.NewEncoder(w).Encode(t.s.JWKS()); err != nil {
+		t.l.Errorw(ErrFailedWriteJwks, err)
+	}
+}
+
+// OpenIDConfiguration — discovery для OIDC-клиентов; 404, если issuer не публичный адрес SSO
+func (t wellKnownTransport) OpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	cfg, err := t.s.OpenIDConfiguration()
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		t.l.Errorw(ErrOIDCConfig, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+
+	if err := json.NewEncoder(w).Encode(cfg); err != nil {
+		t.l.Errorw(ErrFailedWriteOIDCConfig, err)
+	}
+}
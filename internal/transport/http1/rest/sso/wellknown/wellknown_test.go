@@ -0,0 +1,66 @@
+package resttransportwellknown
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	mocks "github.com/eragon-mdi/sso/internal/transport/http1/rest/sso/wellknown/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWellKnownTransport_JWKS(t *testing.T) {
+	set := domain.JWKS{Keys: []domain.JWK{
+		{Kty: "RSA", Use: "sig", Alg: "RS256", Kid: "k1", N: "n1", E: "AQAB"},
+	}}
+	s := &mocks.WellKnownService{}
+	s.On("JWKS").Return(set)
+
+	rec := httptest.NewRecorder()
+	New(s, zap.NewNop().Sugar()).JWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.Equal(t, "public, max-age=300", rec.Header().Get("Cache-Control"))
+
+	var got map[string][]map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, []map[string]string{
+		{"kty": "RSA", "use": "sig", "alg": "RS256", "kid": "k1", "n": "n1", "e": "AQAB"},
+	}, got["keys"])
+
+	s.AssertExpectations(t)
+}
+
+func TestWellKnownTransport_OpenIDConfiguration(t *testing.T) {
+	t.Run("served as json", func(t *testing.T) {
+		s := &mocks.WellKnownService{}
+		s.On("OpenIDConfiguration").Return(domain.OpenIDConfiguration{
+			Issuer:                           "https://sso.example.com",
+			JwksURI:                          "https://sso.example.com/.well-known/jwks.json",
+			IDTokenSigningAlgValuesSupported: []string{"EdDSA"},
+		}, nil)
+
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).OpenIDConfiguration(rec, httptest.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		require.Equal(t, "https://sso.example.com", got["issuer"])
+		require.Equal(t, []any{"EdDSA"}, got["id_token_signing_alg_values_supported"])
+	})
+
+	t.Run("issuer is not a url", func(t *testing.T) {
+		s := &mocks.WellKnownService{}
+		s.On("OpenIDConfiguration").Return(domain.OpenIDConfiguration{}, domain.ErrNotFound)
+
+		rec := httptest.NewRecorder()
+		New(s, zap.NewNop().Sugar()).OpenIDConfiguration(rec, httptest.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -9,14 +9,42 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 //go:generate mockery --name=AuthService --with-expecter --output=./mocks --exported
 type AuthService interface {
-	Register(context.Context, domain.User) (domain.User, error)
-	Login(context.Context, domain.User, domain.DeviceCtx) (domain.Token, error)
+	Register(_ context.Context, u domain.User, appID int32) (domain.User, error)
+	Login(context.Context, domain.User, domain.DeviceCtx) (domain.LoginResult, error)
 	Refresh(context.Context, string, domain.DeviceCtx) (domain.Token, error)
 	Logout(context.Context, string, domain.DeviceCtx) error
+	LogoutAll(_ context.Context, userID string) error
+	ChangePassword(_ context.Context, refresh string, dctx domain.DeviceCtx, oldPass, newPass string) error
+	RequestPasswordReset(_ context.Context, email string) error
+	ConfirmPasswordReset(_ context.Context, token, newPass string, appID int32) error
+	VerifyEmail(_ context.Context, token string) error
+	ResendEmailVerification(_ context.Context, email string) error
+	BeginTotpEnrollment(_ context.Context, refresh string, dctx domain.DeviceCtx) (domain.TotpEnrollment, error)
+	ConfirmTotpEnrollment(_ context.Context, refresh string, dctx domain.DeviceCtx, code string) error
+	CompleteMfaLogin(_ context.Context, challenge, code string, dctx domain.DeviceCtx) (domain.Token, error)
+	RegisterDevice(_ context.Context, refresh string, dctx domain.DeviceCtx, platform, name string) (domain.Device, domain.Token, error)
+	BeginFederatedLogin(_ context.Context, provider string, dctx domain.DeviceCtx) (domain.FederatedLogin, error)
+	CompleteFederatedLogin(_ context.Context, provider, state, code string, dctx domain.DeviceCtx) (domain.LoginResult, error)
+	StartPasswordlessLogin(_ context.Context, email string, method domain.PasswordlessMethod, dctx domain.DeviceCtx) (domain.PasswordlessLogin, error)
+	CompletePasswordlessLogin(_ context.Context, challenge, code string, dctx domain.DeviceCtx) (domain.LoginResult, error)
+	ClearLoginLockout(_ context.Context, email, ip string) error
+	JWKS() domain.JWKS
+	Introspect(_ context.Context, token string) (domain.Introspection, error)
+	IsAccessTokenRevoked(_ context.Context, jti string) (bool, error)
+	CreateMachineClient(_ context.Context, name string, scopes []string) (domain.MachineClientSecret, error)
+	RotateMachineClientSecret(_ context.Context, clientID string) (domain.MachineClientSecret, error)
+	DisableMachineClient(_ context.Context, clientID string) error
+	CreateApp(context.Context, domain.App) (domain.App, error)
+	UpdateApp(context.Context, domain.App) (domain.App, error)
+	GetApp(_ context.Context, appID int32) (domain.App, error)
+	ListApps(context.Context) ([]domain.App, error)
+	DisableApp(_ context.Context, appID int32) error
+	EnableApp(_ context.Context, appID int32) error
 }
 
 const (
@@ -25,6 +53,38 @@ const (
 	ErrFailedLoginReq    = "failed to login user"
 	ErrFailedRefreshReq  = "failed to refrsh user token"
 	ErrFailedLogoutReq   = "failed to logout user"
+	ErrRefreshTokenReuse = "refresh token reuse detected"
+	ErrFailedLogoutAll   = "failed to logout user from all sessions"
+	ErrVerifyNotSent     = "user registered, email verification not sent"
+	ErrFailedChangePass  = "failed to change user password"
+	ErrFailedResetReq    = "failed to request password reset"
+	ErrFailedResetConf   = "failed to confirm password reset"
+	ErrEmailNotVerified  = "email is not verified"
+	ErrFailedVerifyEmail = "failed to verify email"
+	ErrFailedResendEmail = "failed to resend email verification"
+	ErrFailedBeginTotp   = "failed to begin totp enrollment"
+	ErrFailedConfirmTotp = "failed to confirm totp enrollment"
+	ErrFailedMfaLogin    = "failed to complete mfa login"
+	ErrTooManyAttempts   = "too many failed login attempts"
+	ErrFailedClearLock   = "failed to clear login lockout"
+	ErrWeakPassword      = "password does not satisfy policy"
+	ErrFailedIntrospect  = "failed to introspect token"
+	ErrFailedCheckRevoke = "failed to check access token revocation"
+	ErrFailedCreateMc    = "failed to create machine client"
+	ErrFailedRotateMc    = "failed to rotate machine client secret"
+	ErrFailedDisableMc   = "failed to disable machine client"
+	ErrAppAccessDenied   = "app is unknown, disabled or not allowed"
+	ErrFailedCreateApp   = "failed to create app"
+	ErrFailedUpdateApp   = "failed to update app"
+	ErrFailedGetApp      = "failed to get app"
+	ErrFailedListApps    = "failed to list apps"
+	ErrFailedDisableApp  = "failed to disable app"
+	ErrFailedEnableApp   = "failed to enable app"
+	ErrFailedRegDevice   = "failed to register device"
+	ErrFailedBeginFed    = "failed to begin federated login"
+	ErrFailedFedLogin    = "failed to complete federated login"
+	ErrFailedStartPwl    = "failed to start passwordless login"
+	ErrFailedPwlLogin    = "failed to complete passwordless login"
 )
 
 func (t authTransport) Register(ctx context.Context, req *sso.RegisterRequest) (*sso.RegisterResponse, error) {
@@ -33,8 +93,20 @@ func (t authTransport) Register(ctx context.Context, req *sso.RegisterRequest) (
 		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
 	}
 
-	user, err := t.s.Register(ctx, userFromRegisterReq(req))
+	user, err := t.s.Register(ctx, userFromRegisterReq(req), req.AppId)
+	// пользователь создан, письмо не ушло: регистрация успешна, письмо запрашивается через ResendEmailVerification
+	if errors.Is(err, domain.ErrNotDelivered) {
+		t.l.Errorw(ErrVerifyNotSent, "user_id", user.ID, "cause", err)
+		return &sso.RegisterResponse{
+			UserId: user.ID,
+		}, nil
+	}
 	if err != nil {
+		var policyErr *domain.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			t.l.Errorw(ErrFailedRegisterReq, err)
+			return nil, passwordPolicyStatus(policyErr)
+		}
 		if errors.Is(err, domain.ErrDuplicate) {
 			t.l.Errorw(ErrFailedRegisterReq, err)
 			return nil, status.Error(codes.AlreadyExists, ErrFailedRegisterReq)
@@ -54,8 +126,21 @@ func (t authTransport) Login(ctx context.Context, req *sso.LoginRequest) (*sso.L
 		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
 	}
 
-	token, err := t.s.Login(ctx, userFromLoginReq(req), deviceCtxFromReq(req.Ctx))
+	res, err := t.s.Login(ctx, userFromLoginReq(req), deviceCtxFromReq(req.Ctx))
 	if err != nil {
+		var lockErr *domain.LockoutError
+		if errors.As(err, &lockErr) {
+			t.l.Errorw(ErrTooManyAttempts, err)
+			return nil, lockoutStatus(lockErr.RetryAfter)
+		}
+		if errors.Is(err, domain.ErrEmailNotVerified) {
+			t.l.Infow(ErrEmailNotVerified, "app_id", req.Ctx.AppId)
+			return nil, status.Error(codes.FailedPrecondition, ErrEmailNotVerified)
+		}
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			t.l.Infow(ErrAppAccessDenied, "app_id", req.Ctx.AppId, "cause", err)
+			return nil, status.Error(codes.PermissionDenied, ErrAppAccessDenied)
+		}
 		if errors.Is(err, domain.ErrValidation) {
 			t.l.Errorw(ErrFailedLoginReq, err)
 			return nil, status.Error(codes.Unauthenticated, ErrFailedLoginReq)
@@ -64,8 +149,15 @@ func (t authTransport) Login(ctx context.Context, req *sso.LoginRequest) (*sso.L
 		return nil, status.Error(codes.Internal, ErrFailedLoginReq)
 	}
 
+	// включена MFA: токены выдаст CompleteMfaLogin
+	if res.MfaRequired() {
+		return &sso.LoginResponse{
+			MfaChallenge: res.MfaChallenge,
+		}, nil
+	}
+
 	return &sso.LoginResponse{
-		Tokens: tokenResponse(token),
+		Tokens: tokenResponse(res.Token),
 	}, nil
 }
 
@@ -77,7 +169,15 @@ func (t authTransport) Refresh(ctx context.Context, req *sso.RefreshRequest) (*s
 
 	token, err := t.s.Refresh(ctx, req.Refresh, deviceCtxFromReq(req.Ctx))
 	if err != nil {
-		if errors.Is(err, domain.ErrValidation) {
+		if errors.Is(err, domain.ErrTokenReuse) {
+			t.l.Warnw(ErrRefreshTokenReuse, "app_id", req.Ctx.AppId, "device_id", req.Ctx.DeviceId, "cause", err)
+			return nil, status.Error(codes.Unauthenticated, ErrFailedRefreshReq)
+		}
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			t.l.Infow(ErrAppAccessDenied, "app_id", req.Ctx.AppId, "cause", err)
+			return nil, status.Error(codes.PermissionDenied, ErrAppAccessDenied)
+		}
+		if errors.Is(err, domain.ErrValidation) || errors.Is(err, domain.ErrNotFound) {
 			t.l.Errorw(ErrFailedRefreshReq, err)
 			return nil, status.Error(codes.Unauthenticated, ErrFailedRefreshReq)
 		}
@@ -97,6 +197,10 @@ func (t authTransport) Logout(ctx context.Context, req *sso.LogoutRequest) (*emp
 	}
 
 	if err := t.s.Logout(ctx, req.Refresh, deviceCtxFromReq(req.Ctx)); err != nil {
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			t.l.Infow(ErrAppAccessDenied, "app_id", req.Ctx.AppId, "cause", err)
+			return nil, status.Error(codes.PermissionDenied, ErrAppAccessDenied)
+		}
 		if errors.Is(err, domain.ErrValidation) {
 			t.l.Errorw(ErrFailedLogoutReq, err)
 			return nil, status.Error(codes.Unauthenticated, ErrFailedLogoutReq)
@@ -108,9 +212,596 @@ func (t authTransport) Logout(ctx context.Context, req *sso.LogoutRequest) (*emp
 	return &emptypb.Empty{}, nil
 }
 
+// LogoutAll — user_id уже сверен с владельцем access-токена (grpctransportauthz.OwnerMethods)
+func (t authTransport) LogoutAll(ctx context.Context, req *sso.LogoutAllRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	if err := t.s.LogoutAll(ctx, req.UserId); err != nil {
+		t.l.Errorw(ErrFailedLogoutAll, err)
+		return nil, status.Error(codes.Internal, ErrFailedLogoutAll)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t authTransport) ChangePassword(ctx context.Context, req *sso.ChangePasswordRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	err := t.s.ChangePassword(ctx, req.Refresh, deviceCtxFromReq(req.Ctx), req.OldPassword, req.NewPassword)
+	if err != nil {
+		var lockErr *domain.LockoutError
+		if errors.As(err, &lockErr) {
+			t.l.Errorw(ErrTooManyAttempts, err)
+			return nil, lockoutStatus(lockErr.RetryAfter)
+		}
+		var policyErr *domain.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			t.l.Errorw(ErrFailedChangePass, err)
+			return nil, passwordPolicyStatus(policyErr)
+		}
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedChangePass, err)
+			return nil, status.Error(codes.Unauthenticated, ErrFailedChangePass)
+		}
+		t.l.Errorw(ErrFailedChangePass, err)
+		return nil, status.Error(codes.Internal, ErrFailedChangePass)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// RequestPasswordReset отвечает одинаково для существующего и несуществующего email
+func (t authTransport) RequestPasswordReset(ctx context.Context, req *sso.RequestPasswordResetRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	if err := t.s.RequestPasswordReset(ctx, req.Email); err != nil {
+		t.l.Errorw(ErrFailedResetReq, err)
+		return nil, status.Error(codes.Internal, ErrFailedResetReq)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t authTransport) ConfirmPasswordReset(ctx context.Context, req *sso.ConfirmPasswordResetRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	if err := t.s.ConfirmPasswordReset(ctx, req.Token, req.NewPassword, req.AppId); err != nil {
+		var policyErr *domain.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			t.l.Errorw(ErrFailedResetConf, err)
+			return nil, passwordPolicyStatus(policyErr)
+		}
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedResetConf, err)
+			return nil, status.Error(codes.Unauthenticated, ErrFailedResetConf)
+		}
+		t.l.Errorw(ErrFailedResetConf, err)
+		return nil, status.Error(codes.Internal, ErrFailedResetConf)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t authTransport) VerifyEmail(ctx context.Context, req *sso.VerifyEmailRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	if err := t.s.VerifyEmail(ctx, req.Token); err != nil {
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedVerifyEmail, err)
+			return nil, status.Error(codes.Unauthenticated, ErrFailedVerifyEmail)
+		}
+		t.l.Errorw(ErrFailedVerifyEmail, err)
+		return nil, status.Error(codes.Internal, ErrFailedVerifyEmail)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ResendEmailVerification отвечает одинаково для любого email
+func (t authTransport) ResendEmailVerification(ctx context.Context, req *sso.ResendEmailVerificationRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	if err := t.s.ResendEmailVerification(ctx, req.Email); err != nil {
+		t.l.Errorw(ErrFailedResendEmail, err)
+		return nil, status.Error(codes.Internal, ErrFailedResendEmail)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t authTransport) BeginTotpEnrollment(ctx context.Context, req *sso.BeginTotpEnrollmentRequest) (*sso.BeginTotpEnrollmentResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	enr, err := t.s.BeginTotpEnrollment(ctx, req.Refresh, deviceCtxFromReq(req.Ctx))
+	if err != nil {
+		if errors.Is(err, domain.ErrDuplicate) {
+			t.l.Errorw(ErrFailedBeginTotp, err)
+			return nil, status.Error(codes.AlreadyExists, ErrFailedBeginTotp)
+		}
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedBeginTotp, err)
+			return nil, status.Error(codes.Unauthenticated, ErrFailedBeginTotp)
+		}
+		t.l.Errorw(ErrFailedBeginTotp, err)
+		return nil, status.Error(codes.Internal, ErrFailedBeginTotp)
+	}
+
+	return &sso.BeginTotpEnrollmentResponse{
+		Secret:     enr.Secret,
+		OtpauthUri: enr.URI,
+	}, nil
+}
+
+func (t authTransport) ConfirmTotpEnrollment(ctx context.Context, req *sso.ConfirmTotpEnrollmentRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	if err := t.s.ConfirmTotpEnrollment(ctx, req.Refresh, deviceCtxFromReq(req.Ctx), req.Code); err != nil {
+		if errors.Is(err, domain.ErrDuplicate) {
+			t.l.Errorw(ErrFailedConfirmTotp, err)
+			return nil, status.Error(codes.AlreadyExists, ErrFailedConfirmTotp)
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			t.l.Errorw(ErrFailedConfirmTotp, err)
+			return nil, status.Error(codes.FailedPrecondition, ErrFailedConfirmTotp)
+		}
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedConfirmTotp, err)
+			return nil, status.Error(codes.Unauthenticated, ErrFailedConfirmTotp)
+		}
+		t.l.Errorw(ErrFailedConfirmTotp, err)
+		return nil, status.Error(codes.Internal, ErrFailedConfirmTotp)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t authTransport) CompleteMfaLogin(ctx context.Context, req *sso.CompleteMfaLoginRequest) (*sso.CompleteMfaLoginResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	token, err := t.s.CompleteMfaLogin(ctx, req.Challenge, req.Code, deviceCtxFromReq(req.Ctx))
+	if err != nil {
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			t.l.Infow(ErrAppAccessDenied, "app_id", req.Ctx.AppId, "cause", err)
+			return nil, status.Error(codes.PermissionDenied, ErrAppAccessDenied)
+		}
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedMfaLogin, err)
+			return nil, status.Error(codes.Unauthenticated, ErrFailedMfaLogin)
+		}
+		t.l.Errorw(ErrFailedMfaLogin, err)
+		return nil, status.Error(codes.Internal, ErrFailedMfaLogin)
+	}
+
+	return &sso.CompleteMfaLoginResponse{
+		Tokens: tokenResponse(token),
+	}, nil
+}
+
+// RegisterDevice — выдаёт device_id и переносит на него сессию предъявленного refresh
+func (t authTransport) RegisterDevice(ctx context.Context, req *sso.RegisterDeviceRequest) (*sso.RegisterDeviceResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	device, token, err := t.s.RegisterDevice(ctx, req.Refresh, deviceCtxFromReq(req.Ctx), req.Platform, req.Name)
+	if err != nil {
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			t.l.Infow(ErrAppAccessDenied, "app_id", req.Ctx.AppId, "cause", err)
+			return nil, status.Error(codes.PermissionDenied, ErrAppAccessDenied)
+		}
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedRegDevice, err)
+			return nil, status.Error(codes.Unauthenticated, ErrFailedRegDevice)
+		}
+		t.l.Errorw(ErrFailedRegDevice, err)
+		return nil, status.Error(codes.Internal, ErrFailedRegDevice)
+	}
+
+	return &sso.RegisterDeviceResponse{
+		DeviceId: device.ID,
+		Tokens:   tokenResponse(token),
+	}, nil
+}
+
+// BeginFederatedLogin — адрес входа у внешнего IdP; клиент открывает его в браузере
+func (t authTransport) BeginFederatedLogin(ctx context.Context, req *sso.BeginFederatedLoginRequest) (*sso.BeginFederatedLoginResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	login, err := t.s.BeginFederatedLogin(ctx, req.Provider, deviceCtxFromReq(req.Ctx))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			t.l.Infow(ErrFailedBeginFed, "provider", req.Provider, "cause", err)
+			return nil, status.Error(codes.NotFound, ErrFailedBeginFed)
+		}
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			t.l.Infow(ErrAppAccessDenied, "app_id", req.Ctx.AppId, "cause", err)
+			return nil, status.Error(codes.PermissionDenied, ErrAppAccessDenied)
+		}
+		t.l.Errorw(ErrFailedBeginFed, err)
+		return nil, status.Error(codes.Internal, ErrFailedBeginFed)
+	}
+
+	return &sso.BeginFederatedLoginResponse{
+		AuthUrl: login.AuthURL,
+		State:   login.State,
+	}, nil
+}
+
+// CompleteFederatedLogin — code и state из redirect IdP; ответ как у Login
+func (t authTransport) CompleteFederatedLogin(ctx context.Context, req *sso.CompleteFederatedLoginRequest) (*sso.CompleteFederatedLoginResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	res, err := t.s.CompleteFederatedLogin(ctx, req.Provider, req.State, req.Code, deviceCtxFromReq(req.Ctx))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			t.l.Infow(ErrFailedFedLogin, "provider", req.Provider, "cause", err)
+			return nil, status.Error(codes.NotFound, ErrFailedFedLogin)
+		}
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			t.l.Infow(ErrAppAccessDenied, "app_id", req.Ctx.AppId, "cause", err)
+			return nil, status.Error(codes.PermissionDenied, ErrAppAccessDenied)
+		}
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedFedLogin, err)
+			return nil, status.Error(codes.Unauthenticated, ErrFailedFedLogin)
+		}
+		t.l.Errorw(ErrFailedFedLogin, err)
+		return nil, status.Error(codes.Internal, ErrFailedFedLogin)
+	}
+
+	if res.MfaRequired() {
+		return &sso.CompleteFederatedLoginResponse{
+			MfaChallenge: res.MfaChallenge,
+		}, nil
+	}
+
+	return &sso.CompleteFederatedLoginResponse{
+		Tokens: tokenResponse(res.Token),
+	}, nil
+}
+
+// StartPasswordlessLogin — ссылка или код на email; для кода в ответе challenge, который нужен CompletePasswordlessLogin.
+// Неизвестный email не раскрывается: ответ тот же, письма нет
+func (t authTransport) StartPasswordlessLogin(ctx context.Context, req *sso.StartPasswordlessLoginRequest) (*sso.StartPasswordlessLoginResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	login, err := t.s.StartPasswordlessLogin(ctx, req.Email, domain.PasswordlessMethod(req.Method), deviceCtxFromReq(req.Ctx))
+	if err != nil {
+		var lockErr *domain.LockoutError
+		if errors.As(err, &lockErr) {
+			t.l.Errorw(ErrTooManyAttempts, err)
+			return nil, lockoutStatus(lockErr.RetryAfter)
+		}
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			t.l.Infow(ErrAppAccessDenied, "app_id", req.Ctx.AppId, "cause", err)
+			return nil, status.Error(codes.PermissionDenied, ErrAppAccessDenied)
+		}
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedStartPwl, err)
+			return nil, status.Error(codes.InvalidArgument, ErrFailedStartPwl)
+		}
+		t.l.Errorw(ErrFailedStartPwl, err)
+		return nil, status.Error(codes.Internal, ErrFailedStartPwl)
+	}
+
+	return &sso.StartPasswordlessLoginResponse{
+		Challenge: login.Challenge,
+		ExpiresAt: timestamppb.New(login.ExpiresAt),
+	}, nil
+}
+
+// CompletePasswordlessLogin — токен ссылки (code пустой) или challenge и код из письма; ответ как у Login
+func (t authTransport) CompletePasswordlessLogin(ctx context.Context, req *sso.CompletePasswordlessLoginRequest) (*sso.CompletePasswordlessLoginResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	res, err := t.s.CompletePasswordlessLogin(ctx, req.Challenge, req.Code, deviceCtxFromReq(req.Ctx))
+	if err != nil {
+		var lockErr *domain.LockoutError
+		if errors.As(err, &lockErr) {
+			t.l.Errorw(ErrTooManyAttempts, err)
+			return nil, lockoutStatus(lockErr.RetryAfter)
+		}
+		if errors.Is(err, domain.ErrAppAccessDenied) {
+			t.l.Infow(ErrAppAccessDenied, "app_id", req.Ctx.AppId, "cause", err)
+			return nil, status.Error(codes.PermissionDenied, ErrAppAccessDenied)
+		}
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedPwlLogin, err)
+			return nil, status.Error(codes.Unauthenticated, ErrFailedPwlLogin)
+		}
+		t.l.Errorw(ErrFailedPwlLogin, err)
+		return nil, status.Error(codes.Internal, ErrFailedPwlLogin)
+	}
+
+	if res.MfaRequired() {
+		return &sso.CompletePasswordlessLoginResponse{
+			MfaChallenge: res.MfaChallenge,
+		}, nil
+	}
+
+	return &sso.CompletePasswordlessLoginResponse{
+		Tokens: tokenResponse(res.Token),
+	}, nil
+}
+
+// ClearLoginLockout — админская операция (роль admin проверяет grpctransportauthz)
+func (t authTransport) ClearLoginLockout(ctx context.Context, req *sso.ClearLoginLockoutRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	if err := t.s.ClearLoginLockout(ctx, req.Email, req.Ip); err != nil {
+		t.l.Errorw(ErrFailedClearLock, err)
+		return nil, status.Error(codes.Internal, ErrFailedClearLock)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
 func tokenResponse(token domain.Token) *sso.TokenPair {
 	return &sso.TokenPair{
 		Refresh: token.Refresh,
 		Access:  token.Access,
 	}
 }
+
+// GetJwks — открытые ключи подписи; то же, что GET /.well-known/jwks.json
+func (t authTransport) GetJwks(context.Context, *emptypb.Empty) (*sso.GetJwksResponse, error) {
+	return jwksToResponse(t.s.JWKS()), nil
+}
+
+// Introspect — для сервисов, которые не проверяют JWT сами; доступ ограничивается снаружи
+func (t authTransport) Introspect(ctx context.Context, req *sso.IntrospectRequest) (*sso.IntrospectResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	i, err := t.s.Introspect(ctx, req.Token)
+	if err != nil {
+		t.l.Errorw(ErrFailedIntrospect, err)
+		return nil, status.Error(codes.Internal, ErrFailedIntrospect)
+	}
+
+	return introspectionToResponse(i), nil
+}
+
+// IsAccessTokenRevoked — дешёвая проверка по jti для тех, кто проверяет подпись сам
+func (t authTransport) IsAccessTokenRevoked(ctx context.Context, req *sso.IsAccessTokenRevokedRequest) (*sso.IsAccessTokenRevokedResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	revoked, err := t.s.IsAccessTokenRevoked(ctx, req.Jti)
+	if err != nil {
+		t.l.Errorw(ErrFailedCheckRevoke, err)
+		return nil, status.Error(codes.Internal, ErrFailedCheckRevoke)
+	}
+
+	return &sso.IsAccessTokenRevokedResponse{Revoked: revoked}, nil
+}
+
+// CreateMachineClient — админская операция; client_secret в ответе единственный раз
+func (t authTransport) CreateMachineClient(ctx context.Context, req *sso.CreateMachineClientRequest) (*sso.CreateMachineClientResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	cs, err := t.s.CreateMachineClient(ctx, req.Name, req.Scopes)
+	if err != nil {
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedCreateMc, err)
+			return nil, status.Error(codes.InvalidArgument, ErrFailedCreateMc)
+		}
+		t.l.Errorw(ErrFailedCreateMc, err)
+		return nil, status.Error(codes.Internal, ErrFailedCreateMc)
+	}
+
+	return &sso.CreateMachineClientResponse{
+		ClientId:     cs.ClientID,
+		ClientSecret: cs.ClientSecret,
+	}, nil
+}
+
+// RotateMachineClientSecret — админская операция; прежний секрет перестаёт работать сразу
+func (t authTransport) RotateMachineClientSecret(ctx context.Context, req *sso.RotateMachineClientSecretRequest) (*sso.RotateMachineClientSecretResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	cs, err := t.s.RotateMachineClientSecret(ctx, req.ClientId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			t.l.Errorw(ErrFailedRotateMc, err)
+			return nil, status.Error(codes.NotFound, ErrFailedRotateMc)
+		}
+		t.l.Errorw(ErrFailedRotateMc, err)
+		return nil, status.Error(codes.Internal, ErrFailedRotateMc)
+	}
+
+	return &sso.RotateMachineClientSecretResponse{
+		ClientId:     cs.ClientID,
+		ClientSecret: cs.ClientSecret,
+	}, nil
+}
+
+// DisableMachineClient — админская операция; повторный вызов не ошибка
+func (t authTransport) DisableMachineClient(ctx context.Context, req *sso.DisableMachineClientRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	if err := t.s.DisableMachineClient(ctx, req.ClientId); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			t.l.Errorw(ErrFailedDisableMc, err)
+			return nil, status.Error(codes.NotFound, ErrFailedDisableMc)
+		}
+		t.l.Errorw(ErrFailedDisableMc, err)
+		return nil, status.Error(codes.Internal, ErrFailedDisableMc)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// CreateApp — админская операция (роль admin проверяет grpctransportauthz); app_id задаёт администратор
+func (t authTransport) CreateApp(ctx context.Context, req *sso.CreateAppRequest) (*sso.CreateAppResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	app, err := t.s.CreateApp(ctx, appFromReq(req.App))
+	if err != nil {
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedCreateApp, err)
+			return nil, status.Error(codes.InvalidArgument, ErrFailedCreateApp)
+		}
+		if errors.Is(err, domain.ErrDuplicate) {
+			t.l.Errorw(ErrFailedCreateApp, err)
+			return nil, status.Error(codes.AlreadyExists, ErrFailedCreateApp)
+		}
+		t.l.Errorw(ErrFailedCreateApp, err)
+		return nil, status.Error(codes.Internal, ErrFailedCreateApp)
+	}
+
+	return &sso.CreateAppResponse{App: appToResponse(app)}, nil
+}
+
+// UpdateApp — админская операция; заменяет настройки целиком, status в запросе не учитывается
+func (t authTransport) UpdateApp(ctx context.Context, req *sso.UpdateAppRequest) (*sso.UpdateAppResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	app, err := t.s.UpdateApp(ctx, appFromReq(req.App))
+	if err != nil {
+		if errors.Is(err, domain.ErrValidation) {
+			t.l.Errorw(ErrFailedUpdateApp, err)
+			return nil, status.Error(codes.InvalidArgument, ErrFailedUpdateApp)
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			t.l.Errorw(ErrFailedUpdateApp, err)
+			return nil, status.Error(codes.NotFound, ErrFailedUpdateApp)
+		}
+		t.l.Errorw(ErrFailedUpdateApp, err)
+		return nil, status.Error(codes.Internal, ErrFailedUpdateApp)
+	}
+
+	return &sso.UpdateAppResponse{App: appToResponse(app)}, nil
+}
+
+func (t authTransport) GetApp(ctx context.Context, req *sso.GetAppRequest) (*sso.GetAppResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	app, err := t.s.GetApp(ctx, req.AppId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			t.l.Errorw(ErrFailedGetApp, err)
+			return nil, status.Error(codes.NotFound, ErrFailedGetApp)
+		}
+		t.l.Errorw(ErrFailedGetApp, err)
+		return nil, status.Error(codes.Internal, ErrFailedGetApp)
+	}
+
+	return &sso.GetAppResponse{App: appToResponse(app)}, nil
+}
+
+func (t authTransport) ListApps(ctx context.Context, _ *emptypb.Empty) (*sso.ListAppsResponse, error) {
+	apps, err := t.s.ListApps(ctx)
+	if err != nil {
+		t.l.Errorw(ErrFailedListApps, err)
+		return nil, status.Error(codes.Internal, ErrFailedListApps)
+	}
+
+	resp := &sso.ListAppsResponse{Apps: make([]*sso.App, 0, len(apps))}
+	for _, app := range apps {
+		resp.Apps = append(resp.Apps, appToResponse(app))
+	}
+	return resp, nil
+}
+
+// DisableApp — админская операция; повторный вызов не ошибка
+func (t authTransport) DisableApp(ctx context.Context, req *sso.DisableAppRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	if err := t.s.DisableApp(ctx, req.AppId); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			t.l.Errorw(ErrFailedDisableApp, err)
+			return nil, status.Error(codes.NotFound, ErrFailedDisableApp)
+		}
+		t.l.Errorw(ErrFailedDisableApp, err)
+		return nil, status.Error(codes.Internal, ErrFailedDisableApp)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t authTransport) EnableApp(ctx context.Context, req *sso.EnableAppRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	if err := t.s.EnableApp(ctx, req.AppId); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			t.l.Errorw(ErrFailedEnableApp, err)
+			return nil, status.Error(codes.NotFound, ErrFailedEnableApp)
+		}
+		t.l.Errorw(ErrFailedEnableApp, err)
+		return nil, status.Error(codes.Internal, ErrFailedEnableApp)
+	}
+
+	return &emptypb.Empty{}, nil
+}
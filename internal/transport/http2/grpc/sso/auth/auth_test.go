@@ -3,7 +3,9 @@ package grpctransportauth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/eragon-mdi/protos/gen/go/sso/v1"
 	"github.com/eragon-mdi/sso/internal/domain"
@@ -11,8 +13,11 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 func TestAuthTransport_Register(t *testing.T) {
@@ -30,7 +35,7 @@ func TestAuthTransport_Register(t *testing.T) {
 
 	t.Run("service duplicate error", func(t *testing.T) {
 		s := &mocks.AuthService{}
-		s.On("Register", mock.Anything, mock.Anything).Return(domain.User{}, domain.ErrDuplicate)
+		s.On("Register", mock.Anything, mock.Anything, int32(0)).Return(domain.User{}, domain.ErrDuplicate)
 
 		srv := New(s, zap.NewNop().Sugar())
 		_, err := srv.Register(ctx, &sso.RegisterRequest{User: user})
@@ -43,7 +48,7 @@ func TestAuthTransport_Register(t *testing.T) {
 
 	t.Run("service internal error", func(t *testing.T) {
 		s := &mocks.AuthService{}
-		s.On("Register", mock.Anything, mock.Anything).Return(domain.User{}, errors.New("boom"))
+		s.On("Register", mock.Anything, mock.Anything, int32(0)).Return(domain.User{}, errors.New("boom"))
 
 		srv := New(s, zap.NewNop().Sugar())
 		_, err := srv.Register(ctx, &sso.RegisterRequest{User: user})
@@ -52,9 +57,20 @@ func TestAuthTransport_Register(t *testing.T) {
 		require.Equal(t, codes.Internal, st.Code())
 	})
 
+	t.Run("verification not delivered is still success", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("Register", mock.Anything, mock.Anything, int32(0)).
+			Return(domain.User{ID: "123"}, fmt.Errorf("smtp down: %w", domain.ErrNotDelivered))
+
+		srv := New(s, zap.NewNop().Sugar())
+		resp, err := srv.Register(ctx, &sso.RegisterRequest{User: user})
+		require.NoError(t, err)
+		require.Equal(t, "123", resp.UserId)
+	})
+
 	t.Run("success", func(t *testing.T) {
 		s := &mocks.AuthService{}
-		s.On("Register", mock.Anything, mock.Anything).Return(domain.User{ID: "123"}, nil)
+		s.On("Register", mock.Anything, mock.Anything, int32(0)).Return(domain.User{ID: "123"}, nil)
 
 		srv := New(s, zap.NewNop().Sugar())
 		resp, err := srv.Register(ctx, &sso.RegisterRequest{
@@ -70,6 +86,56 @@ func TestAuthTransport_Register(t *testing.T) {
 	})
 }
 
+func TestAuthTransport_PasswordPolicy(t *testing.T) {
+	ctx := context.Background()
+	policyErr := fmt.Errorf("wrapped: %w", &domain.PasswordPolicyError{Violations: []domain.PasswordViolation{
+		{Rule: domain.RuleMinLength, Message: "must be at least 8 characters"},
+		{Rule: domain.RuleCommon, Message: "is too common"},
+	}})
+
+	requireViolations := func(t *testing.T, err error) {
+		t.Helper()
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.InvalidArgument, st.Code())
+		require.Len(t, st.Details(), 1)
+		br, ok := st.Details()[0].(*errdetails.BadRequest)
+		require.True(t, ok)
+		require.Len(t, br.FieldViolations, 2)
+		require.Equal(t, "password", br.FieldViolations[0].Field)
+		require.Equal(t, "min_length: must be at least 8 characters", br.FieldViolations[0].Description)
+		require.Equal(t, "common: is too common", br.FieldViolations[1].Description)
+	}
+
+	t.Run("register", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("Register", mock.Anything, mock.Anything, int32(4)).Return(domain.User{}, policyErr)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.Register(ctx, &sso.RegisterRequest{User: &sso.User{Email: "a@b.c", Password: "123456"}, AppId: 4})
+		requireViolations(t, err)
+	})
+
+	t.Run("change password", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ChangePassword", mock.Anything, "r", mock.Anything, "old", "123456").Return(policyErr)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.ChangePassword(ctx, &sso.ChangePasswordRequest{
+			Refresh: "r", Ctx: &sso.DeviceContext{AppId: 1, DeviceId: 1}, OldPassword: "old", NewPassword: "123456",
+		})
+		requireViolations(t, err)
+	})
+
+	t.Run("confirm reset", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ConfirmPasswordReset", mock.Anything, "tok", "123456", int32(0)).Return(policyErr)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.ConfirmPasswordReset(ctx, &sso.ConfirmPasswordResetRequest{Token: "tok", NewPassword: "123456"})
+		requireViolations(t, err)
+	})
+}
+
 func TestAuthTransport_Login(t *testing.T) {
 	ctx := context.Background()
 	user := &sso.User{Email: "a@b.c", Password: "123456"}
@@ -86,7 +152,7 @@ func TestAuthTransport_Login(t *testing.T) {
 
 	t.Run("service validation error", func(t *testing.T) {
 		s := &mocks.AuthService{}
-		s.On("Login", mock.Anything, mock.Anything, mock.Anything).Return(domain.Token{}, domain.ErrValidation)
+		s.On("Login", mock.Anything, mock.Anything, mock.Anything).Return(domain.LoginResult{}, domain.ErrValidation)
 
 		srv := New(s, zap.NewNop().Sugar())
 		_, err := srv.Login(ctx, &sso.LoginRequest{User: user, Ctx: device})
@@ -96,7 +162,7 @@ func TestAuthTransport_Login(t *testing.T) {
 
 	t.Run("service internal error", func(t *testing.T) {
 		s := &mocks.AuthService{}
-		s.On("Login", mock.Anything, mock.Anything, mock.Anything).Return(domain.Token{}, errors.New("boom"))
+		s.On("Login", mock.Anything, mock.Anything, mock.Anything).Return(domain.LoginResult{}, errors.New("boom"))
 
 		srv := New(s, zap.NewNop().Sugar())
 		_, err := srv.Login(ctx, &sso.LoginRequest{User: user, Ctx: device})
@@ -106,7 +172,7 @@ func TestAuthTransport_Login(t *testing.T) {
 
 	t.Run("success", func(t *testing.T) {
 		s := &mocks.AuthService{}
-		s.On("Login", mock.Anything, mock.Anything, mock.Anything).Return(token, nil)
+		s.On("Login", mock.Anything, mock.Anything, mock.Anything).Return(domain.LoginResult{Token: token}, nil)
 
 		srv := New(s, zap.NewNop().Sugar())
 		resp, err := srv.Login(ctx, &sso.LoginRequest{User: user, Ctx: device})
@@ -138,6 +204,26 @@ func TestAuthTransport_Refresh(t *testing.T) {
 		require.Equal(t, codes.Unauthenticated, st.Code())
 	})
 
+	t.Run("service token reuse error", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("Refresh", mock.Anything, "r", mock.Anything).Return(domain.Token{}, domain.ErrTokenReuse)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.Refresh(ctx, &sso.RefreshRequest{Refresh: "r", Ctx: device})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Unauthenticated, st.Code())
+	})
+
+	t.Run("service not found error", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("Refresh", mock.Anything, "r", mock.Anything).Return(domain.Token{}, domain.ErrNotFound)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.Refresh(ctx, &sso.RefreshRequest{Refresh: "r", Ctx: device})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Unauthenticated, st.Code())
+	})
+
 	t.Run("service internal error", func(t *testing.T) {
 		s := &mocks.AuthService{}
 		s.On("Refresh", mock.Anything, "r", mock.Anything).Return(domain.Token{}, errors.New("boom"))
@@ -202,6 +288,303 @@ func TestAuthTransport_Logout(t *testing.T) {
 	})
 }
 
+func TestAuthTransport_LogoutAll(t *testing.T) {
+	ctx := context.Background()
+	userID := "0b8e6a3c-1f1e-4d51-9c4c-2a6a5b1f0e11"
+
+	t.Run("service internal error", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("LogoutAll", mock.Anything, userID).Return(errors.New("boom"))
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.LogoutAll(ctx, &sso.LogoutAllRequest{UserId: userID})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Internal, st.Code())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("LogoutAll", mock.Anything, userID).Return(nil)
+
+		srv := New(s, zap.NewNop().Sugar())
+		resp, err := srv.LogoutAll(ctx, &sso.LogoutAllRequest{UserId: userID})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		s.AssertExpectations(t)
+	})
+}
+
+func TestAuthTransport_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+	device := &sso.DeviceContext{AppId: 1, DeviceId: 2}
+	req := &sso.ChangePasswordRequest{Refresh: "r", Ctx: device, OldPassword: "old-pass", NewPassword: "new-pass"}
+
+	t.Run("invalid request", func(t *testing.T) {
+		srv := New(&mocks.AuthService{}, zap.NewNop().Sugar())
+		_, err := srv.ChangePassword(ctx, &sso.ChangePasswordRequest{Refresh: "r"})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.InvalidArgument, st.Code())
+	})
+
+	t.Run("wrong old password", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ChangePassword", mock.Anything, "r", mock.Anything, "old-pass", "new-pass").Return(domain.ErrValidation)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.ChangePassword(ctx, req)
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Unauthenticated, st.Code())
+	})
+
+	t.Run("too many wrong old passwords", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ChangePassword", mock.Anything, "r", mock.Anything, "old-pass", "new-pass").
+			Return(fmt.Errorf("wrong password: %w", &domain.LockoutError{RetryAfter: time.Minute}))
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.ChangePassword(ctx, req)
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.ResourceExhausted, st.Code())
+	})
+
+	t.Run("service internal error", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ChangePassword", mock.Anything, "r", mock.Anything, "old-pass", "new-pass").Return(errors.New("boom"))
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.ChangePassword(ctx, req)
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Internal, st.Code())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ChangePassword", mock.Anything, "r", mock.Anything, "old-pass", "new-pass").Return(nil)
+
+		srv := New(s, zap.NewNop().Sugar())
+		resp, err := srv.ChangePassword(ctx, req)
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		s.AssertExpectations(t)
+	})
+}
+
+func TestAuthTransport_PasswordReset(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("request success", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("RequestPasswordReset", mock.Anything, "a@b.c").Return(nil)
+
+		srv := New(s, zap.NewNop().Sugar())
+		resp, err := srv.RequestPasswordReset(ctx, &sso.RequestPasswordResetRequest{Email: "a@b.c"})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		s.AssertExpectations(t)
+	})
+
+	t.Run("request internal error", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("RequestPasswordReset", mock.Anything, "a@b.c").Return(errors.New("boom"))
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.RequestPasswordReset(ctx, &sso.RequestPasswordResetRequest{Email: "a@b.c"})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Internal, st.Code())
+	})
+
+	t.Run("confirm invalid token", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ConfirmPasswordReset", mock.Anything, "tok", "new-pass", int32(0)).Return(domain.ErrValidation)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.ConfirmPasswordReset(ctx, &sso.ConfirmPasswordResetRequest{Token: "tok", NewPassword: "new-pass"})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Unauthenticated, st.Code())
+	})
+
+	t.Run("confirm success", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ConfirmPasswordReset", mock.Anything, "tok", "new-pass", int32(0)).Return(nil)
+
+		srv := New(s, zap.NewNop().Sugar())
+		resp, err := srv.ConfirmPasswordReset(ctx, &sso.ConfirmPasswordResetRequest{Token: "tok", NewPassword: "new-pass"})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		s.AssertExpectations(t)
+	})
+}
+
+func TestAuthTransport_EmailVerification(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("login unverified -> failed precondition", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("Login", mock.Anything, mock.Anything, mock.Anything).Return(domain.LoginResult{}, domain.ErrEmailNotVerified)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.Login(ctx, &sso.LoginRequest{
+			User: &sso.User{Email: "a@b.c", Password: "123456"},
+			Ctx:  &sso.DeviceContext{AppId: 1, DeviceId: 1},
+		})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.FailedPrecondition, st.Code())
+	})
+
+	t.Run("verify invalid token", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("VerifyEmail", mock.Anything, "tok").Return(domain.ErrValidation)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.VerifyEmail(ctx, &sso.VerifyEmailRequest{Token: "tok"})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Unauthenticated, st.Code())
+	})
+
+	t.Run("verify success", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("VerifyEmail", mock.Anything, "tok").Return(nil)
+
+		srv := New(s, zap.NewNop().Sugar())
+		resp, err := srv.VerifyEmail(ctx, &sso.VerifyEmailRequest{Token: "tok"})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		s.AssertExpectations(t)
+	})
+
+	t.Run("resend internal error", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ResendEmailVerification", mock.Anything, "a@b.c").Return(errors.New("boom"))
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.ResendEmailVerification(ctx, &sso.ResendEmailVerificationRequest{Email: "a@b.c"})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Internal, st.Code())
+	})
+}
+
+func TestAuthTransport_Mfa(t *testing.T) {
+	ctx := context.Background()
+	device := &sso.DeviceContext{AppId: 1, DeviceId: 2}
+
+	t.Run("login returns challenge without tokens", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("Login", mock.Anything, mock.Anything, mock.Anything).Return(domain.LoginResult{MfaChallenge: "ch"}, nil)
+
+		srv := New(s, zap.NewNop().Sugar())
+		resp, err := srv.Login(ctx, &sso.LoginRequest{User: &sso.User{Email: "a@b.c", Password: "123456"}, Ctx: device})
+		require.NoError(t, err)
+		require.Equal(t, "ch", resp.MfaChallenge)
+		require.Nil(t, resp.Tokens)
+	})
+
+	t.Run("begin already enabled", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("BeginTotpEnrollment", mock.Anything, "r", mock.Anything).Return(domain.TotpEnrollment{}, domain.ErrDuplicate)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.BeginTotpEnrollment(ctx, &sso.BeginTotpEnrollmentRequest{Refresh: "r", Ctx: device})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.AlreadyExists, st.Code())
+	})
+
+	t.Run("begin success", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("BeginTotpEnrollment", mock.Anything, "r", mock.Anything).
+			Return(domain.TotpEnrollment{Secret: "S", URI: "otpauth://totp/x"}, nil)
+
+		srv := New(s, zap.NewNop().Sugar())
+		resp, err := srv.BeginTotpEnrollment(ctx, &sso.BeginTotpEnrollmentRequest{Refresh: "r", Ctx: device})
+		require.NoError(t, err)
+		require.Equal(t, "S", resp.Secret)
+		require.Equal(t, "otpauth://totp/x", resp.OtpauthUri)
+	})
+
+	t.Run("confirm without pending enrollment", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ConfirmTotpEnrollment", mock.Anything, "r", mock.Anything, "123456").Return(domain.ErrNotFound)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.ConfirmTotpEnrollment(ctx, &sso.ConfirmTotpEnrollmentRequest{Refresh: "r", Ctx: device, Code: "123456"})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.FailedPrecondition, st.Code())
+	})
+
+	t.Run("complete wrong code", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("CompleteMfaLogin", mock.Anything, "ch", "123456", mock.Anything).Return(domain.Token{}, domain.ErrValidation)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.CompleteMfaLogin(ctx, &sso.CompleteMfaLoginRequest{Challenge: "ch", Code: "123456", Ctx: device})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Unauthenticated, st.Code())
+	})
+
+	t.Run("complete success", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("CompleteMfaLogin", mock.Anything, "ch", "123456", mock.Anything).Return(domain.NewToken("acc", "ref"), nil)
+
+		srv := New(s, zap.NewNop().Sugar())
+		resp, err := srv.CompleteMfaLogin(ctx, &sso.CompleteMfaLoginRequest{Challenge: "ch", Code: "123456", Ctx: device})
+		require.NoError(t, err)
+		require.Equal(t, "acc", resp.Tokens.Access)
+		require.Equal(t, "ref", resp.Tokens.Refresh)
+	})
+
+	t.Run("complete without device context", func(t *testing.T) {
+		srv := New(&mocks.AuthService{}, zap.NewNop().Sugar())
+		_, err := srv.CompleteMfaLogin(ctx, &sso.CompleteMfaLoginRequest{Challenge: "ch", Code: "123456"})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.InvalidArgument, st.Code())
+	})
+}
+
+func TestAuthTransport_LoginLockout(t *testing.T) {
+	user := &sso.User{Email: "a@b.c", Password: "123456"}
+	device := &sso.DeviceContext{AppId: 1, DeviceId: 2}
+
+	t.Run("locked -> resource exhausted with retry info", func(t *testing.T) {
+		// адрес клиента кладёт clientip-интерсептор
+		ctx := domain.WithClientIP(context.Background(), "203.0.113.7")
+
+		s := &mocks.AuthService{}
+		s.On("Login", mock.MatchedBy(func(ctx context.Context) bool {
+			return domain.ClientIP(ctx) == "203.0.113.7"
+		}), mock.Anything, mock.Anything).Return(domain.LoginResult{}, fmt.Errorf("wrapped: %w", &domain.LockoutError{RetryAfter: 90 * time.Second}))
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.Login(ctx, &sso.LoginRequest{User: user, Ctx: device})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.ResourceExhausted, st.Code())
+		require.Len(t, st.Details(), 1)
+		info, ok := st.Details()[0].(*errdetails.RetryInfo)
+		require.True(t, ok)
+		require.Equal(t, 90*time.Second, info.RetryDelay.AsDuration())
+
+		s.AssertExpectations(t)
+	})
+
+	t.Run("clear lockout", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ClearLoginLockout", mock.Anything, "a@b.c", "").Return(nil)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.ClearLoginLockout(context.Background(), &sso.ClearLoginLockoutRequest{Email: "a@b.c"})
+		require.NoError(t, err)
+		s.AssertExpectations(t)
+	})
+
+	t.Run("clear lockout internal error", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ClearLoginLockout", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("boom"))
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.ClearLoginLockout(context.Background(), &sso.ClearLoginLockoutRequest{Ip: "10.0.0.1"})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Internal, st.Code())
+	})
+}
+
 func TestValidate_RequestStructs(t *testing.T) {
 	// 1. RegisterRequest без User
 	err := validate(&sso.RegisterRequest{})
@@ -241,3 +624,448 @@ func TestValidate_RequestStructs(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, "bad request type", err.Error())
 }
+
+func TestAuthTransport_GetJwks(t *testing.T) {
+	s := &mocks.AuthService{}
+	s.On("JWKS").Return(domain.JWKS{Keys: []domain.JWK{
+		{Kty: "RSA", Use: "sig", Alg: "RS256", Kid: "k2", N: "n2", E: "AQAB"},
+		{Kty: "RSA", Use: "sig", Alg: "RS256", Kid: "k1", N: "n1", E: "AQAB"},
+	}})
+
+	resp, err := New(s, zap.NewNop().Sugar()).GetJwks(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, resp.Keys, 2)
+	require.Equal(t, "k2", resp.Keys[0].Kid)
+	require.Equal(t, "n2", resp.Keys[0].N)
+	require.Equal(t, "k1", resp.Keys[1].Kid)
+
+	s.AssertExpectations(t)
+}
+
+func TestAuthTransport_Introspect(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("active token", func(t *testing.T) {
+		exp := time.Now().Add(time.Minute).Truncate(time.Second)
+		s := &mocks.AuthService{}
+		s.On("Introspect", mock.Anything, "tok").Return(domain.Introspection{
+			Active:    true,
+			Type:      domain.TokenTypeAccess,
+			UserID:    "u1",
+			SessionID: "fam",
+			Ctx:       domain.NewDeviceCtx(2, 3),
+			Exp:       exp,
+			Roles:     []string{"admin"},
+		}, nil)
+
+		resp, err := New(s, zap.NewNop().Sugar()).Introspect(ctx, &sso.IntrospectRequest{Token: "tok"})
+		require.NoError(t, err)
+		require.True(t, resp.Active)
+		require.Equal(t, "access", resp.TokenType)
+		require.Equal(t, "u1", resp.UserId)
+		require.Equal(t, "fam", resp.SessionId)
+		require.Equal(t, int32(2), resp.AppId)
+		require.Equal(t, int32(3), resp.DeviceId)
+		require.Equal(t, exp, resp.ExpiresAt.AsTime().Local())
+		require.Equal(t, []string{"admin"}, resp.Roles)
+	})
+
+	t.Run("inactive token carries nothing but active", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("Introspect", mock.Anything, "tok").Return(domain.Introspection{}, nil)
+
+		resp, err := New(s, zap.NewNop().Sugar()).Introspect(ctx, &sso.IntrospectRequest{Token: "tok"})
+		require.NoError(t, err)
+		require.False(t, resp.Active)
+		require.Empty(t, resp.UserId)
+		require.Nil(t, resp.ExpiresAt)
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("Introspect", mock.Anything, "tok").Return(domain.Introspection{}, errors.New("redis down"))
+
+		_, err := New(s, zap.NewNop().Sugar()).Introspect(ctx, &sso.IntrospectRequest{Token: "tok"})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Internal, st.Code())
+	})
+}
+
+func TestAuthTransport_IsAccessTokenRevoked(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("revoked", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("IsAccessTokenRevoked", mock.Anything, "jti-1").Return(true, nil)
+
+		resp, err := New(s, zap.NewNop().Sugar()).IsAccessTokenRevoked(ctx, &sso.IsAccessTokenRevokedRequest{Jti: "jti-1"})
+		require.NoError(t, err)
+		require.True(t, resp.Revoked)
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("IsAccessTokenRevoked", mock.Anything, "jti-1").Return(false, errors.New("redis down"))
+
+		_, err := New(s, zap.NewNop().Sugar()).IsAccessTokenRevoked(ctx, &sso.IsAccessTokenRevokedRequest{Jti: "jti-1"})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Internal, st.Code())
+	})
+}
+
+func TestAuthTransport_MachineClients(t *testing.T) {
+	ctx := context.Background()
+	const clientID = "6f1c2a6e-2d55-4c36-9a47-6f0f3f0b9d11"
+
+	t.Run("create returns secret once", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("CreateMachineClient", mock.Anything, "billing", []string{"orders:read"}).
+			Return(domain.MachineClientSecret{ClientID: clientID, ClientSecret: "sec"}, nil)
+
+		resp, err := New(s, zap.NewNop().Sugar()).CreateMachineClient(ctx, &sso.CreateMachineClientRequest{Name: "billing", Scopes: []string{"orders:read"}})
+		require.NoError(t, err)
+		require.Equal(t, clientID, resp.ClientId)
+		require.Equal(t, "sec", resp.ClientSecret)
+	})
+
+	t.Run("create with bad scope", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("CreateMachineClient", mock.Anything, mock.Anything, mock.Anything).
+			Return(domain.MachineClientSecret{}, fmt.Errorf("bad scope: %w", domain.ErrValidation))
+
+		_, err := New(s, zap.NewNop().Sugar()).CreateMachineClient(ctx, &sso.CreateMachineClientRequest{Name: "billing", Scopes: []string{"a b"}})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("rotate and disable unknown client", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("RotateMachineClientSecret", mock.Anything, clientID).Return(domain.MachineClientSecret{}, domain.ErrNotFound)
+		s.On("DisableMachineClient", mock.Anything, clientID).Return(domain.ErrNotFound)
+		srv := New(s, zap.NewNop().Sugar())
+
+		_, err := srv.RotateMachineClientSecret(ctx, &sso.RotateMachineClientSecretRequest{ClientId: clientID})
+		require.Equal(t, codes.NotFound, status.Code(err))
+		_, err = srv.DisableMachineClient(ctx, &sso.DisableMachineClientRequest{ClientId: clientID})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("service errors", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("RotateMachineClientSecret", mock.Anything, clientID).Return(domain.MachineClientSecret{}, errors.New("db down"))
+		s.On("DisableMachineClient", mock.Anything, clientID).Return(errors.New("db down"))
+		srv := New(s, zap.NewNop().Sugar())
+
+		_, err := srv.RotateMachineClientSecret(ctx, &sso.RotateMachineClientSecretRequest{ClientId: clientID})
+		require.Equal(t, codes.Internal, status.Code(err))
+		_, err = srv.DisableMachineClient(ctx, &sso.DisableMachineClientRequest{ClientId: clientID})
+		require.Equal(t, codes.Internal, status.Code(err))
+	})
+}
+
+func TestAuthTransport_AppAccessDenied(t *testing.T) {
+	ctx := context.Background()
+	device := &sso.DeviceContext{AppId: 9, DeviceId: 2}
+	denied := fmt.Errorf("app disabled: %w", domain.ErrAppAccessDenied)
+
+	s := &mocks.AuthService{}
+	s.On("Login", mock.Anything, mock.Anything, mock.Anything).Return(domain.LoginResult{}, denied)
+	s.On("Refresh", mock.Anything, mock.Anything, mock.Anything).Return(domain.Token{}, denied)
+	s.On("Logout", mock.Anything, mock.Anything, mock.Anything).Return(denied)
+	s.On("CompleteMfaLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(domain.Token{}, denied)
+	srv := New(s, zap.NewNop().Sugar())
+
+	_, err := srv.Login(ctx, &sso.LoginRequest{User: &sso.User{Email: "a@b.c", Password: "p"}, Ctx: device})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = srv.Refresh(ctx, &sso.RefreshRequest{Refresh: "r", Ctx: device})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = srv.Logout(ctx, &sso.LogoutRequest{Refresh: "r", Ctx: device})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = srv.CompleteMfaLogin(ctx, &sso.CompleteMfaLoginRequest{Challenge: "ch", Code: "123456", Ctx: device})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestAuthTransport_Apps(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+
+	t.Run("create maps request and response", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("CreateApp", mock.Anything, domain.App{
+			ID: 3, Name: "web", GrantTypes: []string{"password"},
+			AccessTokenTTL: 5 * time.Minute, EmailDomains: []string{"corp.example"},
+		}).Return(domain.App{
+			ID: 3, Name: "web", Status: domain.AppActive, GrantTypes: []string{"password"},
+			AccessTokenTTL: 5 * time.Minute, EmailDomains: []string{"corp.example"}, CreatedAt: created, UpdatedAt: created,
+		}, nil)
+
+		resp, err := New(s, zap.NewNop().Sugar()).CreateApp(ctx, &sso.CreateAppRequest{App: &sso.App{
+			Id: 3, Name: "web", GrantTypes: []string{"password"},
+			AccessTokenTtl: durationpb.New(5 * time.Minute), EmailDomains: []string{"corp.example"},
+		}})
+		require.NoError(t, err)
+		require.Equal(t, "active", resp.App.Status)
+		require.Equal(t, 5*time.Minute, resp.App.AccessTokenTtl.AsDuration())
+		require.Zero(t, resp.App.RefreshTokenTtl.AsDuration())
+		require.True(t, resp.App.CreatedAt.AsTime().Equal(created))
+		s.AssertExpectations(t)
+	})
+
+	t.Run("create errors", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			err  error
+			code codes.Code
+		}{
+			"invalid":   {fmt.Errorf("bad grant: %w", domain.ErrValidation), codes.InvalidArgument},
+			"duplicate": {domain.ErrDuplicate, codes.AlreadyExists},
+			"internal":  {errors.New("db down"), codes.Internal},
+		} {
+			s := &mocks.AuthService{}
+			s.On("CreateApp", mock.Anything, mock.Anything).Return(domain.App{}, tc.err)
+
+			_, err := New(s, zap.NewNop().Sugar()).CreateApp(ctx, &sso.CreateAppRequest{App: &sso.App{Id: 3, Name: "web"}})
+			require.Equal(t, tc.code, status.Code(err), name)
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		srv := New(&mocks.AuthService{}, zap.NewNop().Sugar())
+
+		_, err := srv.CreateApp(ctx, &sso.CreateAppRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = srv.UpdateApp(ctx, &sso.UpdateAppRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("unknown app", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("UpdateApp", mock.Anything, mock.Anything).Return(domain.App{}, domain.ErrNotFound)
+		s.On("GetApp", mock.Anything, int32(4)).Return(domain.App{}, domain.ErrNotFound)
+		s.On("DisableApp", mock.Anything, int32(4)).Return(domain.ErrNotFound)
+		s.On("EnableApp", mock.Anything, int32(4)).Return(domain.ErrNotFound)
+		srv := New(s, zap.NewNop().Sugar())
+
+		_, err := srv.UpdateApp(ctx, &sso.UpdateAppRequest{App: &sso.App{Id: 4, Name: "web"}})
+		require.Equal(t, codes.NotFound, status.Code(err))
+		_, err = srv.GetApp(ctx, &sso.GetAppRequest{AppId: 4})
+		require.Equal(t, codes.NotFound, status.Code(err))
+		_, err = srv.DisableApp(ctx, &sso.DisableAppRequest{AppId: 4})
+		require.Equal(t, codes.NotFound, status.Code(err))
+		_, err = srv.EnableApp(ctx, &sso.EnableAppRequest{AppId: 4})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("ListApps", mock.Anything).Return([]domain.App{{ID: 1, Status: domain.AppActive}, {ID: 2, Status: domain.AppDisabled}}, nil)
+
+		resp, err := New(s, zap.NewNop().Sugar()).ListApps(ctx, &emptypb.Empty{})
+		require.NoError(t, err)
+		require.Len(t, resp.Apps, 2)
+		require.Equal(t, "disabled", resp.Apps[1].Status)
+	})
+}
+
+func TestAuthTransport_RegisterDevice(t *testing.T) {
+	ctx := context.Background()
+	fresh := &sso.DeviceContext{AppId: 1, DeviceId: 0}
+
+	t.Run("success", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("RegisterDevice", mock.Anything, "r", domain.NewDeviceCtx(1, 0), "ios", "iPhone").
+			Return(domain.Device{ID: 123456, UserID: "u1"}, domain.Token{Access: "a", Refresh: "r2"}, nil)
+
+		resp, err := New(s, zap.NewNop().Sugar()).RegisterDevice(ctx, &sso.RegisterDeviceRequest{
+			Refresh: "r", Ctx: fresh, Platform: "ios", Name: "iPhone",
+		})
+		require.NoError(t, err)
+		require.Equal(t, int32(123456), resp.DeviceId)
+		require.Equal(t, "a", resp.Tokens.Access)
+		require.Equal(t, "r2", resp.Tokens.Refresh)
+	})
+
+	t.Run("no device context", func(t *testing.T) {
+		_, err := New(&mocks.AuthService{}, zap.NewNop().Sugar()).RegisterDevice(ctx, &sso.RegisterDeviceRequest{Refresh: "r", Platform: "ios"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			err  error
+			code codes.Code
+		}{
+			"foreign device or used refresh": {fmt.Errorf("device: %w", domain.ErrValidation), codes.Unauthenticated},
+			"app denied":                     {fmt.Errorf("app: %w", domain.ErrAppAccessDenied), codes.PermissionDenied},
+			"internal":                       {errors.New("db down"), codes.Internal},
+		} {
+			s := &mocks.AuthService{}
+			s.On("RegisterDevice", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(domain.Device{}, domain.Token{}, tc.err)
+
+			_, err := New(s, zap.NewNop().Sugar()).RegisterDevice(ctx, &sso.RegisterDeviceRequest{Refresh: "r", Ctx: fresh, Platform: "ios"})
+			require.Equal(t, tc.code, status.Code(err), name)
+		}
+	})
+}
+
+func TestAuthTransport_FederatedLogin(t *testing.T) {
+	ctx := context.Background()
+	dctx := &sso.DeviceContext{AppId: 1, DeviceId: 0}
+
+	t.Run("begin", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("BeginFederatedLogin", mock.Anything, "corp", domain.NewDeviceCtx(1, 0)).
+			Return(domain.FederatedLogin{AuthURL: "https://idp.example.com/authorize?state=st", State: "st"}, nil)
+
+		resp, err := New(s, zap.NewNop().Sugar()).BeginFederatedLogin(ctx, &sso.BeginFederatedLoginRequest{Provider: "corp", Ctx: dctx})
+		require.NoError(t, err)
+		require.Equal(t, "https://idp.example.com/authorize?state=st", resp.AuthUrl)
+		require.Equal(t, "st", resp.State)
+	})
+
+	t.Run("begin: unknown provider", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("BeginFederatedLogin", mock.Anything, mock.Anything, mock.Anything).
+			Return(domain.FederatedLogin{}, fmt.Errorf("idp: %w", domain.ErrNotFound))
+
+		_, err := New(s, zap.NewNop().Sugar()).BeginFederatedLogin(ctx, &sso.BeginFederatedLoginRequest{Provider: "nope", Ctx: dctx})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("complete: tokens or mfa challenge", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("CompleteFederatedLogin", mock.Anything, "corp", "st", "code", domain.NewDeviceCtx(1, 0)).
+			Return(domain.LoginResult{Token: domain.Token{Access: "a", Refresh: "r"}}, nil).Once()
+		s.On("CompleteFederatedLogin", mock.Anything, "corp", "st", "code", domain.NewDeviceCtx(1, 0)).
+			Return(domain.LoginResult{MfaChallenge: "ch"}, nil).Once()
+		tr := New(s, zap.NewNop().Sugar())
+		req := &sso.CompleteFederatedLoginRequest{Provider: "corp", State: "st", Code: "code", Ctx: dctx}
+
+		resp, err := tr.CompleteFederatedLogin(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, "a", resp.Tokens.Access)
+		require.Empty(t, resp.MfaChallenge)
+
+		resp, err = tr.CompleteFederatedLogin(ctx, req)
+		require.NoError(t, err)
+		require.Nil(t, resp.Tokens)
+		require.Equal(t, "ch", resp.MfaChallenge)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		tr := New(&mocks.AuthService{}, zap.NewNop().Sugar())
+		_, err := tr.BeginFederatedLogin(ctx, &sso.BeginFederatedLoginRequest{Provider: "corp"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = tr.CompleteFederatedLogin(ctx, &sso.CompleteFederatedLoginRequest{Provider: "corp", State: "st", Code: "c"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("complete: errors", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			err  error
+			code codes.Code
+		}{
+			"bad state or id_token": {fmt.Errorf("state: %w", domain.ErrValidation), codes.Unauthenticated},
+			"no local account":      {fmt.Errorf("link: %w", domain.ErrNotFound), codes.NotFound},
+			"app denied":            {fmt.Errorf("app: %w", domain.ErrAppAccessDenied), codes.PermissionDenied},
+			"internal":              {errors.New("idp down"), codes.Internal},
+		} {
+			s := &mocks.AuthService{}
+			s.On("CompleteFederatedLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(domain.LoginResult{}, tc.err)
+
+			_, err := New(s, zap.NewNop().Sugar()).CompleteFederatedLogin(ctx, &sso.CompleteFederatedLoginRequest{
+				Provider: "corp", State: "st", Code: "code", Ctx: dctx,
+			})
+			require.Equal(t, tc.code, status.Code(err), name)
+		}
+	})
+}
+
+func TestAuthTransport_PasswordlessLogin(t *testing.T) {
+	ctx := context.Background()
+	dctx := &sso.DeviceContext{AppId: 1, DeviceId: 0}
+
+	t.Run("start: code returns challenge", func(t *testing.T) {
+		exp := time.Now().Add(10 * time.Minute)
+		s := &mocks.AuthService{}
+		s.On("StartPasswordlessLogin", mock.Anything, "a@b.c", domain.PasswordlessCode, domain.NewDeviceCtx(1, 0)).
+			Return(domain.PasswordlessLogin{Challenge: "ch", ExpiresAt: exp}, nil)
+
+		resp, err := New(s, zap.NewNop().Sugar()).StartPasswordlessLogin(ctx, &sso.StartPasswordlessLoginRequest{
+			Email: "a@b.c", Method: "code", Ctx: dctx,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "ch", resp.Challenge)
+		require.True(t, exp.Equal(resp.ExpiresAt.AsTime()))
+	})
+
+	t.Run("start: errors", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			err  error
+			code codes.Code
+		}{
+			"locked":     {fmt.Errorf("lock: %w", &domain.LockoutError{RetryAfter: time.Minute}), codes.ResourceExhausted},
+			"app denied": {fmt.Errorf("app: %w", domain.ErrAppAccessDenied), codes.PermissionDenied},
+			"validation": {fmt.Errorf("method: %w", domain.ErrValidation), codes.InvalidArgument},
+			"internal":   {errors.New("smtp down"), codes.Internal},
+		} {
+			s := &mocks.AuthService{}
+			s.On("StartPasswordlessLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(domain.PasswordlessLogin{}, tc.err)
+
+			_, err := New(s, zap.NewNop().Sugar()).StartPasswordlessLogin(ctx, &sso.StartPasswordlessLoginRequest{
+				Email: "a@b.c", Method: "link", Ctx: dctx,
+			})
+			require.Equal(t, tc.code, status.Code(err), name)
+		}
+	})
+
+	t.Run("complete: tokens or mfa challenge", func(t *testing.T) {
+		s := &mocks.AuthService{}
+		s.On("CompletePasswordlessLogin", mock.Anything, "ch", "012345", domain.NewDeviceCtx(1, 0)).
+			Return(domain.LoginResult{Token: domain.Token{Access: "a", Refresh: "r"}}, nil).Once()
+		s.On("CompletePasswordlessLogin", mock.Anything, "ch", "012345", domain.NewDeviceCtx(1, 0)).
+			Return(domain.LoginResult{MfaChallenge: "mfa"}, nil).Once()
+		tr := New(s, zap.NewNop().Sugar())
+		req := &sso.CompletePasswordlessLoginRequest{Challenge: "ch", Code: "012345", Ctx: dctx}
+
+		resp, err := tr.CompletePasswordlessLogin(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, "a", resp.Tokens.Access)
+		require.Empty(t, resp.MfaChallenge)
+
+		resp, err = tr.CompletePasswordlessLogin(ctx, req)
+		require.NoError(t, err)
+		require.Nil(t, resp.Tokens)
+		require.Equal(t, "mfa", resp.MfaChallenge)
+	})
+
+	t.Run("complete: errors", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			err  error
+			code codes.Code
+		}{
+			"wrong code": {fmt.Errorf("code: %w", domain.ErrValidation), codes.Unauthenticated},
+			"locked":     {fmt.Errorf("lock: %w", &domain.LockoutError{RetryAfter: time.Minute}), codes.ResourceExhausted},
+			"app denied": {fmt.Errorf("app: %w", domain.ErrAppAccessDenied), codes.PermissionDenied},
+			"internal":   {errors.New("redis down"), codes.Internal},
+		} {
+			s := &mocks.AuthService{}
+			s.On("CompletePasswordlessLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(domain.LoginResult{}, tc.err)
+
+			_, err := New(s, zap.NewNop().Sugar()).CompletePasswordlessLogin(ctx, &sso.CompletePasswordlessLoginRequest{
+				Challenge: "link-token", Ctx: dctx,
+			})
+			require.Equal(t, tc.code, status.Code(err), name)
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		tr := New(&mocks.AuthService{}, zap.NewNop().Sugar())
+		_, err := tr.StartPasswordlessLogin(ctx, &sso.StartPasswordlessLoginRequest{Email: "a@b.c", Method: "link"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = tr.CompletePasswordlessLogin(ctx, &sso.CompletePasswordlessLoginRequest{Challenge: "ch", Code: "123456"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
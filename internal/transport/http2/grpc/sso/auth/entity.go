@@ -3,22 +3,144 @@ package grpctransportauth
 import (
 	"github.com/eragon-mdi/protos/gen/go/sso/v1"
 	"github.com/eragon-mdi/sso/internal/domain"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// длину и состав пароля проверяет политика паролей сервиса; здесь только защита от огромных значений
 type UserValidation struct {
 	Email    string `validate:"required,email"`
-	Password string `validate:"required,min=6,max=12"`
+	Password string `validate:"required,max=1024"`
 }
 
+// DeviceId: 0 — устройство ещё не зарегистрировано (RegisterDevice), < 0 — выдан сервером,
+// > 0 — выбран клиентом до реестра устройств; допустимость проверяет сервис
 type DeviceCtxValidation struct {
 	AppId    int32 `validate:"required,gt=0"`
-	DeviceId int32 `validate:"required,gt=0"`
+	DeviceId int32
 }
 
 type RefreshTokenValidate struct {
 	Refresh string `validate:"required"`
 }
 
+type LogoutAllReqValidation struct {
+	UserId string `validate:"required,uuid4"`
+}
+
+type ChangePasswordReqValidation struct {
+	RefreshTokenValidate
+	DeviceCtxValidation
+	OldPassword string `validate:"required,max=1024"`
+	NewPassword string `validate:"required,max=1024"`
+}
+
+type RequestPasswordResetReqValidation struct {
+	Email string `validate:"required,email"`
+}
+
+type ConfirmPasswordResetReqValidation struct {
+	Token       string `validate:"required"`
+	NewPassword string `validate:"required,max=1024"`
+}
+
+type VerifyEmailReqValidation struct {
+	Token string `validate:"required"`
+}
+
+type ResendEmailVerificationReqValidation struct {
+	Email string `validate:"required,email"`
+}
+
+type TotpCodeValidation struct {
+	Code string `validate:"required,len=6,numeric"`
+}
+
+type BeginTotpEnrollmentReqValidation struct {
+	RefreshTokenValidate
+	DeviceCtxValidation
+}
+
+type ConfirmTotpEnrollmentReqValidation struct {
+	RefreshTokenValidate
+	DeviceCtxValidation
+	TotpCodeValidation
+}
+
+type CompleteMfaLoginReqValidation struct {
+	Challenge string `validate:"required"`
+	TotpCodeValidation
+	DeviceCtxValidation
+}
+
+type RegisterDeviceReqValidation struct {
+	RefreshTokenValidate
+	DeviceCtxValidation
+	Platform string `validate:"required,max=32"`
+	Name     string `validate:"max=128"`
+}
+
+type BeginFederatedLoginReqValidation struct {
+	Provider string `validate:"required,max=64"`
+	DeviceCtxValidation
+}
+
+type CompleteFederatedLoginReqValidation struct {
+	Provider string `validate:"required,max=64"`
+	State    string `validate:"required,max=256"`
+	Code     string `validate:"required,max=2048"`
+	DeviceCtxValidation
+}
+
+type StartPasswordlessLoginReqValidation struct {
+	Email  string `validate:"required,email"`
+	Method string `validate:"required,oneof=link code"`
+	DeviceCtxValidation
+}
+
+// Code пустой — вход по ссылке, Challenge — её токен
+type CompletePasswordlessLoginReqValidation struct {
+	Challenge string `validate:"required,max=256"`
+	Code      string `validate:"omitempty,len=6,numeric"`
+	DeviceCtxValidation
+}
+
+// ClearLoginLockoutReqValidation — нужен хотя бы один из ключей блокировки
+type ClearLoginLockoutReqValidation struct {
+	Email string `validate:"required_without=Ip,omitempty,email"`
+	Ip    string `validate:"required_without=Email,omitempty,ip"`
+}
+
+type IntrospectReqValidation struct {
+	Token string `validate:"required,max=8192"`
+}
+
+type IsAccessTokenRevokedReqValidation struct {
+	Jti string `validate:"required,max=128"`
+}
+
+type CreateMachineClientReqValidation struct {
+	Name   string   `validate:"required,max=128"`
+	Scopes []string `validate:"max=64,dive,required,max=128"`
+}
+
+type MachineClientIDValidation struct {
+	ClientId string `validate:"required,uuid"`
+}
+
+type AppValidation struct {
+	Id           int32    `validate:"required,gt=0"`
+	Name         string   `validate:"required,max=128"`
+	GrantTypes   []string `validate:"max=8,dive,required,max=64"`
+	EmailDomains []string `validate:"max=64,dive,required,fqdn"`
+	RedirectUris []string `validate:"max=32,dive,required,max=2048"`
+	Scopes       []string `validate:"max=64,dive,required,max=128"`
+}
+
+type AppIDValidation struct {
+	AppId int32 `validate:"required,gt=0"`
+}
+
 type RegisterReqValidation struct {
 	UserValidation
 }
@@ -55,3 +177,74 @@ func userFromLoginReq(req *sso.LoginRequest) domain.User {
 func deviceCtxFromReq(reqDeviceCtx *sso.DeviceContext) domain.DeviceCtx {
 	return domain.NewDeviceCtx(reqDeviceCtx.AppId, reqDeviceCtx.DeviceId)
 }
+
+func jwksToResponse(set domain.JWKS) *sso.GetJwksResponse {
+	keys := make([]*sso.Jwk, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		keys = append(keys, &sso.Jwk{
+			Kty: k.Kty,
+			Use: k.Use,
+			Alg: k.Alg,
+			Kid: k.Kid,
+			N:   k.N,
+			E:   k.E,
+			Crv: k.Crv,
+			X:   k.X,
+			Y:   k.Y,
+		})
+	}
+	return &sso.GetJwksResponse{Keys: keys}
+}
+
+// introspectionToResponse — у неактивного токена по RFC 7662 отдаётся только active=false
+func introspectionToResponse(i domain.Introspection) *sso.IntrospectResponse {
+	if !i.Active {
+		return &sso.IntrospectResponse{}
+	}
+	return &sso.IntrospectResponse{
+		Active:    true,
+		TokenType: string(i.Type),
+		Jti:       i.ID,
+		Issuer:    i.Issuer,
+		Audience:  i.Audience,
+		UserId:    i.UserID,
+		SessionId: i.SessionID,
+		AppId:     i.Ctx.AppId,
+		DeviceId:  i.Ctx.DeviceID,
+		IssuedAt:  timestamppb.New(i.IssuedAt),
+		ExpiresAt: timestamppb.New(i.Exp),
+		Scopes:    i.Scopes,
+		Roles:     i.Roles,
+		ClientId:  i.ClientID,
+	}
+}
+
+// appFromReq — TTL не задан: значение по умолчанию из конфига SSO
+func appFromReq(a *sso.App) domain.App {
+	return domain.App{
+		ID:              a.GetId(),
+		Name:            a.GetName(),
+		GrantTypes:      a.GetGrantTypes(),
+		AccessTokenTTL:  a.GetAccessTokenTtl().AsDuration(),
+		RefreshTokenTTL: a.GetRefreshTokenTtl().AsDuration(),
+		EmailDomains:    a.GetEmailDomains(),
+		RedirectURIs:    a.GetRedirectUris(),
+		Scopes:          a.GetScopes(),
+	}
+}
+
+func appToResponse(a domain.App) *sso.App {
+	return &sso.App{
+		Id:              a.ID,
+		Name:            a.Name,
+		Status:          string(a.Status),
+		GrantTypes:      a.GrantTypes,
+		AccessTokenTtl:  durationpb.New(a.AccessTokenTTL),
+		RefreshTokenTtl: durationpb.New(a.RefreshTokenTTL),
+		EmailDomains:    a.EmailDomains,
+		RedirectUris:    a.RedirectURIs,
+		Scopes:          a.Scopes,
+		CreatedAt:       timestamppb.New(a.CreatedAt),
+		UpdatedAt:       timestamppb.New(a.UpdatedAt),
+	}
+}
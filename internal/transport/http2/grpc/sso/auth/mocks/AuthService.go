@@ -6,7 +6,6 @@ import (
 	context "context"
 
 	domain "github.com/eragon-mdi/sso/internal/domain"
-
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -23,27 +22,27 @@ func (_m *AuthService) EXPECT() *AuthService_Expecter {
 	return &AuthService_Expecter{mock: &_m.Mock}
 }
 
-// Login provides a mock function with given fields: _a0, _a1, _a2
-func (_m *AuthService) Login(_a0 context.Context, _a1 domain.User, _a2 domain.DeviceCtx) (domain.Token, error) {
-	ret := _m.Called(_a0, _a1, _a2)
+// BeginFederatedLogin provides a mock function with given fields: _a0, provider, dctx
+func (_m *AuthService) BeginFederatedLogin(_a0 context.Context, provider string, dctx domain.DeviceCtx) (domain.FederatedLogin, error) {
+	ret := _m.Called(_a0, provider, dctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Login")
+		panic("no return value specified for BeginFederatedLogin")
 	}
 
-	var r0 domain.Token
+	var r0 domain.FederatedLogin
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, domain.User, domain.DeviceCtx) (domain.Token, error)); ok {
-		return rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx) (domain.FederatedLogin, error)); ok {
+		return rf(_a0, provider, dctx)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, domain.User, domain.DeviceCtx) domain.Token); ok {
-		r0 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx) domain.FederatedLogin); ok {
+		r0 = rf(_a0, provider, dctx)
 	} else {
-		r0 = ret.Get(0).(domain.Token)
+		r0 = ret.Get(0).(domain.FederatedLogin)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, domain.User, domain.DeviceCtx) error); ok {
-		r1 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(1).(func(context.Context, string, domain.DeviceCtx) error); ok {
+		r1 = rf(_a0, provider, dctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -51,47 +50,105 @@ func (_m *AuthService) Login(_a0 context.Context, _a1 domain.User, _a2 domain.De
 	return r0, r1
 }
 
-// AuthService_Login_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Login'
-type AuthService_Login_Call struct {
+// AuthService_BeginFederatedLogin_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BeginFederatedLogin'
+type AuthService_BeginFederatedLogin_Call struct {
 	*mock.Call
 }
 
-// Login is a helper method to define mock.On call
+// BeginFederatedLogin is a helper method to define mock.On call
 //   - _a0 context.Context
-//   - _a1 domain.User
-//   - _a2 domain.DeviceCtx
-func (_e *AuthService_Expecter) Login(_a0 interface{}, _a1 interface{}, _a2 interface{}) *AuthService_Login_Call {
-	return &AuthService_Login_Call{Call: _e.mock.On("Login", _a0, _a1, _a2)}
+//   - provider string
+//   - dctx domain.DeviceCtx
+func (_e *AuthService_Expecter) BeginFederatedLogin(_a0 interface{}, provider interface{}, dctx interface{}) *AuthService_BeginFederatedLogin_Call {
+	return &AuthService_BeginFederatedLogin_Call{Call: _e.mock.On("BeginFederatedLogin", _a0, provider, dctx)}
 }
 
-func (_c *AuthService_Login_Call) Run(run func(_a0 context.Context, _a1 domain.User, _a2 domain.DeviceCtx)) *AuthService_Login_Call {
+func (_c *AuthService_BeginFederatedLogin_Call) Run(run func(_a0 context.Context, provider string, dctx domain.DeviceCtx)) *AuthService_BeginFederatedLogin_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(domain.User), args[2].(domain.DeviceCtx))
+		run(args[0].(context.Context), args[1].(string), args[2].(domain.DeviceCtx))
 	})
 	return _c
 }
 
-func (_c *AuthService_Login_Call) Return(_a0 domain.Token, _a1 error) *AuthService_Login_Call {
+func (_c *AuthService_BeginFederatedLogin_Call) Return(_a0 domain.FederatedLogin, _a1 error) *AuthService_BeginFederatedLogin_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *AuthService_Login_Call) RunAndReturn(run func(context.Context, domain.User, domain.DeviceCtx) (domain.Token, error)) *AuthService_Login_Call {
+func (_c *AuthService_BeginFederatedLogin_Call) RunAndReturn(run func(context.Context, string, domain.DeviceCtx) (domain.FederatedLogin, error)) *AuthService_BeginFederatedLogin_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Logout provides a mock function with given fields: _a0, _a1, _a2
-func (_m *AuthService) Logout(_a0 context.Context, _a1 string, _a2 domain.DeviceCtx) error {
-	ret := _m.Called(_a0, _a1, _a2)
+// BeginTotpEnrollment provides a mock function with given fields: _a0, refresh, dctx
+func (_m *AuthService) BeginTotpEnrollment(_a0 context.Context, refresh string, dctx domain.DeviceCtx) (domain.TotpEnrollment, error) {
+	ret := _m.Called(_a0, refresh, dctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Logout")
+		panic("no return value specified for BeginTotpEnrollment")
+	}
+
+	var r0 domain.TotpEnrollment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx) (domain.TotpEnrollment, error)); ok {
+		return rf(_a0, refresh, dctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx) domain.TotpEnrollment); ok {
+		r0 = rf(_a0, refresh, dctx)
+	} else {
+		r0 = ret.Get(0).(domain.TotpEnrollment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, domain.DeviceCtx) error); ok {
+		r1 = rf(_a0, refresh, dctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_BeginTotpEnrollment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BeginTotpEnrollment'
+type AuthService_BeginTotpEnrollment_Call struct {
+	*mock.Call
+}
+
+// BeginTotpEnrollment is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - refresh string
+//   - dctx domain.DeviceCtx
+func (_e *AuthService_Expecter) BeginTotpEnrollment(_a0 interface{}, refresh interface{}, dctx interface{}) *AuthService_BeginTotpEnrollment_Call {
+	return &AuthService_BeginTotpEnrollment_Call{Call: _e.mock.On("BeginTotpEnrollment", _a0, refresh, dctx)}
+}
+
+func (_c *AuthService_BeginTotpEnrollment_Call) Run(run func(_a0 context.Context, refresh string, dctx domain.DeviceCtx)) *AuthService_BeginTotpEnrollment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(domain.DeviceCtx))
+	})
+	return _c
+}
+
+func (_c *AuthService_BeginTotpEnrollment_Call) Return(_a0 domain.TotpEnrollment, _a1 error) *AuthService_BeginTotpEnrollment_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_BeginTotpEnrollment_Call) RunAndReturn(run func(context.Context, string, domain.DeviceCtx) (domain.TotpEnrollment, error)) *AuthService_BeginTotpEnrollment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ChangePassword provides a mock function with given fields: _a0, refresh, dctx, oldPass, newPass
+func (_m *AuthService) ChangePassword(_a0 context.Context, refresh string, dctx domain.DeviceCtx, oldPass string, newPass string) error {
+	ret := _m.Called(_a0, refresh, dctx, oldPass, newPass)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChangePassword")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx) error); ok {
-		r0 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx, string, string) error); ok {
+		r0 = rf(_a0, refresh, dctx, oldPass, newPass)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -99,57 +156,167 @@ func (_m *AuthService) Logout(_a0 context.Context, _a1 string, _a2 domain.Device
 	return r0
 }
 
-// AuthService_Logout_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Logout'
-type AuthService_Logout_Call struct {
+// AuthService_ChangePassword_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ChangePassword'
+type AuthService_ChangePassword_Call struct {
 	*mock.Call
 }
 
-// Logout is a helper method to define mock.On call
+// ChangePassword is a helper method to define mock.On call
 //   - _a0 context.Context
-//   - _a1 string
-//   - _a2 domain.DeviceCtx
-func (_e *AuthService_Expecter) Logout(_a0 interface{}, _a1 interface{}, _a2 interface{}) *AuthService_Logout_Call {
-	return &AuthService_Logout_Call{Call: _e.mock.On("Logout", _a0, _a1, _a2)}
+//   - refresh string
+//   - dctx domain.DeviceCtx
+//   - oldPass string
+//   - newPass string
+func (_e *AuthService_Expecter) ChangePassword(_a0 interface{}, refresh interface{}, dctx interface{}, oldPass interface{}, newPass interface{}) *AuthService_ChangePassword_Call {
+	return &AuthService_ChangePassword_Call{Call: _e.mock.On("ChangePassword", _a0, refresh, dctx, oldPass, newPass)}
 }
 
-func (_c *AuthService_Logout_Call) Run(run func(_a0 context.Context, _a1 string, _a2 domain.DeviceCtx)) *AuthService_Logout_Call {
+func (_c *AuthService_ChangePassword_Call) Run(run func(_a0 context.Context, refresh string, dctx domain.DeviceCtx, oldPass string, newPass string)) *AuthService_ChangePassword_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string), args[2].(domain.DeviceCtx))
+		run(args[0].(context.Context), args[1].(string), args[2].(domain.DeviceCtx), args[3].(string), args[4].(string))
 	})
 	return _c
 }
 
-func (_c *AuthService_Logout_Call) Return(_a0 error) *AuthService_Logout_Call {
+func (_c *AuthService_ChangePassword_Call) Return(_a0 error) *AuthService_ChangePassword_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *AuthService_Logout_Call) RunAndReturn(run func(context.Context, string, domain.DeviceCtx) error) *AuthService_Logout_Call {
+func (_c *AuthService_ChangePassword_Call) RunAndReturn(run func(context.Context, string, domain.DeviceCtx, string, string) error) *AuthService_ChangePassword_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Refresh provides a mock function with given fields: _a0, _a1, _a2
-func (_m *AuthService) Refresh(_a0 context.Context, _a1 string, _a2 domain.DeviceCtx) (domain.Token, error) {
-	ret := _m.Called(_a0, _a1, _a2)
+// ClearLoginLockout provides a mock function with given fields: _a0, email, ip
+func (_m *AuthService) ClearLoginLockout(_a0 context.Context, email string, ip string) error {
+	ret := _m.Called(_a0, email, ip)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Refresh")
+		panic("no return value specified for ClearLoginLockout")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, email, ip)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthService_ClearLoginLockout_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ClearLoginLockout'
+type AuthService_ClearLoginLockout_Call struct {
+	*mock.Call
+}
+
+// ClearLoginLockout is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - email string
+//   - ip string
+func (_e *AuthService_Expecter) ClearLoginLockout(_a0 interface{}, email interface{}, ip interface{}) *AuthService_ClearLoginLockout_Call {
+	return &AuthService_ClearLoginLockout_Call{Call: _e.mock.On("ClearLoginLockout", _a0, email, ip)}
+}
+
+func (_c *AuthService_ClearLoginLockout_Call) Run(run func(_a0 context.Context, email string, ip string)) *AuthService_ClearLoginLockout_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *AuthService_ClearLoginLockout_Call) Return(_a0 error) *AuthService_ClearLoginLockout_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_ClearLoginLockout_Call) RunAndReturn(run func(context.Context, string, string) error) *AuthService_ClearLoginLockout_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CompleteFederatedLogin provides a mock function with given fields: _a0, provider, state, code, dctx
+func (_m *AuthService) CompleteFederatedLogin(_a0 context.Context, provider string, state string, code string, dctx domain.DeviceCtx) (domain.LoginResult, error) {
+	ret := _m.Called(_a0, provider, state, code, dctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompleteFederatedLogin")
+	}
+
+	var r0 domain.LoginResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, domain.DeviceCtx) (domain.LoginResult, error)); ok {
+		return rf(_a0, provider, state, code, dctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, domain.DeviceCtx) domain.LoginResult); ok {
+		r0 = rf(_a0, provider, state, code, dctx)
+	} else {
+		r0 = ret.Get(0).(domain.LoginResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, domain.DeviceCtx) error); ok {
+		r1 = rf(_a0, provider, state, code, dctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_CompleteFederatedLogin_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CompleteFederatedLogin'
+type AuthService_CompleteFederatedLogin_Call struct {
+	*mock.Call
+}
+
+// CompleteFederatedLogin is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - provider string
+//   - state string
+//   - code string
+//   - dctx domain.DeviceCtx
+func (_e *AuthService_Expecter) CompleteFederatedLogin(_a0 interface{}, provider interface{}, state interface{}, code interface{}, dctx interface{}) *AuthService_CompleteFederatedLogin_Call {
+	return &AuthService_CompleteFederatedLogin_Call{Call: _e.mock.On("CompleteFederatedLogin", _a0, provider, state, code, dctx)}
+}
+
+func (_c *AuthService_CompleteFederatedLogin_Call) Run(run func(_a0 context.Context, provider string, state string, code string, dctx domain.DeviceCtx)) *AuthService_CompleteFederatedLogin_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(domain.DeviceCtx))
+	})
+	return _c
+}
+
+func (_c *AuthService_CompleteFederatedLogin_Call) Return(_a0 domain.LoginResult, _a1 error) *AuthService_CompleteFederatedLogin_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_CompleteFederatedLogin_Call) RunAndReturn(run func(context.Context, string, string, string, domain.DeviceCtx) (domain.LoginResult, error)) *AuthService_CompleteFederatedLogin_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CompleteMfaLogin provides a mock function with given fields: _a0, challenge, code, dctx
+func (_m *AuthService) CompleteMfaLogin(_a0 context.Context, challenge string, code string, dctx domain.DeviceCtx) (domain.Token, error) {
+	ret := _m.Called(_a0, challenge, code, dctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompleteMfaLogin")
 	}
 
 	var r0 domain.Token
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx) (domain.Token, error)); ok {
-		return rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, domain.DeviceCtx) (domain.Token, error)); ok {
+		return rf(_a0, challenge, code, dctx)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx) domain.Token); ok {
-		r0 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, domain.DeviceCtx) domain.Token); ok {
+		r0 = rf(_a0, challenge, code, dctx)
 	} else {
 		r0 = ret.Get(0).(domain.Token)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, domain.DeviceCtx) error); ok {
-		r1 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, domain.DeviceCtx) error); ok {
+		r1 = rf(_a0, challenge, code, dctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -157,57 +324,58 @@ func (_m *AuthService) Refresh(_a0 context.Context, _a1 string, _a2 domain.Devic
 	return r0, r1
 }
 
-// AuthService_Refresh_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Refresh'
-type AuthService_Refresh_Call struct {
+// AuthService_CompleteMfaLogin_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CompleteMfaLogin'
+type AuthService_CompleteMfaLogin_Call struct {
 	*mock.Call
 }
 
-// Refresh is a helper method to define mock.On call
+// CompleteMfaLogin is a helper method to define mock.On call
 //   - _a0 context.Context
-//   - _a1 string
-//   - _a2 domain.DeviceCtx
-func (_e *AuthService_Expecter) Refresh(_a0 interface{}, _a1 interface{}, _a2 interface{}) *AuthService_Refresh_Call {
-	return &AuthService_Refresh_Call{Call: _e.mock.On("Refresh", _a0, _a1, _a2)}
+//   - challenge string
+//   - code string
+//   - dctx domain.DeviceCtx
+func (_e *AuthService_Expecter) CompleteMfaLogin(_a0 interface{}, challenge interface{}, code interface{}, dctx interface{}) *AuthService_CompleteMfaLogin_Call {
+	return &AuthService_CompleteMfaLogin_Call{Call: _e.mock.On("CompleteMfaLogin", _a0, challenge, code, dctx)}
 }
 
-func (_c *AuthService_Refresh_Call) Run(run func(_a0 context.Context, _a1 string, _a2 domain.DeviceCtx)) *AuthService_Refresh_Call {
+func (_c *AuthService_CompleteMfaLogin_Call) Run(run func(_a0 context.Context, challenge string, code string, dctx domain.DeviceCtx)) *AuthService_CompleteMfaLogin_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string), args[2].(domain.DeviceCtx))
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(domain.DeviceCtx))
 	})
 	return _c
 }
 
-func (_c *AuthService_Refresh_Call) Return(_a0 domain.Token, _a1 error) *AuthService_Refresh_Call {
+func (_c *AuthService_CompleteMfaLogin_Call) Return(_a0 domain.Token, _a1 error) *AuthService_CompleteMfaLogin_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *AuthService_Refresh_Call) RunAndReturn(run func(context.Context, string, domain.DeviceCtx) (domain.Token, error)) *AuthService_Refresh_Call {
+func (_c *AuthService_CompleteMfaLogin_Call) RunAndReturn(run func(context.Context, string, string, domain.DeviceCtx) (domain.Token, error)) *AuthService_CompleteMfaLogin_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Register provides a mock function with given fields: _a0, _a1
-func (_m *AuthService) Register(_a0 context.Context, _a1 domain.User) (domain.User, error) {
-	ret := _m.Called(_a0, _a1)
+// CompletePasswordlessLogin provides a mock function with given fields: _a0, challenge, code, dctx
+func (_m *AuthService) CompletePasswordlessLogin(_a0 context.Context, challenge string, code string, dctx domain.DeviceCtx) (domain.LoginResult, error) {
+	ret := _m.Called(_a0, challenge, code, dctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Register")
+		panic("no return value specified for CompletePasswordlessLogin")
 	}
 
-	var r0 domain.User
+	var r0 domain.LoginResult
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, domain.User) (domain.User, error)); ok {
-		return rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, domain.DeviceCtx) (domain.LoginResult, error)); ok {
+		return rf(_a0, challenge, code, dctx)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, domain.User) domain.User); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, domain.DeviceCtx) domain.LoginResult); ok {
+		r0 = rf(_a0, challenge, code, dctx)
 	} else {
-		r0 = ret.Get(0).(domain.User)
+		r0 = ret.Get(0).(domain.LoginResult)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, domain.User) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, domain.DeviceCtx) error); ok {
+		r1 = rf(_a0, challenge, code, dctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -215,31 +383,1311 @@ func (_m *AuthService) Register(_a0 context.Context, _a1 domain.User) (domain.Us
 	return r0, r1
 }
 
-// AuthService_Register_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Register'
-type AuthService_Register_Call struct {
+// AuthService_CompletePasswordlessLogin_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CompletePasswordlessLogin'
+type AuthService_CompletePasswordlessLogin_Call struct {
 	*mock.Call
 }
 
-// Register is a helper method to define mock.On call
+// CompletePasswordlessLogin is a helper method to define mock.On call
 //   - _a0 context.Context
-//   - _a1 domain.User
-func (_e *AuthService_Expecter) Register(_a0 interface{}, _a1 interface{}) *AuthService_Register_Call {
-	return &AuthService_Register_Call{Call: _e.mock.On("Register", _a0, _a1)}
+//   - challenge string
+//   - code string
+//   - dctx domain.DeviceCtx
+func (_e *AuthService_Expecter) CompletePasswordlessLogin(_a0 interface{}, challenge interface{}, code interface{}, dctx interface{}) *AuthService_CompletePasswordlessLogin_Call {
+	return &AuthService_CompletePasswordlessLogin_Call{Call: _e.mock.On("CompletePasswordlessLogin", _a0, challenge, code, dctx)}
 }
 
-func (_c *AuthService_Register_Call) Run(run func(_a0 context.Context, _a1 domain.User)) *AuthService_Register_Call {
+func (_c *AuthService_CompletePasswordlessLogin_Call) Run(run func(_a0 context.Context, challenge string, code string, dctx domain.DeviceCtx)) *AuthService_CompletePasswordlessLogin_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(domain.User))
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(domain.DeviceCtx))
 	})
 	return _c
 }
 
-func (_c *AuthService_Register_Call) Return(_a0 domain.User, _a1 error) *AuthService_Register_Call {
+func (_c *AuthService_CompletePasswordlessLogin_Call) Return(_a0 domain.LoginResult, _a1 error) *AuthService_CompletePasswordlessLogin_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *AuthService_Register_Call) RunAndReturn(run func(context.Context, domain.User) (domain.User, error)) *AuthService_Register_Call {
+func (_c *AuthService_CompletePasswordlessLogin_Call) RunAndReturn(run func(context.Context, string, string, domain.DeviceCtx) (domain.LoginResult, error)) *AuthService_CompletePasswordlessLogin_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ConfirmPasswordReset provides a mock function with given fields: _a0, token, newPass, appID
+func (_m *AuthService) ConfirmPasswordReset(_a0 context.Context, token string, newPass string, appID int32) error {
+	ret := _m.Called(_a0, token, newPass, appID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmPasswordReset")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int32) error); ok {
+		r0 = rf(_a0, token, newPass, appID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthService_ConfirmPasswordReset_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ConfirmPasswordReset'
+type AuthService_ConfirmPasswordReset_Call struct {
+	*mock.Call
+}
+
+// ConfirmPasswordReset is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - token string
+//   - newPass string
+//   - appID int32
+func (_e *AuthService_Expecter) ConfirmPasswordReset(_a0 interface{}, token interface{}, newPass interface{}, appID interface{}) *AuthService_ConfirmPasswordReset_Call {
+	return &AuthService_ConfirmPasswordReset_Call{Call: _e.mock.On("ConfirmPasswordReset", _a0, token, newPass, appID)}
+}
+
+func (_c *AuthService_ConfirmPasswordReset_Call) Run(run func(_a0 context.Context, token string, newPass string, appID int32)) *AuthService_ConfirmPasswordReset_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(int32))
+	})
+	return _c
+}
+
+func (_c *AuthService_ConfirmPasswordReset_Call) Return(_a0 error) *AuthService_ConfirmPasswordReset_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_ConfirmPasswordReset_Call) RunAndReturn(run func(context.Context, string, string, int32) error) *AuthService_ConfirmPasswordReset_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ConfirmTotpEnrollment provides a mock function with given fields: _a0, refresh, dctx, code
+func (_m *AuthService) ConfirmTotpEnrollment(_a0 context.Context, refresh string, dctx domain.DeviceCtx, code string) error {
+	ret := _m.Called(_a0, refresh, dctx, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTotpEnrollment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx, string) error); ok {
+		r0 = rf(_a0, refresh, dctx, code)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthService_ConfirmTotpEnrollment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ConfirmTotpEnrollment'
+type AuthService_ConfirmTotpEnrollment_Call struct {
+	*mock.Call
+}
+
+// ConfirmTotpEnrollment is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - refresh string
+//   - dctx domain.DeviceCtx
+//   - code string
+func (_e *AuthService_Expecter) ConfirmTotpEnrollment(_a0 interface{}, refresh interface{}, dctx interface{}, code interface{}) *AuthService_ConfirmTotpEnrollment_Call {
+	return &AuthService_ConfirmTotpEnrollment_Call{Call: _e.mock.On("ConfirmTotpEnrollment", _a0, refresh, dctx, code)}
+}
+
+func (_c *AuthService_ConfirmTotpEnrollment_Call) Run(run func(_a0 context.Context, refresh string, dctx domain.DeviceCtx, code string)) *AuthService_ConfirmTotpEnrollment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(domain.DeviceCtx), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *AuthService_ConfirmTotpEnrollment_Call) Return(_a0 error) *AuthService_ConfirmTotpEnrollment_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_ConfirmTotpEnrollment_Call) RunAndReturn(run func(context.Context, string, domain.DeviceCtx, string) error) *AuthService_ConfirmTotpEnrollment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateApp provides a mock function with given fields: _a0, _a1
+func (_m *AuthService) CreateApp(_a0 context.Context, _a1 domain.App) (domain.App, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateApp")
+	}
+
+	var r0 domain.App
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.App) (domain.App, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.App) domain.App); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(domain.App)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.App) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_CreateApp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateApp'
+type AuthService_CreateApp_Call struct {
+	*mock.Call
+}
+
+// CreateApp is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.App
+func (_e *AuthService_Expecter) CreateApp(_a0 interface{}, _a1 interface{}) *AuthService_CreateApp_Call {
+	return &AuthService_CreateApp_Call{Call: _e.mock.On("CreateApp", _a0, _a1)}
+}
+
+func (_c *AuthService_CreateApp_Call) Run(run func(_a0 context.Context, _a1 domain.App)) *AuthService_CreateApp_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.App))
+	})
+	return _c
+}
+
+func (_c *AuthService_CreateApp_Call) Return(_a0 domain.App, _a1 error) *AuthService_CreateApp_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_CreateApp_Call) RunAndReturn(run func(context.Context, domain.App) (domain.App, error)) *AuthService_CreateApp_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateMachineClient provides a mock function with given fields: _a0, name, scopes
+func (_m *AuthService) CreateMachineClient(_a0 context.Context, name string, scopes []string) (domain.MachineClientSecret, error) {
+	ret := _m.Called(_a0, name, scopes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateMachineClient")
+	}
+
+	var r0 domain.MachineClientSecret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) (domain.MachineClientSecret, error)); ok {
+		return rf(_a0, name, scopes)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) domain.MachineClientSecret); ok {
+		r0 = rf(_a0, name, scopes)
+	} else {
+		r0 = ret.Get(0).(domain.MachineClientSecret)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, []string) error); ok {
+		r1 = rf(_a0, name, scopes)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_CreateMachineClient_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateMachineClient'
+type AuthService_CreateMachineClient_Call struct {
+	*mock.Call
+}
+
+// CreateMachineClient is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - name string
+//   - scopes []string
+func (_e *AuthService_Expecter) CreateMachineClient(_a0 interface{}, name interface{}, scopes interface{}) *AuthService_CreateMachineClient_Call {
+	return &AuthService_CreateMachineClient_Call{Call: _e.mock.On("CreateMachineClient", _a0, name, scopes)}
+}
+
+func (_c *AuthService_CreateMachineClient_Call) Run(run func(_a0 context.Context, name string, scopes []string)) *AuthService_CreateMachineClient_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]string))
+	})
+	return _c
+}
+
+func (_c *AuthService_CreateMachineClient_Call) Return(_a0 domain.MachineClientSecret, _a1 error) *AuthService_CreateMachineClient_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_CreateMachineClient_Call) RunAndReturn(run func(context.Context, string, []string) (domain.MachineClientSecret, error)) *AuthService_CreateMachineClient_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DisableApp provides a mock function with given fields: _a0, appID
+func (_m *AuthService) DisableApp(_a0 context.Context, appID int32) error {
+	ret := _m.Called(_a0, appID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DisableApp")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32) error); ok {
+		r0 = rf(_a0, appID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthService_DisableApp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DisableApp'
+type AuthService_DisableApp_Call struct {
+	*mock.Call
+}
+
+// DisableApp is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - appID int32
+func (_e *AuthService_Expecter) DisableApp(_a0 interface{}, appID interface{}) *AuthService_DisableApp_Call {
+	return &AuthService_DisableApp_Call{Call: _e.mock.On("DisableApp", _a0, appID)}
+}
+
+func (_c *AuthService_DisableApp_Call) Run(run func(_a0 context.Context, appID int32)) *AuthService_DisableApp_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int32))
+	})
+	return _c
+}
+
+func (_c *AuthService_DisableApp_Call) Return(_a0 error) *AuthService_DisableApp_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_DisableApp_Call) RunAndReturn(run func(context.Context, int32) error) *AuthService_DisableApp_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DisableMachineClient provides a mock function with given fields: _a0, clientID
+func (_m *AuthService) DisableMachineClient(_a0 context.Context, clientID string) error {
+	ret := _m.Called(_a0, clientID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DisableMachineClient")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, clientID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthService_DisableMachineClient_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DisableMachineClient'
+type AuthService_DisableMachineClient_Call struct {
+	*mock.Call
+}
+
+// DisableMachineClient is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - clientID string
+func (_e *AuthService_Expecter) DisableMachineClient(_a0 interface{}, clientID interface{}) *AuthService_DisableMachineClient_Call {
+	return &AuthService_DisableMachineClient_Call{Call: _e.mock.On("DisableMachineClient", _a0, clientID)}
+}
+
+func (_c *AuthService_DisableMachineClient_Call) Run(run func(_a0 context.Context, clientID string)) *AuthService_DisableMachineClient_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *AuthService_DisableMachineClient_Call) Return(_a0 error) *AuthService_DisableMachineClient_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_DisableMachineClient_Call) RunAndReturn(run func(context.Context, string) error) *AuthService_DisableMachineClient_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// EnableApp provides a mock function with given fields: _a0, appID
+func (_m *AuthService) EnableApp(_a0 context.Context, appID int32) error {
+	ret := _m.Called(_a0, appID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnableApp")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32) error); ok {
+		r0 = rf(_a0, appID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthService_EnableApp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EnableApp'
+type AuthService_EnableApp_Call struct {
+	*mock.Call
+}
+
+// EnableApp is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - appID int32
+func (_e *AuthService_Expecter) EnableApp(_a0 interface{}, appID interface{}) *AuthService_EnableApp_Call {
+	return &AuthService_EnableApp_Call{Call: _e.mock.On("EnableApp", _a0, appID)}
+}
+
+func (_c *AuthService_EnableApp_Call) Run(run func(_a0 context.Context, appID int32)) *AuthService_EnableApp_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int32))
+	})
+	return _c
+}
+
+func (_c *AuthService_EnableApp_Call) Return(_a0 error) *AuthService_EnableApp_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_EnableApp_Call) RunAndReturn(run func(context.Context, int32) error) *AuthService_EnableApp_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetApp provides a mock function with given fields: _a0, appID
+func (_m *AuthService) GetApp(_a0 context.Context, appID int32) (domain.App, error) {
+	ret := _m.Called(_a0, appID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetApp")
+	}
+
+	var r0 domain.App
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32) (domain.App, error)); ok {
+		return rf(_a0, appID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32) domain.App); ok {
+		r0 = rf(_a0, appID)
+	} else {
+		r0 = ret.Get(0).(domain.App)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32) error); ok {
+		r1 = rf(_a0, appID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_GetApp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetApp'
+type AuthService_GetApp_Call struct {
+	*mock.Call
+}
+
+// GetApp is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - appID int32
+func (_e *AuthService_Expecter) GetApp(_a0 interface{}, appID interface{}) *AuthService_GetApp_Call {
+	return &AuthService_GetApp_Call{Call: _e.mock.On("GetApp", _a0, appID)}
+}
+
+func (_c *AuthService_GetApp_Call) Run(run func(_a0 context.Context, appID int32)) *AuthService_GetApp_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int32))
+	})
+	return _c
+}
+
+func (_c *AuthService_GetApp_Call) Return(_a0 domain.App, _a1 error) *AuthService_GetApp_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_GetApp_Call) RunAndReturn(run func(context.Context, int32) (domain.App, error)) *AuthService_GetApp_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Introspect provides a mock function with given fields: _a0, token
+func (_m *AuthService) Introspect(_a0 context.Context, token string) (domain.Introspection, error) {
+	ret := _m.Called(_a0, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Introspect")
+	}
+
+	var r0 domain.Introspection
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.Introspection, error)); ok {
+		return rf(_a0, token)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Introspection); ok {
+		r0 = rf(_a0, token)
+	} else {
+		r0 = ret.Get(0).(domain.Introspection)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_Introspect_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Introspect'
+type AuthService_Introspect_Call struct {
+	*mock.Call
+}
+
+// Introspect is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - token string
+func (_e *AuthService_Expecter) Introspect(_a0 interface{}, token interface{}) *AuthService_Introspect_Call {
+	return &AuthService_Introspect_Call{Call: _e.mock.On("Introspect", _a0, token)}
+}
+
+func (_c *AuthService_Introspect_Call) Run(run func(_a0 context.Context, token string)) *AuthService_Introspect_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *AuthService_Introspect_Call) Return(_a0 domain.Introspection, _a1 error) *AuthService_Introspect_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_Introspect_Call) RunAndReturn(run func(context.Context, string) (domain.Introspection, error)) *AuthService_Introspect_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IsAccessTokenRevoked provides a mock function with given fields: _a0, jti
+func (_m *AuthService) IsAccessTokenRevoked(_a0 context.Context, jti string) (bool, error) {
+	ret := _m.Called(_a0, jti)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsAccessTokenRevoked")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(_a0, jti)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(_a0, jti)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, jti)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_IsAccessTokenRevoked_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IsAccessTokenRevoked'
+type AuthService_IsAccessTokenRevoked_Call struct {
+	*mock.Call
+}
+
+// IsAccessTokenRevoked is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - jti string
+func (_e *AuthService_Expecter) IsAccessTokenRevoked(_a0 interface{}, jti interface{}) *AuthService_IsAccessTokenRevoked_Call {
+	return &AuthService_IsAccessTokenRevoked_Call{Call: _e.mock.On("IsAccessTokenRevoked", _a0, jti)}
+}
+
+func (_c *AuthService_IsAccessTokenRevoked_Call) Run(run func(_a0 context.Context, jti string)) *AuthService_IsAccessTokenRevoked_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *AuthService_IsAccessTokenRevoked_Call) Return(_a0 bool, _a1 error) *AuthService_IsAccessTokenRevoked_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_IsAccessTokenRevoked_Call) RunAndReturn(run func(context.Context, string) (bool, error)) *AuthService_IsAccessTokenRevoked_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// JWKS provides a mock function with no fields
+func (_m *AuthService) JWKS() domain.JWKS {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for JWKS")
+	}
+
+	var r0 domain.JWKS
+	if rf, ok := ret.Get(0).(func() domain.JWKS); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(domain.JWKS)
+	}
+
+	return r0
+}
+
+// AuthService_JWKS_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'JWKS'
+type AuthService_JWKS_Call struct {
+	*mock.Call
+}
+
+// JWKS is a helper method to define mock.On call
+func (_e *AuthService_Expecter) JWKS() *AuthService_JWKS_Call {
+	return &AuthService_JWKS_Call{Call: _e.mock.On("JWKS")}
+}
+
+func (_c *AuthService_JWKS_Call) Run(run func()) *AuthService_JWKS_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *AuthService_JWKS_Call) Return(_a0 domain.JWKS) *AuthService_JWKS_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_JWKS_Call) RunAndReturn(run func() domain.JWKS) *AuthService_JWKS_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListApps provides a mock function with given fields: _a0
+func (_m *AuthService) ListApps(_a0 context.Context) ([]domain.App, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListApps")
+	}
+
+	var r0 []domain.App
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]domain.App, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []domain.App); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.App)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_ListApps_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListApps'
+type AuthService_ListApps_Call struct {
+	*mock.Call
+}
+
+// ListApps is a helper method to define mock.On call
+//   - _a0 context.Context
+func (_e *AuthService_Expecter) ListApps(_a0 interface{}) *AuthService_ListApps_Call {
+	return &AuthService_ListApps_Call{Call: _e.mock.On("ListApps", _a0)}
+}
+
+func (_c *AuthService_ListApps_Call) Run(run func(_a0 context.Context)) *AuthService_ListApps_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *AuthService_ListApps_Call) Return(_a0 []domain.App, _a1 error) *AuthService_ListApps_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_ListApps_Call) RunAndReturn(run func(context.Context) ([]domain.App, error)) *AuthService_ListApps_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Login provides a mock function with given fields: _a0, _a1, _a2
+func (_m *AuthService) Login(_a0 context.Context, _a1 domain.User, _a2 domain.DeviceCtx) (domain.LoginResult, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Login")
+	}
+
+	var r0 domain.LoginResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.User, domain.DeviceCtx) (domain.LoginResult, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.User, domain.DeviceCtx) domain.LoginResult); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(domain.LoginResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.User, domain.DeviceCtx) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_Login_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Login'
+type AuthService_Login_Call struct {
+	*mock.Call
+}
+
+// Login is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.User
+//   - _a2 domain.DeviceCtx
+func (_e *AuthService_Expecter) Login(_a0 interface{}, _a1 interface{}, _a2 interface{}) *AuthService_Login_Call {
+	return &AuthService_Login_Call{Call: _e.mock.On("Login", _a0, _a1, _a2)}
+}
+
+func (_c *AuthService_Login_Call) Run(run func(_a0 context.Context, _a1 domain.User, _a2 domain.DeviceCtx)) *AuthService_Login_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.User), args[2].(domain.DeviceCtx))
+	})
+	return _c
+}
+
+func (_c *AuthService_Login_Call) Return(_a0 domain.LoginResult, _a1 error) *AuthService_Login_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_Login_Call) RunAndReturn(run func(context.Context, domain.User, domain.DeviceCtx) (domain.LoginResult, error)) *AuthService_Login_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Logout provides a mock function with given fields: _a0, _a1, _a2
+func (_m *AuthService) Logout(_a0 context.Context, _a1 string, _a2 domain.DeviceCtx) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Logout")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthService_Logout_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Logout'
+type AuthService_Logout_Call struct {
+	*mock.Call
+}
+
+// Logout is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 string
+//   - _a2 domain.DeviceCtx
+func (_e *AuthService_Expecter) Logout(_a0 interface{}, _a1 interface{}, _a2 interface{}) *AuthService_Logout_Call {
+	return &AuthService_Logout_Call{Call: _e.mock.On("Logout", _a0, _a1, _a2)}
+}
+
+func (_c *AuthService_Logout_Call) Run(run func(_a0 context.Context, _a1 string, _a2 domain.DeviceCtx)) *AuthService_Logout_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(domain.DeviceCtx))
+	})
+	return _c
+}
+
+func (_c *AuthService_Logout_Call) Return(_a0 error) *AuthService_Logout_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_Logout_Call) RunAndReturn(run func(context.Context, string, domain.DeviceCtx) error) *AuthService_Logout_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LogoutAll provides a mock function with given fields: _a0, userID
+func (_m *AuthService) LogoutAll(_a0 context.Context, userID string) error {
+	ret := _m.Called(_a0, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LogoutAll")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthService_LogoutAll_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LogoutAll'
+type AuthService_LogoutAll_Call struct {
+	*mock.Call
+}
+
+// LogoutAll is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - userID string
+func (_e *AuthService_Expecter) LogoutAll(_a0 interface{}, userID interface{}) *AuthService_LogoutAll_Call {
+	return &AuthService_LogoutAll_Call{Call: _e.mock.On("LogoutAll", _a0, userID)}
+}
+
+func (_c *AuthService_LogoutAll_Call) Run(run func(_a0 context.Context, userID string)) *AuthService_LogoutAll_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *AuthService_LogoutAll_Call) Return(_a0 error) *AuthService_LogoutAll_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_LogoutAll_Call) RunAndReturn(run func(context.Context, string) error) *AuthService_LogoutAll_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Refresh provides a mock function with given fields: _a0, _a1, _a2
+func (_m *AuthService) Refresh(_a0 context.Context, _a1 string, _a2 domain.DeviceCtx) (domain.Token, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Refresh")
+	}
+
+	var r0 domain.Token
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx) (domain.Token, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx) domain.Token); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(domain.Token)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, domain.DeviceCtx) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_Refresh_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Refresh'
+type AuthService_Refresh_Call struct {
+	*mock.Call
+}
+
+// Refresh is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 string
+//   - _a2 domain.DeviceCtx
+func (_e *AuthService_Expecter) Refresh(_a0 interface{}, _a1 interface{}, _a2 interface{}) *AuthService_Refresh_Call {
+	return &AuthService_Refresh_Call{Call: _e.mock.On("Refresh", _a0, _a1, _a2)}
+}
+
+func (_c *AuthService_Refresh_Call) Run(run func(_a0 context.Context, _a1 string, _a2 domain.DeviceCtx)) *AuthService_Refresh_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(domain.DeviceCtx))
+	})
+	return _c
+}
+
+func (_c *AuthService_Refresh_Call) Return(_a0 domain.Token, _a1 error) *AuthService_Refresh_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_Refresh_Call) RunAndReturn(run func(context.Context, string, domain.DeviceCtx) (domain.Token, error)) *AuthService_Refresh_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Register provides a mock function with given fields: _a0, u, appID
+func (_m *AuthService) Register(_a0 context.Context, u domain.User, appID int32) (domain.User, error) {
+	ret := _m.Called(_a0, u, appID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Register")
+	}
+
+	var r0 domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.User, int32) (domain.User, error)); ok {
+		return rf(_a0, u, appID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.User, int32) domain.User); ok {
+		r0 = rf(_a0, u, appID)
+	} else {
+		r0 = ret.Get(0).(domain.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.User, int32) error); ok {
+		r1 = rf(_a0, u, appID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_Register_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Register'
+type AuthService_Register_Call struct {
+	*mock.Call
+}
+
+// Register is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - u domain.User
+//   - appID int32
+func (_e *AuthService_Expecter) Register(_a0 interface{}, u interface{}, appID interface{}) *AuthService_Register_Call {
+	return &AuthService_Register_Call{Call: _e.mock.On("Register", _a0, u, appID)}
+}
+
+func (_c *AuthService_Register_Call) Run(run func(_a0 context.Context, u domain.User, appID int32)) *AuthService_Register_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.User), args[2].(int32))
+	})
+	return _c
+}
+
+func (_c *AuthService_Register_Call) Return(_a0 domain.User, _a1 error) *AuthService_Register_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_Register_Call) RunAndReturn(run func(context.Context, domain.User, int32) (domain.User, error)) *AuthService_Register_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RegisterDevice provides a mock function with given fields: _a0, refresh, dctx, platform, name
+func (_m *AuthService) RegisterDevice(_a0 context.Context, refresh string, dctx domain.DeviceCtx, platform string, name string) (domain.Device, domain.Token, error) {
+	ret := _m.Called(_a0, refresh, dctx, platform, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RegisterDevice")
+	}
+
+	var r0 domain.Device
+	var r1 domain.Token
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx, string, string) (domain.Device, domain.Token, error)); ok {
+		return rf(_a0, refresh, dctx, platform, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.DeviceCtx, string, string) domain.Device); ok {
+		r0 = rf(_a0, refresh, dctx, platform, name)
+	} else {
+		r0 = ret.Get(0).(domain.Device)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, domain.DeviceCtx, string, string) domain.Token); ok {
+		r1 = rf(_a0, refresh, dctx, platform, name)
+	} else {
+		r1 = ret.Get(1).(domain.Token)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, domain.DeviceCtx, string, string) error); ok {
+		r2 = rf(_a0, refresh, dctx, platform, name)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// AuthService_RegisterDevice_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RegisterDevice'
+type AuthService_RegisterDevice_Call struct {
+	*mock.Call
+}
+
+// RegisterDevice is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - refresh string
+//   - dctx domain.DeviceCtx
+//   - platform string
+//   - name string
+func (_e *AuthService_Expecter) RegisterDevice(_a0 interface{}, refresh interface{}, dctx interface{}, platform interface{}, name interface{}) *AuthService_RegisterDevice_Call {
+	return &AuthService_RegisterDevice_Call{Call: _e.mock.On("RegisterDevice", _a0, refresh, dctx, platform, name)}
+}
+
+func (_c *AuthService_RegisterDevice_Call) Run(run func(_a0 context.Context, refresh string, dctx domain.DeviceCtx, platform string, name string)) *AuthService_RegisterDevice_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(domain.DeviceCtx), args[3].(string), args[4].(string))
+	})
+	return _c
+}
+
+func (_c *AuthService_RegisterDevice_Call) Return(_a0 domain.Device, _a1 domain.Token, _a2 error) *AuthService_RegisterDevice_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *AuthService_RegisterDevice_Call) RunAndReturn(run func(context.Context, string, domain.DeviceCtx, string, string) (domain.Device, domain.Token, error)) *AuthService_RegisterDevice_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RequestPasswordReset provides a mock function with given fields: _a0, email
+func (_m *AuthService) RequestPasswordReset(_a0 context.Context, email string) error {
+	ret := _m.Called(_a0, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequestPasswordReset")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthService_RequestPasswordReset_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RequestPasswordReset'
+type AuthService_RequestPasswordReset_Call struct {
+	*mock.Call
+}
+
+// RequestPasswordReset is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - email string
+func (_e *AuthService_Expecter) RequestPasswordReset(_a0 interface{}, email interface{}) *AuthService_RequestPasswordReset_Call {
+	return &AuthService_RequestPasswordReset_Call{Call: _e.mock.On("RequestPasswordReset", _a0, email)}
+}
+
+func (_c *AuthService_RequestPasswordReset_Call) Run(run func(_a0 context.Context, email string)) *AuthService_RequestPasswordReset_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *AuthService_RequestPasswordReset_Call) Return(_a0 error) *AuthService_RequestPasswordReset_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_RequestPasswordReset_Call) RunAndReturn(run func(context.Context, string) error) *AuthService_RequestPasswordReset_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResendEmailVerification provides a mock function with given fields: _a0, email
+func (_m *AuthService) ResendEmailVerification(_a0 context.Context, email string) error {
+	ret := _m.Called(_a0, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResendEmailVerification")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthService_ResendEmailVerification_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResendEmailVerification'
+type AuthService_ResendEmailVerification_Call struct {
+	*mock.Call
+}
+
+// ResendEmailVerification is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - email string
+func (_e *AuthService_Expecter) ResendEmailVerification(_a0 interface{}, email interface{}) *AuthService_ResendEmailVerification_Call {
+	return &AuthService_ResendEmailVerification_Call{Call: _e.mock.On("ResendEmailVerification", _a0, email)}
+}
+
+func (_c *AuthService_ResendEmailVerification_Call) Run(run func(_a0 context.Context, email string)) *AuthService_ResendEmailVerification_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *AuthService_ResendEmailVerification_Call) Return(_a0 error) *AuthService_ResendEmailVerification_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_ResendEmailVerification_Call) RunAndReturn(run func(context.Context, string) error) *AuthService_ResendEmailVerification_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RotateMachineClientSecret provides a mock function with given fields: _a0, clientID
+func (_m *AuthService) RotateMachineClientSecret(_a0 context.Context, clientID string) (domain.MachineClientSecret, error) {
+	ret := _m.Called(_a0, clientID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateMachineClientSecret")
+	}
+
+	var r0 domain.MachineClientSecret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.MachineClientSecret, error)); ok {
+		return rf(_a0, clientID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.MachineClientSecret); ok {
+		r0 = rf(_a0, clientID)
+	} else {
+		r0 = ret.Get(0).(domain.MachineClientSecret)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, clientID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_RotateMachineClientSecret_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RotateMachineClientSecret'
+type AuthService_RotateMachineClientSecret_Call struct {
+	*mock.Call
+}
+
+// RotateMachineClientSecret is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - clientID string
+func (_e *AuthService_Expecter) RotateMachineClientSecret(_a0 interface{}, clientID interface{}) *AuthService_RotateMachineClientSecret_Call {
+	return &AuthService_RotateMachineClientSecret_Call{Call: _e.mock.On("RotateMachineClientSecret", _a0, clientID)}
+}
+
+func (_c *AuthService_RotateMachineClientSecret_Call) Run(run func(_a0 context.Context, clientID string)) *AuthService_RotateMachineClientSecret_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *AuthService_RotateMachineClientSecret_Call) Return(_a0 domain.MachineClientSecret, _a1 error) *AuthService_RotateMachineClientSecret_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_RotateMachineClientSecret_Call) RunAndReturn(run func(context.Context, string) (domain.MachineClientSecret, error)) *AuthService_RotateMachineClientSecret_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// StartPasswordlessLogin provides a mock function with given fields: _a0, email, method, dctx
+func (_m *AuthService) StartPasswordlessLogin(_a0 context.Context, email string, method domain.PasswordlessMethod, dctx domain.DeviceCtx) (domain.PasswordlessLogin, error) {
+	ret := _m.Called(_a0, email, method, dctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StartPasswordlessLogin")
+	}
+
+	var r0 domain.PasswordlessLogin
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.PasswordlessMethod, domain.DeviceCtx) (domain.PasswordlessLogin, error)); ok {
+		return rf(_a0, email, method, dctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.PasswordlessMethod, domain.DeviceCtx) domain.PasswordlessLogin); ok {
+		r0 = rf(_a0, email, method, dctx)
+	} else {
+		r0 = ret.Get(0).(domain.PasswordlessLogin)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, domain.PasswordlessMethod, domain.DeviceCtx) error); ok {
+		r1 = rf(_a0, email, method, dctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_StartPasswordlessLogin_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StartPasswordlessLogin'
+type AuthService_StartPasswordlessLogin_Call struct {
+	*mock.Call
+}
+
+// StartPasswordlessLogin is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - email string
+//   - method domain.PasswordlessMethod
+//   - dctx domain.DeviceCtx
+func (_e *AuthService_Expecter) StartPasswordlessLogin(_a0 interface{}, email interface{}, method interface{}, dctx interface{}) *AuthService_StartPasswordlessLogin_Call {
+	return &AuthService_StartPasswordlessLogin_Call{Call: _e.mock.On("StartPasswordlessLogin", _a0, email, method, dctx)}
+}
+
+func (_c *AuthService_StartPasswordlessLogin_Call) Run(run func(_a0 context.Context, email string, method domain.PasswordlessMethod, dctx domain.DeviceCtx)) *AuthService_StartPasswordlessLogin_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(domain.PasswordlessMethod), args[3].(domain.DeviceCtx))
+	})
+	return _c
+}
+
+func (_c *AuthService_StartPasswordlessLogin_Call) Return(_a0 domain.PasswordlessLogin, _a1 error) *AuthService_StartPasswordlessLogin_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_StartPasswordlessLogin_Call) RunAndReturn(run func(context.Context, string, domain.PasswordlessMethod, domain.DeviceCtx) (domain.PasswordlessLogin, error)) *AuthService_StartPasswordlessLogin_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateApp provides a mock function with given fields: _a0, _a1
+func (_m *AuthService) UpdateApp(_a0 context.Context, _a1 domain.App) (domain.App, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateApp")
+	}
+
+	var r0 domain.App
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.App) (domain.App, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.App) domain.App); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(domain.App)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.App) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthService_UpdateApp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateApp'
+type AuthService_UpdateApp_Call struct {
+	*mock.Call
+}
+
+// UpdateApp is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.App
+func (_e *AuthService_Expecter) UpdateApp(_a0 interface{}, _a1 interface{}) *AuthService_UpdateApp_Call {
+	return &AuthService_UpdateApp_Call{Call: _e.mock.On("UpdateApp", _a0, _a1)}
+}
+
+func (_c *AuthService_UpdateApp_Call) Run(run func(_a0 context.Context, _a1 domain.App)) *AuthService_UpdateApp_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.App))
+	})
+	return _c
+}
+
+func (_c *AuthService_UpdateApp_Call) Return(_a0 domain.App, _a1 error) *AuthService_UpdateApp_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthService_UpdateApp_Call) RunAndReturn(run func(context.Context, domain.App) (domain.App, error)) *AuthService_UpdateApp_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// VerifyEmail provides a mock function with given fields: _a0, token
+func (_m *AuthService) VerifyEmail(_a0 context.Context, token string) error {
+	ret := _m.Called(_a0, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyEmail")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthService_VerifyEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'VerifyEmail'
+type AuthService_VerifyEmail_Call struct {
+	*mock.Call
+}
+
+// VerifyEmail is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - token string
+func (_e *AuthService_Expecter) VerifyEmail(_a0 interface{}, token interface{}) *AuthService_VerifyEmail_Call {
+	return &AuthService_VerifyEmail_Call{Call: _e.mock.On("VerifyEmail", _a0, token)}
+}
+
+func (_c *AuthService_VerifyEmail_Call) Run(run func(_a0 context.Context, token string)) *AuthService_VerifyEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *AuthService_VerifyEmail_Call) Return(_a0 error) *AuthService_VerifyEmail_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthService_VerifyEmail_Call) RunAndReturn(run func(context.Context, string) error) *AuthService_VerifyEmail_Call {
 	_c.Call.Return(run)
 	return _c
 }
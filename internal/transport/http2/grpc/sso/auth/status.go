@@ -0,0 +1,45 @@
+package grpctransportauth
+
+import (
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// поле запроса, к которому относятся нарушения политики паролей
+const passwordField = "password"
+
+// lockoutStatus — ResourceExhausted с RetryInfo, чтобы клиент знал, когда повторить
+func lockoutStatus(retryAfter time.Duration) error {
+	st := status.New(codes.ResourceExhausted, ErrTooManyAttempts)
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(retryAfter),
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// passwordPolicyStatus — InvalidArgument с BadRequest: по нарушению на каждое правило.
+// Description — "<правило>: <описание>", чтобы клиент мог сопоставить правило без разбора текста
+func passwordPolicyStatus(e *domain.PasswordPolicyError) error {
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       passwordField,
+			Description: v.Rule + ": " + v.Message,
+		})
+	}
+
+	st := status.New(codes.InvalidArgument, ErrWeakPassword)
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
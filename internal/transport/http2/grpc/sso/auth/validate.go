@@ -61,6 +61,190 @@ func reqToInternalValidateStruct(v any) (any, error) {
 			DeviceCtxValidation: newDeviceCtxTovalidate(t.Ctx),
 		}, nil
 
+	case *sso.LogoutAllRequest:
+		return LogoutAllReqValidation{
+			UserId: t.UserId,
+		}, nil
+
+	case *sso.ChangePasswordRequest:
+		if t.Ctx == nil {
+			return nil, errors.New("device context is required")
+		}
+		return ChangePasswordReqValidation{
+			RefreshTokenValidate: RefreshTokenValidate{
+				Refresh: t.Refresh,
+			},
+			DeviceCtxValidation: newDeviceCtxTovalidate(t.Ctx),
+			OldPassword:         t.OldPassword,
+			NewPassword:         t.NewPassword,
+		}, nil
+
+	case *sso.RequestPasswordResetRequest:
+		return RequestPasswordResetReqValidation{
+			Email: t.Email,
+		}, nil
+
+	case *sso.ConfirmPasswordResetRequest:
+		return ConfirmPasswordResetReqValidation{
+			Token:       t.Token,
+			NewPassword: t.NewPassword,
+		}, nil
+
+	case *sso.VerifyEmailRequest:
+		return VerifyEmailReqValidation{
+			Token: t.Token,
+		}, nil
+
+	case *sso.ResendEmailVerificationRequest:
+		return ResendEmailVerificationReqValidation{
+			Email: t.Email,
+		}, nil
+
+	case *sso.BeginTotpEnrollmentRequest:
+		if t.Ctx == nil {
+			return nil, errors.New("device context is required")
+		}
+		return BeginTotpEnrollmentReqValidation{
+			RefreshTokenValidate: RefreshTokenValidate{
+				Refresh: t.Refresh,
+			},
+			DeviceCtxValidation: newDeviceCtxTovalidate(t.Ctx),
+		}, nil
+
+	case *sso.ConfirmTotpEnrollmentRequest:
+		if t.Ctx == nil {
+			return nil, errors.New("device context is required")
+		}
+		return ConfirmTotpEnrollmentReqValidation{
+			RefreshTokenValidate: RefreshTokenValidate{
+				Refresh: t.Refresh,
+			},
+			DeviceCtxValidation: newDeviceCtxTovalidate(t.Ctx),
+			TotpCodeValidation:  TotpCodeValidation{Code: t.Code},
+		}, nil
+
+	case *sso.CompleteMfaLoginRequest:
+		if t.Ctx == nil {
+			return nil, errors.New("device context is required")
+		}
+		return CompleteMfaLoginReqValidation{
+			Challenge:           t.Challenge,
+			TotpCodeValidation:  TotpCodeValidation{Code: t.Code},
+			DeviceCtxValidation: newDeviceCtxTovalidate(t.Ctx),
+		}, nil
+
+	case *sso.RegisterDeviceRequest:
+		if t.Ctx == nil {
+			return nil, errors.New("device context is required")
+		}
+		return RegisterDeviceReqValidation{
+			RefreshTokenValidate: RefreshTokenValidate{
+				Refresh: t.Refresh,
+			},
+			DeviceCtxValidation: newDeviceCtxTovalidate(t.Ctx),
+			Platform:            t.Platform,
+			Name:                t.Name,
+		}, nil
+
+	case *sso.BeginFederatedLoginRequest:
+		if t.Ctx == nil {
+			return nil, errors.New("device context is required")
+		}
+		return BeginFederatedLoginReqValidation{
+			Provider:            t.Provider,
+			DeviceCtxValidation: newDeviceCtxTovalidate(t.Ctx),
+		}, nil
+
+	case *sso.CompleteFederatedLoginRequest:
+		if t.Ctx == nil {
+			return nil, errors.New("device context is required")
+		}
+		return CompleteFederatedLoginReqValidation{
+			Provider:            t.Provider,
+			State:               t.State,
+			Code:                t.Code,
+			DeviceCtxValidation: newDeviceCtxTovalidate(t.Ctx),
+		}, nil
+
+	case *sso.StartPasswordlessLoginRequest:
+		if t.Ctx == nil {
+			return nil, errors.New("device context is required")
+		}
+		return StartPasswordlessLoginReqValidation{
+			Email:               t.Email,
+			Method:              t.Method,
+			DeviceCtxValidation: newDeviceCtxTovalidate(t.Ctx),
+		}, nil
+
+	case *sso.CompletePasswordlessLoginRequest:
+		if t.Ctx == nil {
+			return nil, errors.New("device context is required")
+		}
+		return CompletePasswordlessLoginReqValidation{
+			Challenge:           t.Challenge,
+			Code:                t.Code,
+			DeviceCtxValidation: newDeviceCtxTovalidate(t.Ctx),
+		}, nil
+
+	case *sso.ClearLoginLockoutRequest:
+		return ClearLoginLockoutReqValidation{
+			Email: t.Email,
+			Ip:    t.Ip,
+		}, nil
+
+	case *sso.IntrospectRequest:
+		return IntrospectReqValidation{
+			Token: t.Token,
+		}, nil
+
+	case *sso.IsAccessTokenRevokedRequest:
+		return IsAccessTokenRevokedReqValidation{
+			Jti: t.Jti,
+		}, nil
+
+	case *sso.CreateMachineClientRequest:
+		return CreateMachineClientReqValidation{
+			Name:   t.Name,
+			Scopes: t.Scopes,
+		}, nil
+
+	case *sso.RotateMachineClientSecretRequest:
+		return MachineClientIDValidation{
+			ClientId: t.ClientId,
+		}, nil
+
+	case *sso.DisableMachineClientRequest:
+		return MachineClientIDValidation{
+			ClientId: t.ClientId,
+		}, nil
+
+	case *sso.CreateAppRequest:
+		if t.App == nil {
+			return nil, errors.New("app is required")
+		}
+		return newAppToValidate(t.App), nil
+
+	case *sso.UpdateAppRequest:
+		if t.App == nil {
+			return nil, errors.New("app is required")
+		}
+		return newAppToValidate(t.App), nil
+
+	case *sso.GetAppRequest:
+		return AppIDValidation{
+			AppId: t.AppId,
+		}, nil
+
+	case *sso.DisableAppRequest:
+		return AppIDValidation{
+			AppId: t.AppId,
+		}, nil
+
+	case *sso.EnableAppRequest:
+		return AppIDValidation{
+			AppId: t.AppId,
+		}, nil
+
 	default:
 		return nil, errors.New("bad request type")
 	}
@@ -79,3 +263,14 @@ func newDeviceCtxTovalidate(ctx *sso.DeviceContext) DeviceCtxValidation {
 		DeviceId: ctx.DeviceId,
 	}
 }
+
+func newAppToValidate(a *sso.App) AppValidation {
+	return AppValidation{
+		Id:           a.Id,
+		Name:         a.Name,
+		GrantTypes:   a.GrantTypes,
+		EmailDomains: a.EmailDomains,
+		RedirectUris: a.RedirectUris,
+		Scopes:       a.Scopes,
+	}
+}
@@ -0,0 +1,123 @@
+package grpctransportauthz
+
+import (
+	"context"
+	"slices"
+
+	"github.com/eragon-mdi/protos/gen/go/sso/v1"
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/eragon-mdi/sso/pkg/ssoverify"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//go:generate mockery --name=Introspector --with-expecter --output=./mocks --exported
+type Introspector interface {
+	Introspect(_ context.Context, token string) (domain.Introspection, error)
+}
+
+const (
+	ErrInactiveToken    = "access token is invalid, expired or revoked"
+	ErrFailedIntrospect = "failed to check access token"
+	ErrNoBearer         = "missing bearer token"
+	ErrForeignUser      = "user_id of another user requires role admin"
+)
+
+// AdminMethods — RPC администратора: нужен access-токен пользователя с ролью admin
+var AdminMethods = []string{
+	sso.Auth_ClearLoginLockout_FullMethodName,
+	sso.Auth_CreateMachineClient_FullMethodName,
+	sso.Auth_RotateMachineClientSecret_FullMethodName,
+	sso.Auth_DisableMachineClient_FullMethodName,
+	sso.Auth_CreateApp_FullMethodName,
+	sso.Auth_UpdateApp_FullMethodName,
+	sso.Auth_GetApp_FullMethodName,
+	sso.Auth_ListApps_FullMethodName,
+	sso.Auth_DisableApp_FullMethodName,
+	sso.Auth_EnableApp_FullMethodName,
+}
+
+// OwnerMethods — RPC над аккаунтом user_id из запроса: нужен access-токен этого же пользователя
+// (поддержка — access-токен с ролью admin)
+var OwnerMethods = []string{
+	sso.Auth_LogoutAll_FullMethodName,
+	sso.Sessions_ListSessions_FullMethodName,
+	sso.Sessions_RevokeSession_FullMethodName,
+}
+
+// userScoped — запрос OwnerMethods
+type userScoped interface {
+	GetUserId() string
+}
+
+type authz struct {
+	s Introspector
+	l *zap.SugaredLogger
+}
+
+// UnaryInterceptors — сначала кто вызывает (Principal из bearer-токена), затем роль admin для AdminMethods
+// и владелец user_id для OwnerMethods
+func UnaryInterceptors(s Introspector, l *zap.SugaredLogger) []grpc.UnaryServerInterceptor {
+	a := authz{s: s, l: l}
+	return []grpc.UnaryServerInterceptor{
+		a.authenticate,
+		ssoverify.RequireRole(domain.RoleAdmin, AdminMethods...),
+		requireOwner(OwnerMethods...),
+	}
+}
+
+// authenticate — "authorization: Bearer <access>" необязателен: без него вызов анонимный (Login, Register и т.п.).
+// Присланный токен проверяется интроспекцией — с отзывом, живой сессией и текущими ролями, а не ролями на момент выдачи
+func (a authz) authenticate(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	token, ok := ssoverify.BearerToken(ctx)
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	i, err := a.s.Introspect(ctx, token)
+	if err != nil {
+		a.l.Errorw(ErrFailedIntrospect, err)
+		return nil, status.Error(codes.Unavailable, ErrFailedIntrospect)
+	}
+	if !i.Active || i.Type != domain.TokenTypeAccess {
+		return nil, status.Error(codes.Unauthenticated, ErrInactiveToken)
+	}
+
+	return handler(ssoverify.ContextWithPrincipal(ctx, principal(i)), req)
+}
+
+// requireOwner — user_id запроса должен совпадать с владельцем access-токена; admin может указать любой.
+// Нет Principal — Unauthenticated, чужой user_id — PermissionDenied
+func requireOwner(methods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !slices.Contains(methods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		p, ok := ssoverify.PrincipalFromContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, ErrNoBearer)
+		}
+		r, ok := req.(userScoped)
+		if !ok || (r.GetUserId() != p.UserID && !p.HasRole(domain.RoleAdmin)) {
+			return nil, status.Error(codes.PermissionDenied, ErrForeignUser)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func principal(i domain.Introspection) ssoverify.Principal {
+	return ssoverify.Principal{
+		UserID:    i.UserID,
+		ClientID:  i.ClientID,
+		SessionID: i.SessionID,
+		TokenID:   i.ID,
+		AppID:     i.Ctx.AppId,
+		DeviceID:  i.Ctx.DeviceID,
+		Roles:     i.Roles,
+		Scopes:    i.Scopes,
+		ExpiresAt: i.Exp,
+	}
+}
@@ -0,0 +1,138 @@
+package grpctransportauthz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eragon-mdi/protos/gen/go/sso/v1"
+	"github.com/eragon-mdi/sso/internal/domain"
+	mocks "github.com/eragon-mdi/sso/internal/transport/http2/grpc/sso/authz/mocks"
+	"github.com/eragon-mdi/sso/pkg/ssoverify"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// call — вызов method через цепочку UnaryInterceptors; хендлер возвращает Principal из контекста
+func call(s Introspector, ctx context.Context, method string) (ssoverify.Principal, error) {
+	return callWith(s, ctx, method, nil)
+}
+
+func callWith(s Introspector, ctx context.Context, method string, req any) (ssoverify.Principal, error) {
+	chain := UnaryInterceptors(s, zap.NewNop().Sugar())
+	handler := func(ctx context.Context, _ any) (any, error) {
+		p, _ := ssoverify.PrincipalFromContext(ctx)
+		return p, nil
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		ic, next := chain[i], handler
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		handler = func(ctx context.Context, req any) (any, error) { return ic(ctx, req, info, next) }
+	}
+
+	out, err := handler(ctx, req)
+	if err != nil {
+		return ssoverify.Principal{}, err
+	}
+	return out.(ssoverify.Principal), nil
+}
+
+func withBearer(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	introspector := func() *mocks.Introspector {
+		s := &mocks.Introspector{}
+		s.On("Introspect", mock.Anything, "admin").Return(domain.Introspection{
+			Active: true, Type: domain.TokenTypeAccess, UserID: "root", Roles: []string{domain.RoleAdmin},
+		}, nil).Maybe()
+		s.On("Introspect", mock.Anything, "user").Return(domain.Introspection{
+			Active: true, Type: domain.TokenTypeAccess, UserID: "u1", SessionID: "sid", Ctx: domain.NewDeviceCtx(1, 2),
+		}, nil).Maybe()
+		s.On("Introspect", mock.Anything, "refresh").Return(domain.Introspection{
+			Active: true, Type: domain.TokenTypeRefresh, UserID: "u1",
+		}, nil).Maybe()
+		s.On("Introspect", mock.Anything, "revoked").Return(domain.Introspection{}, nil).Maybe()
+		s.On("Introspect", mock.Anything, "down").Return(domain.Introspection{}, errors.New("redis down")).Maybe()
+		return s
+	}
+
+	t.Run("anonymous call to public method", func(t *testing.T) {
+		p, err := call(introspector(), context.Background(), sso.Auth_Login_FullMethodName)
+		require.NoError(t, err)
+		require.Empty(t, p.UserID)
+	})
+
+	t.Run("principal from active access token", func(t *testing.T) {
+		p, err := call(introspector(), withBearer("user"), sso.Auth_Login_FullMethodName)
+		require.NoError(t, err)
+		require.Equal(t, "u1", p.UserID)
+		require.Equal(t, "sid", p.SessionID)
+		require.Equal(t, int32(2), p.DeviceID)
+	})
+
+	t.Run("sent token must be active access", func(t *testing.T) {
+		for _, token := range []string{"refresh", "revoked"} {
+			_, err := call(introspector(), withBearer(token), sso.Auth_Login_FullMethodName)
+			require.Equal(t, codes.Unauthenticated, status.Code(err), token)
+		}
+		_, err := call(introspector(), withBearer("down"), sso.Auth_Login_FullMethodName)
+		require.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("admin methods", func(t *testing.T) {
+		for _, method := range AdminMethods {
+			_, err := call(introspector(), context.Background(), method)
+			require.Equal(t, codes.Unauthenticated, status.Code(err), method)
+
+			_, err = call(introspector(), withBearer("user"), method)
+			require.Equal(t, codes.PermissionDenied, status.Code(err), method)
+
+			p, err := call(introspector(), withBearer("admin"), method)
+			require.NoError(t, err, method)
+			require.Equal(t, "root", p.UserID)
+		}
+	})
+
+	t.Run("owner methods", func(t *testing.T) {
+		own := map[string]any{
+			sso.Auth_LogoutAll_FullMethodName:         &sso.LogoutAllRequest{UserId: "u1"},
+			sso.Sessions_ListSessions_FullMethodName:  &sso.ListSessionsRequest{UserId: "u1"},
+			sso.Sessions_RevokeSession_FullMethodName: &sso.RevokeSessionRequest{UserId: "u1", SessionId: "sid"},
+		}
+		foreign := map[string]any{
+			sso.Auth_LogoutAll_FullMethodName:         &sso.LogoutAllRequest{UserId: "u2"},
+			sso.Sessions_ListSessions_FullMethodName:  &sso.ListSessionsRequest{UserId: "u2"},
+			sso.Sessions_RevokeSession_FullMethodName: &sso.RevokeSessionRequest{UserId: "u2", SessionId: "sid"},
+		}
+		for _, method := range OwnerMethods {
+			_, err := callWith(introspector(), context.Background(), method, own[method])
+			require.Equal(t, codes.Unauthenticated, status.Code(err), method)
+
+			p, err := callWith(introspector(), withBearer("user"), method, own[method])
+			require.NoError(t, err, method)
+			require.Equal(t, "u1", p.UserID)
+
+			_, err = callWith(introspector(), withBearer("user"), method, foreign[method])
+			require.Equal(t, codes.PermissionDenied, status.Code(err), method)
+
+			_, err = callWith(introspector(), withBearer("admin"), method, foreign[method])
+			require.NoError(t, err, method)
+		}
+	})
+	t.Run("foreign user_id in session methods is rejected", func(t *testing.T) {
+		s := introspector()
+		_, err := callWith(s, withBearer("user"), sso.Sessions_ListSessions_FullMethodName, &sso.ListSessionsRequest{UserId: "u2"})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = callWith(s, withBearer("user"), sso.Sessions_RevokeSession_FullMethodName,
+			&sso.RevokeSessionRequest{UserId: "u2", SessionId: "sid-of-u2"})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
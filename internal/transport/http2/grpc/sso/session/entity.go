@@ -0,0 +1,31 @@
+package grpctransportsession
+
+import (
+	"github.com/eragon-mdi/protos/gen/go/sso/v1"
+	"github.com/eragon-mdi/sso/internal/domain"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type ListSessionsReqValidation struct {
+	UserId string `validate:"required,uuid4"`
+}
+
+type RevokeSessionReqValidation struct {
+	UserId    string `validate:"required,uuid4"`
+	SessionId string `validate:"required,uuid4"`
+}
+
+func sessionsResponse(sessions []domain.Session) []*sso.Session {
+	resp := make([]*sso.Session, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, &sso.Session{
+			SessionId:       s.ID,
+			AppId:           s.Ctx.AppId,
+			DeviceId:        s.Ctx.DeviceID,
+			CreatedAt:       timestamppb.New(s.CreatedAt),
+			LastRefreshedAt: timestamppb.New(s.LastRefreshedAt),
+			ExpiresAt:       timestamppb.New(s.ExpiresAt),
+		})
+	}
+	return resp
+}
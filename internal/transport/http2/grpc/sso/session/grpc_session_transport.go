@@ -0,0 +1,20 @@
+package grpctransportsession
+
+import (
+	"github.com/eragon-mdi/protos/gen/go/sso/v1"
+	"github.com/eragon-mdi/sso/internal/common/api"
+	"go.uber.org/zap"
+)
+
+type sessionTransport struct {
+	s SessionService
+	l *zap.SugaredLogger
+	sso.UnimplementedSessionsServer
+}
+
+func New(s SessionService, l *zap.SugaredLogger) api.SessionTransport {
+	return &sessionTransport{
+		s: s,
+		l: l,
+	}
+}
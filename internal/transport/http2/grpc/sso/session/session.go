@@ -0,0 +1,60 @@
+package grpctransportsession
+
+import (
+	"context"
+	"errors"
+
+	"github.com/eragon-mdi/protos/gen/go/sso/v1"
+	"github.com/eragon-mdi/sso/internal/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+//go:generate mockery --name=SessionService --with-expecter --output=./mocks --exported
+type SessionService interface {
+	ListSessions(_ context.Context, userID string) ([]domain.Session, error)
+	RevokeSession(_ context.Context, userID, sessionID string) error
+}
+
+const (
+	ErrFailedValidateReq      = "failed to validate request"
+	ErrFailedListSessionsReq  = "failed to list user sessions"
+	ErrFailedRevokeSessionReq = "failed to revoke session"
+)
+
+// ListSessions и RevokeSession — user_id уже сверен с владельцем access-токена (grpctransportauthz.OwnerMethods)
+func (t sessionTransport) ListSessions(ctx context.Context, req *sso.ListSessionsRequest) (*sso.ListSessionsResponse, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	sessions, err := t.s.ListSessions(ctx, req.UserId)
+	if err != nil {
+		t.l.Errorw(ErrFailedListSessionsReq, err)
+		return nil, status.Error(codes.Internal, ErrFailedListSessionsReq)
+	}
+
+	return &sso.ListSessionsResponse{
+		Sessions: sessionsResponse(sessions),
+	}, nil
+}
+
+func (t sessionTransport) RevokeSession(ctx context.Context, req *sso.RevokeSessionRequest) (*emptypb.Empty, error) {
+	if err := validate(req); err != nil {
+		t.l.Errorw(ErrFailedValidateReq, err)
+		return nil, status.Error(codes.InvalidArgument, ErrFailedValidateReq)
+	}
+
+	if err := t.s.RevokeSession(ctx, req.UserId, req.SessionId); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			t.l.Errorw(ErrFailedRevokeSessionReq, err)
+			return nil, status.Error(codes.NotFound, ErrFailedRevokeSessionReq)
+		}
+		t.l.Errorw(ErrFailedRevokeSessionReq, err)
+		return nil, status.Error(codes.Internal, ErrFailedRevokeSessionReq)
+	}
+
+	return &emptypb.Empty{}, nil
+}
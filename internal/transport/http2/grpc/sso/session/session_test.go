@@ -0,0 +1,100 @@
+package grpctransportsession
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eragon-mdi/protos/gen/go/sso/v1"
+	"github.com/eragon-mdi/sso/internal/domain"
+	mocks "github.com/eragon-mdi/sso/internal/transport/http2/grpc/sso/session/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testUserID    = "0b8e6a3c-1f1e-4d51-9c4c-2a6a5b1f0e11"
+	testSessionID = "6f2b3f5e-8c1d-4a8e-9b7a-3c2d1e0f9a8b"
+)
+
+func TestSessionTransport_ListSessions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("service internal error", func(t *testing.T) {
+		s := &mocks.SessionService{}
+		s.On("ListSessions", mock.Anything, testUserID).Return(nil, errors.New("boom"))
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.ListSessions(ctx, &sso.ListSessionsRequest{UserId: testUserID})
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Internal, st.Code())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		now := time.Now()
+		s := &mocks.SessionService{}
+		s.On("ListSessions", mock.Anything, testUserID).Return([]domain.Session{{
+			ID:              testSessionID,
+			UserID:          testUserID,
+			Ctx:             domain.NewDeviceCtx(1, 2),
+			CreatedAt:       now.Add(-time.Hour),
+			LastRefreshedAt: now,
+			ExpiresAt:       now.Add(time.Hour),
+		}}, nil)
+
+		srv := New(s, zap.NewNop().Sugar())
+		resp, err := srv.ListSessions(ctx, &sso.ListSessionsRequest{UserId: testUserID})
+		require.NoError(t, err)
+		require.Len(t, resp.Sessions, 1)
+		require.Equal(t, testSessionID, resp.Sessions[0].SessionId)
+		require.Equal(t, int32(1), resp.Sessions[0].AppId)
+		require.Equal(t, int32(2), resp.Sessions[0].DeviceId)
+		require.Equal(t, now.Unix(), resp.Sessions[0].LastRefreshedAt.AsTime().Unix())
+		s.AssertExpectations(t)
+	})
+}
+
+func TestSessionTransport_RevokeSession(t *testing.T) {
+	ctx := context.Background()
+	req := &sso.RevokeSessionRequest{UserId: testUserID, SessionId: testSessionID}
+
+	t.Run("not found", func(t *testing.T) {
+		s := &mocks.SessionService{}
+		s.On("RevokeSession", mock.Anything, testUserID, testSessionID).Return(domain.ErrNotFound)
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.RevokeSession(ctx, req)
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.NotFound, st.Code())
+	})
+
+	t.Run("service internal error", func(t *testing.T) {
+		s := &mocks.SessionService{}
+		s.On("RevokeSession", mock.Anything, testUserID, testSessionID).Return(errors.New("boom"))
+
+		srv := New(s, zap.NewNop().Sugar())
+		_, err := srv.RevokeSession(ctx, req)
+		st, _ := status.FromError(err)
+		require.Equal(t, codes.Internal, st.Code())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		s := &mocks.SessionService{}
+		s.On("RevokeSession", mock.Anything, testUserID, testSessionID).Return(nil)
+
+		srv := New(s, zap.NewNop().Sugar())
+		resp, err := srv.RevokeSession(ctx, req)
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+	})
+}
+
+func TestValidate_RequestStructs(t *testing.T) {
+	err := validate("string")
+	require.Error(t, err)
+	require.Equal(t, "bad request", err.Error())
+}
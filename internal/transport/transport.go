@@ -2,24 +2,48 @@ package transport
 
 import (
 	"github.com/eragon-mdi/sso/internal/common/api"
+	"github.com/eragon-mdi/sso/internal/transport/clientip"
+	resttransportoauth "github.com/eragon-mdi/sso/internal/transport/http1/rest/sso/oauth"
+	resttransportwellknown "github.com/eragon-mdi/sso/internal/transport/http1/rest/sso/wellknown"
 	grpctransportauth "github.com/eragon-mdi/sso/internal/transport/http2/grpc/sso/auth"
+	grpctransportauthz "github.com/eragon-mdi/sso/internal/transport/http2/grpc/sso/authz"
 	grpctransportpermission "github.com/eragon-mdi/sso/internal/transport/http2/grpc/sso/permission"
+	grpctransportsession "github.com/eragon-mdi/sso/internal/transport/http2/grpc/sso/session"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 type Service interface {
 	grpctransportauth.AuthService
 	grpctransportpermission.PermissionService
+	grpctransportsession.SessionService
+	resttransportwellknown.WellKnownService
+	resttransportoauth.OAuthService
 }
 
 type transport struct {
 	api.AuthTransport
 	api.PermissionTransport
+	api.SessionTransport
+	api.WellKnownTransport
+	api.OAuthTransport
 }
 
 func New(s Service, l *zap.SugaredLogger) api.Transport {
 	return &transport{
 		AuthTransport:       grpctransportauth.New(s, l),
 		PermissionTransport: grpctransportpermission.New(s, l),
+		SessionTransport:    grpctransportsession.New(s, l),
+		WellKnownTransport:  resttransportwellknown.New(s, l),
+		OAuthTransport:      resttransportoauth.New(s, l),
+	}
+}
+
+// GRPCServerOptions — адрес клиента, кто вызывает gRPC-методы и можно ли ему (админские RPC)
+func GRPCServerOptions(s Service, l *zap.SugaredLogger, proxies clientip.Proxies) []grpc.ServerOption {
+	interceptors := append([]grpc.UnaryServerInterceptor{proxies.UnaryServerInterceptor()},
+		grpctransportauthz.UnaryInterceptors(s, l)...)
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(interceptors...),
 	}
 }
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+-- аккаунты, созданные до подтверждения email, считаются подтверждёнными: иначе их владельцы не смогут войти
+UPDATE users SET email_verified_at = now() WHERE email_verified_at IS NULL;
@@ -0,0 +1,15 @@
+-- app_id до реестра нигде не хранились, засеять их нечем: пока BUSSINES_LOGIC_APPS_ENFORCED=false,
+-- незарегистрированные app_id входят как раньше (см. «Реестр приложений» в readme сервиса)
+CREATE TABLE IF NOT EXISTS apps (
+    id INTEGER PRIMARY KEY CHECK (id > 0),
+    name TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'disabled')),
+    grant_types TEXT[] NOT NULL DEFAULT '{}',
+    access_token_ttl_seconds INTEGER NOT NULL DEFAULT 0 CHECK (access_token_ttl_seconds >= 0),
+    refresh_token_ttl_seconds INTEGER NOT NULL DEFAULT 0 CHECK (refresh_token_ttl_seconds >= 0),
+    email_domains TEXT[] NOT NULL DEFAULT '{}',
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
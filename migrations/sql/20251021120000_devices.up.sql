@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS devices (
+    -- выданные сервером id отрицательные: положительные device_id выбирали сами клиенты до реестра
+    id INTEGER PRIMARY KEY CHECK (id < 0),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS devices_user_id_idx ON devices (user_id);
@@ -0,0 +1,125 @@
+package ssoverify
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationHeader = "authorization"
+	bearerScheme        = "bearer "
+)
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext — владелец токена, положенный интерсептором
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// UnaryServerInterceptor — проверяет "authorization: Bearer <access>" и кладёт Principal в контекст.
+// skip — полные имена методов без проверки ("/grpc.health.v1.Health/Check")
+func (v *Verifier) UnaryServerInterceptor(skip ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if slices.Contains(skip, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		ctx, err := v.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (v *Verifier) StreamServerInterceptor(skip ...string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if slices.Contains(skip, info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		ctx, err := v.authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &principalStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// RequireRole — методы methods только для Principal с ролью role; ставится после интерсептора, кладущего Principal.
+// Нет Principal — Unauthenticated, нет роли — PermissionDenied; остальные методы не проверяются
+func RequireRole(role string, methods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !slices.Contains(methods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		p, ok := PrincipalFromContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+		if !p.HasRole(role) {
+			return nil, status.Errorf(codes.PermissionDenied, "role %s required", role)
+		}
+		return handler(ctx, req)
+	}
+}
+
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate — Unauthenticated на отсутствующий или непринятый токен,
+// Unavailable, если не удалось получить ключи или проверить отзыв
+func (v *Verifier) authenticate(ctx context.Context) (context.Context, error) {
+	token, ok := BearerToken(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	p, err := v.Verify(ctx, token)
+	switch {
+	case err == nil:
+		return ContextWithPrincipal(ctx, p), nil
+	case errors.Is(err, ErrInvalidToken):
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	default:
+		return nil, status.Error(codes.Unavailable, "token verification unavailable")
+	}
+}
+
+// BearerToken — access-токен из метаданных "authorization: Bearer <access>"
+func BearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return "", false
+	}
+
+	v := values[0]
+	if len(v) <= len(bearerScheme) || !strings.EqualFold(v[:len(bearerScheme)], bearerScheme) {
+		return "", false
+	}
+	return strings.TrimSpace(v[len(bearerScheme):]), true
+}
@@ -0,0 +1,161 @@
+package ssoverify
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	tokeneradapter "github.com/eragon-mdi/sso/internal/service/sso/auth/tokener"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	whoamiService = "ssoverify.test.Whoami"
+	methodPrivate = "/" + whoamiService + "/Private"
+	methodPublic  = "/" + whoamiService + "/Public"
+	methodStream  = "/" + whoamiService + "/Stream"
+)
+
+// whoami — user_id из Principal в контексте, пусто без него
+func whoami(ctx context.Context) *wrapperspb.StringValue {
+	p, _ := PrincipalFromContext(ctx)
+	return wrapperspb.String(p.UserID)
+}
+
+func unaryWhoami(fullMethod string) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: fullMethod[len(whoamiService)+2:],
+		Handler: func(_ any, ctx context.Context, dec func(any) error, ic grpc.UnaryServerInterceptor) (any, error) {
+			in := new(emptypb.Empty)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			h := func(ctx context.Context, _ any) (any, error) { return whoami(ctx), nil }
+			return ic(ctx, in, &grpc.UnaryServerInfo{FullMethod: fullMethod}, h)
+		},
+	}
+}
+
+func startGrpc(t *testing.T, v *Verifier) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(v.UnaryServerInterceptor(methodPublic)),
+		grpc.StreamInterceptor(v.StreamServerInterceptor()),
+	)
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: whoamiService,
+		HandlerType: (*any)(nil),
+		Methods:     []grpc.MethodDesc{unaryWhoami(methodPrivate), unaryWhoami(methodPublic)},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Stream",
+			ServerStreams: true,
+			Handler: func(_ any, ss grpc.ServerStream) error {
+				return ss.SendMsg(whoami(ss.Context()))
+			},
+		}},
+	}, struct{}{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func withBearer(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestInterceptors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sso := newSSO(t, "k1", tokeneradapter.AlgEdDSA)
+	v := newVerifier(t, Config{JWKSURL: sso.jwksURL(), Issuer: testIssuer, Audience: testAudience})
+	conn := startGrpc(t, v)
+	access, refresh := sso.issue(t, time.Minute, "admin")
+
+	callUnary := func(ctx context.Context, method string) (string, error) {
+		out := new(wrapperspb.StringValue)
+		err := conn.Invoke(ctx, method, &emptypb.Empty{}, out)
+		return out.GetValue(), err
+	}
+
+	t.Run("unary: principal in context", func(t *testing.T) {
+		got, err := callUnary(withBearer(ctx, access), methodPrivate)
+		require.NoError(t, err)
+		require.Equal(t, "user-1", got)
+	})
+
+	t.Run("unary: missing or rejected token", func(t *testing.T) {
+		for name, callCtx := range map[string]context.Context{
+			"no header":  ctx,
+			"no scheme":  metadata.AppendToOutgoingContext(ctx, "authorization", access),
+			"refresh":    withBearer(ctx, refresh),
+			"bad bearer": withBearer(ctx, "garbage"),
+		} {
+			_, err := callUnary(callCtx, methodPrivate)
+			require.Equal(t, codes.Unauthenticated, status.Code(err), name)
+		}
+	})
+
+	t.Run("unary: skipped method", func(t *testing.T) {
+		got, err := callUnary(ctx, methodPublic)
+		require.NoError(t, err)
+		require.Empty(t, got)
+	})
+
+	t.Run("stream: principal in context", func(t *testing.T) {
+		desc := &grpc.StreamDesc{ServerStreams: true}
+
+		cs, err := conn.NewStream(withBearer(ctx, access), desc, methodStream)
+		require.NoError(t, err)
+		require.NoError(t, cs.SendMsg(&emptypb.Empty{}))
+		require.NoError(t, cs.CloseSend())
+		out := new(wrapperspb.StringValue)
+		require.NoError(t, cs.RecvMsg(out))
+		require.Equal(t, "user-1", out.GetValue())
+
+		cs, err = conn.NewStream(ctx, desc, methodStream)
+		require.NoError(t, err)
+		require.Equal(t, codes.Unauthenticated, status.Code(cs.RecvMsg(new(wrapperspb.StringValue))))
+	})
+
+	t.Run("keys unavailable -> Unavailable", func(t *testing.T) {
+		down := newVerifier(t, Config{JWKSURL: "http://127.0.0.1:1/.well-known/jwks.json", Issuer: testIssuer})
+
+		conn := startGrpc(t, down)
+		err := conn.Invoke(withBearer(ctx, access), methodPrivate, &emptypb.Empty{}, new(wrapperspb.StringValue))
+		require.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+func TestRequireRole(t *testing.T) {
+	ic := RequireRole("admin", methodPrivate)
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+	call := func(ctx context.Context, method string) error {
+		_, err := ic(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+	ctx := context.Background()
+
+	require.Equal(t, codes.Unauthenticated, status.Code(call(ctx, methodPrivate)))
+	require.Equal(t, codes.PermissionDenied, status.Code(call(ContextWithPrincipal(ctx, Principal{UserID: "u1"}), methodPrivate)))
+	require.NoError(t, call(ContextWithPrincipal(ctx, Principal{UserID: "u1", Roles: []string{"admin"}}), methodPrivate))
+	require.NoError(t, call(ctx, methodPublic))
+}
@@ -0,0 +1,199 @@
+package ssoverify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+// незнакомый kid перечитывает JWKS не чаще этого: мусорные kid не должны долбить SSO
+const minRefetchInterval = 10 * time.Second
+
+var supportedAlgs = []string{"RS256", "ES256", "EdDSA"}
+
+type verificationKey struct {
+	alg string
+	pub crypto.PublicKey
+}
+
+// keyCache — ключи из JWKS по kid. Перечитывается по интервалу и на незнакомый kid (ротация у SSO);
+// при ошибке чтения остаются прежние ключи
+type keyCache struct {
+	url      string
+	client   *http.Client
+	interval time.Duration
+
+	mu        sync.Mutex
+	byKid     map[string]verificationKey
+	first     string    // active-ключ SSO публикует первым: им подписаны токены без kid
+	checkedAt time.Time // последняя попытка чтения, удачная или нет
+	lastErr   error
+}
+
+func newKeyCache(url string, client *http.Client, interval time.Duration) *keyCache {
+	return &keyCache{
+		url:      url,
+		client:   client,
+		interval: interval,
+	}
+}
+
+func (c *keyCache) get(ctx context.Context, kid string) (verificationKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checkedAt) > c.interval {
+		c.refresh(ctx)
+	}
+
+	k, ok := c.lookup(kid)
+	if !ok && time.Since(c.checkedAt) > minRefetchInterval {
+		c.refresh(ctx)
+		k, ok = c.lookup(kid)
+	}
+	if !ok {
+		// SSO недоступен: возможно, kid нового ключа, которого ещё не видели
+		if c.lastErr != nil {
+			return verificationKey{}, c.lastErr
+		}
+		return verificationKey{}, errors.Errorf("unknown signing key id %q", kid)
+	}
+	return k, nil
+}
+
+func (c *keyCache) lookup(kid string) (verificationKey, bool) {
+	if kid == "" {
+		kid = c.first
+	}
+	k, ok := c.byKid[kid]
+	return k, ok
+}
+
+func (c *keyCache) refresh(ctx context.Context) {
+	c.checkedAt = time.Now()
+
+	set, err := c.fetch(ctx)
+	if err != nil {
+		c.lastErr = errors.Wrap(ErrKeysUnavailable, err.Error())
+		return
+	}
+
+	byKid := make(map[string]verificationKey, len(set.Keys))
+	first := ""
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		k, err := parseJWK(j)
+		if err != nil {
+			// ключ нового типа не должен ломать проверку остальных
+			continue
+		}
+		if first == "" {
+			first = j.Kid
+		}
+		byKid[j.Kid] = k
+	}
+
+	c.byKid, c.first, c.lastErr = byKid, first, nil
+}
+
+func (c *keyCache) fetch(ctx context.Context) (domain.JWKS, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return domain.JWKS{}, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return domain.JWKS{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return domain.JWKS{}, errors.Errorf("jwks: status %d", resp.StatusCode)
+	}
+
+	var set domain.JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return domain.JWKS{}, errors.Wrap(err, "decode jwks")
+	}
+	return set, nil
+}
+
+// parseJWK — обратное к публикации ключей в SSO: RSA (n, e), EC P-256 (x, y), OKP Ed25519 (x)
+func parseJWK(j domain.JWK) (verificationKey, error) {
+	switch {
+	case j.Kty == "RSA" && (j.Alg == "" || j.Alg == "RS256"):
+		n, err := b64Int(j.N)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		e, err := b64Int(j.E)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return verificationKey{}, errors.New("rsa exponent too large")
+		}
+		return verificationKey{alg: "RS256", pub: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+
+	case j.Kty == "EC" && j.Crv == "P-256" && (j.Alg == "" || j.Alg == "ES256"):
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return verificationKey{}, errors.New("ec coordinates must be 32 bytes")
+		}
+		// точка вне кривой отбрасывается здесь, а не при проверке подписи
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return verificationKey{}, errors.Wrap(err, "ec point")
+		}
+		return verificationKey{alg: "ES256", pub: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+
+	case j.Kty == "OKP" && j.Crv == "Ed25519" && (j.Alg == "" || j.Alg == "EdDSA"):
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return verificationKey{}, errors.New("ed25519 key must be 32 bytes")
+		}
+		return verificationKey{alg: "EdDSA", pub: ed25519.PublicKey(x)}, nil
+	}
+
+	return verificationKey{}, errors.Errorf("unsupported jwk kty=%q crv=%q alg=%q", j.Kty, j.Crv, j.Alg)
+}
+
+func b64Int(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
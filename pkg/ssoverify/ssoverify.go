@@ -0,0 +1,155 @@
+// Package ssoverify — проверка access-токенов SSO на стороне resource-сервиса:
+// ключи берутся из JWKS SSO и кэшируются, claims разбираются в Principal.
+package ssoverify
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	"github.com/go-faster/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// как Cache-Control у /.well-known/jwks.json
+	DefaultRefreshInterval = 5 * time.Minute
+	defaultHTTPTimeout     = 5 * time.Second
+)
+
+var (
+	// ErrInvalidToken — токен не принят: подпись, срок, iss/aud, тип или отозван
+	ErrInvalidToken = errors.New("invalid sso token")
+	// ErrKeysUnavailable — JWKS не получен, проверить токен нечем
+	ErrKeysUnavailable = errors.New("sso signing keys unavailable")
+)
+
+type Config struct {
+	JWKSURL  string // https://<sso>/.well-known/jwks.json
+	Issuer   string // BUSSINES_LOGIC_TOKEN_ISSUER у SSO
+	Audience string // BUSSINES_LOGIC_TOKEN_AUDIENCE у SSO; пусто — aud не проверяется
+
+	// RefreshInterval — как часто перечитывать JWKS; незнакомый kid перечитывает сразу
+	RefreshInterval time.Duration
+	HTTPClient      *http.Client
+
+	// Revoked — необязательная проверка отзыва по jti, например через Auth.IsAccessTokenRevoked.
+	// Без неё отозванный access-токен принимается до exp
+	Revoked func(ctx context.Context, jti string) (bool, error)
+}
+
+// Principal — владелец проверенного access-токена: пользователь или машинный клиент (client_credentials)
+type Principal struct {
+	UserID    string
+	ClientID  string // машинный клиент; UserID тогда пуст
+	SessionID string // пусто у токенов, выданных до появления sid
+	TokenID   string // jti
+	AppID     int32
+	DeviceID  int32
+	Roles     []string // на момент выдачи токена
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+func (p Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// IsMachine — токен выдан сервису, а не пользователю
+func (p Principal) IsMachine() bool {
+	return p.ClientID != "" && p.UserID == ""
+}
+
+type Verifier struct {
+	cfg  Config
+	keys *keyCache
+}
+
+func New(cfg Config) (*Verifier, error) {
+	if cfg.JWKSURL == "" {
+		return nil, errors.New("ssoverify: JWKSURL required")
+	}
+	if cfg.Issuer == "" {
+		return nil, errors.New("ssoverify: Issuer required")
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = DefaultRefreshInterval
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	return &Verifier{
+		cfg:  cfg,
+		keys: newKeyCache(cfg.JWKSURL, cfg.HTTPClient, cfg.RefreshInterval),
+	}, nil
+}
+
+// Verify — подпись по JWKS, iss, aud, срок и тип access; refresh-токен не принимается
+func (v *Verifier) Verify(ctx context.Context, token string) (Principal, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(supportedAlgs),
+		jwt.WithIssuer(v.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if v.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.cfg.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(tk *jwt.Token) (any, error) {
+		kid, _ := tk.Header["kid"].(string)
+		k, err := v.keys.get(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if tk.Method.Alg() != k.alg {
+			return nil, errors.Errorf("kid %q: token alg %s, key alg %s", kid, tk.Method.Alg(), k.alg)
+		}
+		return k.pub, nil
+	}, opts...)
+	if err != nil {
+		if errors.Is(err, ErrKeysUnavailable) {
+			return Principal{}, err
+		}
+		return Principal{}, errors.Wrap(ErrInvalidToken, err.Error())
+	}
+
+	// разбор claims тот же, что у самого SSO
+	var m domain.Meta
+	if err := m.UnClaims(claims); err != nil {
+		return Principal{}, errors.Wrap(ErrInvalidToken, err.Error())
+	}
+	if m.Type != domain.TokenTypeAccess {
+		return Principal{}, errors.Wrapf(ErrInvalidToken, "unexpected token type: %s", m.Type)
+	}
+
+	if v.cfg.Revoked != nil {
+		revoked, err := v.cfg.Revoked(ctx, m.ID)
+		if err != nil {
+			return Principal{}, errors.Wrap(err, "check revocation")
+		}
+		if revoked {
+			return Principal{}, errors.Wrap(ErrInvalidToken, "token revoked")
+		}
+	}
+
+	return Principal{
+		UserID:    m.UserID,
+		ClientID:  m.ClientID,
+		SessionID: m.FamilyID,
+		TokenID:   m.ID,
+		AppID:     m.Ctx.AppId,
+		DeviceID:  m.Ctx.DeviceID,
+		Roles:     m.Roles,
+		Scopes:    m.Scopes,
+		ExpiresAt: m.Exp,
+	}, nil
+}
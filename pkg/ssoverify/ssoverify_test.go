@@ -0,0 +1,305 @@
+package ssoverify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eragon-mdi/sso/internal/domain"
+	authservice "github.com/eragon-mdi/sso/internal/service/sso/auth"
+	tokeneradapter "github.com/eragon-mdi/sso/internal/service/sso/auth/tokener"
+	resttransportwellknown "github.com/eragon-mdi/sso/internal/transport/http1/rest/sso/wellknown"
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testIssuer   = "sso-test"
+	testAudience = "clients-test"
+)
+
+// ssoFixture — SSO в процессе: настоящий tokener на каталоге ключей и настоящий JWKS-эндпоинт
+type ssoFixture struct {
+	dir     string
+	tokener atomic.Pointer[authservice.Tokener]
+	srv     *httptest.Server
+	fetches atomic.Int32
+}
+
+func newSSO(t *testing.T, kid, alg string) *ssoFixture {
+	t.Helper()
+
+	f := &ssoFixture{dir: t.TempDir()}
+	writeSigningKey(t, f.dir, kid, alg)
+	f.load(t, `{"active": "`+kid+`"}`)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		f.fetches.Add(1)
+		resttransportwellknown.New(jwksOnly{*f.tokener.Load()}, zap.NewNop().Sugar()).JWKS(w, r)
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+
+	return f
+}
+
+// jwksOnly — wellknown-транспорту нужен только JWKS; discovery в этих тестах не участвует
+type jwksOnly struct{ authservice.Tokener }
+
+func (jwksOnly) OpenIDConfiguration() (domain.OpenIDConfiguration, error) {
+	return domain.OpenIDConfiguration{}, domain.ErrNotFound
+}
+
+// rotate — новый active-ключ, прежний уходит в retiring
+func (f *ssoFixture) rotate(t *testing.T, oldKid, newKid, alg string) {
+	t.Helper()
+	writeSigningKey(t, f.dir, newKid, alg)
+	f.load(t, `{"active": "`+newKid+`", "retiring": ["`+oldKid+`"]}`)
+}
+
+func (f *ssoFixture) load(t *testing.T, manifest string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(f.dir, tokeneradapter.KeySetManifest), []byte(manifest), 0o600))
+
+	ks, err := tokeneradapter.LoadKeySet(f.dir, "")
+	require.NoError(t, err)
+	tk := tokeneradapter.New(ks, testIssuer, testAudience)
+	f.tokener.Store(&tk)
+}
+
+func (f *ssoFixture) jwksURL() string {
+	return f.srv.URL + "/.well-known/jwks.json"
+}
+
+// issue — пара токенов так же, как её выпускает Auth
+func (f *ssoFixture) issue(t *testing.T, ttl time.Duration, roles ...string) (access, refresh string) {
+	t.Helper()
+
+	am := domain.NewAccessMeta(ttl, "user-1", 3, 4)
+	am.SetFamily("family-1")
+	am.SetRoles(roles)
+	am.Scopes = []string{"profile"}
+	rm := domain.NewRefreshMeta(time.Hour, "user-1", 3, 4)
+	rm.SetFamily("family-1")
+
+	a, r, err := (*f.tokener.Load()).GenPair(am, rm)
+	require.NoError(t, err)
+	return string(a), string(r)
+}
+
+func writeSigningKey(t *testing.T, dir, kid, alg string) {
+	t.Helper()
+
+	var (
+		key crypto.Signer
+		err error
+	)
+	switch alg {
+	case tokeneradapter.AlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case tokeneradapter.AlgES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case tokeneradapter.AlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	}
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), pemBytes, 0o600))
+}
+
+func newVerifier(t *testing.T, cfg Config) *Verifier {
+	t.Helper()
+	v, err := New(cfg)
+	require.NoError(t, err)
+	return v
+}
+
+func TestNew_RequiresEndpointAndIssuer(t *testing.T) {
+	_, err := New(Config{Issuer: testIssuer})
+	require.Error(t, err)
+	_, err = New(Config{JWKSURL: "http://sso/.well-known/jwks.json"})
+	require.Error(t, err)
+}
+
+func TestVerifier_Algorithms(t *testing.T) {
+	ctx := context.Background()
+
+	for _, alg := range []string{tokeneradapter.AlgRS256, tokeneradapter.AlgES256, tokeneradapter.AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			sso := newSSO(t, "k1", alg)
+			v := newVerifier(t, Config{JWKSURL: sso.jwksURL(), Issuer: testIssuer, Audience: testAudience})
+
+			access, _ := sso.issue(t, time.Minute, "admin")
+			p, err := v.Verify(ctx, access)
+			require.NoError(t, err)
+			require.Equal(t, "user-1", p.UserID)
+		})
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	ctx := context.Background()
+	sso := newSSO(t, "k1", tokeneradapter.AlgEdDSA)
+	v := newVerifier(t, Config{JWKSURL: sso.jwksURL(), Issuer: testIssuer, Audience: testAudience})
+
+	access, refresh := sso.issue(t, time.Minute, "admin", "user")
+
+	t.Run("access gives typed principal", func(t *testing.T) {
+		p, err := v.Verify(ctx, access)
+		require.NoError(t, err)
+		require.Equal(t, "user-1", p.UserID)
+		require.Equal(t, "family-1", p.SessionID)
+		require.NotEmpty(t, p.TokenID)
+		require.Equal(t, int32(3), p.AppID)
+		require.Equal(t, int32(4), p.DeviceID)
+		require.Equal(t, []string{"admin", "user"}, p.Roles)
+		require.Equal(t, []string{"profile"}, p.Scopes)
+		require.True(t, p.HasRole("admin"))
+		require.False(t, p.HasRole("root"))
+		require.WithinDuration(t, time.Now().Add(time.Minute), p.ExpiresAt, 2*time.Second)
+	})
+
+	t.Run("machine client principal", func(t *testing.T) {
+		am := domain.NewClientAccessMeta(time.Minute, "client-1", []string{"orders:read"})
+		token, err := (*sso.tokener.Load()).GenAccess(am)
+		require.NoError(t, err)
+
+		p, err := v.Verify(ctx, string(token))
+		require.NoError(t, err)
+		require.True(t, p.IsMachine())
+		require.Empty(t, p.UserID)
+		require.Equal(t, "client-1", p.ClientID)
+		require.True(t, p.HasScope("orders:read"))
+		require.Empty(t, p.Roles)
+	})
+
+	t.Run("jwks cached between calls", func(t *testing.T) {
+		for range 3 {
+			_, err := v.Verify(ctx, access)
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(1), sso.fetches.Load())
+	})
+
+	t.Run("refresh rejected", func(t *testing.T) {
+		_, err := v.Verify(ctx, refresh)
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("expired rejected", func(t *testing.T) {
+		expired, _ := sso.issue(t, -time.Minute)
+		_, err := v.Verify(ctx, expired)
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("tampered rejected", func(t *testing.T) {
+		parts := strings.Split(access, ".")
+		other, _ := sso.issue(t, time.Minute, "root")
+		parts[1] = strings.Split(other, ".")[1]
+		_, err := v.Verify(ctx, strings.Join(parts, "."))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("garbage rejected", func(t *testing.T) {
+		_, err := v.Verify(ctx, "not-a-jwt")
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("foreign issuer and audience rejected", func(t *testing.T) {
+		other := newVerifier(t, Config{JWKSURL: sso.jwksURL(), Issuer: "other-sso"})
+		_, err := other.Verify(ctx, access)
+		require.ErrorIs(t, err, ErrInvalidToken)
+
+		other = newVerifier(t, Config{JWKSURL: sso.jwksURL(), Issuer: testIssuer, Audience: "other-clients"})
+		_, err = other.Verify(ctx, access)
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestVerifier_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	sso := newSSO(t, "k1", tokeneradapter.AlgRS256)
+	v := newVerifier(t, Config{JWKSURL: sso.jwksURL(), Issuer: testIssuer})
+
+	oldAccess, _ := sso.issue(t, time.Minute)
+	_, err := v.Verify(ctx, oldAccess)
+	require.NoError(t, err)
+
+	sso.rotate(t, "k1", "k2", tokeneradapter.AlgEdDSA)
+	newAccess, _ := sso.issue(t, time.Minute)
+
+	// незнакомый kid сразу после чтения JWKS не перечитывает его
+	_, err = v.Verify(ctx, newAccess)
+	require.Error(t, err)
+	require.Equal(t, int32(1), sso.fetches.Load())
+
+	v.keys.checkedAt = time.Now().Add(-time.Minute)
+	_, err = v.Verify(ctx, newAccess)
+	require.NoError(t, err, "unknown kid must trigger jwks refetch")
+	require.Equal(t, int32(2), sso.fetches.Load())
+
+	_, err = v.Verify(ctx, oldAccess)
+	require.NoError(t, err, "retiring key still verifies")
+}
+
+func TestVerifier_KeysUnavailable(t *testing.T) {
+	ctx := context.Background()
+	sso := newSSO(t, "k1", tokeneradapter.AlgEdDSA)
+	access, _ := sso.issue(t, time.Minute)
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	v := newVerifier(t, Config{JWKSURL: down.URL + "/.well-known/jwks.json", Issuer: testIssuer})
+	_, err := v.Verify(ctx, access)
+	require.ErrorIs(t, err, ErrKeysUnavailable)
+	require.NotErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerifier_Revoked(t *testing.T) {
+	ctx := context.Background()
+	sso := newSSO(t, "k1", tokeneradapter.AlgEdDSA)
+	access, _ := sso.issue(t, time.Minute)
+
+	denied := map[string]bool{}
+	v := newVerifier(t, Config{
+		JWKSURL: sso.jwksURL(),
+		Issuer:  testIssuer,
+		Revoked: func(_ context.Context, jti string) (bool, error) { return denied[jti], nil },
+	})
+
+	p, err := v.Verify(ctx, access)
+	require.NoError(t, err)
+
+	denied[p.TokenID] = true
+	_, err = v.Verify(ctx, access)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	failing := newVerifier(t, Config{
+		JWKSURL: sso.jwksURL(),
+		Issuer:  testIssuer,
+		Revoked: func(context.Context, string) (bool, error) { return false, errors.New("sso down") },
+	})
+	_, err = failing.Verify(ctx, access)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrInvalidToken)
+}